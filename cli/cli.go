@@ -39,6 +39,7 @@ import (
 	"github.com/hasura/graphql-engine/cli/v2/internal/statestore/settings"
 
 	"github.com/hasura/graphql-engine/cli/v2/internal/statestore/migrations"
+	"github.com/hasura/graphql-engine/cli/v2/internal/statestore/seeds"
 
 	"github.com/hasura/graphql-engine/cli/v2/internal/statestore"
 
@@ -73,6 +74,10 @@ const (
 	DefaultMigrationsDirectory = "migrations"
 	DefaultMetadataDirectory   = "metadata"
 	DefaultSeedsDirectory      = "seeds"
+
+	// MetadataOverlaysDirectory is the directory of the metadata directory
+	// holding an overlay per environment
+	MetadataOverlaysDirectory = "overlays"
 )
 
 const (
@@ -346,6 +351,33 @@ type Config struct {
 	SeedsDirectory string `yaml:"seeds_directory,omitempty"`
 	// ActionConfig defines the config required to create or generate codegen for an action.
 	ActionConfig *types.ActionExecutionConfig `yaml:"actions,omitempty"`
+	// MigrationsLint configures the rules used by migrate lint.
+	MigrationsLint *MigrationsLintConfig `yaml:"migrations_lint,omitempty"`
+	// MetadataEnv selects the overlay directory of the metadata directory
+	// (overlays/<env>) merged over the metadata files when building metadata.
+	MetadataEnv string `yaml:"metadata_env,omitempty"`
+	// MetadataLayout configures how metadata export splits objects into files.
+	MetadataLayout *MetadataLayoutConfig `yaml:"metadata_layout,omitempty"`
+}
+
+// MigrationsLintConfig configures the rules used to lint migrations.
+type MigrationsLintConfig struct {
+	// Rules maps lint rule names to a severity: off, warn or error.
+	Rules map[string]string `yaml:"rules,omitempty"`
+}
+
+// MetadataLayoutConfig configures how metadata export splits objects into
+// files. Metadata is built from files of any layout.
+type MetadataLayoutConfig struct {
+	// SplitTables exports the permissions of a table to a file per role and
+	// its relationships to a file per relationship.
+	SplitTables bool `yaml:"split_tables,omitempty"`
+	// SplitRemoteSchemas exports each remote schema to its own directory,
+	// remote_schemas/<name>, with the schema of each role in a file.
+	SplitRemoteSchemas bool `yaml:"split_remote_schemas,omitempty"`
+	// SplitActions exports each action to actions/<name>.yaml and
+	// actions/<name>.graphql.
+	SplitActions bool `yaml:"split_actions,omitempty"`
 }
 
 // ExecutionContext contains various contextual information required by the cli
@@ -375,8 +407,14 @@ type ExecutionContext struct {
 	Envfile string
 	// MigrationDir is the name of directory where migrations are stored.
 	MigrationDir string
+	// MigrationsSourceURL is the URL of a source driver (eg: git://HEAD~3:migrations, bundle://migrations.tar.gz)
+	// from which migrations are read instead of MigrationDir.
+	MigrationsSourceURL string
 	// MetadataDir is the name of directory where metadata files are stored.
 	MetadataDir string
+	// MetadataOverlayDir is the overlay of the environment selected by
+	// Config.MetadataEnv, empty if there is none.
+	MetadataOverlayDir string
 	// Seed directory -- directory in which seed files are to be stored
 	SeedsDirectory string
 	// ConfigFile is the file where endpoint etc. are stored.
@@ -412,6 +450,11 @@ type ExecutionContext struct {
 	// NoColor indicates if the outputs shouldn't be colorized
 	NoColor bool
 
+	// OutputFormat is the format in which commands report their result
+	OutputFormat OutputFormat
+	// output is the result of the command written by WriteOutput
+	output interface{}
+
 	// Telemetry collects the telemetry data throughout the execution
 	Telemetry *telemetry.Data
 
@@ -492,6 +535,10 @@ func (ec *ExecutionContext) Prepare() error {
 	// set logger
 	ec.setupLogger()
 
+	if err := ec.OutputFormat.validate(); err != nil {
+		return err
+	}
+
 	// populate version
 	ec.setVersion()
 
@@ -632,53 +679,9 @@ func (ec *ExecutionContext) Validate() error {
 		return errors.Wrap(err, "ensuring codegen-assets repo failed")
 	}
 
-	// validate execution directory
-	err = ec.validateDirectory()
+	err = ec.ValidateProject()
 	if err != nil {
-		return errors.Wrap(err, "validating current directory failed")
-	}
-
-	// load .env file
-	err = ec.loadEnvfile()
-	if err != nil {
-		return errors.Wrap(err, "loading .env file failed")
-	}
-
-	// set names of config file
-	ec.ConfigFile = filepath.Join(ec.ExecutionDirectory, "config.yaml")
-
-	// read config and parse the values into Config
-	err = ec.readConfig()
-	if err != nil {
-		return errors.Wrap(err, "cannot read config")
-	}
-
-	// set name of migration directory
-	ec.MigrationDir = filepath.Join(ec.ExecutionDirectory, ec.Config.MigrationsDirectory)
-	if _, err := os.Stat(ec.MigrationDir); os.IsNotExist(err) {
-		err = os.MkdirAll(ec.MigrationDir, os.ModePerm)
-		if err != nil {
-			return errors.Wrap(err, "cannot create migrations directory")
-		}
-	}
-
-	ec.SeedsDirectory = filepath.Join(ec.ExecutionDirectory, ec.Config.SeedsDirectory)
-	if _, err := os.Stat(ec.SeedsDirectory); os.IsNotExist(err) {
-		err = os.MkdirAll(ec.SeedsDirectory, os.ModePerm)
-		if err != nil {
-			return errors.Wrap(err, "cannot create seeds directory")
-		}
-	}
-
-	if ec.Config.Version >= V2 && ec.Config.MetadataDirectory != "" {
-		// set name of metadata directory
-		ec.MetadataDir = filepath.Join(ec.ExecutionDirectory, ec.Config.MetadataDirectory)
-		if _, err := os.Stat(ec.MetadataDir); os.IsNotExist(err) {
-			err = os.MkdirAll(ec.MetadataDir, os.ModePerm)
-			if err != nil {
-				return errors.Wrap(err, "cannot create metadata directory")
-			}
-		}
+		return err
 	}
 
 	ec.Logger.Debug("graphql engine endpoint: ", ec.Config.ServerConfig.Endpoint)
@@ -762,6 +765,68 @@ func (ec *ExecutionContext) Validate() error {
 	return nil
 }
 
+// ValidateProject validates the project directory and reads its config,
+// without requiring a server. It is called by Validate, commands which
+// work offline call it instead.
+func (ec *ExecutionContext) ValidateProject() error {
+	// validate execution directory
+	err := ec.validateDirectory()
+	if err != nil {
+		return errors.Wrap(err, "validating current directory failed")
+	}
+
+	// load .env file
+	err = ec.loadEnvfile()
+	if err != nil {
+		return errors.Wrap(err, "loading .env file failed")
+	}
+
+	// set names of config file
+	ec.ConfigFile = filepath.Join(ec.ExecutionDirectory, "config.yaml")
+
+	// read config and parse the values into Config
+	err = ec.readConfig()
+	if err != nil {
+		return errors.Wrap(err, "cannot read config")
+	}
+
+	// set name of migration directory
+	ec.MigrationDir = filepath.Join(ec.ExecutionDirectory, ec.Config.MigrationsDirectory)
+	if _, err := os.Stat(ec.MigrationDir); os.IsNotExist(err) {
+		err = os.MkdirAll(ec.MigrationDir, os.ModePerm)
+		if err != nil {
+			return errors.Wrap(err, "cannot create migrations directory")
+		}
+	}
+
+	ec.SeedsDirectory = filepath.Join(ec.ExecutionDirectory, ec.Config.SeedsDirectory)
+	if _, err := os.Stat(ec.SeedsDirectory); os.IsNotExist(err) {
+		err = os.MkdirAll(ec.SeedsDirectory, os.ModePerm)
+		if err != nil {
+			return errors.Wrap(err, "cannot create seeds directory")
+		}
+	}
+
+	if ec.Config.Version >= V2 && ec.Config.MetadataDirectory != "" {
+		// set name of metadata directory
+		ec.MetadataDir = filepath.Join(ec.ExecutionDirectory, ec.Config.MetadataDirectory)
+		if _, err := os.Stat(ec.MetadataDir); os.IsNotExist(err) {
+			err = os.MkdirAll(ec.MetadataDir, os.ModePerm)
+			if err != nil {
+				return errors.Wrap(err, "cannot create metadata directory")
+			}
+		}
+		if ec.Config.MetadataEnv != "" {
+			ec.MetadataOverlayDir = filepath.Join(ec.MetadataDir, MetadataOverlaysDirectory, ec.Config.MetadataEnv)
+			if _, err := os.Stat(ec.MetadataOverlayDir); err != nil {
+				return errors.Wrapf(err, "cannot find metadata overlay of environment %s", ec.Config.MetadataEnv)
+			}
+		}
+	}
+
+	return nil
+}
+
 func (ec *ExecutionContext) checkServerVersion() error {
 	v, err := version.FetchServerVersion(ec.Config.ServerConfig.GetVersionEndpoint(), ec.Config.ServerConfig.HTTPClient)
 	if err != nil {
@@ -854,6 +919,7 @@ func (ec *ExecutionContext) readConfig() error {
 		MetadataDirectory:   v.GetString("metadata_directory"),
 		MigrationsDirectory: v.GetString("migrations_directory"),
 		SeedsDirectory:      v.GetString("seeds_directory"),
+		MetadataEnv:         v.GetString("metadata_env"),
 		ActionConfig: &types.ActionExecutionConfig{
 			Kind:                  v.GetString("actions.kind"),
 			HandlerWebhookBaseURL: v.GetString("actions.handler_webhook_baseurl"),
@@ -864,6 +930,17 @@ func (ec *ExecutionContext) readConfig() error {
 			},
 		},
 	}
+	if rules := v.GetStringMapString("migrations_lint.rules"); len(rules) > 0 {
+		ec.Config.MigrationsLint = &MigrationsLintConfig{Rules: rules}
+	}
+	layout := MetadataLayoutConfig{
+		SplitTables:        v.GetBool("metadata_layout.split_tables"),
+		SplitRemoteSchemas: v.GetBool("metadata_layout.split_remote_schemas"),
+		SplitActions:       v.GetBool("metadata_layout.split_actions"),
+	}
+	if layout != (MetadataLayoutConfig{}) {
+		ec.Config.MetadataLayout = &layout
+	}
 	if !ec.Config.Version.IsValid() {
 		return ErrInvalidConfigVersion
 	}
@@ -974,14 +1051,25 @@ func GetCommonMetadataOps(ec *ExecutionContext) hasura.CommonMetadataOperations
 	return ec.APIClient.V1Metadata
 }
 
-func GetMigrationsStateStore(ec *ExecutionContext) statestore.MigrationsStateStore {
+// GetMigrationsStateStore returns the store of the migrations state of a
+// database of the given kind
+func GetMigrationsStateStore(ec *ExecutionContext, sourceKind hasura.SourceKind) statestore.MigrationsStateStore {
 	if ec.Config.Version <= V2 {
 		if !ec.HasMetadataV3 {
 			return migrations.NewMigrationStateStoreHdbTable(ec.APIClient.V1Query, migrations.DefaultSchema, migrations.DefaultMigrationsTable)
 		}
 		return migrations.NewMigrationStateStoreHdbTable(ec.APIClient.V2Query, migrations.DefaultSchema, migrations.DefaultMigrationsTable)
 	}
-	return migrations.NewCatalogStateStore(statestore.NewCLICatalogState(ec.APIClient.V1Metadata))
+	return migrations.NewCatalogStateStore(statestore.NewCLICatalogState(ec.APIClient.V1Metadata), migrations.NewLocker(ec.APIClient.V2Query, sourceKind, migrations.DefaultSchema, migrations.DefaultMigrationsLockTable))
+}
+
+// GetSeedsStateStore returns the store of the applied seed files, seed files
+// are tracked in the catalog state from config v3 and are not tracked before
+func GetSeedsStateStore(ec *ExecutionContext) statestore.SeedsStateStore {
+	if ec.Config.Version <= V2 {
+		return nil
+	}
+	return seeds.NewCatalogStateStore(statestore.NewCLICatalogState(ec.APIClient.V1Metadata))
 }
 
 func GetSettingsStateStore(ec *ExecutionContext, databaseName string) statestore.SettingsStateStore {
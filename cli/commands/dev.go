@@ -0,0 +1,356 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hasura/graphql-engine/cli/v2"
+	"github.com/hasura/graphql-engine/cli/v2/internal/hasura"
+	"github.com/hasura/graphql-engine/cli/v2/internal/metadataobject"
+	"github.com/hasura/graphql-engine/cli/v2/internal/metadatautil"
+	"github.com/hasura/graphql-engine/cli/v2/internal/scripts"
+	"github.com/hasura/graphql-engine/cli/v2/internal/watcher"
+	"github.com/hasura/graphql-engine/cli/v2/migrate"
+	"github.com/hasura/graphql-engine/cli/v2/seed"
+	"github.com/hasura/graphql-engine/cli/v2/util"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// NewDevCmd returns the dev command
+func NewDevCmd(ec *cli.ExecutionContext) *cobra.Command {
+	v := viper.New()
+	opts := &DevOptions{
+		EC: ec,
+	}
+	devCmd := &cobra.Command{
+		Use:   "dev",
+		Short: "Apply migrations, metadata and seeds to the server on each change of their files",
+		Long: `Apply the migrations and metadata of the project, then watch the migrations, metadata and seeds directories and apply their changes while developing:
+  - new migrations of a database are applied
+  - metadata is applied when its files change or migrations were applied, inconsistent objects are printed right away
+  - seed files created while watching are applied once
+
+Changes are applied once files stopped changing for the debounce delay.`,
+		Example: `  # Apply the project and watch for changes:
+  hasura dev
+
+  # Merge the metadata overlay of an environment:
+  hasura dev --env staging
+
+  # Wait for files to stop changing for 2 seconds before applying them:
+  hasura dev --debounce 2s`,
+		SilenceUsage: true,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			ec.Viper = v
+			err := ec.Prepare()
+			if err != nil {
+				return err
+			}
+			if err := ec.Validate(); err != nil {
+				return err
+			}
+			return scripts.CheckIfUpdateToConfigV3IsRequired(ec)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return opts.Run()
+		},
+	}
+	f := devCmd.Flags()
+
+	f.DurationVar(&opts.Debounce, "debounce", 500*time.Millisecond, "time to wait for files to stop changing before applying them")
+
+	f.String("endpoint", "", "http(s) endpoint for Hasura GraphQL engine")
+	f.String("admin-secret", "", "admin secret for Hasura GraphQL engine")
+	f.String("access-key", "", "access key for Hasura GraphQL engine")
+	f.MarkDeprecated("access-key", "use --admin-secret instead")
+	f.Bool("insecure-skip-tls-verify", false, "skip TLS verification and disable cert checking (default: false)")
+	f.String("certificate-authority", "", "path to a cert file for the certificate authority")
+	f.String("env", "", "environment whose overlay (metadata/overlays/<env>) is merged over the metadata files, eg: prod")
+
+	util.BindPFlag(v, "endpoint", f.Lookup("endpoint"))
+	util.BindPFlag(v, "admin_secret", f.Lookup("admin-secret"))
+	util.BindPFlag(v, "access_key", f.Lookup("access-key"))
+	util.BindPFlag(v, "insecure_skip_tls_verify", f.Lookup("insecure-skip-tls-verify"))
+	util.BindPFlag(v, "certificate_authority", f.Lookup("certificate-authority"))
+	util.BindPFlag(v, "metadata_env", f.Lookup("env"))
+
+	return devCmd
+}
+
+type DevOptions struct {
+	EC *cli.ExecutionContext
+
+	// Debounce is the time to wait for files to stop changing
+	Debounce time.Duration
+
+	// migrators are the migrate instances of the databases by name, kept
+	// for the whole session like the one of the console API server
+	migrators map[string]*migrate.Migrate
+	// metadata is the last metadata applied
+	metadata []byte
+	// seedFiles are the seed files which were present when the session
+	// started or were applied since, seed files are applied once
+	seedFiles map[string]bool
+}
+
+func (o *DevOptions) Run() error {
+	o.migrators = map[string]*migrate.Migrate{}
+	defer func() {
+		for _, m := range o.migrators {
+			m.Close()
+		}
+	}()
+	o.seedFiles = map[string]bool{}
+	err := filepath.Walk(o.EC.SeedsDirectory, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			o.seedFiles[path] = true
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("reading seeds directory: %w", err)
+	}
+
+	migrationsApplied := o.applyMigrations(nil)
+	o.applyMetadata(migrationsApplied)
+
+	w, err := watcher.New(o.Debounce, o.EC.MigrationDir, o.EC.MetadataDir, o.EC.SeedsDirectory)
+	if err != nil {
+		return fmt.Errorf("watching project files: %w", err)
+	}
+	defer w.Close()
+	o.EC.Logger.Info("Watching migrations, metadata and seeds for changes, press Ctrl+C to stop")
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	for {
+		select {
+		case changes := <-w.Changes:
+			o.apply(changes)
+		case err := <-w.Errors:
+			o.EC.Logger.Warnf("watching project files: %v", err)
+		case <-interrupt:
+			return nil
+		}
+	}
+}
+
+// apply applies the migrations, metadata and seeds of the changed files, in
+// this order since metadata and seeds depend on the database schema
+func (o *DevOptions) apply(changes []string) {
+	var databases, seedFiles []string
+	metadataChanged := false
+	for _, path := range changes {
+		if rel, ok := relativePath(o.EC.MigrationDir, path); ok {
+			database := ""
+			if o.EC.Config.Version >= cli.V3 {
+				database = strings.Split(rel, string(filepath.Separator))[0]
+			}
+			databases = append(databases, database)
+		} else if _, ok := relativePath(o.EC.MetadataDir, path); ok {
+			metadataChanged = true
+		} else if _, ok := relativePath(o.EC.SeedsDirectory, path); ok {
+			seedFiles = append(seedFiles, path)
+		}
+	}
+	migrationsApplied := false
+	if len(databases) > 0 {
+		migrationsApplied = o.applyMigrations(databases)
+	}
+	if metadataChanged || migrationsApplied {
+		o.applyMetadata(migrationsApplied)
+	}
+	if len(seedFiles) > 0 {
+		o.applySeeds(seedFiles)
+	}
+}
+
+// databases returns the databases of the project by name, the databases
+// connected to the server for config v3
+func (o *DevOptions) databases() (map[string]cli.Source, error) {
+	if o.EC.Config.Version < cli.V3 {
+		return map[string]cli.Source{"": {Kind: hasura.SourceKindPG}}, nil
+	}
+	sources, err := metadatautil.GetSourcesAndKind(o.EC.APIClient.V1Metadata.ExportMetadata)
+	if err != nil {
+		return nil, fmt.Errorf("determining list of connected databases: %w", err)
+	}
+	databases := map[string]cli.Source{}
+	for _, source := range sources {
+		databases[source.Name] = cli.Source{Name: source.Name, Kind: source.Kind}
+	}
+	return databases, nil
+}
+
+// applyMigrations applies the pending migrations of the databases in names,
+// of all databases if names is empty. It reports whether a migration was
+// applied.
+func (o *DevOptions) applyMigrations(names []string) bool {
+	databases, err := o.databases()
+	if err != nil {
+		o.EC.Logger.Error(err)
+		return false
+	}
+	if len(names) == 0 {
+		for name := range databases {
+			names = append(names, name)
+		}
+	}
+	applied := false
+	done := map[string]bool{}
+	for _, name := range names {
+		if done[name] {
+			continue
+		}
+		done[name] = true
+		database, ok := databases[name]
+		if !ok {
+			o.EC.Logger.Warnf("skipping migrations of database %s, it is not connected to the server", name)
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(o.EC.MigrationDir, name)); err != nil {
+			continue
+		}
+		m, err := o.migrator(database)
+		if err != nil {
+			o.EC.Logger.Errorf("applying migrations on database %s: %v", name, err)
+			continue
+		}
+		before := len(m.Applied)
+		if err := m.ReScan(); err != nil {
+			o.EC.Logger.Errorf("reading migrations of database %s: %v", name, err)
+			continue
+		}
+		err = m.Up()
+		if err != nil && !isNothingToApply(err) {
+			o.EC.Logger.Errorf("applying migrations on database %s: %v", name, err)
+		}
+		for _, migration := range m.Applied[before:] {
+			o.EC.Logger.Infof("applied migration %d_%s on database %s", migration.Version, migration.Name, displayDatabase(name))
+			applied = true
+		}
+	}
+	return applied
+}
+
+// migrator returns the migrate instance of database, it is created on
+// first use
+func (o *DevOptions) migrator(database cli.Source) (*migrate.Migrate, error) {
+	if m, ok := o.migrators[database.Name]; ok {
+		return m, nil
+	}
+	m, err := migrate.NewMigrate(o.EC, true, database.Name, database.Kind)
+	if err != nil {
+		return nil, err
+	}
+	o.migrators[database.Name] = m
+	return m, nil
+}
+
+// applyMetadata applies the metadata of the project if it changed since it
+// was last applied, or if force is set, and prints the inconsistent objects
+func (o *DevOptions) applyMetadata(force bool) {
+	handler := metadataobject.NewHandlerFromEC(o.EC)
+	metadata, err := handler.MakeJSONMetadata()
+	if err != nil {
+		o.EC.Logger.Errorf("building metadata: %v", err)
+		return
+	}
+	if !force && bytes.Equal(metadata, o.metadata) {
+		o.EC.Logger.Debug("metadata did not change, skipping apply")
+		return
+	}
+	if o.EC.Config.Version == cli.V2 {
+		_, err = handler.V1ApplyMetadata()
+	} else {
+		_, err = handler.V2ApplyMetadata()
+	}
+	if err != nil {
+		o.EC.Logger.Error(errorApplyingMetadata(err))
+		return
+	}
+	o.metadata = metadata
+	o.EC.Logger.Info("Metadata applied")
+
+	isConsistent, objects, err := handler.GetInconsistentMetadata()
+	if err != nil {
+		o.EC.Logger.Errorf("getting inconsistent metadata: %v", err)
+		return
+	}
+	if !isConsistent {
+		o.EC.Logger.Warn("Metadata is inconsistent")
+		fmt.Fprintln(o.EC.Stdout, inconsistentObjectsTable(objects))
+	}
+}
+
+// applySeeds applies the seed files created since the session started
+func (o *DevOptions) applySeeds(paths []string) {
+	var databases map[string]cli.Source
+	for _, path := range paths {
+		if !seed.IsSeedFile(path) {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		rel, _ := relativePath(o.EC.SeedsDirectory, path)
+		if o.seedFiles[path] {
+			o.EC.Logger.Infof("seed file %s changed, seed files are applied once while watching, run \"hasura seed apply --file\" to apply it again", rel)
+			continue
+		}
+		if databases == nil {
+			var err error
+			if databases, err = o.databases(); err != nil {
+				o.EC.Logger.Error(err)
+				return
+			}
+		}
+		name, file := "", rel
+		if o.EC.Config.Version >= cli.V3 {
+			parts := strings.SplitN(rel, string(filepath.Separator), 2)
+			if len(parts) < 2 {
+				continue
+			}
+			name, file = parts[0], parts[1]
+		}
+		database, ok := databases[name]
+		if !ok {
+			o.EC.Logger.Warnf("skipping seed file %s, database %s is not connected to the server", rel, name)
+			continue
+		}
+		driver := getSeedDriver(o.EC.Config.Version)
+		if _, err := driver.ApplySeedsToDatabase(afero.NewOsFs(), o.EC.SeedsDirectory, []string{file}, database); err != nil {
+			o.EC.Logger.Errorf("applying seed file %s: %v", rel, err)
+			continue
+		}
+		o.seedFiles[path] = true
+		o.EC.Logger.Infof("applied seed file %s on database %s", rel, displayDatabase(name))
+	}
+}
+
+// relativePath returns path relative to dir if path is in dir
+func relativePath(dir, path string) (string, bool) {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
+func displayDatabase(name string) string {
+	if name == "" {
+		return "default"
+	}
+	return name
+}
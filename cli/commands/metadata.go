@@ -48,6 +48,8 @@ func NewMetadataCmd(ec *cli.ExecutionContext) *cobra.Command {
 		newMetadataReloadCmd(ec),
 		newMetadataApplyCmd(ec),
 		newMetadataInconsistencyCmd(ec),
+		newMetadataValidateCmd(ec, v),
+		newMetadataPlanCmd(ec),
 	)
 
 	f := metadataCmd.PersistentFlags()
@@ -58,12 +60,14 @@ func NewMetadataCmd(ec *cli.ExecutionContext) *cobra.Command {
 	f.MarkDeprecated("access-key", "use --admin-secret instead")
 	f.Bool("insecure-skip-tls-verify", false, "skip TLS verification and disable cert checking (default: false)")
 	f.String("certificate-authority", "", "path to a cert file for the certificate authority")
+	f.String("env", "", "environment whose overlay (metadata/overlays/<env>) is merged over the metadata files, eg: prod")
 
 	util.BindPFlag(v, "endpoint", f.Lookup("endpoint"))
 	util.BindPFlag(v, "admin_secret", f.Lookup("admin-secret"))
 	util.BindPFlag(v, "access_key", f.Lookup("access-key"))
 	util.BindPFlag(v, "insecure_skip_tls_verify", f.Lookup("insecure-skip-tls-verify"))
 	util.BindPFlag(v, "certificate_authority", f.Lookup("certificate-authority"))
+	util.BindPFlag(v, "metadata_env", f.Lookup("env"))
 
 	return metadataCmd
 }
@@ -1,11 +1,18 @@
 package commands
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/hasura/graphql-engine/cli/v2"
+	"github.com/hasura/graphql-engine/cli/v2/internal/hasura"
 	"github.com/hasura/graphql-engine/cli/v2/internal/metadataobject"
+	"github.com/hasura/graphql-engine/cli/v2/util"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
@@ -24,7 +31,16 @@ func newMetadataApplyCmd(ec *cli.ExecutionContext) *cobra.Command {
   hasura metadata apply --admin-secret "<admin-secret>"
 
   # Apply metadata to an instance specified by the flag:
-  hasura metadata apply --endpoint "<endpoint>"`,
+  hasura metadata apply --endpoint "<endpoint>"
+
+  # Apply only the metadata of a table, leaving the other objects on the server as they are:
+  hasura metadata apply --only databases/default/tables/public_orders.yaml
+
+  # Apply only the metadata of a database and of a remote schema:
+  hasura metadata apply --source default --remote-schema countries
+
+  # Apply a plan made by metadata plan, refused if metadata on the server changed since:
+  hasura metadata apply --plan metadata.plan.json`,
 		SilenceUsage: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if opts.FromFile {
@@ -46,6 +62,11 @@ func newMetadataApplyCmd(ec *cli.ExecutionContext) *cobra.Command {
 
 	f.BoolVar(&opts.DryRun, "dry-run", false, "show metadata generated from project directory without applying to server.  generated metadata will be printed as JSON by default, use -o flag for other display formats")
 	f.StringVarP(&opts.rawOutput, "output", "o", "", `specify an output format to show applied metadata. Allowed values: json, yaml (default "json")`)
+	f.StringSliceVar(&opts.Selection.Files, "only", nil, "apply only the objects of metadata files, relative to the metadata directory (eg: databases/default/tables/public_orders.yaml). Objects are created, changed and dropped one by one instead of replacing the whole metadata")
+	f.StringSliceVar(&opts.Selection.Sources, "source", nil, "apply only the metadata of a database, can be repeated")
+	f.StringVar(&opts.PlanFile, "plan", "", "apply a plan made by metadata plan instead of the project metadata, the plan is refused if metadata on the server changed since it was made")
+	f.BoolVar(&opts.Force, "force", false, "when set applies the plan without any confirmation")
+	f.StringSliceVar(&opts.Selection.RemoteSchemas, "remote-schema", nil, "apply only the metadata of a remote schema, can be repeated")
 	return metadataApplyCmd
 }
 
@@ -55,11 +76,32 @@ type MetadataApplyOptions struct {
 	FromFile  bool
 	DryRun    bool
 	rawOutput string
+
+	// Selection selects the objects of a partial apply
+	Selection metadataobject.ObjectSelection
+	// PlanFile is a plan made by metadata plan
+	PlanFile string
+	Force    bool
+}
+
+func (o *MetadataApplyOptions) isPartial() bool {
+	return len(o.Selection.Files) > 0 || len(o.Selection.Sources) > 0 || len(o.Selection.RemoteSchemas) > 0
 }
 
 func (o *MetadataApplyOptions) Run() error {
 	metadataHandler := metadataobject.NewHandlerFromEC(o.EC)
+	if o.PlanFile != "" {
+		if o.isPartial() || o.DryRun {
+			return fmt.Errorf("--plan cannot be used with --dry-run, --only, --source or --remote-schema")
+		}
+		return o.applyPlan()
+	}
+	if o.isPartial() {
+		return o.partialApply(metadataHandler)
+	}
 	if !o.DryRun {
+		output := &metadataApplyOutput{IsConsistent: true, InconsistentObjects: []interface{}{}}
+		start := time.Now()
 		o.EC.Spin("Applying metadata...")
 		if o.EC.Config.Version == cli.V2 {
 			_, err := metadataHandler.V1ApplyMetadata()
@@ -76,13 +118,27 @@ func (o *MetadataApplyOptions) Run() error {
 			}
 			if !r.IsConsistent {
 				o.EC.Logger.Warn("Metadata is inconsistent")
+				output.IsConsistent = false
+				if r.InconsistentObjects != nil {
+					output.InconsistentObjects = r.InconsistentObjects
+				}
 			}
 			o.EC.Logger.Debug("metadata applied using v2 replace_metadata")
 		}
+		output.DurationMS = time.Since(start).Milliseconds()
+		o.EC.SetOutput(output)
 		if len(o.rawOutput) <= 0 {
 			o.EC.Logger.Info("Metadata applied")
 		}
 
+		if o.EC.IsJSONOutput() {
+			metadata, err := getMetadataFromServer(o.EC)
+			if err != nil {
+				return err
+			}
+			output.Metadata = metadata
+			return nil
+		}
 		if len(o.rawOutput) != 0 {
 			// if not a dry run fetch metadata from and server and print it to stdout
 			return getMetadataFromServerAndWriteToStdoutByFormat(o.EC, rawOutputFormat(o.rawOutput))
@@ -112,6 +168,142 @@ func (o *MetadataApplyOptions) Run() error {
 	return nil
 }
 
+// partialApply applies the selected objects with the metadata API calls
+// creating, changing and dropping them, sent as one bulk request
+func (o *MetadataApplyOptions) partialApply(metadataHandler *metadataobject.Handler) error {
+	if o.EC.Config.Version < cli.V3 {
+		return fmt.Errorf("applying selected objects requires config v3 or later")
+	}
+	if o.rawOutput == string(rawOutputFormatJSON) {
+		o.EC.OutputFormat = cli.OutputFormatJSON
+	}
+	selection := o.Selection
+	selection.Files = nil
+	for _, file := range o.Selection.Files {
+		// files can be given relative to the project directory as well
+		file = filepath.ToSlash(filepath.Clean(file))
+		file = strings.TrimPrefix(file, filepath.ToSlash(filepath.Clean(o.EC.Config.MetadataDirectory))+"/")
+		selection.Files = append(selection.Files, file)
+	}
+	o.EC.Spin("Computing changes of the selected objects...")
+	operations, err := metadataHandler.PartialMetadataOperations(selection, o.EC.MetadataDir)
+	o.EC.Spinner.Stop()
+	if err != nil {
+		return errorApplyingMetadata(err)
+	}
+	if o.DryRun {
+		// show the metadata API calls which would be sent
+		b, err := json.Marshal(hasura.RequestBody{Type: "bulk", Args: operations})
+		if err != nil {
+			return err
+		}
+		format := rawOutputFormatJSON
+		if len(o.rawOutput) != 0 {
+			format = rawOutputFormat(o.rawOutput)
+		}
+		return writeByOutputFormat(o.EC.Stdout, b, format)
+	}
+	output := &metadataApplyOutput{IsConsistent: true, InconsistentObjects: []interface{}{}, Operations: len(operations)}
+	o.EC.SetOutput(output)
+	if len(operations) == 0 {
+		o.EC.Logger.Info("Selected objects are up to date")
+		return nil
+	}
+	start := time.Now()
+	o.EC.Spin("Applying metadata...")
+	err = metadataHandler.ApplyMetadataOperations(operations)
+	o.EC.Spinner.Stop()
+	if err != nil {
+		return errorApplyingMetadata(err)
+	}
+	output.DurationMS = time.Since(start).Milliseconds()
+	o.EC.Logger.Infof("Metadata applied (%d operations)", len(operations))
+	return nil
+}
+
+// applyPlan applies the metadata of a plan if metadata on the server did not
+// change since the plan was made
+func (o *MetadataApplyOptions) applyPlan() error {
+	if o.EC.Config.Version < cli.V3 {
+		return fmt.Errorf("applying a plan requires config v3 or later")
+	}
+	if o.rawOutput == string(rawOutputFormatJSON) {
+		o.EC.OutputFormat = cli.OutputFormatJSON
+	}
+	plan, err := readMetadataPlan(o.PlanFile)
+	if err != nil {
+		return err
+	}
+	if plan.Endpoint != o.EC.Config.ServerConfig.Endpoint {
+		return fmt.Errorf("plan was made against %s, not %s", plan.Endpoint, o.EC.Config.ServerConfig.Endpoint)
+	}
+	server, err := o.EC.APIClient.V1Metadata.V2ExportMetadata()
+	if err != nil {
+		return errors.Wrap(err, "cannot export metadata from server")
+	}
+	if server.ResourceVersion != plan.ResourceVersion {
+		return fmt.Errorf("metadata on the server changed since the plan was made (resource version %d, plan made at %d), make a new plan with metadata plan", server.ResourceVersion, plan.ResourceVersion)
+	}
+	metadata, err := metadataobject.NewHandlerFromEC(o.EC).ExpandJSONPlaceholders(plan.Metadata)
+	if err != nil {
+		return errors.Wrap(err, "cannot expand the placeholders of the plan")
+	}
+	if metadataHash(metadata) != plan.MetadataHash {
+		return errors.New("the {{ env }} and {{ file }} placeholders of the plan have other values than when it was made, make a new plan with metadata plan")
+	}
+	if !o.EC.IsJSONOutput() {
+		printMetadataPlan(o.EC.Stdout, plan, o.EC.NoColor)
+	}
+	if len(plan.Changes) == 0 {
+		o.EC.SetOutput(&metadataApplyOutput{IsConsistent: true, InconsistentObjects: []interface{}{}})
+		return nil
+	}
+	if o.EC.IsTerminal && !o.Force && !o.EC.IsJSONOutput() {
+		confirmation, err := util.GetYesNoPrompt("apply the plan?")
+		if err != nil {
+			return fmt.Errorf("error getting user input: %w", err)
+		}
+		if confirmation == "n" {
+			return nil
+		}
+	}
+	output := &metadataApplyOutput{IsConsistent: true, InconsistentObjects: []interface{}{}}
+	start := time.Now()
+	o.EC.Spin("Applying metadata...")
+	// the server refuses to replace metadata if it changed since the check
+	// above, as the resource version of the plan is sent along
+	r, err := o.EC.APIClient.V1Metadata.V2ReplaceMetadata(hasura.V2ReplaceMetadataArgs{
+		AllowInconsistentMetadata: true,
+		Metadata:                  json.RawMessage(metadata),
+		ResourceVersion:           &plan.ResourceVersion,
+	})
+	o.EC.Spinner.Stop()
+	if err != nil {
+		return errorApplyingMetadata(err)
+	}
+	if !r.IsConsistent {
+		o.EC.Logger.Warn("Metadata is inconsistent")
+		output.IsConsistent = false
+		if r.InconsistentObjects != nil {
+			output.InconsistentObjects = r.InconsistentObjects
+		}
+	}
+	output.DurationMS = time.Since(start).Milliseconds()
+	o.EC.SetOutput(output)
+	o.EC.Logger.Info("Metadata applied")
+	return nil
+}
+
+// metadataApplyOutput is the result of metadata apply reported with --output-format json
+type metadataApplyOutput struct {
+	IsConsistent        bool        `json:"is_consistent"`
+	InconsistentObjects interface{} `json:"inconsistent_objects"`
+	DurationMS          int64       `json:"duration_ms"`
+	// Operations is the number of metadata API calls of a partial apply
+	Operations int             `json:"operations,omitempty"`
+	Metadata   json.RawMessage `json:"metadata,omitempty"`
+}
+
 func errorApplyingMetadata(err error) error {
 	// a helper function to have consistent error messages for errors
 	// when applying metadata
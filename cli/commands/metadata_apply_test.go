@@ -7,6 +7,7 @@ import (
 
 	"github.com/Pallinder/go-randomdata"
 
+	"github.com/hasura/graphql-engine/cli/v2"
 	"github.com/hasura/graphql-engine/cli/v2/internal/testutil"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -28,10 +29,41 @@ var commonMetadataCommandsTest = func(projectDirectory string) {
 			WorkingDirectory: projectDirectory,
 		})
 		Eventually(session, 60*40).Should(Exit(0))
-		Eventually(isJSON(session.Wait().Out.Contents())).Should(BeTrue())
+		stdout := session.Wait().Out.Contents()
+		Eventually(isJSON(stdout)).Should(BeTrue())
+		// -o json writes the metadata, not the document of --output-format json
+		Expect(string(stdout)).Should(ContainSubstring(`"version"`))
+		Expect(string(stdout)).ShouldNot(ContainSubstring(`"command"`))
+	})
+	Context("apply metadata to server and report the result as a JSON document", func() {
+		session := testutil.Hasura(testutil.CmdOpts{
+			Args:             []string{"metadata", "apply", "--output-format", "json"},
+			WorkingDirectory: projectDirectory,
+		})
+		Eventually(session, 60*40).Should(Exit(0))
+		stdout := session.Wait().Out.Contents()
+		Eventually(isJSON(stdout)).Should(BeTrue())
+		Expect(string(stdout)).Should(ContainSubstring(`"command": "hasura metadata apply"`))
 	})
 }
 
+var _ = Describe("hasura metadata -o flags", func() {
+	It("are not shadowed by the global --output-format flag", func() {
+		for _, args := range [][]string{
+			{"metadata", "apply", "-o", "json"},
+			{"metadata", "export", "-o", "yaml"},
+		} {
+			cmd, flags, err := rootCmd.Find(args)
+			Expect(err).To(BeNil())
+			Expect(cmd.ParseFlags(flags)).To(Succeed())
+			output := cmd.Flags().Lookup("output")
+			Expect(output.Value.String()).To(Equal(args[3]))
+			Expect(output.Usage).To(ContainSubstring("yaml"))
+			Expect(ec.OutputFormat).To(Equal(cli.OutputFormatText))
+		}
+	})
+})
+
 var testConfigV2 = func(projectDirectory string) {
 	Context("apply migrations", func() {
 		testutil.RunCommandAndSucceed(testutil.CmdOpts{
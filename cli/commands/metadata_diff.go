@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -8,7 +9,9 @@ import (
 	"strings"
 
 	"github.com/aryann/difflib"
+	gyaml "github.com/goccy/go-yaml"
 
+	"github.com/hasura/graphql-engine/cli/v2/internal/metadatadiff"
 	"github.com/hasura/graphql-engine/cli/v2/internal/metadataobject"
 
 	"github.com/hasura/graphql-engine/cli/v2"
@@ -59,6 +62,12 @@ By default, it shows changes between the exported metadata file and server metad
   # For unified diff as the default diff just outputs only the difference:
   hasura metadata diff --type "unified-common"
 
+  # List added, removed and changed metadata objects instead of a text diff:
+  hasura metadata diff --type "semantic"
+
+  # Report the changed metadata objects as JSON:
+  hasura metadata diff metadata metadata_new --output-format json
+
   # Diff metadata on a different Hasura instance:
   hasura metadata diff --endpoint "<endpoint>"`,
 		Args: cobra.MaximumNArgs(2),
@@ -70,7 +79,7 @@ By default, it shows changes between the exported metadata file and server metad
 
 	f := metadataDiffCmd.Flags()
 
-	f.StringVar(&opts.DiffType, "type", "default", fmt.Sprintf(`specify a type of diff [allowed values: %v]`, DifftypeUnifiedCommon))
+	f.StringVar(&opts.DiffType, "type", "default", fmt.Sprintf(`specify a type of diff [allowed values: %v, %v]`, DifftypeUnifiedCommon, DifftypeSemantic))
 
 	return metadataDiffCmd
 }
@@ -138,6 +147,9 @@ func (o *MetadataDiffOptions) runv2(args []string) error {
 
 	// build local metadata
 	metadataHandler.SetMetadataObjects(metadataobject.GetMetadataObjectsWithDir(o.EC, o.Metadata[0]))
+	if o.Metadata[0] == o.EC.MetadataDir {
+		metadataHandler.SetOverlayFromEC(o.EC)
+	}
 	localMeta, err := metadataHandler.BuildMetadata()
 	if err != nil {
 		return err
@@ -147,6 +159,10 @@ func (o *MetadataDiffOptions) runv2(args []string) error {
 		return errors.Wrap(err, "cannot unmarshal local metadata")
 	}
 
+	if Difftype(o.DiffType) == DifftypeSemantic || o.EC.IsJSONOutput() {
+		return o.printSemanticDiff(localMeta, serverMeta)
+	}
+
 	if o.Metadata[1] != "" {
 		err = printDiff(string(oldYaml), string(newYaml), o.Metadata[0], o.Metadata[1], o.Output, o.DiffType, o.DisableColor)
 	} else {
@@ -171,6 +187,68 @@ type Difftype string
 
 const DifftypeUnifiedCommon Difftype = "unified-common"
 
+// DifftypeSemantic lists the added, removed and changed metadata objects,
+// it is the only type of diff reported with --output-format json
+const DifftypeSemantic Difftype = "semantic"
+
+// metadataDiffOutput is the result of metadata diff reported with --output-format json
+type metadataDiffOutput struct {
+	Changes []metadatadiff.Change `json:"changes"`
+}
+
+func (o *MetadataDiffOptions) printSemanticDiff(before, after yaml.MapSlice) error {
+	b, err := metadataToJSONValue(before)
+	if err != nil {
+		return err
+	}
+	a, err := metadataToJSONValue(after)
+	if err != nil {
+		return err
+	}
+	changes := metadatadiff.Diff(b, a)
+	o.EC.SetOutput(metadataDiffOutput{Changes: append([]metadatadiff.Change{}, changes...)})
+	if o.EC.IsJSONOutput() {
+		return nil
+	}
+	counts := printChanges(o.Output, changes, o.DisableColor || o.EC.NoColor)
+	o.EC.Logger.Infof("%d added, %d removed, %d changed", counts[metadatadiff.Added], counts[metadatadiff.Removed], counts[metadatadiff.Changed])
+	return nil
+}
+
+// printChanges prints metadata changes one per line prefixed with +, - or ~
+// and returns the number of changes of each type
+func printChanges(w io.Writer, changes []metadatadiff.Change, disableColor bool) map[metadatadiff.ChangeType]int {
+	prefixes := map[metadatadiff.ChangeType]string{metadatadiff.Added: "+ ", metadatadiff.Removed: "- ", metadatadiff.Changed: "~ "}
+	colors := map[metadatadiff.ChangeType]string{metadatadiff.Added: "green", metadatadiff.Removed: "red", metadatadiff.Changed: "yellow"}
+	counts := map[metadatadiff.ChangeType]int{}
+	for _, change := range changes {
+		counts[change.Type]++
+		line := prefixes[change.Type] + change.String()
+		if !disableColor {
+			line = ansi.Color(line, colors[change.Type])
+		}
+		fmt.Fprintln(w, line)
+	}
+	return counts
+}
+
+// metadataToJSONValue converts built metadata to the value it decodes to from JSON
+func metadataToJSONValue(metadata yaml.MapSlice) (interface{}, error) {
+	yByt, err := yaml.Marshal(metadata)
+	if err != nil {
+		return nil, err
+	}
+	jByt, err := gyaml.YAMLToJSON(yByt)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	if err := json.Unmarshal(jByt, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
 func printDiff(before, after, firstArg, SecondArg string, to io.Writer, difftype string, disableColor bool) error {
 	diffType := Difftype(difftype)
 	switch diffType {
@@ -75,14 +75,22 @@ func (o *MetadataExportOptions) Run() error {
 }
 
 func getMetadataFromServerAndWriteToStdoutByFormat(ec *cli.ExecutionContext, format rawOutputFormat) error {
+	jsonMetadata, err := getMetadataFromServer(ec)
+	if err != nil {
+		return err
+	}
+	return writeByOutputFormat(os.Stdout, jsonMetadata, format)
+}
+
+func getMetadataFromServer(ec *cli.ExecutionContext) ([]byte, error) {
 	metadataReader, err := cli.GetCommonMetadataOps(ec).ExportMetadata()
 	if err != nil {
-		return errors.Wrap(err, "failed to export metadata")
+		return nil, errors.Wrap(err, "failed to export metadata")
 	}
 
 	jsonMetadata, err := ioutil.ReadAll(metadataReader)
 	if err != nil {
-		return errors.Wrap(err, "reading metadata failed")
+		return nil, errors.Wrap(err, "reading metadata failed")
 	}
-	return writeByOutputFormat(os.Stdout, jsonMetadata, format)
+	return jsonMetadata, nil
 }
@@ -22,6 +22,9 @@ var testExportMetadataToStdout = func(projectDirectory string) {
 		Eventually(session, 60*40).Should(Exit(0))
 		stdout := session.Wait().Out.Contents()
 		Eventually(isYAML(stdout)).Should(BeTrue())
+		// -o yaml writes the metadata, not the document of --output-format json
+		Expect(string(stdout)).Should(ContainSubstring("version:"))
+		Expect(string(stdout)).ShouldNot(ContainSubstring("command:"))
 
 		session = testutil.Hasura(testutil.CmdOpts{
 			Args:             []string{"metadata", "export", "--output", "json"},
@@ -16,6 +16,7 @@ func newMetadataInconsistencyCmd(ec *cli.ExecutionContext) *cobra.Command {
 	metadataInconsistencyCmd.AddCommand(
 		newMetadataInconsistencyListCmd(ec),
 		newMetadataInconsistencyDropCmd(ec),
+		newMetadataInconsistencyFixCmd(ec),
 		newMetadataInconsistencyStatusCmd(ec),
 	)
 	return metadataInconsistencyCmd
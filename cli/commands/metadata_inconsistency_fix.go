@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+
+	"github.com/hasura/graphql-engine/cli/v2"
+	"github.com/hasura/graphql-engine/cli/v2/internal/metadataobject"
+	"github.com/hasura/graphql-engine/cli/v2/util"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func newMetadataInconsistencyFixCmd(ec *cli.ExecutionContext) *cobra.Command {
+	opts := &metadataInconsistencyFixOptions{
+		EC: ec,
+	}
+	metadataInconsistencyFixCmd := &cobra.Command{
+		Use:   "fix",
+		Short: "Edit the local metadata files to remove the inconsistent objects",
+		Long: `Propose edits of the local metadata files removing the objects the server reports as inconsistent, show them as a diff and apply them after confirmation, which requires a terminal unless --force is set:
+  - a broken relationship, permission, computed field or event trigger is removed from its table
+  - a permission on a missing column is kept, with the column commented out
+  - a missing table or function and an unreachable remote schema are removed
+
+Other inconsistent objects are listed, they can be dropped from the server with "hasura metadata inconsistency drop".`,
+		Example: `  # Review and apply the fixes of the inconsistent objects:
+  hasura metadata inconsistency fix
+
+  # Apply the fixes without confirmation:
+  hasura metadata inconsistency fix --force`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := opts.run()
+			opts.EC.Spinner.Stop()
+			if err != nil {
+				return errors.Wrap(err, "failed to fix inconsistent metadata")
+			}
+			return nil
+		},
+	}
+
+	f := metadataInconsistencyFixCmd.Flags()
+	f.BoolVar(&opts.force, "force", false, "apply the fixes without confirmation")
+
+	return metadataInconsistencyFixCmd
+}
+
+type metadataInconsistencyFixOptions struct {
+	EC *cli.ExecutionContext
+
+	force bool
+}
+
+func (o *metadataInconsistencyFixOptions) run() error {
+	o.EC.Spin("Getting inconsistent metadata...")
+	handler := metadataobject.NewHandlerFromEC(o.EC)
+	isConsistent, objects, err := handler.GetInconsistentMetadata()
+	o.EC.Spinner.Stop()
+	if err != nil {
+		return err
+	}
+	if isConsistent {
+		o.EC.Logger.Info("metadata is consistent")
+		return nil
+	}
+	fixes, unfixed, err := handler.InconsistentMetadataFixes(o.EC.MetadataDir, objects)
+	if err != nil {
+		return err
+	}
+	for _, u := range unfixed {
+		o.EC.Logger.Warnf("no fix for %s %s: %s", u.Object.GetType(), u.Object.GetName(), u.Reason)
+	}
+	if len(fixes) == 0 {
+		o.EC.Logger.Info("no fix to propose for the inconsistent objects")
+		return nil
+	}
+	files, err := metadataobject.FixMetadataFiles(o.EC.MetadataDir, fixes)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(o.EC.Stdout, "Proposed fixes:")
+	for _, fix := range fixes {
+		fmt.Fprintf(o.EC.Stdout, "  - %s:%d: %s\n", fix.File, fix.Line, fix.Description)
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		before, err := ioutil.ReadFile(filepath.Join(o.EC.MetadataDir, name))
+		if err != nil {
+			return errors.Wrapf(err, "cannot read metadata file %s", name)
+		}
+		fmt.Fprintln(o.EC.Stdout)
+		if err := printDiffv2(string(before), string(files[name]), name, name, o.EC.Stdout, o.EC.NoColor); err != nil {
+			return err
+		}
+	}
+
+	if !o.force {
+		if !o.EC.IsTerminal {
+			return errors.New("the fixes have to be confirmed in a terminal, use --force to apply them without confirmation")
+		}
+		confirmation, err := util.GetYesNoPrompt("apply the fixes to the metadata files?")
+		if err != nil {
+			return fmt.Errorf("error getting user input: %w", err)
+		}
+		if confirmation == "n" {
+			return nil
+		}
+	}
+	for _, name := range names {
+		if err := ioutil.WriteFile(filepath.Join(o.EC.MetadataDir, name), files[name], 0644); err != nil {
+			return errors.Wrapf(err, "cannot write metadata file %s", name)
+		}
+	}
+	o.EC.Logger.Infof("%d fixes applied to the metadata files, run \"hasura metadata apply\" to apply the metadata to the server", len(fixes))
+	return nil
+}
@@ -0,0 +1,86 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/briandowns/spinner"
+
+	"github.com/hasura/graphql-engine/cli/v2"
+	"github.com/hasura/graphql-engine/cli/v2/internal/hasura"
+	"github.com/hasura/graphql-engine/cli/v2/util"
+	"github.com/hasura/graphql-engine/cli/v2/version"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+)
+
+// stubInconsistentMetadata reports a missing table as inconsistent, other
+// calls are not expected
+type stubInconsistentMetadata struct {
+	hasura.V1Metadata
+}
+
+func (s *stubInconsistentMetadata) GetInconsistentMetadata() (*hasura.GetInconsistentMetadataResponse, error) {
+	return &hasura.GetInconsistentMetadataResponse{
+		IsConsistent: false,
+		InconsistentObjects: []interface{}{
+			map[string]interface{}{
+				"type":       "table",
+				"reason":     `no such table/view exists in source: "comments"`,
+				"definition": map[string]interface{}{"schema": "public", "name": "comments"},
+			},
+		},
+	}, nil
+}
+
+var _ = Describe("hasura metadata inconsistency fix", func() {
+	var metadataDir string
+	var teardown func()
+	var opts *metadataInconsistencyFixOptions
+	tablesFile := func() string {
+		b, err := ioutil.ReadFile(filepath.Join(metadataDir, "databases", "default", "tables", "tables.yaml"))
+		Expect(err).To(BeNil())
+		return string(b)
+	}
+
+	BeforeEach(func() {
+		dir, err := ioutil.TempDir("", "metadata-inconsistency-fix-*")
+		Expect(err).To(BeNil())
+		teardown = func() { os.RemoveAll(dir) }
+		metadataDir = filepath.Join(dir, "metadata")
+		Expect(util.CopyDir("../internal/metadataobject/testdata/fix/metadata", metadataDir)).To(Succeed())
+		testEC := cli.NewExecutionContext()
+		testEC.Logger = logrus.New()
+		testEC.Logger.Out = ioutil.Discard
+		testEC.Version = version.New()
+		testEC.Config = &cli.Config{Version: cli.V3}
+		testEC.HasMetadataV3 = true
+		testEC.MetadataDir = metadataDir
+		testEC.ExecutionDirectory = dir
+		testEC.APIClient = &hasura.Client{V1Metadata: &stubInconsistentMetadata{}}
+		testEC.Stdout = ioutil.Discard
+		testEC.NoColor = true
+		testEC.Spinner = spinner.New(spinner.CharSets[7], 100*time.Millisecond)
+		opts = &metadataInconsistencyFixOptions{EC: testEC}
+	})
+
+	AfterEach(func() { teardown() })
+
+	It("refuses to apply the fixes without a terminal to confirm them", func() {
+		before := tablesFile()
+		opts.EC.IsTerminal = false
+		err := opts.run()
+		Expect(err).To(MatchError("the fixes have to be confirmed in a terminal, use --force to apply them without confirmation"))
+		Expect(tablesFile()).To(Equal(before))
+	})
+
+	It("applies the fixes without confirmation with --force", func() {
+		opts.EC.IsTerminal = false
+		opts.force = true
+		Expect(opts.run()).To(Succeed())
+		Expect(tablesFile()).NotTo(ContainSubstring("public_comments.yaml"))
+	})
+})
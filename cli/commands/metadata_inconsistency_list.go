@@ -47,6 +47,21 @@ type metadataInconsistencyListOptions struct {
 	inconsistentObjects []metadataobject.InconsistentMetadataObject
 }
 
+// metadataInconsistencyOutput is the consistency of metadata reported with --output-format json
+type metadataInconsistencyOutput struct {
+	IsConsistent        bool                                        `json:"is_consistent"`
+	InconsistentObjects []metadataobject.InconsistentMetadataObject `json:"inconsistent_objects"`
+}
+
+func newMetadataInconsistencyOutput(isConsistent bool, objects []metadataobject.InconsistentMetadataObject) metadataInconsistencyOutput {
+	output := metadataInconsistencyOutput{
+		IsConsistent:        isConsistent,
+		InconsistentObjects: []metadataobject.InconsistentMetadataObject{},
+	}
+	output.InconsistentObjects = append(output.InconsistentObjects, objects...)
+	return output
+}
+
 func (o *metadataInconsistencyListOptions) read(handler *metadataobject.Handler) error {
 	var err error
 	o.isConsistent, o.inconsistentObjects, err = handler.GetInconsistentMetadata()
@@ -63,15 +78,23 @@ func (o *metadataInconsistencyListOptions) run() error {
 	if err != nil {
 		return err
 	}
-	if o.isConsistent {
+	o.EC.SetOutput(newMetadataInconsistencyOutput(o.isConsistent, o.inconsistentObjects))
+	if o.isConsistent || o.EC.IsJSONOutput() {
 		return nil
 	}
+	o.EC.Spinner.Stop()
+	fmt.Println(inconsistentObjectsTable(o.inconsistentObjects))
+	return nil
+}
+
+// inconsistentObjectsTable returns a table of inconsistent metadata objects
+func inconsistentObjectsTable(objects []metadataobject.InconsistentMetadataObject) string {
 	out := new(tabwriter.Writer)
 	buf := &bytes.Buffer{}
 	out.Init(buf, 0, 8, 2, ' ', 0)
 	w := util.NewPrefixWriter(out)
 	w.Write(util.LEVEL_0, "NAME\tTYPE\tDESCRIPTION\tREASON\n")
-	for _, obj := range o.inconsistentObjects {
+	for _, obj := range objects {
 		w.Write(util.LEVEL_0, "%s\t%s\t%s\t%s\n",
 			obj.GetName(),
 			obj.GetType(),
@@ -80,7 +103,5 @@ func (o *metadataInconsistencyListOptions) run() error {
 		)
 	}
 	out.Flush()
-	o.EC.Spinner.Stop()
-	fmt.Println(buf.String())
-	return nil
+	return buf.String()
 }
@@ -23,6 +23,7 @@ func newMetadataInconsistencyStatusCmd(ec *cli.ExecutionContext) *cobra.Command
 			if err != nil {
 				return errors.Wrap(err, "failed to read metadata status")
 			}
+			opts.EC.SetOutput(newMetadataInconsistencyOutput(opts.isConsistent, opts.inconsistentObjects))
 			if opts.isConsistent {
 				opts.EC.Logger.Println("metadata is consistent")
 			} else {
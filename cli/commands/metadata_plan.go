@@ -0,0 +1,168 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/hasura/graphql-engine/cli/v2"
+	"github.com/hasura/graphql-engine/cli/v2/internal/metadatadiff"
+	"github.com/hasura/graphql-engine/cli/v2/internal/metadataobject"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const defaultMetadataPlanFile = "metadata.plan.json"
+
+func newMetadataPlanCmd(ec *cli.ExecutionContext) *cobra.Command {
+	opts := &MetadataPlanOptions{
+		EC:     ec,
+		Output: os.Stdout,
+	}
+
+	metadataPlanCmd := &cobra.Command{
+		Use:   "plan",
+		Short: "Show the metadata objects metadata apply would create, change and drop, and save them in a plan file",
+		Long: `Compare project metadata with metadata on the server and list the objects (tables, relationships, permissions, actions, triggers, ...) that applying the project would create, change or drop.
+The plan is saved in a file which can be applied with "metadata apply --plan". The plan is refused if metadata on the server changed after it was made.`,
+		Example: `  # Make a plan and save it in metadata.plan.json:
+  hasura metadata plan
+
+  # Save the plan in another file:
+  hasura metadata plan --out prod.plan.json
+
+  # Apply the plan once it is reviewed:
+  hasura metadata apply --plan prod.plan.json`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			plan, err := opts.Run()
+			if err != nil {
+				return err
+			}
+			opts.EC.SetOutput(plan)
+			if opts.EC.IsJSONOutput() {
+				return nil
+			}
+			printMetadataPlan(opts.Output, plan, opts.EC.NoColor)
+			opts.EC.Logger.Infof("Plan saved to %s, apply it with: hasura metadata apply --plan %s", opts.PlanFile, opts.PlanFile)
+			return nil
+		},
+	}
+
+	f := metadataPlanCmd.Flags()
+	f.StringVar(&opts.PlanFile, "out", defaultMetadataPlanFile, "file to save the plan to")
+
+	return metadataPlanCmd
+}
+
+type MetadataPlanOptions struct {
+	EC     *cli.ExecutionContext
+	Output io.Writer
+	// PlanFile is the file the plan is written to, the plan is not written
+	// if it is empty
+	PlanFile string
+}
+
+// MetadataPlan is the plan made by metadata plan and applied by
+// metadata apply --plan
+type MetadataPlan struct {
+	// Endpoint is the server the plan was made against
+	Endpoint string `json:"endpoint"`
+	// ResourceVersion is the version of server metadata the plan was made
+	// against, the plan is refused once metadata on the server changed
+	ResourceVersion int                   `json:"resource_version"`
+	CreatedAt       time.Time             `json:"created_at"`
+	Changes         []metadatadiff.Change `json:"changes"`
+	// Metadata is the project metadata the plan applies, with the {{ env }}
+	// and {{ file }} placeholders of the files left as they are so that the
+	// plan file holds no secrets
+	Metadata json.RawMessage `json:"metadata"`
+	// MetadataHash is the SHA-256 of Metadata with its placeholders expanded,
+	// the plan is refused if they expand to other values when it is applied
+	MetadataHash string `json:"metadata_hash"`
+}
+
+// Run compares metadata of the project with metadata on the server and
+// writes the plan to PlanFile
+func (o *MetadataPlanOptions) Run() (*MetadataPlan, error) {
+	if o.EC.Config.Version < cli.V3 {
+		return nil, fmt.Errorf("metadata plan requires config v3 or later")
+	}
+	o.EC.Spin("Making plan...")
+	defer o.EC.Spinner.Stop()
+	handler := metadataobject.NewHandlerFromEC(o.EC)
+	handler.KeepPlaceholders()
+	metadata, err := handler.MakeJSONMetadata()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot build project metadata")
+	}
+	local, err := handler.ExpandJSONPlaceholders(metadata)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot build project metadata")
+	}
+	server, err := o.EC.APIClient.V1Metadata.V2ExportMetadata()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot export metadata from server")
+	}
+	var before, after interface{}
+	if err := json.Unmarshal(server.Metadata, &before); err != nil {
+		return nil, errors.Wrap(err, "cannot decode server metadata")
+	}
+	if err := json.Unmarshal(local, &after); err != nil {
+		return nil, errors.Wrap(err, "cannot decode project metadata")
+	}
+	plan := &MetadataPlan{
+		Endpoint:        o.EC.Config.ServerConfig.Endpoint,
+		ResourceVersion: server.ResourceVersion,
+		CreatedAt:       time.Now().UTC(),
+		Changes:         append([]metadatadiff.Change{}, metadatadiff.Diff(before, after)...),
+		Metadata:        metadata,
+		MetadataHash:    metadataHash(local),
+	}
+	if o.PlanFile != "" {
+		b, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := ioutil.WriteFile(o.PlanFile, b, 0644); err != nil {
+			return nil, errors.Wrap(err, "cannot write plan")
+		}
+	}
+	return plan, nil
+}
+
+// readMetadataPlan reads a plan written by metadata plan
+func readMetadataPlan(planFile string) (*MetadataPlan, error) {
+	b, err := ioutil.ReadFile(planFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read plan")
+	}
+	plan := new(MetadataPlan)
+	if err := json.Unmarshal(b, plan); err != nil {
+		return nil, errors.Wrapf(err, "cannot parse plan %s", planFile)
+	}
+	if len(plan.Metadata) == 0 || plan.MetadataHash == "" {
+		return nil, fmt.Errorf("%s is not a metadata plan", planFile)
+	}
+	return plan, nil
+}
+
+// metadataHash returns the hex encoded SHA-256 of metadata
+func metadataHash(metadata []byte) string {
+	sum := sha256.Sum256(metadata)
+	return hex.EncodeToString(sum[:])
+}
+
+func printMetadataPlan(w io.Writer, plan *MetadataPlan, disableColor bool) {
+	if len(plan.Changes) == 0 {
+		fmt.Fprintln(w, "No changes, metadata on the server is up to date with the project.")
+		return
+	}
+	counts := printChanges(w, plan.Changes, disableColor)
+	fmt.Fprintf(w, "\nPlan: %d to create, %d to change, %d to drop.\n", counts[metadatadiff.Added], counts[metadatadiff.Changed], counts[metadatadiff.Removed])
+}
@@ -0,0 +1,114 @@
+package commands
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/briandowns/spinner"
+
+	"github.com/hasura/graphql-engine/cli/v2"
+	"github.com/hasura/graphql-engine/cli/v2/internal/hasura"
+	"github.com/hasura/graphql-engine/cli/v2/internal/metadatadiff"
+	"github.com/hasura/graphql-engine/cli/v2/version"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+)
+
+// stubV1Metadata exports metadata at a resource version and records
+// replaced metadata, other calls are not expected
+type stubV1Metadata struct {
+	hasura.V1Metadata
+	resourceVersion int
+	replaced        []hasura.V2ReplaceMetadataArgs
+}
+
+func (s *stubV1Metadata) V2ExportMetadata() (*hasura.V2ExportMetadataResponse, error) {
+	return &hasura.V2ExportMetadataResponse{ResourceVersion: s.resourceVersion, Metadata: json.RawMessage(`{"version": 3, "sources": []}`)}, nil
+}
+
+func (s *stubV1Metadata) V2ReplaceMetadata(args hasura.V2ReplaceMetadataArgs) (*hasura.V2ReplaceMetadataResponse, error) {
+	s.replaced = append(s.replaced, args)
+	return &hasura.V2ReplaceMetadataResponse{IsConsistent: true}, nil
+}
+
+var _ = Describe("hasura metadata apply --plan", func() {
+	const endpoint = "http://localhost:8080"
+	var planFile string
+	var teardown func()
+	var metadataOps *stubV1Metadata
+	var opts *MetadataApplyOptions
+	writePlan := func(plan MetadataPlan) {
+		b, err := json.Marshal(plan)
+		Expect(err).To(BeNil())
+		Expect(ioutil.WriteFile(planFile, b, 0644)).To(Succeed())
+	}
+	metadata := json.RawMessage(`{"version":3,"remote_schemas":[{"name":"payments","definition":{"url":"{{ env \"METADATA_PLAN_TEST_URL\" }}"}}]}`)
+
+	BeforeEach(func() {
+		dir, err := ioutil.TempDir("", "metadata-plan-*")
+		Expect(err).To(BeNil())
+		teardown = func() { os.RemoveAll(dir) }
+		planFile = filepath.Join(dir, "metadata.plan.json")
+		Expect(os.Setenv("METADATA_PLAN_TEST_URL", "https://payments.example.com")).To(Succeed())
+		metadataOps = &stubV1Metadata{resourceVersion: 7}
+		testEC := cli.NewExecutionContext()
+		testEC.Logger = logrus.New()
+		testEC.Version = version.New()
+		testEC.Config = &cli.Config{Version: cli.V3, ServerConfig: cli.ServerConfig{Endpoint: endpoint}}
+		testEC.APIClient = &hasura.Client{V1Metadata: metadataOps}
+		testEC.Stdout = ioutil.Discard
+		testEC.NoColor = true
+		testEC.Spinner = spinner.New(spinner.CharSets[7], 100*time.Millisecond)
+		opts = &MetadataApplyOptions{EC: testEC, PlanFile: planFile}
+	})
+
+	AfterEach(func() {
+		os.Unsetenv("METADATA_PLAN_TEST_URL")
+		teardown()
+	})
+
+	It("refuses a plan made at another resource version", func() {
+		writePlan(MetadataPlan{
+			Endpoint:        endpoint,
+			ResourceVersion: 6,
+			Changes:         nil,
+			Metadata:        metadata,
+			MetadataHash:    metadataHash([]byte(`{"remote_schemas":[{"definition":{"url":"https://payments.example.com"},"name":"payments"}],"version":3}`)),
+		})
+		err := opts.Run()
+		Expect(err).To(MatchError(ContainSubstring("metadata on the server changed since the plan was made (resource version 7, plan made at 6)")))
+		Expect(metadataOps.replaced).To(BeEmpty())
+	})
+
+	It("refuses a plan whose placeholders expand to other values", func() {
+		writePlan(MetadataPlan{
+			Endpoint:        endpoint,
+			ResourceVersion: 7,
+			Metadata:        metadata,
+			MetadataHash:    metadataHash([]byte(`{"remote_schemas":[{"definition":{"url":"https://payments.staging.example.com"},"name":"payments"}],"version":3}`)),
+		})
+		err := opts.Run()
+		Expect(err).To(MatchError(ContainSubstring("placeholders of the plan have other values")))
+		Expect(metadataOps.replaced).To(BeEmpty())
+	})
+
+	It("applies the metadata of the plan with its placeholders expanded", func() {
+		writePlan(MetadataPlan{
+			Endpoint:        endpoint,
+			ResourceVersion: 7,
+			Changes:         []metadatadiff.Change{{Type: metadatadiff.Added, Object: []metadatadiff.ObjectID{{Kind: "remote_schema", Name: "payments"}}}},
+			Metadata:        metadata,
+			MetadataHash:    metadataHash([]byte(`{"remote_schemas":[{"definition":{"url":"https://payments.example.com"},"name":"payments"}],"version":3}`)),
+		})
+		Expect(opts.Run()).To(Succeed())
+		Expect(metadataOps.replaced).To(HaveLen(1))
+		Expect(*metadataOps.replaced[0].ResourceVersion).To(Equal(7))
+		b, err := json.Marshal(metadataOps.replaced[0].Metadata)
+		Expect(err).To(BeNil())
+		Expect(string(b)).To(ContainSubstring("https://payments.example.com"))
+	})
+})
@@ -0,0 +1,95 @@
+package commands
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/hasura/graphql-engine/cli/v2"
+	"github.com/hasura/graphql-engine/cli/v2/internal/metadataobject"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newMetadataValidateCmd(ec *cli.ExecutionContext, v *viper.Viper) *cobra.Command {
+	opts := &MetadataValidateOptions{
+		EC: ec,
+	}
+
+	metadataValidateCmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Validate metadata files of the project without a server",
+		Long: `Build metadata from the files of the project and validate it against the metadata schema shipped with the CLI.
+Unknown fields, missing required fields and values of the wrong type are reported with the file and the YAML path they are found at. No server is needed, references to database objects are checked by the server when metadata is applied.
+The overlay of --env is merged like metadata apply does, {{ env }} and {{ file }} placeholders are not expanded.`,
+		Example: `  # Validate metadata of the project:
+  hasura metadata validate
+
+  # Report the violations as JSON:
+  hasura metadata validate --output-format json`,
+		SilenceUsage: true,
+		// unlike the other metadata commands, validate does not connect to the server
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			cmd.Root().PersistentPreRun(cmd, args)
+			ec.Viper = v
+			err := ec.Prepare()
+			if err != nil {
+				return err
+			}
+			return ec.ValidateProject()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			violations, err := opts.Run()
+			if err != nil {
+				return err
+			}
+			opts.EC.SetOutput(metadataValidateOutput{Violations: append([]metadataobject.ValidationError{}, violations...)})
+			if !opts.EC.IsJSONOutput() {
+				for _, violation := range violations {
+					fmt.Fprintln(opts.EC.Stdout, violation)
+				}
+			}
+			if len(violations) > 0 {
+				return fmt.Errorf("metadata is invalid: %d violation(s) found", len(violations))
+			}
+			opts.EC.Logger.Info("metadata is valid")
+			return nil
+		},
+	}
+
+	return metadataValidateCmd
+}
+
+type MetadataValidateOptions struct {
+	EC *cli.ExecutionContext
+}
+
+// metadataValidateOutput is the result of metadata validate reported with --output-format json
+type metadataValidateOutput struct {
+	Violations []metadataobject.ValidationError `json:"violations"`
+}
+
+// Run validates the metadata of the project, the files of the violations
+// are relative to the project directory
+func (o *MetadataValidateOptions) Run() ([]metadataobject.ValidationError, error) {
+	if o.EC.MetadataDir == "" {
+		return nil, errors.New("metadata validate requires config v2 or later with a metadata directory")
+	}
+	// without a server the layout of the metadata directory is told by the
+	// config version, config v3 projects have metadata v3
+	o.EC.HasMetadataV3 = o.EC.Config.Version >= cli.V3
+	handler := metadataobject.NewHandlerFromEC(o.EC)
+	// an expanded placeholder is a string like the placeholder, validating
+	// the placeholders does not need the environment variables and files
+	handler.KeepPlaceholders()
+	violations, err := handler.ValidateMetadata(o.EC.MetadataDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot validate metadata")
+	}
+	for i := range violations {
+		if violations[i].File != "" {
+			violations[i].File = filepath.ToSlash(filepath.Join(o.EC.Config.MetadataDirectory, violations[i].File))
+		}
+	}
+	return violations, nil
+}
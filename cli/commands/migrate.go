@@ -17,7 +17,9 @@ import (
 
 	// Initialize migration drivers
 	_ "github.com/hasura/graphql-engine/cli/v2/migrate/database/hasuradb"
+	_ "github.com/hasura/graphql-engine/cli/v2/migrate/source/bundle"
 	_ "github.com/hasura/graphql-engine/cli/v2/migrate/source/file"
+	_ "github.com/hasura/graphql-engine/cli/v2/migrate/source/git"
 )
 
 // NewMigrateCmd returns the migrate command
@@ -68,6 +70,11 @@ func NewMigrateCmd(ec *cli.ExecutionContext) *cobra.Command {
 		newMigrateCreateCmd(ec),
 		newMigrateSquashCmd(ec),
 		newMigrateDeleteCmd(ec),
+		newMigrateVerifyCmd(ec),
+		newMigrateBundleCmd(ec),
+		newMigrateRebaseCmd(ec),
+		newMigrateLintCmd(ec),
+		newMigrateUnlockCmd(ec),
 	)
 
 	return migrateCmd
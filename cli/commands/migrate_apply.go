@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"time"
 
 	"github.com/hasura/graphql-engine/cli/v2/internal/hasura"
 
@@ -12,6 +13,7 @@ import (
 
 	"github.com/hasura/graphql-engine/cli/v2"
 	migrate "github.com/hasura/graphql-engine/cli/v2/migrate"
+	"github.com/hasura/graphql-engine/cli/v2/migrate/lint"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
@@ -57,7 +59,22 @@ func newMigrateApplyCmd(ec *cli.ExecutionContext) *cobra.Command {
   hasura migrate apply --type down --version "<version>"
 
   # Rollback all migrations:
-  hasura migrate apply --down all`,
+  hasura migrate apply --down all
+
+  # Apply all pending migrations in a single transaction, nothing is applied if one of them fails:
+  hasura migrate apply --atomic
+
+  # Wait up to 5 minutes for another process applying migrations on the database to finish:
+  hasura migrate apply --lock-timeout 5m
+
+  # Lint pending migrations and apply them only if no lint rule with error severity is violated:
+  hasura migrate apply --lint
+
+  # Apply migrations from a bundle created by "hasura migrate bundle":
+  hasura migrate apply --migrations-url bundle://migrations.tar.gz
+
+  # Apply migrations as they were 3 commits ago:
+  hasura migrate apply --migrations-url git://HEAD~3:migrations`,
 		SilenceUsage: true,
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			return validateConfigV3Flags(cmd, ec)
@@ -78,7 +95,11 @@ func newMigrateApplyCmd(ec *cli.ExecutionContext) *cobra.Command {
 	f.StringVar(&opts.MigrationType, "type", "up", "type of migration (up, down) to be used with version flag")
 
 	f.BoolVar(&opts.DryRun, "dry-run", false, "print the names of migrations which are going to be applied")
+	f.BoolVar(&opts.Atomic, "atomic", false, "apply all migrations of a database in a single transaction, the database is left untouched if any of them fails")
+	f.BoolVar(&opts.Lint, "lint", false, "lint pending migrations before applying them, nothing is applied if a lint rule with error severity is violated (see hasura migrate lint)")
+	f.DurationVar(&opts.LockTimeout, "lock-timeout", migrate.DefaultLockTimeout, "maximum time to wait for the migrations lock held by another process applying migrations on the database")
 	f.BoolVar(&opts.AllDatabases, "all-databases", false, "set this flag to attempt to apply migrations on all databases present on server")
+	f.StringVar(&ec.MigrationsSourceURL, "migrations-url", "", "read migrations from a source other than the migrations directory (eg: git://HEAD~3:migrations, bundle://migrations.tar.gz)")
 	return migrateApplyCmd
 }
 
@@ -93,8 +114,14 @@ type MigrateApplyOptions struct {
 	GotoVersion   string
 	SkipExecution bool
 	DryRun        bool
+	Atomic        bool
+	Lint          bool
+	LockTimeout   time.Duration
 	Source        cli.Source
 	AllDatabases  bool
+
+	// migrations applied by the last Exec
+	applied []migrate.AppliedMigration
 }
 
 func (o *MigrateApplyOptions) Validate() error {
@@ -145,6 +172,11 @@ func (o *MigrateApplyOptions) Run() error {
 	if o.DryRun && o.SkipExecution {
 		return errors.New("both --skip-execution and --dry-run flags cannot be used together")
 	}
+	if o.Atomic && o.SkipExecution {
+		return errors.New("both --skip-execution and --atomic flags cannot be used together")
+	}
+	output := &migrateApplyOutput{Databases: []migrateApplyDatabaseOutput{}}
+	o.EC.SetOutput(output)
 	if o.AllDatabases && o.EC.Config.Version >= cli.V3 {
 		o.EC.Spin("getting lists of databases from server ")
 		sourcesAndKind, err := metadatautil.GetSourcesAndKind(o.EC.APIClient.V1Metadata.ExportMetadata)
@@ -158,8 +190,10 @@ func (o *MigrateApplyOptions) Run() error {
 			if !o.DryRun {
 				o.EC.Spin(fmt.Sprintf("Applying migrations on database: %s ", o.Source.Name))
 			}
+			start := time.Now()
 			err := o.Exec()
 			o.EC.Spinner.Stop()
+			output.add(o, time.Since(start), err)
 			if err != nil {
 				if err == migrate.ErrNoChange {
 					o.EC.Logger.Infof("nothing to apply on database: %s", o.Source.Name)
@@ -188,8 +222,10 @@ func (o *MigrateApplyOptions) Run() error {
 		if !o.DryRun {
 			o.EC.Spin("Applying migrations...")
 		}
+		start := time.Now()
 		err := o.Exec()
 		o.EC.Spinner.Stop()
+		output.add(o, time.Since(start), err)
 		if err != nil {
 			if err == migrate.ErrNoChange {
 				o.EC.Logger.Info("nothing to apply")
@@ -217,7 +253,8 @@ func (o *MigrateApplyOptions) Run() error {
 	return nil
 }
 func (o *MigrateApplyOptions) Exec() error {
-	if o.EC.Config.Version >= cli.V3 {
+	o.applied = nil
+	if o.EC.Config.Version >= cli.V3 && o.EC.MigrationsSourceURL == "" {
 		// check if  a migrations directory exists for source in project
 		migrationDirectory := filepath.Join(o.EC.MigrationDir, o.Source.Name)
 		if f, err := os.Stat(migrationDirectory); err != nil || f == nil {
@@ -238,8 +275,105 @@ func (o *MigrateApplyOptions) Exec() error {
 	}
 	migrateDrv.SkipExecution = o.SkipExecution
 	migrateDrv.DryRun = o.DryRun
+	migrateDrv.Atomic = o.Atomic
+	migrateDrv.Quiet = o.EC.IsJSONOutput()
+	if o.LockTimeout > 0 {
+		migrateDrv.LockTimeout = o.LockTimeout
+	}
+
+	if o.Lint {
+		if err := o.lintPendingMigrations(migrateDrv); err != nil {
+			return err
+		}
+	}
+
+	err = ExecuteMigration(migrationType, migrateDrv, step)
+	o.applied = migrateDrv.Applied
+	return err
+}
+
+// lintPendingMigrations lints the migrations which are present locally
+// but not applied on the database, violations are logged
+func (o *MigrateApplyOptions) lintPendingMigrations(migrateDrv *migrate.Migrate) error {
+	if o.Source.Kind != hasura.SourceKindPG {
+		o.EC.Logger.Warnf("skipping lint of migrations on database %s, lint is supported only for postgres databases", o.Source.Name)
+		return nil
+	}
+	linter, err := newMigrationsLinter(o.EC)
+	if err != nil {
+		return err
+	}
+	status, err := executeStatus(migrateDrv)
+	if err != nil {
+		return errors.Wrap(err, "cannot fetch migrate status")
+	}
+	var pending []uint64
+	for _, version := range status.Index {
+		if m := status.Migrations[version]; m.IsPresent && !m.IsApplied {
+			pending = append(pending, version)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	migrations, err := lint.ReadMigrations(migrateDrv.SourceDriver(), pending, migrationsDisplayDirectory(o.EC, o.Source.Name))
+	if err != nil {
+		return err
+	}
+	violations := linter.Lint(migrations)
+	for _, v := range violations {
+		if v.Severity == lint.SeverityError {
+			o.EC.Logger.Error(v)
+		} else {
+			o.EC.Logger.Warn(v)
+		}
+	}
+	if lint.HasErrors(violations) {
+		return errors.New("pending migrations failed lint, see hasura migrate lint")
+	}
+	return nil
+}
+
+// migrateApplyOutput is the result of migrate apply reported with --output-format json
+type migrateApplyOutput struct {
+	Databases []migrateApplyDatabaseOutput `json:"databases"`
+}
+
+type migrateApplyDatabaseOutput struct {
+	Database   string                   `json:"database,omitempty"`
+	DryRun     bool                     `json:"dry_run,omitempty"`
+	Migrations []appliedMigrationOutput `json:"migrations"`
+	DurationMS int64                    `json:"duration_ms"`
+	Error      string                   `json:"error,omitempty"`
+}
 
-	return ExecuteMigration(migrationType, migrateDrv, step)
+type appliedMigrationOutput struct {
+	migrate.AppliedMigration
+	DurationMS int64 `json:"duration_ms"`
+}
+
+func (out *migrateApplyOutput) add(o *MigrateApplyOptions, took time.Duration, err error) {
+	database := migrateApplyDatabaseOutput{
+		Database:   o.Source.Name,
+		DryRun:     o.DryRun,
+		Migrations: []appliedMigrationOutput{},
+		DurationMS: took.Milliseconds(),
+	}
+	for _, applied := range o.applied {
+		database.Migrations = append(database.Migrations, appliedMigrationOutput{applied, applied.Duration.Milliseconds()})
+	}
+	if err != nil && !isNothingToApply(err) {
+		database.Error = err.Error()
+	}
+	out.Databases = append(out.Databases, database)
+}
+
+func isNothingToApply(err error) bool {
+	if err == migrate.ErrNoChange {
+		return true
+	}
+	e, ok := err.(*os.PathError)
+	return ok && e.Op == "first"
 }
 
 // Only one flag out of up, down and version can be set at a time. This function
@@ -0,0 +1,67 @@
+package commands
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/hasura/graphql-engine/cli/v2"
+	"github.com/hasura/graphql-engine/cli/v2/migrate/source/bundle"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func newMigrateBundleCmd(ec *cli.ExecutionContext) *cobra.Command {
+	opts := &MigrateBundleOptions{
+		EC: ec,
+	}
+	migrateBundleCmd := &cobra.Command{
+		Use:   "bundle",
+		Short: "Create an archive of the migrations directory",
+		Long: `Create a tar or zip archive of the migrations of all databases in the project.
+The archive can be applied using "hasura migrate apply --migrations-url bundle://<path>",
+so that deployments apply an immutable artifact instead of a checkout of the project.`,
+		Example: `  # Bundle migrations into migrations.tar.gz:
+  hasura migrate bundle
+
+  # Bundle migrations into a zip file:
+  hasura migrate bundle --file dist/migrations.zip
+
+  # Apply the bundle on a server:
+  hasura migrate apply --migrations-url bundle://dist/migrations.zip --database-name default`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.EC.Spin("Creating migrations bundle...")
+			err := opts.Run()
+			opts.EC.Spinner.Stop()
+			if err != nil {
+				return err
+			}
+			opts.EC.Logger.Infof("migrations bundle created at %s", opts.File)
+			opts.EC.SetOutput(map[string]string{"file": opts.File})
+			return nil
+		},
+	}
+	f := migrateBundleCmd.Flags()
+	f.StringVar(&opts.File, "file", "migrations.tar.gz", "path of the bundle to be created, the format is decided by the extension (.tar, .tar.gz, .tgz, .zip)")
+	return migrateBundleCmd
+}
+
+type MigrateBundleOptions struct {
+	EC   *cli.ExecutionContext
+	File string
+}
+
+func (o *MigrateBundleOptions) Run() error {
+	output, err := filepath.Abs(o.File)
+	if err != nil {
+		return err
+	}
+	if rel, err := filepath.Rel(o.EC.MigrationDir, output); err == nil && !strings.HasPrefix(rel, "..") {
+		return fmt.Errorf("bundle cannot be created inside the migrations directory %s", o.EC.MigrationDir)
+	}
+	if err := bundle.Create(o.EC.MigrationDir, output); err != nil {
+		return errors.Wrap(err, "cannot create migrations bundle")
+	}
+	return nil
+}
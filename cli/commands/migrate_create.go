@@ -2,11 +2,16 @@ package commands
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 	"time"
 
 	"github.com/hasura/graphql-engine/cli/v2"
+	"github.com/hasura/graphql-engine/cli/v2/internal/hasura"
+	"github.com/hasura/graphql-engine/cli/v2/internal/pgdiff"
 	"github.com/hasura/graphql-engine/cli/v2/migrate"
+	"github.com/hasura/graphql-engine/cli/v2/migrate/source"
+	"github.com/hasura/graphql-engine/cli/v2/migrate/source/file"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -32,6 +37,15 @@ const migrateCreateCmdExamples = `  # Setup migration files for the first time b
   
   # Create up and down SQL migrations, providing contents as flags
   hasura migrate create migration-name --up-sql "CREATE TABLE article(id serial NOT NULL, title text NOT NULL, content text NOT NULL);"  --down-sql "DROP TABLE article;"
+
+  # Create an up migration from a string and generate the down migration from it
+  hasura migrate create migration-name --up-sql "CREATE TABLE article(id serial NOT NULL, title text NOT NULL);" --generate-down
+
+  # Create a migration from the changes made on the database which are not in the local migrations
+  hasura migrate create migration-name --diff --database-name default
+
+  # Replay the local migrations into an empty database connected to Hasura instead of a temporary schema
+  hasura migrate create migration-name --diff --diff-database scratch --database-name default
 `
 
 func newMigrateCreateCmd(ec *cli.ExecutionContext) *cobra.Command {
@@ -68,6 +82,7 @@ func newMigrateCreateCmd(ec *cli.ExecutionContext) *cobra.Command {
 				"version": version,
 				"name":    opts.name,
 			}).Info("Migrations files created")
+			opts.EC.SetOutput(migrateCreateOutput{Version: version, Name: opts.name})
 			return nil
 		},
 	}
@@ -81,6 +96,9 @@ func newMigrateCreateCmd(ec *cli.ExecutionContext) *cobra.Command {
 	f.BoolVar(&opts.metaDataServer, "metadata-from-server", false, "take metadata from the server and write it as an up migration file")
 	f.StringVar(&opts.upSQL, "up-sql", "", "sql string/query that is to be used to create an up migration")
 	f.StringVar(&opts.downSQL, "down-sql", "", "sql string/query that is to be used to create a down migration")
+	f.BoolVar(&opts.generateDown, "generate-down", false, "generate the down migration from the statements of the up migration given by --up-sql or --sql-from-file when --down-sql is not set (postgres only)")
+	f.BoolVar(&opts.diff, "diff", false, "create the migration from the differences between the tables of the database and the tables created by the local migrations (postgres only). tables, columns, constraints and indexes of the schemas given by --schema are compared")
+	f.StringVar(&opts.diffDatabase, "diff-database", "", "name of an empty database connected to Hasura into which local migrations are replayed for --diff, the replayed schemas are dropped afterwards (default: a temporary schema in the database, which requires migrations not to change objects outside of the diffed schema)")
 
 	migrateCreateCmd.MarkFlagFilename("sql-from-file")
 	migrateCreateCmd.MarkFlagFilename("metadata-from-file")
@@ -103,9 +121,18 @@ type migrateCreateOptions struct {
 	schemaNames    []string
 	upSQL          string
 	downSQL        string
+	generateDown   bool
+	diff           bool
+	diffDatabase   string
 	Source         cli.Source
 }
 
+// migrateCreateOutput is the result of migrate create reported with --output-format json
+type migrateCreateOutput struct {
+	Version int64  `json:"version"`
+	Name    string `json:"name"`
+}
+
 func (o *migrateCreateOptions) run() (version int64, err error) {
 	timestamp := getTime()
 	createOptions := mig.New(timestamp, o.name, filepath.Join(o.EC.MigrationDir, o.Source.Name))
@@ -133,7 +160,9 @@ func (o *migrateCreateOptions) run() (version int64, err error) {
 		return 0, errors.New("only one metadata type can be set")
 	}
 
-	if o.flags.Changed("up-sql") && !o.flags.Changed("down-sql") {
+	generateDown := o.generateDown && (o.flags.Changed("up-sql") || o.flags.Changed("sql-from-file")) && !o.flags.Changed("down-sql") &&
+		(o.EC.Config.Version < cli.V3 || o.Source.Kind == hasura.SourceKindPG)
+	if o.flags.Changed("up-sql") && !o.flags.Changed("down-sql") && !generateDown {
 		o.EC.Logger.Warn("you are creating an up migration without a down migration")
 	}
 
@@ -141,6 +170,13 @@ func (o *migrateCreateOptions) run() (version int64, err error) {
 		o.EC.Logger.Warn("you are creating a down migration without an up migration")
 	}
 
+	if o.diff && (o.sqlServer || o.metaDataServer || o.flags.Changed("sql-from-file") || o.flags.Changed("metadata-from-file") || o.flags.Changed("up-sql") || o.flags.Changed("down-sql")) {
+		return 0, errors.New("--diff cannot be used with other flags which set the contents of the migration")
+	}
+	if o.flags.Changed("diff-database") && !o.diff {
+		return 0, errors.New("--diff-database can be used only with --diff")
+	}
+
 	var migrateDrv *migrate.Migrate
 	if o.sqlServer || o.metaDataServer || o.flags.Changed("up-sql") || o.flags.Changed("down-sql") {
 		migrateDrv, err = migrate.NewMigrate(o.EC, true, o.Source.Name, o.Source.Kind)
@@ -164,6 +200,18 @@ func (o *migrateCreateOptions) run() (version int64, err error) {
 		createOptions.SetSQLUp(string(data))
 	}
 
+	if o.diff {
+		up, down, err := o.diffSchema()
+		if err != nil {
+			return 0, errors.Wrap(err, "cannot diff schema")
+		}
+		if up == "" {
+			return 0, errors.New("no differences found between the local migrations and the database")
+		}
+		createOptions.SetSQLUp(up)
+		createOptions.SetSQLDown(down)
+	}
+
 	if o.flags.Changed("metadata-from-file") {
 		// metadata-file flag is set
 		err := createOptions.SetMetaUpFromFile(o.metaDataFile)
@@ -187,13 +235,23 @@ func (o *migrateCreateOptions) run() (version int64, err error) {
 		}
 	}
 
+	if generateDown && createOptions.SQLUp != nil {
+		down, err := o.generateDownSQL(string(createOptions.SQLUp))
+		if err != nil {
+			return 0, errors.Wrap(err, "cannot generate down migration")
+		}
+		if down != "" {
+			createOptions.SetSQLDown(down)
+		}
+	}
+
 	if !o.flags.Changed("sql-from-file") && !o.flags.Changed("metadata-from-file") && !o.metaDataServer && !o.sqlServer && o.EC.Config.Version == cli.V1 && !o.flags.Changed("up-sql") && !o.flags.Changed("down-sql") {
 		// Set empty data for [up|down].yaml
 		createOptions.MetaUp = []byte(`[]`)
 		createOptions.MetaDown = []byte(`[]`)
 	}
 
-	if !o.flags.Changed("sql-from-file") && !o.flags.Changed("metadata-from-file") && !o.metaDataServer && !o.sqlServer && o.EC.Config.Version != cli.V1 && !o.flags.Changed("up-sql") && !o.flags.Changed("down-sql") {
+	if !o.flags.Changed("sql-from-file") && !o.flags.Changed("metadata-from-file") && !o.metaDataServer && !o.sqlServer && o.EC.Config.Version != cli.V1 && !o.flags.Changed("up-sql") && !o.flags.Changed("down-sql") && !o.diff {
 		// Set empty data for [up|down].sql
 		createOptions.SQLUp = []byte(``)
 		createOptions.SQLDown = []byte(``)
@@ -211,6 +269,65 @@ func (o *migrateCreateOptions) run() (version int64, err error) {
 	return timestamp, nil
 }
 
+// diffSchema returns the up and down SQL which bring the schema created by the
+// local migrations to the schema of the database
+func (o *migrateCreateOptions) diffSchema() (up, down string, err error) {
+	if o.EC.Config.Version >= cli.V3 && o.Source.Kind != hasura.SourceKindPG {
+		return "", "", fmt.Errorf("--diff is not supported on database %s of kind %s", o.Source.Name, o.Source.Kind)
+	}
+	parser := source.DefaultParse
+	if o.EC.Config.Version >= cli.V2 {
+		parser = source.DefaultParsev2
+	}
+	opts := mig.SchemaDiffOptions{
+		Directory: filepath.Join(o.EC.MigrationDir, o.Source.Name),
+		Parser:    parser,
+		Database:  o.pgDatabase(o.Source.Name),
+		Schemas:   o.schemaNames,
+		Logger:    o.EC.Logger,
+	}
+	if o.diffDatabase != "" {
+		opts.Shadow = o.pgDatabase(o.diffDatabase)
+	}
+	return mig.DiffSchema(opts)
+}
+
+// generateDownSQL returns the SQL which reverts up, objects dropped by
+// up are restored from their definitions in the local migrations
+func (o *migrateCreateOptions) generateDownSQL(up string) (string, error) {
+	directory := filepath.Join(o.EC.MigrationDir, o.Source.Name)
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return "", err
+	}
+	f, err := file.New(migrate.GetFilePath(directory).String(), o.EC.Logger)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if o.EC.Config.Version >= cli.V2 {
+		f.DefaultParser(source.DefaultParsev2)
+	} else {
+		f.DefaultParser(source.DefaultParse)
+	}
+	if err := f.Scan(); err != nil {
+		return "", err
+	}
+	return mig.GenerateDownSQL(f, 0, up, o.EC.Logger)
+}
+
+func (o *migrateCreateOptions) pgDatabase(sourceName string) *pgdiff.Database {
+	db := &pgdiff.Database{
+		PGDump: o.EC.APIClient.PGDump,
+		Source: sourceName,
+	}
+	if o.EC.HasMetadataV3 {
+		db.SourceOps = o.EC.APIClient.V2Query
+	} else {
+		db.SourceOps = o.EC.APIClient.V1Query
+	}
+	return db
+}
+
 func getTime() int64 {
 	startTime := time.Now()
 	return startTime.UnixNano() / int64(time.Millisecond)
@@ -0,0 +1,151 @@
+package commands
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/hasura/graphql-engine/cli/v2"
+	"github.com/hasura/graphql-engine/cli/v2/internal/hasura"
+	"github.com/hasura/graphql-engine/cli/v2/migrate"
+	"github.com/hasura/graphql-engine/cli/v2/migrate/lint"
+	"github.com/hasura/graphql-engine/cli/v2/migrate/source"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func newMigrateLintCmd(ec *cli.ExecutionContext) *cobra.Command {
+	opts := &MigrateLintOptions{
+		EC: ec,
+	}
+	migrateLintCmd := &cobra.Command{
+		Use:   "lint",
+		Short: "Check migrations for statements which are unsafe to run on a live database",
+		Long: `Check the SQL of migrations for statements which lock or rewrite existing tables, or which cannot be reverted by the down migration.
+Each violation is reported with the file and line of the statement. The command fails if a rule with error severity is violated.
+
+The severity of rules can be changed in config.yaml:
+
+  migrations_lint:
+    rules:
+      create-index-not-concurrently: error
+      alter-column-type: off
+      # on Postgres 11 and later, defaults which are not volatile do not rewrite tables
+      add-column-default-before-pg11: off
+
+Available rules:
+` + lintRulesUsage(),
+		Example: `  # Lint migrations of a database:
+  hasura migrate lint --database-name default
+
+  # Lint migrations from a bundle:
+  hasura migrate lint --database-name default --migrations-url bundle://migrations.tar.gz`,
+		SilenceUsage: true,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return validateConfigV3Flags(cmd, ec)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.Source = ec.Source
+			violations, err := opts.Run()
+			if err != nil {
+				return err
+			}
+			opts.EC.SetOutput(migrateLintOutput{Violations: append([]lint.Violation{}, violations...)})
+			if !opts.EC.IsJSONOutput() {
+				for _, v := range violations {
+					fmt.Fprintln(opts.EC.Stdout, v)
+				}
+			}
+			if lint.HasErrors(violations) {
+				return errors.New("migrations failed lint")
+			}
+			if len(violations) == 0 {
+				opts.EC.Logger.Info("no lint violations found")
+			}
+			return nil
+		},
+	}
+	f := migrateLintCmd.Flags()
+	f.StringVar(&ec.MigrationsSourceURL, "migrations-url", "", "read migrations from a source other than the migrations directory (eg: git://HEAD~3:migrations, bundle://migrations.tar.gz)")
+	return migrateLintCmd
+}
+
+type MigrateLintOptions struct {
+	EC     *cli.ExecutionContext
+	Source cli.Source
+}
+
+// migrateLintOutput is the result of migrate lint reported with --output-format json
+type migrateLintOutput struct {
+	Violations []lint.Violation `json:"violations"`
+}
+
+// Run returns the lint violations of all migrations of the database
+func (o *MigrateLintOptions) Run() ([]lint.Violation, error) {
+	if o.EC.Config.Version <= cli.V2 {
+		o.Source.Name = ""
+		o.Source.Kind = hasura.SourceKindPG
+	}
+	if o.Source.Kind != hasura.SourceKindPG {
+		return nil, fmt.Errorf("migrate lint is supported only for postgres databases, %s is a %s database", o.Source.Name, o.Source.Kind)
+	}
+	linter, err := newMigrationsLinter(o.EC)
+	if err != nil {
+		return nil, err
+	}
+	sourceURL := migrate.GetFilePath(filepath.Join(o.EC.MigrationDir, o.Source.Name)).String()
+	if o.EC.MigrationsSourceURL != "" {
+		sourceURL = migrate.GetSourceURL(o.EC.MigrationsSourceURL, o.Source.Name)
+	}
+	drv, err := source.Open(sourceURL, o.EC.Logger)
+	if err != nil {
+		return nil, err
+	}
+	defer drv.Close()
+	if o.EC.Config.Version >= cli.V2 {
+		drv.DefaultParser(source.DefaultParsev2)
+	} else {
+		drv.DefaultParser(source.DefaultParse)
+	}
+	if err := drv.Scan(); err != nil {
+		return nil, err
+	}
+	migrations, err := lint.ReadMigrations(drv, nil, migrationsDisplayDirectory(o.EC, o.Source.Name))
+	if err != nil {
+		return nil, err
+	}
+	return linter.Lint(migrations), nil
+}
+
+// newMigrationsLinter returns a linter using the rule severities of config.yaml
+func newMigrationsLinter(ec *cli.ExecutionContext) (*lint.Linter, error) {
+	var severities map[string]string
+	if ec.Config.MigrationsLint != nil {
+		severities = ec.Config.MigrationsLint.Rules
+	}
+	linter, err := lint.New(severities)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid migrations_lint config")
+	}
+	return linter, nil
+}
+
+// migrationsDisplayDirectory returns the directory against which migration
+// files of a database are reported, relative to the project when possible
+func migrationsDisplayDirectory(ec *cli.ExecutionContext, sourceName string) string {
+	if ec.MigrationsSourceURL != "" {
+		return sourceName
+	}
+	directory := filepath.Join(ec.MigrationDir, sourceName)
+	if rel, err := filepath.Rel(ec.ExecutionDirectory, directory); err == nil {
+		return rel
+	}
+	return directory
+}
+
+func lintRulesUsage() string {
+	var usage string
+	for _, r := range lint.Rules() {
+		usage += fmt.Sprintf("  %s (default: %s)\n      %s\n", r.Name, r.Severity, r.Description)
+	}
+	return usage
+}
@@ -0,0 +1,190 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/hasura/graphql-engine/cli/v2"
+	"github.com/hasura/graphql-engine/cli/v2/internal/hasura"
+	"github.com/hasura/graphql-engine/cli/v2/migrate"
+	mig "github.com/hasura/graphql-engine/cli/v2/migrate/cmd"
+	"github.com/hasura/graphql-engine/cli/v2/migrate/source"
+	"github.com/hasura/graphql-engine/cli/v2/util"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func newMigrateRebaseCmd(ec *cli.ExecutionContext) *cobra.Command {
+	opts := &MigrateRebaseOptions{
+		EC: ec,
+	}
+	migrateRebaseCmd := &cobra.Command{
+		Use:   "rebase",
+		Short: "Renumber local migrations which are older than the latest migration applied on the database",
+		Long: `Find local migrations which are not applied on the database but have a version older than the latest applied migration
+(usually migrations of a branch merged after newer migrations were applied) and rename them to fresh versions, keeping their relative order.
+References to the renamed migrations in depends_on declarations are updated.
+Migrations selected with --version which are already applied on the database are recorded as applied under their new version.`,
+		Example: `  # Renumber out of order migrations of a database:
+  hasura migrate rebase --database-name default
+
+  # Print the migrations which will be renumbered:
+  hasura migrate rebase --database-name default --dry-run
+
+  # Renumber particular migrations:
+  hasura migrate rebase --database-name default --version 1620000000000 --version 1620000000001`,
+		SilenceUsage: true,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return validateConfigV3Flags(cmd, ec)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.Source = ec.Source
+			opts.EC.Spin("Rebasing migrations...")
+			renames, err := opts.Run()
+			opts.EC.Spinner.Stop()
+			if err != nil {
+				return err
+			}
+			opts.EC.SetOutput(migrateRebaseOutput{DryRun: opts.DryRun, Renames: append([]MigrationRename{}, renames...)})
+			if len(renames) == 0 {
+				opts.EC.Logger.Info("nothing to rebase")
+				return nil
+			}
+			if !opts.EC.IsJSONOutput() {
+				fmt.Fprintf(os.Stdout, "%s", printRenames(renames))
+			}
+			if !opts.DryRun {
+				opts.EC.Logger.Info("migrations rebased")
+			}
+			return nil
+		},
+	}
+	f := migrateRebaseCmd.Flags()
+	f.Int64SliceVar(&opts.Versions, "version", nil, "rebase only these migration versions")
+	f.BoolVar(&opts.DryRun, "dry-run", false, "print the migrations which will be renumbered without changing anything")
+	return migrateRebaseCmd
+}
+
+type MigrateRebaseOptions struct {
+	EC       *cli.ExecutionContext
+	Source   cli.Source
+	Versions []int64
+	DryRun   bool
+}
+
+// MigrationRename is a local migration renamed from From to To
+type MigrationRename struct {
+	From uint64 `json:"from"`
+	To   uint64 `json:"to"`
+	Name string `json:"name"`
+}
+
+// migrateRebaseOutput is the result of migrate rebase reported with --output-format json
+type migrateRebaseOutput struct {
+	DryRun  bool              `json:"dry_run,omitempty"`
+	Renames []MigrationRename `json:"renames"`
+}
+
+func (o *MigrateRebaseOptions) Run() ([]MigrationRename, error) {
+	if o.EC.Config.Version <= cli.V2 {
+		o.Source.Name = ""
+		o.Source.Kind = hasura.SourceKindPG
+	}
+	migrateDrv, err := migrate.NewMigrate(o.EC, true, o.Source.Name, o.Source.Kind)
+	if err != nil {
+		return nil, err
+	}
+	status, err := executeStatus(migrateDrv)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot fetch migrate status")
+	}
+
+	versions, err := o.versionsToRebase(status)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, nil
+	}
+
+	// fresh versions have to be newer than every local migration
+	next := uint64(getTime())
+	if latest := status.Index[len(status.Index)-1]; next <= latest {
+		next = latest + 1
+	}
+	var renames []MigrationRename
+	renameMap := map[uint64]uint64{}
+	for _, version := range versions {
+		renames = append(renames, MigrationRename{From: version, To: next, Name: status.Migrations[version].Name})
+		renameMap[version] = next
+		next++
+	}
+	if o.DryRun {
+		return renames, nil
+	}
+
+	parser := source.DefaultParse
+	if o.EC.Config.Version >= cli.V2 {
+		parser = source.DefaultParsev2
+	}
+	if err := mig.RenameMigrations(filepath.Join(o.EC.MigrationDir, o.Source.Name), parser, renameMap); err != nil {
+		return nil, errors.Wrap(err, "cannot rename migrations")
+	}
+	if err := migrateDrv.ReScan(); err != nil {
+		return nil, err
+	}
+	if err := migrateDrv.MoveVersions(renameMap); err != nil {
+		return nil, errors.Wrap(err, "migrations were renamed but updating the migrations state on the server failed")
+	}
+	return renames, nil
+}
+
+// versionsToRebase returns the versions selected with --version or else the local
+// migrations which are not applied and are older than the latest applied migration
+func (o *MigrateRebaseOptions) versionsToRebase(status *migrate.Status) ([]uint64, error) {
+	var versions []uint64
+	if len(o.Versions) > 0 {
+		for _, version := range o.Versions {
+			if version < 0 {
+				return nil, fmt.Errorf("invalid migration version %d", version)
+			}
+			if m, ok := status.Read(uint64(version)); !ok || !m.IsPresent {
+				return nil, fmt.Errorf("migration %d is not present in the migrations directory", version)
+			}
+			versions = append(versions, uint64(version))
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+		return versions, nil
+	}
+
+	var latestApplied uint64
+	for _, version := range status.Index {
+		if status.Migrations[version].IsApplied {
+			latestApplied = version
+		}
+	}
+	for _, version := range status.Index {
+		m := status.Migrations[version]
+		if version < latestApplied && m.IsPresent && !m.IsApplied {
+			versions = append(versions, version)
+		}
+	}
+	return versions, nil
+}
+
+func printRenames(renames []MigrationRename) *bytes.Buffer {
+	out := new(tabwriter.Writer)
+	buf := &bytes.Buffer{}
+	out.Init(buf, 0, 8, 2, ' ', 0)
+	w := util.NewPrefixWriter(out)
+	w.Write(util.LEVEL_0, "OLD VERSION\tNEW VERSION\tNAME\n")
+	for _, rename := range renames {
+		w.Write(util.LEVEL_0, "%d\t%d\t%s\n", rename.From, rename.To, rename.Name)
+	}
+	out.Flush()
+	return buf
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"text/tabwriter"
 
+	"github.com/hasura/graphql-engine/cli/v2/internal/hasura"
 	"github.com/hasura/graphql-engine/cli/v2/util"
 
 	"github.com/hasura/graphql-engine/cli/v2/migrate"
@@ -33,7 +34,10 @@ func newMigrateSquashCmd(ec *cli.ExecutionContext) *cobra.Command {
   hasura migrate squash --from 123
 
   # Add a name for the new squashed migration
-  hasura migrate squash --name "<name>" --from 123`,
+  hasura migrate squash --name "<name>" --from 123
+
+  # Generate down SQL when none of the squashed migrations has one
+  hasura migrate squash --from 123 --generate-down`,
 		SilenceUsage: true,
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			return validateConfigV3Flags(cmd, ec)
@@ -52,6 +56,7 @@ func newMigrateSquashCmd(ec *cli.ExecutionContext) *cobra.Command {
 	f.Uint64Var(&opts.from, "from", 0, "start squashing from this version")
 	f.StringVar(&opts.name, "name", "squashed", "name for the new squashed migration")
 	f.BoolVar(&opts.deleteSource, "delete-source", false, "delete the source files after squashing without any confirmation")
+	f.BoolVar(&opts.generateDown, "generate-down", false, "generate the down SQL from the squashed up SQL when none of the squashed migrations has down SQL (postgres only)")
 
 	// mark flag as required
 	migrateSquashCmd.MarkFlagRequired("from")
@@ -67,6 +72,7 @@ type migrateSquashOptions struct {
 	newVersion int64
 
 	deleteSource bool
+	generateDown bool
 	Source       cli.Source
 }
 
@@ -79,7 +85,8 @@ func (o *migrateSquashOptions) run() error {
 		return errors.Wrap(err, "unable to initialize migrations driver")
 	}
 
-	versions, err := mig.SquashCmd(migrateDrv, o.from, o.newVersion, o.name, filepath.Join(o.EC.MigrationDir, o.Source.Name))
+	generateDown := o.generateDown && (o.EC.Config.Version < cli.V3 || o.Source.Kind == hasura.SourceKindPG)
+	versions, err := mig.SquashCmd(migrateDrv, o.from, o.newVersion, o.name, filepath.Join(o.EC.MigrationDir, o.Source.Name), generateDown)
 	o.EC.Spinner.Stop()
 	if err != nil {
 		return errors.Wrap(err, "unable to squash migrations")
@@ -89,8 +96,15 @@ func (o *migrateSquashOptions) run() error {
 	// TODO: capture keyboard interrupt and offer to delete the squashed migration
 
 	o.EC.Logger.Infof("Created '%d_%s' after squashing '%d' till '%d'", o.newVersion, o.name, versions[0], versions[len(versions)-1])
+	output := &migrateSquashOutput{Version: o.newVersion, Name: o.name, SquashedVersions: versions}
+	o.EC.SetOutput(output)
 
 	if !o.deleteSource {
+		// the confirmation prompt would be mixed with the JSON document on stdout
+		if o.EC.IsJSONOutput() {
+			o.EC.Logger.Info("squashed migrations are not deleted, use --delete-source to delete them")
+			return nil
+		}
 		ok := ask2confirmDeleteMigrations(versions, o.EC.Logger)
 		if !ok {
 			return nil
@@ -115,9 +129,18 @@ func (o *migrateSquashOptions) run() error {
 	if err != nil {
 		return err
 	}
+	output.DeletedSource = true
 	return nil
 }
 
+// migrateSquashOutput is the result of migrate squash reported with --output-format json
+type migrateSquashOutput struct {
+	Version          int64   `json:"version"`
+	Name             string  `json:"name"`
+	SquashedVersions []int64 `json:"squashed_versions"`
+	DeletedSource    bool    `json:"deleted_source"`
+}
+
 func ask2confirmDeleteMigrations(versions []int64, log *logrus.Logger) bool {
 	var s string
 
@@ -40,11 +40,17 @@ func newMigrateStatusCmd(ec *cli.ExecutionContext) *cobra.Command {
 			if err != nil {
 				return err
 			}
+			if opts.EC.IsJSONOutput() {
+				opts.EC.SetOutput(status)
+				return nil
+			}
 			buf := printStatus(status)
 			fmt.Fprintf(os.Stdout, "%s", buf)
 			return nil
 		},
 	}
+	f := migrateStatusCmd.Flags()
+	f.StringVar(&ec.MigrationsSourceURL, "migrations-url", "", "read migrations from a source other than the migrations directory (eg: git://HEAD~3:migrations, bundle://migrations.tar.gz)")
 	return migrateStatusCmd
 }
 
@@ -74,13 +80,14 @@ func printStatus(status *migrate.Status) *bytes.Buffer {
 	buf := &bytes.Buffer{}
 	out.Init(buf, 0, 8, 2, ' ', 0)
 	w := util.NewPrefixWriter(out)
-	w.Write(util.LEVEL_0, "VERSION\tNAME\tSOURCE STATUS\tDATABASE STATUS\n")
+	w.Write(util.LEVEL_0, "VERSION\tNAME\tSOURCE STATUS\tDATABASE STATUS\tMODIFIED AFTER APPLY\n")
 	for _, version := range status.Index {
-		w.Write(util.LEVEL_0, "%d\t%s\t%s\t%s\n",
+		w.Write(util.LEVEL_0, "%d\t%s\t%s\t%s\t%s\n",
 			version,
 			status.Migrations[version].Name,
 			convertBool(status.Migrations[version].IsPresent),
 			convertBool(status.Migrations[version].IsApplied),
+			modifiedStatus(status.Migrations[version]),
 		)
 	}
 	out.Flush()
@@ -96,3 +103,16 @@ func convertBool(ok bool) string {
 	}
 	return ""
 }
+
+func modifiedStatus(migrStatus *migrate.MigrationStatus) string {
+	switch {
+	case !migrStatus.IsPresent || !migrStatus.IsApplied:
+		return "-"
+	// migrations applied by older versions of the CLI do not have a checksum recorded
+	case migrStatus.AppliedChecksum == "":
+		return "Unknown"
+	case migrStatus.IsModified:
+		return "Yes"
+	}
+	return "No"
+}
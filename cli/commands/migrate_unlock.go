@@ -0,0 +1,83 @@
+package commands
+
+import (
+	"time"
+
+	"github.com/hasura/graphql-engine/cli/v2"
+	"github.com/hasura/graphql-engine/cli/v2/internal/statestore"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func newMigrateUnlockCmd(ec *cli.ExecutionContext) *cobra.Command {
+	opts := &MigrateUnlockOptions{
+		EC: ec,
+	}
+	migrateUnlockCmd := &cobra.Command{
+		Use:   "unlock",
+		Short: "Release the migrations lock of a database",
+		Long: `Release the lock taken on a database while migrations are applied on it.
+The lock is released when migrate apply finishes, use this command only when the process holding it was killed before releasing it.`,
+		Example: `  # Release the migrations lock of a database:
+  hasura migrate unlock --database-name default`,
+		SilenceUsage: true,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return validateConfigV3Flags(cmd, ec)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.Source = ec.Source
+			lock, err := opts.Run()
+			if err != nil {
+				return err
+			}
+			output := migrateUnlockOutput{}
+			if lock != nil {
+				output.Released = &migrateUnlockLockOutput{Owner: lock.Owner, AcquiredAt: lock.AcquiredAt}
+				opts.EC.Logger.Infof("released migrations lock held by %s since %s", lock.Owner, lock.AcquiredAt.Local().Format(time.RFC1123))
+			} else {
+				opts.EC.Logger.Info("migrations lock is not held")
+			}
+			opts.EC.SetOutput(output)
+			return nil
+		},
+	}
+	return migrateUnlockCmd
+}
+
+type MigrateUnlockOptions struct {
+	EC     *cli.ExecutionContext
+	Source cli.Source
+}
+
+// migrateUnlockOutput is the result of migrate unlock reported with --output-format json
+type migrateUnlockOutput struct {
+	// Released is the lock which was released, it is null if the lock was not held
+	Released *migrateUnlockLockOutput `json:"released"`
+}
+
+type migrateUnlockLockOutput struct {
+	Owner      string    `json:"owner"`
+	AcquiredAt time.Time `json:"acquired_at"`
+}
+
+// Run releases the migrations lock of the database and returns the lock which was released
+func (o *MigrateUnlockOptions) Run() (*statestore.MigrationsLock, error) {
+	if o.EC.Config.Version <= cli.V2 {
+		o.Source.Name = ""
+	}
+	store := cli.GetMigrationsStateStore(o.EC, o.Source.Kind)
+	if err := store.PrepareMigrationsStateStore(o.Source.Name); err != nil {
+		return nil, errors.Wrap(err, "cannot prepare migrations state")
+	}
+	lock, err := store.GetLock(o.Source.Name)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read migrations lock")
+	}
+	if lock == nil {
+		return nil, nil
+	}
+	if err := store.Unlock(o.Source.Name, lock.ID); err != nil {
+		return nil, errors.Wrap(err, "cannot release migrations lock")
+	}
+	return lock, nil
+}
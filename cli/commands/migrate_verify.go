@@ -0,0 +1,114 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/hasura/graphql-engine/cli/v2"
+	"github.com/hasura/graphql-engine/cli/v2/internal/hasura"
+	"github.com/hasura/graphql-engine/cli/v2/migrate"
+	"github.com/hasura/graphql-engine/cli/v2/util"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func newMigrateVerifyCmd(ec *cli.ExecutionContext) *cobra.Command {
+	opts := &MigrateVerifyOptions{
+		EC: ec,
+	}
+	migrateVerifyCmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Verify that applied migrations were not modified locally",
+		Long: `Compare the checksum recorded on the server when a migration was applied with the checksum of the local migration files.
+The command fails if any applied migration was modified after it was applied.
+Migrations applied by older versions of the CLI do not have a checksum recorded and are skipped.`,
+		Example: `  # Verify migrations of a database:
+  hasura migrate verify --database-name default
+
+  # Verify migrations on a different server:
+  hasura migrate verify --endpoint "<endpoint>" --database-name default`,
+		SilenceUsage: true,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return validateConfigV3Flags(cmd, ec)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.EC.Spin("Verifying migrations...")
+			opts.Source = ec.Source
+			modified, err := opts.Run()
+			opts.EC.Spinner.Stop()
+			if err != nil {
+				return err
+			}
+			output := migrateVerifyOutput{ModifiedMigrations: []modifiedMigrationOutput{}}
+			for _, migration := range modified {
+				output.ModifiedMigrations = append(output.ModifiedMigrations, modifiedMigrationOutput{migration.Version, migration.Name})
+			}
+			opts.EC.SetOutput(output)
+			if len(modified) == 0 {
+				opts.EC.Logger.Info("applied migrations match the local migration files")
+				return nil
+			}
+			if !opts.EC.IsJSONOutput() {
+				buf := printModifiedMigrations(modified)
+				fmt.Fprintf(os.Stdout, "%s", buf)
+			}
+			return fmt.Errorf("%d applied migration(s) were modified after being applied", len(modified))
+		},
+	}
+	f := migrateVerifyCmd.Flags()
+	f.StringVar(&ec.MigrationsSourceURL, "migrations-url", "", "read migrations from a source other than the migrations directory (eg: git://HEAD~3:migrations, bundle://migrations.tar.gz)")
+	return migrateVerifyCmd
+}
+
+type MigrateVerifyOptions struct {
+	EC     *cli.ExecutionContext
+	Source cli.Source
+}
+
+// migrateVerifyOutput is the result of migrate verify reported with --output-format json
+type migrateVerifyOutput struct {
+	ModifiedMigrations []modifiedMigrationOutput `json:"modified_migrations"`
+}
+
+type modifiedMigrationOutput struct {
+	Version uint64 `json:"version"`
+	Name    string `json:"name"`
+}
+
+// Run returns the applied migrations whose local files were modified after they were applied
+func (o *MigrateVerifyOptions) Run() ([]*migrate.MigrationStatus, error) {
+	if o.EC.Config.Version <= cli.V2 {
+		o.Source.Name = ""
+		o.Source.Kind = hasura.SourceKindPG
+	}
+	migrateDrv, err := migrate.NewMigrate(o.EC, true, o.Source.Name, o.Source.Kind)
+	if err != nil {
+		return nil, err
+	}
+	status, err := executeStatus(migrateDrv)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot fetch migrate status")
+	}
+	var modified []*migrate.MigrationStatus
+	for _, version := range status.Index {
+		if status.Migrations[version].IsModified {
+			modified = append(modified, status.Migrations[version])
+		}
+	}
+	return modified, nil
+}
+
+func printModifiedMigrations(migrations []*migrate.MigrationStatus) *bytes.Buffer {
+	out := new(tabwriter.Writer)
+	buf := &bytes.Buffer{}
+	out.Init(buf, 0, 8, 2, ' ', 0)
+	w := util.NewPrefixWriter(out)
+	w.Write(util.LEVEL_0, "VERSION\tNAME\n")
+	for _, migration := range migrations {
+		w.Write(util.LEVEL_0, "%d\t%s\n", migration.Version, migration.Name)
+	}
+	out.Flush()
+	return buf
+}
@@ -61,6 +61,7 @@ func init() {
 	rootCmd.AddCommand(
 		NewInitCmd(ec),
 		NewConsoleCmd(ec),
+		NewDevCmd(ec),
 		NewMetadataCmd(ec),
 		NewMigrateCmd(ec),
 		NewSeedCmd(ec),
@@ -79,6 +80,7 @@ func init() {
 	f.BoolVar(&ec.SkipUpdateCheck, "skip-update-check", false, "skip automatic update check on command execution")
 	f.BoolVar(&ec.NoColor, "no-color", false, "do not colorize output (default: false)")
 	f.StringVar(&ec.Envfile, "envfile", ".env", ".env filename to load ENV vars from")
+	f.StringVar((*string)(&ec.OutputFormat), "output-format", "", "report the result of the command as a single JSON document on stdout, logs are still written to stderr. Allowed values: json")
 }
 
 // NewDefaultHasuraCommand creates the `hasura` command with default arguments
@@ -125,5 +127,8 @@ func Execute() error {
 	if ec.Spinner != nil {
 		ec.Spinner.Stop()
 	}
+	if outputErr := ec.WriteOutput(execCmd.CommandPath(), err); outputErr != nil {
+		ec.Logger.WithError(outputErr).Error("writing command output failed")
+	}
 	return err
 }
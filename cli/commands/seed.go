@@ -77,6 +77,7 @@ func NewSeedCmd(ec *cli.ExecutionContext) *cobra.Command {
 	seedCmd.AddCommand(
 		newSeedCreateCmd(ec),
 		newSeedApplyCmd(ec),
+		newSeedStatusCmd(ec),
 	)
 
 	f := seedCmd.PersistentFlags()
@@ -106,5 +107,6 @@ func getSeedDriver(configVersion cli.ConfigVersion) (driver *seed.Driver) {
 	} else {
 		driver = seed.NewDriver(ec.APIClient.V1Query.Bulk, ec.APIClient.PGDump)
 	}
+	driver.StateStore = cli.GetSeedsStateStore(ec)
 	return driver
 }
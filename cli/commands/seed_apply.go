@@ -1,6 +1,8 @@
 package commands
 
 import (
+	"time"
+
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 
@@ -15,6 +17,10 @@ type SeedApplyOptions struct {
 	// seed file to apply
 	FileNames []string
 	Source    cli.Source
+	// Force applies the seed files which were applied already
+	Force bool
+
+	appliedFiles []string
 }
 
 func newSeedApplyCmd(ec *cli.ExecutionContext) *cobra.Command {
@@ -24,11 +30,22 @@ func newSeedApplyCmd(ec *cli.ExecutionContext) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "apply",
 		Short: "Apply seed data",
+		Long: `Apply the seed files of the database: .sql files are run as they are, the rows of .csv, .json and .ndjson files are inserted into the table the file is named after, eg: seeds/<database>/public.authors.csv.
+
+The columns are named by the header row of a CSV file and by the keys of the objects of a JSON file (an array of objects) or an NDJSON file (an object per line). A column named <column>:<type>, eg: created_at:timestamptz, has its values cast to the type. Empty CSV values are NULL and the columns missing from a JSON object take their default value.
+
+From config v3 the applied seed files are recorded in the catalog state of the server with a hash of their contents, like migrations. Only the seed files which were not applied or changed since they were applied are applied, use --force to apply them all again and "hasura seed status" to list them. Seed files are recorded as dirty while they are applied, dirty seed files failed to be applied or to be recorded as applied and are only applied again with --force.`,
 		Example: `  # Apply all seeds on the database:
   hasura seed apply
 
   # Apply only a particular file:
-  hasura seed apply --file seeds/1234_add_some_seed_data.sql`,
+  hasura seed apply --file seeds/1234_add_some_seed_data.sql
+
+  # Insert the rows of a CSV file into the public.authors table:
+  hasura seed apply --file public.authors.csv
+
+  # Apply all seeds again, including the applied ones:
+  hasura seed apply --force`,
 		SilenceUsage: false,
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			return ec.Validate()
@@ -42,15 +59,53 @@ func newSeedApplyCmd(ec *cli.ExecutionContext) *cobra.Command {
 			if err != nil {
 				return err
 			}
+			if len(opts.appliedFiles) == 0 {
+				opts.EC.Logger.Info("No new or changed seed files to apply, use --force to apply them again")
+				return nil
+			}
 			opts.EC.Logger.Info("Seeds planted")
 			return nil
 		},
 	}
 	cmd.Flags().StringArrayVarP(&opts.FileNames, "file", "f", []string{}, "seed file to apply")
+	cmd.Flags().BoolVar(&opts.Force, "force", false, "apply the seed files even if they were applied already")
 	return cmd
 }
 
+// seedApplyOutput is the result of seed apply reported with --output-format json
+type seedApplyOutput struct {
+	Database   string   `json:"database,omitempty"`
+	Files      []string `json:"files"`
+	DurationMS int64    `json:"duration_ms"`
+}
+
 func (o *SeedApplyOptions) Run() error {
 	fs := afero.NewOsFs()
-	return o.Driver.ApplySeedsToDatabase(fs, o.EC.SeedsDirectory, o.FileNames, o.EC.Source)
+	start := time.Now()
+	fileNames := o.FileNames
+	if o.Driver.StateStore != nil && !o.Force {
+		pending, err := o.Driver.PendingSeedFiles(fs, o.EC.SeedsDirectory, o.FileNames, o.EC.Source)
+		if err != nil {
+			return err
+		}
+		if len(pending) == 0 {
+			o.EC.SetOutput(seedApplyOutput{
+				Database: o.EC.Source.Name,
+				Files:    []string{},
+			})
+			return nil
+		}
+		fileNames = pending
+	}
+	files, err := o.Driver.ApplySeedsToDatabase(fs, o.EC.SeedsDirectory, fileNames, o.EC.Source)
+	if err != nil {
+		return err
+	}
+	o.appliedFiles = files
+	o.EC.SetOutput(seedApplyOutput{
+		Database:   o.EC.Source.Name,
+		Files:      files,
+		DurationMS: time.Since(start).Milliseconds(),
+	})
+	return nil
 }
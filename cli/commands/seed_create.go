@@ -3,6 +3,7 @@ package commands
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -26,6 +27,15 @@ type SeedNewOptions struct {
 	SeedName string
 	// table name if seed file has to be created from a database table
 	FromTableNames []string
+	// Where, Limit and SamplePercent select the rows exported from the tables
+	Where         []string
+	Limit         int
+	SamplePercent float64
+	// AnonymizeConfig is the file listing the columns whose values are
+	// replaced by fake values
+	AnonymizeConfig string
+	// WithDependencies exports the rows referenced by the exported rows
+	WithDependencies bool
 
 	// seed file that was created
 	FilePath string
@@ -45,11 +55,42 @@ func newSeedCreateCmd(ec *cli.ExecutionContext) *cobra.Command {
   # Create a new seed by exporting data from tables already present in the database:
   hasura seed create table1_seed --from-table table1
 
+  # On a SQL Server database, tables are in the dbo schema unless qualified:
+  hasura seed create customers_seed --database-name mssql_db --from-table sales.customers
+
   # Export data from multiple tables:
-  hasura seed create tables_seed --from-table table1 --from-table table2`,
+  hasura seed create tables_seed --from-table table1 --from-table table2
+
+  # Export some of the rows of the tables, the articles are the ones of the exported authors:
+  hasura seed create recent_seed --from-table authors --from-table articles --where "authors:created_at > now() - interval '7 days'" --limit 100
+
+  # Export a random sample of 5% of the rows:
+  hasura seed create sample_seed --from-table authors --sample-percent 5
+
+  # Replace personal data by fake values, anonymize.yaml lists the columns of each table with the kind of fake value, eg:
+  #   public.authors:
+  #     email: email
+  #     name: name
+  hasura seed create anonymized_seed --from-table authors --anonymize anonymize.yaml
+
+  # Export the orders with the rows they reference in other tables (eg: customers, products), inserted first:
+  hasura seed create orders_seed --from-table orders --where "created_at > now() - interval '1 day'" --with-dependencies`,
 		Args:         cobra.ExactArgs(1),
 		SilenceUsage: false,
 		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(opts.FromTableNames) == 0 {
+				for _, flag := range []string{"where", "limit", "sample-percent", "anonymize", "with-dependencies"} {
+					if cmd.Flags().Changed(flag) {
+						return fmt.Errorf("--%s requires --from-table", flag)
+					}
+				}
+			}
+			if opts.SamplePercent < 0 || opts.SamplePercent > 100 {
+				return fmt.Errorf("--sample-percent must be between 0 and 100")
+			}
+			if opts.Limit < 0 {
+				return fmt.Errorf("--limit must be positive")
+			}
 			return ec.Validate()
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -66,6 +107,11 @@ func newSeedCreateCmd(ec *cli.ExecutionContext) *cobra.Command {
 	}
 
 	cmd.Flags().StringArrayVar(&opts.FromTableNames, "from-table", []string{}, "name of table from which seed file has to be initialized")
+	cmd.Flags().StringArrayVar(&opts.Where, "where", []string{}, "condition selecting the rows exported from the tables, prefixed with a table name for the rows of the table (eg: \"authors:id < 100\")")
+	cmd.Flags().IntVar(&opts.Limit, "limit", 0, "maximum number of rows exported from each table")
+	cmd.Flags().Float64Var(&opts.SamplePercent, "sample-percent", 0, "percentage of the rows of each table randomly sampled")
+	cmd.Flags().StringVar(&opts.AnonymizeConfig, "anonymize", "", "YAML file listing the columns of each table to replace by fake values of kind email, name, phone or text")
+	cmd.Flags().BoolVar(&opts.WithDependencies, "with-dependencies", false, "also export the rows referenced by the exported rows through foreign keys, from any table, rather than leaving out the rows referencing rows which are not exported. Tables referencing each other, directly or through other tables, are not supported, references of a table to itself are")
 
 	return cmd
 }
@@ -86,11 +132,27 @@ func (o *SeedNewOptions) Run() error {
 	if createSeedOpts.Data == nil {
 		var body []byte
 		if len(o.FromTableNames) > 0 {
-			if o.Source.Kind != hasura.SourceKindPG && o.EC.Config.Version >= cli.V3 {
-				return fmt.Errorf("--from-table is supported only for postgres sources")
+			mssql := o.Source.Kind == hasura.SourceKindMSSQL && o.EC.Config.Version >= cli.V3
+			if o.Source.Kind != hasura.SourceKindPG && !mssql && o.EC.Config.Version >= cli.V3 {
+				return fmt.Errorf("--from-table is supported only for postgres and mssql sources")
+			}
+			exportOpts, err := o.exportDataOptions()
+			if err != nil {
+				return err
+			}
+			if mssql && !exportOpts.IsZero() {
+				return fmt.Errorf("--where, --limit, --sample-percent, --anonymize and --with-dependencies are supported only for postgres sources")
 			}
 			// Send the query
-			bodyReader, err := o.Driver.ExportDatadump(o.FromTableNames, o.Source.Name)
+			var bodyReader io.Reader
+			switch {
+			case mssql:
+				bodyReader, err = o.Driver.ExportMSSQLData(o.FromTableNames, o.Source.Name)
+			case exportOpts.IsZero():
+				bodyReader, err = o.Driver.ExportDatadump(o.FromTableNames, o.Source.Name)
+			default:
+				bodyReader, err = o.Driver.ExportData(o.FromTableNames, o.Source.Name, exportOpts)
+			}
 			if err != nil {
 				return errors.Wrap(err, "exporting seed data")
 			}
@@ -119,3 +181,21 @@ func (o *SeedNewOptions) Run() error {
 
 	return nil
 }
+
+func (o *SeedNewOptions) exportDataOptions() (seed.ExportDataOptions, error) {
+	opts := seed.ExportDataOptions{
+		Limit:            o.Limit,
+		SamplePercent:    o.SamplePercent,
+		WithDependencies: o.WithDependencies,
+	}
+	var err error
+	if opts.Where, err = seed.WhereConditions(o.FromTableNames, o.Where); err != nil {
+		return opts, errors.Wrap(err, "parsing --where")
+	}
+	if o.AnonymizeConfig != "" {
+		if opts.Anonymize, err = seed.ReadAnonymizeConfig(afero.NewOsFs(), o.AnonymizeConfig); err != nil {
+			return opts, err
+		}
+	}
+	return opts, nil
+}
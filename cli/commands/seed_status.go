@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/hasura/graphql-engine/cli/v2"
+	"github.com/hasura/graphql-engine/cli/v2/seed"
+	"github.com/hasura/graphql-engine/cli/v2/util"
+)
+
+func newSeedStatusCmd(ec *cli.ExecutionContext) *cobra.Command {
+	opts := SeedStatusOptions{
+		EC: ec,
+	}
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Display the seed files applied on a database",
+		Long: `List the seed files of the database with their status: applied, changed since they were applied, dirty if they failed to be applied or to be recorded as applied, or not applied.
+Applied seed files are recorded in the catalog state of the server from config v3.`,
+		Example: `  # List the seed files of a database:
+  hasura seed status --database-name default`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.Driver = getSeedDriver(ec.Config.Version)
+			opts.Source = ec.Source
+			opts.EC.Spin("Fetching seeds status...")
+			statuses, err := opts.Run()
+			opts.EC.Spinner.Stop()
+			if err != nil {
+				return err
+			}
+			if opts.EC.IsJSONOutput() {
+				opts.EC.SetOutput(statuses)
+				return nil
+			}
+			fmt.Fprintf(opts.EC.Stdout, "%s", printSeedsStatus(statuses))
+			return nil
+		},
+	}
+	return cmd
+}
+
+type SeedStatusOptions struct {
+	EC     *cli.ExecutionContext
+	Driver *seed.Driver
+	Source cli.Source
+}
+
+func (o *SeedStatusOptions) Run() ([]seed.SeedFileStatus, error) {
+	return o.Driver.SeedsStatus(afero.NewOsFs(), o.EC.SeedsDirectory, o.Source)
+}
+
+func printSeedsStatus(statuses []seed.SeedFileStatus) *bytes.Buffer {
+	out := new(tabwriter.Writer)
+	buf := &bytes.Buffer{}
+	out.Init(buf, 0, 8, 2, ' ', 0)
+	w := util.NewPrefixWriter(out)
+	w.Write(util.LEVEL_0, "FILE\tSTATUS\tAPPLIED AT\n")
+	for _, status := range statuses {
+		appliedAt := "-"
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.Local().Format(time.RFC3339)
+		}
+		w.Write(util.LEVEL_0, "%s\t%s\t%s\n", status.Name, status.Status, appliedAt)
+	}
+	out.Flush()
+	return buf
+}
@@ -29,3 +29,24 @@ func (c *ClientCommonMetadataOps) V2ReplaceMetadata(args hasura.V2ReplaceMetadat
 	}
 	return v2replaceMetadataResponse, nil
 }
+
+func (c *ClientCommonMetadataOps) V2ExportMetadata() (*hasura.V2ExportMetadataResponse, error) {
+	request := hasura.RequestBody{
+		Type:    "export_metadata",
+		Version: 2,
+		Args:    struct{}{},
+	}
+	responseBody := new(bytes.Buffer)
+	response, err := c.send(request, responseBody)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s", responseBody.String())
+	}
+	v2ExportMetadataResponse := new(hasura.V2ExportMetadataResponse)
+	if err := json.NewDecoder(responseBody).Decode(v2ExportMetadataResponse); err != nil {
+		return nil, err
+	}
+	return v2ExportMetadataResponse, nil
+}
@@ -169,3 +169,29 @@ func TestClientCommonMetadataOps_V2ReplaceMetadata(t *testing.T) {
 		})
 	}
 }
+
+func TestClientCommonMetadataOps_V2ExportMetadata(t *testing.T) {
+	port, teardown := testutil.StartHasura(t, testutil.HasuraDockerImage)
+	defer teardown()
+	c := &ClientCommonMetadataOps{
+		Client: testutil.NewHttpcClient(t, port, nil),
+		path:   "v1/metadata",
+	}
+	got, err := c.V2ExportMetadata()
+	assert.NoError(t, err)
+	var metadata struct {
+		Version int `json:"version"`
+	}
+	assert.NoError(t, json.Unmarshal(got.Metadata, &metadata))
+	assert.Equal(t, 3, metadata.Version)
+
+	// replacing metadata with the exported resource version succeeds once,
+	// the version is then outdated
+	var m interface{}
+	assert.NoError(t, json.Unmarshal(got.Metadata, &m))
+	args := hasura.V2ReplaceMetadataArgs{Metadata: m, ResourceVersion: &got.ResourceVersion}
+	_, err = c.V2ReplaceMetadata(args)
+	assert.NoError(t, err)
+	_, err = c.V2ReplaceMetadata(args)
+	assert.Error(t, err)
+}
@@ -1,6 +1,7 @@
 package hasura
 
 import (
+	"encoding/json"
 	"io"
 
 	"github.com/hasura/graphql-engine/cli/v2/internal/httpc"
@@ -21,11 +22,21 @@ type CommonMetadataOperations interface {
 
 type V2CommonMetadataOperations interface {
 	V2ReplaceMetadata(args V2ReplaceMetadataArgs) (*V2ReplaceMetadataResponse, error)
+	V2ExportMetadata() (*V2ExportMetadataResponse, error)
 }
 
 type V2ReplaceMetadataArgs struct {
 	AllowInconsistentMetadata bool        `json:"allow_inconsistent_metadata"`
 	Metadata                  interface{} `json:"metadata"`
+	// ResourceVersion makes the server refuse to replace metadata if it
+	// changed since this version
+	ResourceVersion *int `json:"resource_version,omitempty"`
+}
+
+type V2ExportMetadataResponse struct {
+	// ResourceVersion is incremented by the server on every change of metadata
+	ResourceVersion int             `json:"resource_version"`
+	Metadata        json.RawMessage `json:"metadata"`
 }
 
 type V2ReplaceMetadataResponse struct {
@@ -0,0 +1,453 @@
+// Package jsonschema validates documents against JSON schemas (draft-07).
+//
+// Only the keywords needed by the schemas shipped with the CLI are supported:
+// $ref to local definitions, type, enum, properties, required,
+// additionalProperties, items and anyOf. Annotations (title, description,
+// $schema, $id, $comment) are ignored and any other keyword is rejected by
+// Compile, so that a schema never silently validates less than it says.
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Schema is a compiled JSON schema
+type Schema struct {
+	root *schema
+}
+
+// Error is a violation of the schema
+type Error struct {
+	// Path of the value in the document, made of object keys (string) and
+	// array indexes (int)
+	Path    []interface{}
+	Message string
+}
+
+// PathString returns the path in the $.a[0].b form used by YAML paths
+func (e Error) PathString() string {
+	return PathString(e.Path)
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.PathString(), e.Message)
+}
+
+// PathString formats path in the $.a[0].b form used by YAML paths
+func PathString(path []interface{}) string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, p := range path {
+		switch p := p.(type) {
+		case int:
+			fmt.Fprintf(&b, "[%d]", p)
+		default:
+			b.WriteString(".")
+			b.WriteString(fmt.Sprint(p))
+		}
+	}
+	return b.String()
+}
+
+type schema struct {
+	// always is set for the boolean schemas true and false
+	always *bool
+	// ref is the definition referenced by $ref, the other keywords are
+	// ignored when it is set
+	ref *schema
+
+	types                []string
+	enum                 []interface{}
+	properties           map[string]*schema
+	required             []string
+	additionalProperties *schema
+	items                *schema
+	anyOf                []*schema
+}
+
+var annotations = map[string]bool{
+	"$schema":     true,
+	"$id":         true,
+	"$comment":    true,
+	"title":       true,
+	"description": true,
+	"definitions": true,
+}
+
+// Compile parses a JSON schema
+func Compile(b []byte) (*Schema, error) {
+	var doc interface{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("parsing schema: %w", err)
+	}
+	c := &compiler{doc: doc, definitions: map[string]*schema{}}
+	root, err := c.compile(doc, "#")
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{root: root}, nil
+}
+
+type compiler struct {
+	doc         interface{}
+	definitions map[string]*schema
+}
+
+func (c *compiler) compile(v interface{}, location string) (*schema, error) {
+	if b, ok := v.(bool); ok {
+		return &schema{always: &b}, nil
+	}
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: schema must be an object or a boolean", location)
+	}
+	s := &schema{}
+	for keyword, value := range obj {
+		var err error
+		switch keyword {
+		case "$ref":
+			s.ref, err = c.resolve(value)
+		case "type":
+			s.types, err = stringOrStrings(value)
+		case "enum":
+			values, ok := value.([]interface{})
+			if !ok {
+				err = fmt.Errorf("enum must be an array")
+			}
+			s.enum = values
+		case "properties":
+			properties, ok := value.(map[string]interface{})
+			if !ok {
+				err = fmt.Errorf("properties must be an object")
+				break
+			}
+			s.properties = map[string]*schema{}
+			for name, property := range properties {
+				if s.properties[name], err = c.compile(property, location+"/properties/"+name); err != nil {
+					return nil, err
+				}
+			}
+		case "required":
+			s.required, err = stringOrStrings(value)
+		case "additionalProperties":
+			s.additionalProperties, err = c.compile(value, location+"/additionalProperties")
+		case "items":
+			s.items, err = c.compile(value, location+"/items")
+		case "anyOf":
+			branches, ok := value.([]interface{})
+			if !ok || len(branches) == 0 {
+				err = fmt.Errorf("anyOf must be a non empty array")
+				break
+			}
+			for i, branch := range branches {
+				compiled, err := c.compile(branch, fmt.Sprintf("%s/anyOf/%d", location, i))
+				if err != nil {
+					return nil, err
+				}
+				s.anyOf = append(s.anyOf, compiled)
+			}
+		default:
+			if !annotations[keyword] {
+				err = fmt.Errorf("unsupported keyword %q", keyword)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", location, err)
+		}
+	}
+	return s, nil
+}
+
+// resolve returns the compiled definition referenced by ref
+func (c *compiler) resolve(ref interface{}) (*schema, error) {
+	r, ok := ref.(string)
+	if !ok || !strings.HasPrefix(r, "#/definitions/") {
+		return nil, fmt.Errorf("only references to #/definitions are supported, got %v", ref)
+	}
+	name := strings.TrimPrefix(r, "#/definitions/")
+	if s, ok := c.definitions[name]; ok {
+		return s, nil
+	}
+	root, _ := c.doc.(map[string]interface{})
+	definitions, _ := root["definitions"].(map[string]interface{})
+	definition, ok := definitions[name]
+	if !ok {
+		return nil, fmt.Errorf("definition %s not found", name)
+	}
+	// register the definition before compiling it so that recursive
+	// references terminate
+	s := &schema{}
+	c.definitions[name] = s
+	compiled, err := c.compile(definition, r)
+	if err != nil {
+		return nil, err
+	}
+	*s = *compiled
+	return s, nil
+}
+
+func stringOrStrings(v interface{}) ([]string, error) {
+	switch v := v.(type) {
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		var values []string
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected a string, got %v", item)
+			}
+			values = append(values, s)
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("expected a string or an array of strings, got %v", v)
+}
+
+// Validate validates a document decoded by encoding/json against the schema
+func (s *Schema) Validate(document interface{}) []Error {
+	return validate(s.root, document, nil)
+}
+
+func validate(s *schema, value interface{}, path []interface{}) []Error {
+	if s.always != nil {
+		if *s.always {
+			return nil
+		}
+		return []Error{newError(path, "value is not allowed")}
+	}
+	if s.ref != nil {
+		return validate(s.ref, value, path)
+	}
+	if len(s.types) > 0 && !matchesType(s.types, value) {
+		return []Error{newError(path, fmt.Sprintf("expected %s, got %s", strings.Join(s.types, " or "), typeOf(value)))}
+	}
+	var errs []Error
+	if len(s.enum) > 0 && !inEnum(s.enum, value) {
+		var allowed []string
+		for _, e := range s.enum {
+			allowed = append(allowed, formatValue(e))
+		}
+		errs = append(errs, newError(path, fmt.Sprintf("value %s is not one of %s", formatValue(value), strings.Join(allowed, ", "))))
+	}
+	if obj, ok := value.(map[string]interface{}); ok {
+		errs = append(errs, validateObject(s, obj, path)...)
+	}
+	if arr, ok := value.([]interface{}); ok && s.items != nil {
+		for i, item := range arr {
+			errs = append(errs, validate(s.items, item, appendPath(path, i))...)
+		}
+	}
+	if len(s.anyOf) > 0 {
+		errs = append(errs, validateAnyOf(s.anyOf, value, path)...)
+	}
+	return errs
+}
+
+func validateObject(s *schema, obj map[string]interface{}, path []interface{}) []Error {
+	var errs []Error
+	for _, name := range s.required {
+		if _, ok := obj[name]; !ok {
+			errs = append(errs, newError(path, fmt.Sprintf("missing required field %q", name)))
+		}
+	}
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if property, ok := s.properties[key]; ok {
+			errs = append(errs, validate(property, obj[key], appendPath(path, key))...)
+			continue
+		}
+		if s.additionalProperties == nil {
+			continue
+		}
+		if a := s.additionalProperties.always; a != nil && !*a {
+			message := fmt.Sprintf("unknown field %q", key)
+			if suggestion := closest(key, s.properties); suggestion != "" {
+				message += fmt.Sprintf(", did you mean %q?", suggestion)
+			}
+			errs = append(errs, newError(appendPath(path, key), message))
+			continue
+		}
+		errs = append(errs, validate(s.additionalProperties, obj[key], appendPath(path, key))...)
+	}
+	return errs
+}
+
+// validateAnyOf returns nil if value matches one of the branches. Otherwise
+// the errors of the branch which is closest to the value are returned: the
+// one which accepts the type of the value with the fewest errors, or which
+// knows the most fields of the value on a tie.
+func validateAnyOf(branches []*schema, value interface{}, path []interface{}) []Error {
+	// typeMismatch scores the branches which do not accept the type of value
+	const typeMismatch = math.MaxInt32
+	var best []Error
+	var bestScore, bestFit int
+	for _, branch := range branches {
+		errs := validate(branch, value, path)
+		if len(errs) == 0 {
+			return nil
+		}
+		score, fit := len(errs), knownFields(branch.resolved(), value)
+		if b := branch.resolved(); len(b.types) > 0 && !matchesType(b.types, value) {
+			score = typeMismatch
+		}
+		if best == nil || score < bestScore || (score == bestScore && fit > bestFit) {
+			best, bestScore, bestFit = errs, score, fit
+		}
+	}
+	if bestScore == typeMismatch {
+		var types []string
+		for _, branch := range branches {
+			types = append(types, branch.resolved().types...)
+		}
+		return []Error{newError(path, fmt.Sprintf("expected %s, got %s", strings.Join(unique(types), " or "), typeOf(value)))}
+	}
+	return best
+}
+
+// knownFields returns the number of fields of value which are properties of
+// s or likely typos of them
+func knownFields(s *schema, value interface{}) int {
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return 0
+	}
+	known := 0
+	for key := range obj {
+		if _, ok := s.properties[key]; ok || closest(key, s.properties) != "" {
+			known++
+		}
+	}
+	return known
+}
+
+// resolved follows the $ref of s
+func (s *schema) resolved() *schema {
+	for s.ref != nil {
+		s = s.ref
+	}
+	return s
+}
+
+func newError(path []interface{}, message string) Error {
+	return Error{Path: append([]interface{}(nil), path...), Message: message}
+}
+
+func appendPath(path []interface{}, element interface{}) []interface{} {
+	return append(append([]interface{}(nil), path...), element)
+}
+
+func typeOf(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func matchesType(types []string, value interface{}) bool {
+	actual := typeOf(value)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, e := range enum {
+		if formatValue(e) == formatValue(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatValue(value interface{}) string {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(b)
+}
+
+func unique(values []string) []string {
+	seen := map[string]bool{}
+	var result []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// closest returns the property name closest to key if it is likely a typo of it
+func closest(key string, properties map[string]*schema) string {
+	best, bestDistance := "", len(key)/3+1
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if d := distance(key, name); d < bestDistance {
+			best, bestDistance = name, d
+		}
+	}
+	return best
+}
+
+// distance returns the Levenshtein distance between a and b
+func distance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+func min(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,120 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$ref": "#/definitions/Node",
+  "definitions": {
+    "Node": {
+      "type": "object",
+      "properties": {
+        "name": {"type": "string"},
+        "kind": {"type": "string", "enum": ["leaf", "branch"]},
+        "weight": {"type": ["integer", "null"]},
+        "children": {"type": "array", "items": {"$ref": "#/definitions/Node"}},
+        "labels": {"type": "object", "additionalProperties": {"type": "string"}},
+        "header": {"anyOf": [{"$ref": "#/definitions/FromValue"}, {"$ref": "#/definitions/FromEnv"}]},
+        "columns": {"anyOf": [{"type": "array", "items": {"type": "string"}}, {"type": "string", "enum": ["*"]}]}
+      },
+      "required": ["name"],
+      "additionalProperties": false
+    },
+    "FromValue": {
+      "type": "object",
+      "properties": {"name": {"type": "string"}, "value": {"type": "string"}},
+      "required": ["name", "value"],
+      "additionalProperties": false
+    },
+    "FromEnv": {
+      "type": "object",
+      "properties": {"name": {"type": "string"}, "value_from_env": {"type": "string"}},
+      "required": ["name", "value_from_env"],
+      "additionalProperties": false
+    }
+  }
+}`
+
+func TestSchema_Validate(t *testing.T) {
+	schema, err := Compile([]byte(testSchema))
+	require.NoError(t, err)
+	tests := []struct {
+		name     string
+		document string
+		want     []string
+	}{
+		{
+			"valid document",
+			`{"name": "root", "kind": "branch", "weight": null, "children": [{"name": "a", "weight": 2}], "labels": {"a": "b"}, "header": {"name": "x", "value_from_env": "X"}, "columns": "*"}`,
+			nil,
+		},
+		{
+			"unknown fields with suggestions",
+			`{"name": "root", "childern": [], "colour": "red"}`,
+			[]string{
+				`$.childern: unknown field "childern", did you mean "children"?`,
+				`$.colour: unknown field "colour"`,
+			},
+		},
+		{
+			"nested violations",
+			`{"children": [{"name": "a"}, {"name": 1, "kind": "trunk", "weight": 1.5}], "labels": {"a": 1}}`,
+			[]string{
+				`$: missing required field "name"`,
+				`$.children[1].kind: value "trunk" is not one of "leaf", "branch"`,
+				`$.children[1].name: expected string, got integer`,
+				`$.children[1].weight: expected integer or null, got number`,
+				`$.labels.a: expected string, got integer`,
+			},
+		},
+		{
+			"any of reports the closest branch",
+			`{"name": "root", "header": {"name": "x", "value_form_env": "X"}, "columns": "id"}`,
+			[]string{
+				`$.columns: value "id" is not one of "*"`,
+				`$.header: missing required field "value_from_env"`,
+				`$.header.value_form_env: unknown field "value_form_env", did you mean "value_from_env"?`,
+			},
+		},
+		{
+			"any of with no branch of the type",
+			`{"name": "root", "columns": 1}`,
+			[]string{`$.columns: expected array or string, got integer`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var document interface{}
+			require.NoError(t, json.Unmarshal([]byte(tt.document), &document))
+			var got []string
+			for _, e := range schema.Validate(document) {
+				got = append(got, e.Error())
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  string
+		wantErr string
+	}{
+		{"unsupported keyword", `{"type": "string", "pattern": "^a"}`, `#: unsupported keyword "pattern"`},
+		{"missing definition", `{"properties": {"a": {"$ref": "#/definitions/A"}}}`, `#/properties/a: definition A not found`},
+		{"remote reference", `{"$ref": "other.json#/definitions/A"}`, `#: only references to #/definitions are supported, got other.json#/definitions/A`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile([]byte(tt.schema))
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,385 @@
+// Package metadatadiff compares two versions of Hasura metadata object by
+// object. Objects in arrays are matched by their identity (a table by its
+// qualified name, a permission by its role, a remote schema by its name, ...)
+// rather than by their position, so reordering objects is not a change.
+package metadatadiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ChangeType tells how an object changed
+type ChangeType string
+
+const (
+	Added   ChangeType = "added"
+	Removed ChangeType = "removed"
+	Changed ChangeType = "changed"
+)
+
+// ObjectID identifies a metadata object, eg: the table public.users
+type ObjectID struct {
+	Kind string `json:"kind"`
+	// Name is empty for objects which appear once in metadata, like api_limits
+	Name string `json:"name,omitempty"`
+}
+
+func (id ObjectID) String() string {
+	kind := strings.ReplaceAll(id.Kind, "_", " ")
+	if id.Name == "" {
+		return kind
+	}
+	return fmt.Sprintf("%s %s", kind, id.Name)
+}
+
+// Change is an added, removed or changed metadata object
+type Change struct {
+	Type ChangeType `json:"type"`
+	// Object is the path to the object: the objects it belongs to followed by
+	// the object itself, eg: source default, table public.users, select
+	// permission user
+	Object []ObjectID `json:"object"`
+	// Fields are the changed fields of a changed object, eg: permission.filter.
+	// Changes of the objects it contains are reported as changes of their own
+	Fields []string `json:"fields,omitempty"`
+	// Before and After are the object in both versions, nil if it is absent
+	Before interface{} `json:"-"`
+	After  interface{} `json:"-"`
+}
+
+func (c Change) String() string {
+	var path []string
+	for _, id := range c.Object {
+		path = append(path, id.String())
+	}
+	s := strings.Join(path, " > ")
+	if len(c.Fields) > 0 {
+		s += fmt.Sprintf(" (%s)", strings.Join(c.Fields, ", "))
+	}
+	return s
+}
+
+// collection describes an array of metadata objects
+type collection struct {
+	kind string
+	// key returns the identity of an object of the collection
+	key func(object map[string]interface{}) string
+	// children are the fields of the objects which are collections
+	children map[string]*collection
+}
+
+func byField(field string) func(map[string]interface{}) string {
+	return func(object map[string]interface{}) string {
+		if s, ok := object[field].(string); ok {
+			return s
+		}
+		return ""
+	}
+}
+
+// QualifiedName returns the identity of a table or a function, which is
+// either a name or an object with a name and a schema (or a dataset for
+// BigQuery), eg: public.users
+func QualifiedName(name interface{}) string {
+	switch name := name.(type) {
+	case string:
+		return name
+	case map[string]interface{}:
+		n, _ := name["name"].(string)
+		for _, namespace := range []string{"schema", "dataset"} {
+			if ns, ok := name[namespace].(string); ok {
+				return ns + "." + n
+			}
+		}
+		return n
+	}
+	return ""
+}
+
+func byQualifiedName(field string) func(map[string]interface{}) string {
+	return func(object map[string]interface{}) string {
+		return QualifiedName(object[field])
+	}
+}
+
+func permissions(kind string) *collection {
+	return &collection{kind: kind, key: byField("role")}
+}
+
+var (
+	tables = &collection{
+		kind: "table",
+		key:  byQualifiedName("table"),
+		children: map[string]*collection{
+			"object_relationships": {kind: "object_relationship", key: byField("name")},
+			"array_relationships":  {kind: "array_relationship", key: byField("name")},
+			"remote_relationships": {kind: "remote_relationship", key: byField("name")},
+			"computed_fields":      {kind: "computed_field", key: byField("name")},
+			"event_triggers":       {kind: "event_trigger", key: byField("name")},
+			"insert_permissions":   permissions("insert_permission"),
+			"select_permissions":   permissions("select_permission"),
+			"update_permissions":   permissions("update_permission"),
+			"delete_permissions":   permissions("delete_permission"),
+		},
+	}
+	functions = &collection{
+		kind: "function",
+		key:  byQualifiedName("function"),
+		children: map[string]*collection{
+			"permissions": permissions("function_permission"),
+		},
+	}
+	// collections are the top level arrays of metadata
+	collections = map[string]*collection{
+		"sources": {
+			kind: "source",
+			key:  byField("name"),
+			children: map[string]*collection{
+				"tables":    tables,
+				"functions": functions,
+			},
+		},
+		// metadata v2 has the tables and functions of the only database at
+		// the top level
+		"tables":    tables,
+		"functions": functions,
+		"remote_schemas": {
+			kind: "remote_schema",
+			key:  byField("name"),
+			children: map[string]*collection{
+				"permissions": permissions("remote_schema_permission"),
+			},
+		},
+		"actions": {
+			kind: "action",
+			key:  byField("name"),
+			children: map[string]*collection{
+				"permissions": permissions("action_permission"),
+			},
+		},
+		"query_collections": {kind: "query_collection", key: byField("name")},
+		"allowlist":         {kind: "allowlist", key: byField("collection")},
+		"cron_triggers":     {kind: "cron_trigger", key: byField("name")},
+		"rest_endpoints":    {kind: "rest_endpoint", key: byField("name")},
+		"inherited_roles":   {kind: "inherited_role", key: byField("role_name")},
+	}
+	// customTypes are the arrays of custom_types
+	customTypes = map[string]*collection{
+		"input_objects": {kind: "input_object_type", key: byField("name")},
+		"objects":       {kind: "object_type", key: byField("name")},
+		"scalars":       {kind: "scalar_type", key: byField("name")},
+		"enums":         {kind: "enum_type", key: byField("name")},
+	}
+)
+
+// Diff returns the changes from before to after. Both are metadata decoded
+// from JSON, an empty project is nil. The fields of metadata other than
+// arrays of objects, like api_limits, are compared as a single object.
+func Diff(before, after interface{}) []Change {
+	b, _ := before.(map[string]interface{})
+	a, _ := after.(map[string]interface{})
+	var changes []Change
+	for _, field := range fields(b, a) {
+		switch {
+		case collections[field] != nil:
+			changes = append(changes, diffCollection(collections[field], nil, b[field], a[field])...)
+		case field == "custom_types":
+			bTypes, _ := b[field].(map[string]interface{})
+			aTypes, _ := a[field].(map[string]interface{})
+			for _, kind := range fields(bTypes, aTypes) {
+				if c, ok := customTypes[kind]; ok {
+					changes = append(changes, diffCollection(c, nil, bTypes[kind], aTypes[kind])...)
+				}
+			}
+		default:
+			changes = append(changes, diffSingle(ObjectID{Kind: field}, b, a, field)...)
+		}
+	}
+	return changes
+}
+
+func diffSingle(id ObjectID, before, after map[string]interface{}, field string) []Change {
+	b, inBefore := before[field]
+	a, inAfter := after[field]
+	switch {
+	case !inBefore:
+		return []Change{{Type: Added, Object: []ObjectID{id}, After: a}}
+	case !inAfter:
+		return []Change{{Type: Removed, Object: []ObjectID{id}, Before: b}}
+	case !equal(b, a):
+		return []Change{{Type: Changed, Object: []ObjectID{id}, Before: b, After: a}}
+	}
+	return nil
+}
+
+// element is an object of a collection
+type element struct {
+	key    string
+	object map[string]interface{}
+}
+
+// elements returns the objects of a collection in their order, objects
+// without an identity are identified by their position
+func elements(c *collection, v interface{}) ([]element, map[string]map[string]interface{}) {
+	array, _ := v.([]interface{})
+	var ordered []element
+	byKey := map[string]map[string]interface{}{}
+	for i, item := range array {
+		object, _ := item.(map[string]interface{})
+		key := c.key(object)
+		if key == "" {
+			key = fmt.Sprintf("#%d", i)
+		}
+		if _, ok := byKey[key]; ok {
+			continue
+		}
+		byKey[key] = object
+		ordered = append(ordered, element{key, object})
+	}
+	return ordered, byKey
+}
+
+func diffCollection(c *collection, parents []ObjectID, before, after interface{}) []Change {
+	bElements, bByKey := elements(c, before)
+	aElements, aByKey := elements(c, after)
+	var changes []Change
+	for _, e := range bElements {
+		path := appendID(parents, ObjectID{Kind: c.kind, Name: e.key})
+		if a, ok := aByKey[e.key]; ok {
+			changes = append(changes, diffObject(c, path, e.object, a)...)
+		} else {
+			changes = append(changes, Change{Type: Removed, Object: path, Before: e.object})
+		}
+	}
+	for _, e := range aElements {
+		if _, ok := bByKey[e.key]; !ok {
+			changes = append(changes, Change{Type: Added, Object: appendID(parents, ObjectID{Kind: c.kind, Name: e.key}), After: e.object})
+		}
+	}
+	return changes
+}
+
+// diffObject returns the change of the fields of an object present in both
+// versions, followed by the changes of the objects it contains
+func diffObject(c *collection, path []ObjectID, before, after map[string]interface{}) []Change {
+	var changed []string
+	var nested []Change
+	for _, field := range fields(before, after) {
+		if child, ok := c.children[field]; ok {
+			nested = append(nested, diffCollection(child, path, before[field], after[field])...)
+			continue
+		}
+		changed = append(changed, changedFields(field, before[field], after[field])...)
+	}
+	if len(changed) == 0 {
+		return nested
+	}
+	return append([]Change{{Type: Changed, Object: path, Fields: changed, Before: before, After: after}}, nested...)
+}
+
+// Find returns the object at path in metadata, nil if there is none. Only
+// objects of arrays, like tables and permissions, can be found.
+func Find(metadata interface{}, path []ObjectID) map[string]interface{} {
+	object, _ := metadata.(map[string]interface{})
+	children := collections
+	for _, id := range path {
+		var found map[string]interface{}
+		for field, c := range children {
+			if c.kind != id.Kind || object[field] == nil {
+				continue
+			}
+			_, byKey := elements(c, object[field])
+			found, children = byKey[id.Name], c.children
+			break
+		}
+		if found == nil {
+			return nil
+		}
+		object = found
+	}
+	return object
+}
+
+// changedFields returns field if its values differ. The fields of objects
+// like the permission of a permission are listed instead, eg:
+// permission.filter
+func changedFields(field string, before, after interface{}) []string {
+	if equal(before, after) {
+		return nil
+	}
+	b, bIsObject := before.(map[string]interface{})
+	a, aIsObject := after.(map[string]interface{})
+	if !bIsObject || !aIsObject {
+		return []string{field}
+	}
+	var changed []string
+	for _, f := range fields(b, a) {
+		if !equal(b[f], a[f]) {
+			changed = append(changed, field+"."+f)
+		}
+	}
+	return changed
+}
+
+// fields returns the sorted union of the fields of objects
+func fields(objects ...map[string]interface{}) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, object := range objects {
+		for name := range object {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func appendID(path []ObjectID, id ObjectID) []ObjectID {
+	return append(append([]ObjectID(nil), path...), id)
+}
+
+// equal compares values ignoring the order of arrays of scalars, like the
+// columns of a permission or the roles of an inherited role
+func equal(a, b interface{}) bool {
+	return reflect.DeepEqual(normalize(a), normalize(b))
+}
+
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		normalized := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			normalized[key] = normalize(value)
+		}
+		return normalized
+	case []interface{}:
+		normalized := make([]interface{}, len(v))
+		scalars := true
+		for i, value := range v {
+			normalized[i] = normalize(value)
+			switch value.(type) {
+			case map[string]interface{}, []interface{}:
+				scalars = false
+			}
+		}
+		if scalars {
+			sort.Slice(normalized, func(i, j int) bool {
+				return format(normalized[i]) < format(normalized[j])
+			})
+		}
+		return normalized
+	}
+	return v
+}
+
+func format(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
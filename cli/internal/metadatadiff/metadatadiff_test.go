@@ -0,0 +1,96 @@
+package metadatadiff
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		want   []string
+	}{
+		{
+			"reordered objects are not changes",
+			`{"version": 3, "sources": [
+				{"name": "default", "kind": "postgres", "tables": [
+					{"table": {"schema": "public", "name": "users"}, "select_permissions": [
+						{"role": "user", "permission": {"columns": ["id", "name"], "filter": {}}},
+						{"role": "admin", "permission": {"columns": ["id"], "filter": {}}}
+					]},
+					{"table": {"schema": "public", "name": "posts"}}
+				]}
+			], "remote_schemas": [{"name": "a", "definition": {"url": "http://a"}}, {"name": "b", "definition": {"url": "http://b"}}]}`,
+			`{"version": 3, "sources": [
+				{"name": "default", "kind": "postgres", "tables": [
+					{"table": {"schema": "public", "name": "posts"}},
+					{"table": {"schema": "public", "name": "users"}, "select_permissions": [
+						{"role": "admin", "permission": {"columns": ["id"], "filter": {}}},
+						{"role": "user", "permission": {"columns": ["name", "id"], "filter": {}}}
+					]}
+				]}
+			], "remote_schemas": [{"name": "b", "definition": {"url": "http://b"}}, {"name": "a", "definition": {"url": "http://a"}}]}`,
+			nil,
+		},
+		{
+			"added removed and changed objects",
+			`{"version": 3, "sources": [
+				{"name": "default", "kind": "postgres", "tables": [
+					{"table": {"schema": "public", "name": "users"},
+					 "object_relationships": [{"name": "profile", "using": {"foreign_key_constraint_on": "profile_id"}}],
+					 "select_permissions": [{"role": "user", "permission": {"columns": ["id"], "filter": {}}}]},
+					{"table": {"schema": "public", "name": "logs"}}
+				]}
+			], "actions": [{"name": "login", "definition": {"handler": "http://a"}, "permissions": [{"role": "user"}]}]}`,
+			`{"version": 3, "sources": [
+				{"name": "default", "kind": "postgres", "tables": [
+					{"table": {"schema": "public", "name": "users"}, "is_enum": false,
+					 "select_permissions": [{"role": "user", "permission": {"columns": ["id"], "filter": {"id": {"_eq": "X-Hasura-User-Id"}}}}],
+					 "insert_permissions": [{"role": "user", "permission": {"columns": ["id"], "check": {}}}]}
+				]},
+				{"name": "analytics", "kind": "bigquery", "tables": [{"table": {"dataset": "ds", "name": "events"}}]}
+			], "actions": [{"name": "login", "definition": {"handler": "http://b"}, "permissions": [{"role": "user"}, {"role": "admin"}]}],
+			"custom_types": {"objects": [{"name": "LoginOutput"}]}, "api_limits": {"disabled": true}}`,
+			[]string{
+				"~ action login (definition.handler)",
+				"+ action login > action permission admin",
+				"+ api limits",
+				"+ object type LoginOutput",
+				"~ source default > table public.users (is_enum)",
+				"+ source default > table public.users > insert permission user",
+				"- source default > table public.users > object relationship profile",
+				"~ source default > table public.users > select permission user (permission.filter)",
+				"- source default > table public.logs",
+				"+ source analytics",
+			},
+		},
+		{
+			"metadata v2 and empty project",
+			`{"version": 2, "tables": [{"table": "users"}], "functions": [{"function": {"schema": "public", "name": "search"}}]}`,
+			`null`,
+			[]string{
+				"- function public.search",
+				"- table users",
+				"- version",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var before, after interface{}
+			require.NoError(t, json.Unmarshal([]byte(tt.before), &before))
+			require.NoError(t, json.Unmarshal([]byte(tt.after), &after))
+			var got []string
+			for _, change := range Diff(before, after) {
+				prefix := map[ChangeType]string{Added: "+ ", Removed: "- ", Changed: "~ "}[change.Type]
+				got = append(got, prefix+change.String())
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
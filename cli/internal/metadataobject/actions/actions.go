@@ -3,7 +3,9 @@ package actions
 import (
 	"fmt"
 	"io/ioutil"
+	"os"
 	"path/filepath"
+	"strings"
 
 	errors2 "github.com/hasura/graphql-engine/cli/v2/internal/metadataobject/errors"
 
@@ -12,6 +14,7 @@ import (
 	cliextension "github.com/hasura/graphql-engine/cli/v2/internal/metadataobject/actions/cli_extension"
 	"github.com/hasura/graphql-engine/cli/v2/internal/metadataobject/actions/editor"
 	"github.com/hasura/graphql-engine/cli/v2/internal/metadataobject/actions/types"
+	"github.com/hasura/graphql-engine/cli/v2/internal/metadataobject/include"
 	"github.com/hasura/graphql-engine/cli/v2/util"
 	"github.com/hasura/graphql-engine/cli/v2/version"
 	"github.com/sirupsen/logrus"
@@ -21,15 +24,28 @@ import (
 const (
 	actionsFileName = "actions.yaml"
 	graphqlFileName = "actions.graphql"
+	// actionsDirectory has a file per action in the split layout, see
+	// actionFiles
+	actionsDirectory = "actions"
 )
 
+// cliExtension converts actions between metadata and GraphQL SDL and
+// generates their codegen, it is implemented by cli-ext
+type cliExtension interface {
+	ConvertMetadataToSDL(types.SDLToRequest) (types.SDLToResponse, error)
+	ConvertSDLToMetadata(types.SDLFromRequest) (types.SDLFromResponse, error)
+	GetActionsCodegen(types.ActionsCodegenRequest) (types.ActionsCodegenResponse, error)
+}
+
 type ActionConfig struct {
 	MetadataDir        string
 	ActionConfig       *types.ActionExecutionConfig
 	serverFeatureFlags *version.ServerFeatureFlags
-	cliExtensionConfig *cliextension.Config
+	cliExtensionConfig cliExtension
 	ensureCliExt       func() error
 	cleanupCliExt      func()
+	// splitActions writes each action to its own files
+	splitActions bool
 
 	logger *logrus.Logger
 }
@@ -47,6 +63,7 @@ func New(ec *cli.ExecutionContext, baseDir string) *ActionConfig {
 		cleanupCliExt: func() {
 			cliext.Cleanup(ec)
 		},
+		splitActions: ec.Config.MetadataLayout != nil && ec.Config.MetadataLayout.SplitActions,
 	}
 	return cfg
 }
@@ -186,6 +203,21 @@ input SampleInput {
 	var common types.Common
 	common.Actions = sdlFromResp.Actions
 	common.CustomTypes = sdlFromResp.Types
+	if a.splitActions {
+		files, err := a.actionFiles(common)
+		if err != nil {
+			return err
+		}
+		for name, content := range files {
+			if err := os.MkdirAll(filepath.Dir(name), os.ModePerm); err != nil {
+				return err
+			}
+			if err := ioutil.WriteFile(name, content, 0644); err != nil {
+				return fmt.Errorf("error in writing %s file: %w", name, err)
+			}
+		}
+		return nil
+	}
 	common.SetExportDefault()
 	// write actions.yaml
 	commonByt, err := yaml.Marshal(common)
@@ -417,6 +449,13 @@ func (a *ActionConfig) Export(metadata yaml.MapSlice) (map[string][]byte, errors
 	if err != nil {
 		return nil, a.Error(fmt.Errorf("error in unmarshal to common: %w", err))
 	}
+	if a.splitActions {
+		files, err := a.actionFiles(common)
+		if err != nil {
+			return nil, a.Error(err)
+		}
+		return files, nil
+	}
 	var sdlToReq types.SDLToRequest
 	sdlToReq.Types = common.CustomTypes
 	sdlToReq.Actions = common.Actions
@@ -435,12 +474,59 @@ func (a *ActionConfig) Export(metadata yaml.MapSlice) (map[string][]byte, errors
 	}, nil
 }
 
+// actionFiles returns the files of common in the split layout: each action
+// is written to actions/<name>.yaml, included by actions.yaml, and its
+// GraphQL definition to actions/<name>.graphql. Custom types stay in
+// actions.yaml and actions.graphql.
+func (a *ActionConfig) actionFiles(common types.Common) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	tags := make([]string, 0, len(common.Actions))
+	for _, action := range common.Actions {
+		sdlToResp, err := a.cliExtensionConfig.ConvertMetadataToSDL(types.SDLToRequest{Actions: []types.Action{action}})
+		if err != nil {
+			return nil, fmt.Errorf("error in converting action %s to sdl: %w", action.Name, err)
+		}
+		exported := types.Common{Actions: []types.Action{action}}
+		exported.SetExportDefault()
+		actionByt, err := yaml.Marshal(exported.Actions[0])
+		if err != nil {
+			return nil, fmt.Errorf("error in marshaling action %s: %w", action.Name, err)
+		}
+		actionFileName := filepath.Join(actionsDirectory, action.Name+".yaml")
+		files[filepath.ToSlash(filepath.Join(a.MetadataDir, actionFileName))] = actionByt
+		files[filepath.ToSlash(filepath.Join(a.MetadataDir, actionsDirectory, action.Name+".graphql"))] = []byte(sdlToResp.SDL.Complete)
+		tags = append(tags, include.Tag(actionFileName))
+	}
+	sdlToResp, err := a.cliExtensionConfig.ConvertMetadataToSDL(types.SDLToRequest{Types: common.CustomTypes})
+	if err != nil {
+		return nil, fmt.Errorf("error in converting custom types to sdl: %w", err)
+	}
+	common.Actions = nil
+	common.SetExportDefault()
+	commonByt, err := yaml.Marshal(yaml.MapSlice{
+		{Key: "actions", Value: tags},
+		{Key: "custom_types", Value: common.CustomTypes},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error in marshaling common: %w", err)
+	}
+	files[filepath.ToSlash(filepath.Join(a.MetadataDir, actionsFileName))] = commonByt
+	files[filepath.ToSlash(filepath.Join(a.MetadataDir, graphqlFileName))] = []byte(sdlToResp.SDL.Complete)
+	return files, nil
+}
+
 func (a *ActionConfig) Name() string {
 	return "actions"
 }
 
+// GetActionsFileContent returns the content of actions.yaml with the files
+// of the actions it includes
 func (a *ActionConfig) GetActionsFileContent() (content types.Common, err error) {
-	commonByt, err := ioutil.ReadFile(filepath.Join(a.MetadataDir, actionsFileName))
+	common, err := include.ReadFile(filepath.Join(a.MetadataDir, actionsFileName))
+	if err != nil {
+		return
+	}
+	commonByt, err := yaml.Marshal(common)
 	if err != nil {
 		return
 	}
@@ -448,15 +534,57 @@ func (a *ActionConfig) GetActionsFileContent() (content types.Common, err error)
 	return
 }
 
+// GetActionsGraphQLFileContent returns the content of actions.graphql
+// followed by the GraphQL definitions of the actions included by
+// actions.yaml
 func (a *ActionConfig) GetActionsGraphQLFileContent() (sdl string, err error) {
 	commonByt, err := ioutil.ReadFile(filepath.Join(a.MetadataDir, graphqlFileName))
 	if err != nil {
 		return
 	}
 	sdl = string(commonByt)
+	actionFiles, err := a.includedActionFiles()
+	if err != nil {
+		return
+	}
+	for _, actionFile := range actionFiles {
+		graphqlFile := strings.TrimSuffix(actionFile, filepath.Ext(actionFile)) + ".graphql"
+		actionSDL, err := ioutil.ReadFile(filepath.Join(a.MetadataDir, graphqlFile))
+		if err != nil {
+			return "", fmt.Errorf("error in reading GraphQL definition of %s: %v", actionFile, err)
+		}
+		sdl += "\n" + string(actionSDL)
+	}
 	return
 }
 
+// includedActionFiles returns the files of the actions included by
+// actions.yaml, relative to the metadata directory
+func (a *ActionConfig) includedActionFiles() ([]string, error) {
+	commonByt, err := ioutil.ReadFile(filepath.Join(a.MetadataDir, actionsFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var common struct {
+		Actions []interface{} `yaml:"actions"`
+	}
+	if err := yaml.Unmarshal(commonByt, &common); err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, action := range common.Actions {
+		if s, ok := action.(string); ok {
+			if file, ok := include.File(s); ok {
+				files = append(files, file)
+			}
+		}
+	}
+	return files, nil
+}
+
 func (a *ActionConfig) getActionsCodegenURI(framework string) string {
 	return fmt.Sprintf(`https://raw.githubusercontent.com/%s/master/%s/actions-codegen.js`, util.ActionsCodegenOrg, framework)
 }
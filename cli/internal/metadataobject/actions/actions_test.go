@@ -0,0 +1,210 @@
+package actions
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hasura/graphql-engine/cli/v2/internal/metadataobject/actions/types"
+	"github.com/hasura/graphql-engine/cli/v2/version"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+// fakeCliExtension converts actions without arguments and object types with
+// the fields on one line to and from SDL, in place of cli-ext
+type fakeCliExtension struct{}
+
+func (fakeCliExtension) ConvertMetadataToSDL(req types.SDLToRequest) (types.SDLToResponse, error) {
+	var sdl strings.Builder
+	for _, action := range req.Actions {
+		root := "Query"
+		if action.Definition.Type == types.ActionTypeMutation {
+			root = "Mutation"
+		}
+		fmt.Fprintf(&sdl, "type %s {\n  %s: %s\n}\n", root, action.Name, action.Definition.OutputType)
+	}
+	for _, object := range req.Types.Objects {
+		var fields []string
+		for _, field := range object.Fields {
+			fields = append(fields, fmt.Sprintf("%v: %v", field[0].Value, field[1].Value))
+		}
+		fmt.Fprintf(&sdl, "type %s {\n  %s\n}\n", object.Name, strings.Join(fields, ", "))
+	}
+	for _, scalar := range req.Types.Scalars {
+		fmt.Fprintf(&sdl, "scalar %s\n", scalar.Name)
+	}
+	return types.SDLToResponse{SDL: types.SDLPayload{Complete: sdl.String()}}, nil
+}
+
+func (fakeCliExtension) ConvertSDLToMetadata(req types.SDLFromRequest) (types.SDLFromResponse, error) {
+	var resp types.SDLFromResponse
+	var typeName string
+	for _, line := range strings.Split(req.SDL.Complete, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "", line == "}":
+		case strings.HasPrefix(line, "scalar "):
+			resp.Types.Scalars = append(resp.Types.Scalars, types.CustomTypeDef{Name: strings.TrimPrefix(line, "scalar ")})
+		case strings.HasPrefix(line, "type "):
+			typeName = strings.TrimSuffix(strings.TrimPrefix(line, "type "), " {")
+		case typeName == "Query" || typeName == "Mutation":
+			parts := strings.SplitN(line, ": ", 2)
+			resp.Actions = append(resp.Actions, types.Action{
+				Name: parts[0],
+				Definition: types.ActionDef{
+					Type:       types.ActionType(strings.ToLower(typeName)),
+					OutputType: parts[1],
+				},
+			})
+		default:
+			object := types.CustomTypeDef{Name: typeName}
+			for _, field := range strings.Split(line, ", ") {
+				parts := strings.SplitN(field, ": ", 2)
+				object.Fields = append(object.Fields, yaml.MapSlice{{Key: "name", Value: parts[0]}, {Key: "type", Value: parts[1]}})
+			}
+			resp.Types.Objects = append(resp.Types.Objects, object)
+		}
+	}
+	return resp, nil
+}
+
+func (fakeCliExtension) GetActionsCodegen(types.ActionsCodegenRequest) (types.ActionsCodegenResponse, error) {
+	return types.ActionsCodegenResponse{}, errors.New("codegen is not supported")
+}
+
+func newTestActionConfig(metadataDir string) *ActionConfig {
+	return &ActionConfig{
+		MetadataDir:        metadataDir,
+		serverFeatureFlags: &version.ServerFeatureFlags{HasAction: true},
+		cliExtensionConfig: fakeCliExtension{},
+		ensureCliExt:       func() error { return nil },
+		cleanupCliExt:      func() {},
+		splitActions:       true,
+		logger:             logrus.New(),
+	}
+}
+
+func TestActionConfig_ExportSplitActions(t *testing.T) {
+	metadataDir, err := ioutil.TempDir("", "metadata-*")
+	require.NoError(t, err)
+	defer os.RemoveAll(metadataDir)
+
+	actionsYAML := `actions:
+- name: createUser
+  definition:
+    kind: synchronous
+    type: mutation
+    handler: https://users.example.com/create
+    output_type: User
+  permissions:
+  - role: user
+- name: getUser
+  definition:
+    kind: ""
+    type: query
+    handler: https://users.example.com/get
+    output_type: User
+    timeout: 30
+custom_types:
+  enums: []
+  input_objects: []
+  objects:
+  - name: User
+    fields:
+    - name: id
+      type: ID!
+    - name: name
+      type: String
+  scalars: []
+`
+	var metadata yaml.MapSlice
+	require.NoError(t, yaml.Unmarshal([]byte(actionsYAML), &metadata))
+
+	a := newTestActionConfig(metadataDir)
+	files, err := a.Export(metadata)
+	require.NoError(t, err)
+	got := map[string]string{}
+	for name, content := range files {
+		rel, err := filepath.Rel(metadataDir, name)
+		require.NoError(t, err)
+		got[filepath.ToSlash(rel)] = string(content)
+	}
+	assert.Equal(t, map[string]string{
+		"actions.yaml": `actions:
+- '!include actions/createUser.yaml'
+- '!include actions/getUser.yaml'
+custom_types:
+  enums: []
+  input_objects: []
+  objects:
+  - name: User
+  scalars: []
+`,
+		"actions.graphql": `type User {
+  id: ID!, name: String
+}
+`,
+		"actions/createUser.yaml": `name: createUser
+definition:
+  kind: synchronous
+  handler: https://users.example.com/create
+permissions:
+- role: user
+`,
+		"actions/createUser.graphql": `type Mutation {
+  createUser: User
+}
+`,
+		"actions/getUser.yaml": `name: getUser
+definition:
+  kind: ""
+  handler: https://users.example.com/get
+  timeout: 30
+`,
+		"actions/getUser.graphql": `type Query {
+  getUser: User
+}
+`,
+	}, got)
+
+	// actions are built back from the files
+	for name, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(name), os.ModePerm))
+		require.NoError(t, ioutil.WriteFile(name, content, 0644))
+	}
+	includedFiles, err := a.includedActionFiles()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"actions/createUser.yaml", "actions/getUser.yaml"}, includedFiles)
+	sdl, err := a.GetActionsGraphQLFileContent()
+	require.NoError(t, err)
+	assert.Equal(t, got["actions.graphql"]+"\n"+got["actions/createUser.graphql"]+"\n"+got["actions/getUser.graphql"], sdl)
+
+	var built yaml.MapSlice
+	require.NoError(t, a.Build(&built))
+	b, err := yaml.Marshal(built)
+	require.NoError(t, err)
+	assert.Equal(t, actionsYAML, string(b))
+}
+
+func TestActionConfig_BuildMissingActionGraphQLFile(t *testing.T) {
+	metadataDir, err := ioutil.TempDir("", "metadata-*")
+	require.NoError(t, err)
+	defer os.RemoveAll(metadataDir)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(metadataDir, actionsDirectory), os.ModePerm))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(metadataDir, actionsFileName), []byte("actions:\n- '!include actions/getUser.yaml'\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(metadataDir, graphqlFileName), nil, 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(metadataDir, actionsDirectory, "getUser.yaml"), []byte("name: getUser\n"), 0644))
+
+	var built yaml.MapSlice
+	err = newTestActionConfig(metadataDir).Build(&built)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "error in reading GraphQL definition of actions/getUser.yaml")
+}
@@ -0,0 +1,480 @@
+package metadataobject
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml/ast"
+	"github.com/hasura/graphql-engine/cli/v2/internal/jsonschema"
+	"github.com/hasura/graphql-engine/cli/v2/internal/metadatadiff"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// MetadataFix is an edit of a metadata file removing an inconsistent object
+// from the local metadata, or the part of it which is inconsistent
+type MetadataFix struct {
+	Object InconsistentMetadataObject
+	// Description describes the edit, like "remove array relationship
+	// articles of table public.authors"
+	Description string
+	// File is the edited file, relative to the metadata directory
+	File string
+	// Line is the line of the edited value in File
+	Line int
+
+	// path is the YAML path of the edited value in File
+	path string
+	// commentOut comments out the value instead of removing it
+	commentOut bool
+}
+
+// UnfixedObject is an inconsistent object no fix is proposed for
+type UnfixedObject struct {
+	Object InconsistentMetadataObject
+	Reason string
+}
+
+// tableObject is an object of a table which can be inconsistent
+type tableObject struct {
+	// key of the list of the objects in the table
+	key   string
+	label string
+}
+
+var tableObjects = map[string]tableObject{
+	"object_relation":     {"object_relationships", "object relationship"},
+	"array_relation":      {"array_relationships", "array relationship"},
+	"remote_relationship": {"remote_relationships", "remote relationship"},
+	"computed_field":      {"computed_fields", "computed field"},
+	"event_trigger":       {"event_triggers", "event trigger"},
+	"insert_permission":   {"insert_permissions", "insert permission"},
+	"select_permission":   {"select_permissions", "select permission"},
+	"update_permission":   {"update_permissions", "update permission"},
+	"delete_permission":   {"delete_permissions", "delete permission"},
+}
+
+// InconsistentMetadataFixes proposes edits of the files of metadataDir
+// removing the inconsistent objects reported by the server: a broken
+// relationship, permission, computed field or event trigger is removed from
+// its table, a permission on a missing column is kept without the column,
+// missing tables and functions and unreachable remote schemas are removed.
+// Other objects are returned as unfixed.
+func (h *Handler) InconsistentMetadataFixes(metadataDir string, objects []InconsistentMetadataObject) ([]MetadataFix, []UnfixedObject, error) {
+	jbyt, err := h.MakeJSONMetadata()
+	if err != nil {
+		return nil, nil, err
+	}
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(jbyt, &metadata); err != nil {
+		return nil, nil, err
+	}
+	l := &locator{metadataDir: metadataDir, files: map[string]ast.Node{}}
+	var fixes []MetadataFix
+	var unfixed []UnfixedObject
+	for _, object := range objects {
+		fix, err := fixOf(metadata, object)
+		if err != nil {
+			unfixed = append(unfixed, UnfixedObject{object, err.Error()})
+			continue
+		}
+		violation, found, err := l.find(jsonschema.Error{Path: fix.metadataPath}, metadata)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !found {
+			unfixed = append(unfixed, UnfixedObject{object, fmt.Sprintf("cannot locate %s in the metadata files", jsonschema.PathString(fix.metadataPath))})
+			continue
+		}
+		fix.File, fix.path, fix.Line = violation.File, violation.Path, violation.Line
+		fixes = append(fixes, fix.MetadataFix)
+	}
+	return fixes, unfixed, nil
+}
+
+// metadataFix is a fix with the path of the value it edits in the built
+// metadata
+type metadataFix struct {
+	MetadataFix
+	metadataPath []interface{}
+}
+
+func fixOf(metadata map[string]interface{}, object InconsistentMetadataObject) (metadataFix, error) {
+	fix := metadataFix{MetadataFix: MetadataFix{Object: object}}
+	definition, _ := object.Definition.(map[string]interface{})
+	switch kind := object.GetType(); kind {
+	case "table":
+		source, table := nameOf(object.Definition, "table")
+		path, err := findObject(metadata, source, "tables", "table", table)
+		if err != nil {
+			return fix, err
+		}
+		fix.metadataPath = path
+		fix.Description = fmt.Sprintf("remove table %s", metadatadiff.QualifiedName(table))
+	case "function":
+		source, function := nameOf(object.Definition, "function")
+		path, err := findObject(metadata, source, "functions", "function", function)
+		if err != nil {
+			return fix, err
+		}
+		fix.metadataPath = path
+		fix.Description = fmt.Sprintf("remove function %s", metadatadiff.QualifiedName(function))
+	case "remote_schema":
+		name, _ := definition["name"].(string)
+		index := findElement(valueAt(metadata, "remote_schemas"), "name", name)
+		if index < 0 {
+			return fix, fmt.Errorf("remote schema %s is not in the local metadata", name)
+		}
+		fix.metadataPath = []interface{}{"remote_schemas", index}
+		fix.Description = fmt.Sprintf("remove remote schema %s", name)
+	case "remote_schema_permission":
+		name, _ := definition["remote_schema"].(string)
+		role, _ := definition["role"].(string)
+		index := findElement(valueAt(metadata, "remote_schemas"), "name", name)
+		if index < 0 {
+			return fix, fmt.Errorf("remote schema %s is not in the local metadata", name)
+		}
+		permission := findElement(valueAt(metadata, "remote_schemas", index, "permissions"), "role", role)
+		if permission < 0 {
+			return fix, fmt.Errorf("permission of role %s is not in remote schema %s of the local metadata", role, name)
+		}
+		fix.metadataPath = []interface{}{"remote_schemas", index, "permissions", permission}
+		fix.Description = fmt.Sprintf("remove permission of role %s of remote schema %s", role, name)
+	default:
+		o, ok := tableObjects[kind]
+		if !ok {
+			return fix, fmt.Errorf("no fix is known for objects of type %s, run \"hasura metadata inconsistency drop\" to drop it from the server", kind)
+		}
+		source, table := nameOf(object.Definition, "table")
+		path, err := findObject(metadata, source, "tables", "table", table)
+		if err != nil {
+			return fix, err
+		}
+		key, name := "name", objectName(definition)
+		if strings.HasSuffix(kind, "_permission") {
+			key, name = "role", fmt.Sprint(definition["role"])
+		}
+		index := findElement(valueAt(metadata, append(path, o.key)...), key, name)
+		if index < 0 {
+			return fix, fmt.Errorf("%s %s of table %s is not in the local metadata", o.label, name, metadatadiff.QualifiedName(table))
+		}
+		fix.metadataPath = append(path, o.key, index)
+		fix.Description = fmt.Sprintf("remove %s %s of table %s", o.label, name, metadatadiff.QualifiedName(table))
+		if key != "role" {
+			break
+		}
+		fix.Description = fmt.Sprintf("remove %s of role %s of table %s", o.label, name, metadatadiff.QualifiedName(table))
+		// a permission on a missing column is kept without the column
+		columnsPath := append(append([]interface{}{}, fix.metadataPath...), "permission", "columns")
+		columns, _ := valueAt(metadata, columnsPath...).([]interface{})
+		if column := missingColumn(columns, object.GetReason()); column >= 0 {
+			fix.metadataPath = append(columnsPath, column)
+			fix.commentOut = true
+			fix.Description = fmt.Sprintf("comment out column %v of %s of role %s of table %s", columns[column], o.label, name, metadatadiff.QualifiedName(table))
+		}
+	}
+	return fix, nil
+}
+
+// nameOf returns the source and the name of the object of definition, the
+// name is in key for the objects of a source, or is the definition itself
+func nameOf(definition interface{}, key string) (string, interface{}) {
+	if d, ok := definition.(map[string]interface{}); ok {
+		if name, ok := d[key]; ok {
+			source, _ := d["source"].(string)
+			return source, name
+		}
+	}
+	return "", definition
+}
+
+func objectName(definition map[string]interface{}) string {
+	if name, ok := definition["name"].(string); ok {
+		return name
+	}
+	// event triggers are defined by their configuration
+	configuration, _ := definition["configuration"].(map[string]interface{})
+	name, _ := configuration["name"].(string)
+	return name
+}
+
+// findObject returns the path in metadata of the table or function named name
+// in the list key of source, in the only source having it if source is empty
+func findObject(metadata map[string]interface{}, source, key, nameKey string, name interface{}) ([]interface{}, error) {
+	var paths [][]interface{}
+	var sourcePaths [][]interface{}
+	if sources, ok := metadata["sources"].([]interface{}); ok {
+		for i, s := range sources {
+			s, _ := s.(map[string]interface{})
+			if source == "" || s["name"] == source {
+				sourcePaths = append(sourcePaths, []interface{}{"sources", i})
+			}
+		}
+	} else {
+		// metadata of config v2 has the tables of a single database
+		sourcePaths = append(sourcePaths, []interface{}{})
+	}
+	for _, sourcePath := range sourcePaths {
+		objects, _ := valueAt(metadata, append(sourcePath, key)...).([]interface{})
+		for i, object := range objects {
+			object, _ := object.(map[string]interface{})
+			if sameName(object[nameKey], name) {
+				paths = append(paths, append(append([]interface{}{}, sourcePath...), key, i))
+			}
+		}
+	}
+	qualified := metadatadiff.QualifiedName(name)
+	switch {
+	case len(paths) == 0:
+		return nil, fmt.Errorf("%s %s is not in the local metadata", nameKey, qualified)
+	case len(paths) > 1:
+		return nil, fmt.Errorf("%s %s is in several databases of the local metadata", nameKey, qualified)
+	}
+	return paths[0], nil
+}
+
+// sameName reports whether two table or function names are the same, a name
+// without schema is in the public schema
+func sameName(a, b interface{}) bool {
+	qualified := func(name interface{}) string {
+		q := metadatadiff.QualifiedName(name)
+		if !strings.Contains(q, ".") {
+			q = "public." + q
+		}
+		return q
+	}
+	return qualified(a) == qualified(b)
+}
+
+// findElement returns the index of the element of list whose key is value
+func findElement(list interface{}, key, value string) int {
+	elements, _ := list.([]interface{})
+	for i, element := range elements {
+		element, _ := element.(map[string]interface{})
+		if fmt.Sprint(element[key]) == value {
+			return i
+		}
+	}
+	return -1
+}
+
+func valueAt(value interface{}, path ...interface{}) interface{} {
+	for _, p := range path {
+		switch p := p.(type) {
+		case string:
+			obj, _ := value.(map[string]interface{})
+			value = obj[p]
+		case int:
+			elements, _ := value.([]interface{})
+			if p >= len(elements) {
+				return nil
+			}
+			value = elements[p]
+		}
+	}
+	return value
+}
+
+// missingColumn returns the index of the only column of columns named in
+// reason, -1 if there is none or the permission would be left without
+// columns
+func missingColumn(columns []interface{}, reason string) int {
+	if len(columns) < 2 {
+		return -1
+	}
+	index := -1
+	for i, column := range columns {
+		if !strings.Contains(reason, fmt.Sprintf("%q", fmt.Sprint(column))) {
+			continue
+		}
+		if index >= 0 {
+			return -1
+		}
+		index = i
+	}
+	return index
+}
+
+// FixMetadataFiles returns the content of the files of metadataDir edited by
+// fixes, by file relative to metadataDir
+func FixMetadataFiles(metadataDir string, fixes []MetadataFix) (map[string][]byte, error) {
+	byFile := map[string][]MetadataFix{}
+	for _, fix := range fixes {
+		byFile[fix.File] = append(byFile[fix.File], fix)
+	}
+	files := map[string][]byte{}
+	for file, fixes := range byFile {
+		b, err := ioutil.ReadFile(filepath.Join(metadataDir, file))
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot read metadata file %s", file)
+		}
+		if files[file], err = fixFile(b, fixes); err != nil {
+			return nil, errors.Wrapf(err, "cannot fix metadata file %s", file)
+		}
+	}
+	return files, nil
+}
+
+// lineEdit replaces the lines [start, end) of a file
+type lineEdit struct {
+	start, end int
+	lines      []string
+}
+
+// fixFile edits the lines of content, a removed element is removed with the
+// lines it spans, a sequence whose elements are all removed is removed with
+// its key, or replaced by an empty sequence if it is the whole file
+func fixFile(content []byte, fixes []MetadataFix) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return content, nil
+	}
+	lines := strings.Split(string(content), "\n")
+
+	type sequence struct {
+		node    *yaml.Node
+		key     *yaml.Node
+		removed map[*yaml.Node]bool
+	}
+	sequences := map[*yaml.Node]*sequence{}
+	var order []*sequence
+	var edits []lineEdit
+	for _, fix := range fixes {
+		element, seq, key, err := lookup(doc.Content[0], fix.path)
+		if err != nil {
+			return nil, err
+		}
+		if seq.Style&yaml.FlowStyle != 0 {
+			return nil, fmt.Errorf("%s: cannot edit a flow sequence", fix.path)
+		}
+		start := element.Line - 1
+		if fix.commentOut {
+			edits = append(edits, lineEdit{start, start + 1, []string{commentOut(lines[start])}})
+			continue
+		}
+		s, ok := sequences[seq]
+		if !ok {
+			s = &sequence{node: seq, key: key, removed: map[*yaml.Node]bool{}}
+			sequences[seq] = s
+			order = append(order, s)
+		}
+		s.removed[element] = true
+	}
+	for _, s := range order {
+		if len(s.removed) < len(s.node.Content) {
+			for element := range s.removed {
+				start := element.Line - 1
+				edits = append(edits, lineEdit{start, blockEnd(lines, start, false), nil})
+			}
+			continue
+		}
+		if s.key == nil {
+			start := s.node.Content[0].Line - 1
+			edits = append(edits, lineEdit{start, blockEnd(lines, s.node.Content[len(s.node.Content)-1].Line-1, false), []string{"[]"}})
+			continue
+		}
+		start := s.key.Line - 1
+		edits = append(edits, lineEdit{start, blockEnd(lines, start, true), nil})
+	}
+
+	// an edit within a removed block is dropped, edits are applied from the
+	// end of the file to keep the lines of the others
+	sort.Slice(edits, func(i, j int) bool {
+		if edits[i].start != edits[j].start {
+			return edits[i].start < edits[j].start
+		}
+		return edits[i].end > edits[j].end
+	})
+	var kept []lineEdit
+	for _, edit := range edits {
+		if len(kept) > 0 && edit.end <= kept[len(kept)-1].end {
+			continue
+		}
+		kept = append(kept, edit)
+	}
+	for i := len(kept) - 1; i >= 0; i-- {
+		edit := kept[i]
+		lines = append(lines[:edit.start], append(edit.lines, lines[edit.end:]...)...)
+	}
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+// lookup returns the node at the YAML path in root, which must be an element
+// of a sequence, with the sequence and the key of the sequence if it is the
+// value of a mapping
+func lookup(root *yaml.Node, path string) (element, seq, key *yaml.Node, err error) {
+	node := root
+	var lastKey *yaml.Node
+	for _, p := range splitPath(path) {
+		if index, err := strconv.Atoi(p); err == nil {
+			if node.Kind != yaml.SequenceNode || index >= len(node.Content) {
+				return nil, nil, nil, fmt.Errorf("%s: no element %d", path, index)
+			}
+			seq, key, lastKey = node, lastKey, nil
+			node = node.Content[index]
+			continue
+		}
+		if node.Kind != yaml.MappingNode {
+			return nil, nil, nil, fmt.Errorf("%s: no key %s", path, p)
+		}
+		var value *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == p {
+				lastKey, value = node.Content[i], node.Content[i+1]
+			}
+		}
+		if value == nil {
+			return nil, nil, nil, fmt.Errorf("%s: no key %s", path, p)
+		}
+		seq, node = nil, value
+	}
+	if seq == nil {
+		return nil, nil, nil, fmt.Errorf("%s is not an element of a sequence", path)
+	}
+	return node, seq, key, nil
+}
+
+// splitPath splits a YAML path like $.a[0].b in keys and indexes
+func splitPath(path string) []string {
+	path = strings.TrimPrefix(path, "$")
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+	return strings.FieldsFunc(path, func(r rune) bool { return r == '.' })
+}
+
+// blockEnd returns the end of the block starting at line start: the lines
+// indented more than start, and the elements of a sequence indented like
+// start if start is a key. Trailing blank and comment lines are not part of
+// the block.
+func blockEnd(lines []string, start int, isKey bool) int {
+	startIndent := indent(lines[start])
+	end := start + 1
+	for i := start + 1; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		in := indent(lines[i])
+		if in < startIndent || (in == startIndent && !(isKey && strings.HasPrefix(trimmed, "- "))) {
+			break
+		}
+		end = i + 1
+	}
+	return end
+}
+
+func indent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+func commentOut(line string) string {
+	in := indent(line)
+	return line[:in] + "# " + line[in:]
+}
@@ -0,0 +1,127 @@
+package metadataobject
+
+import (
+	"testing"
+
+	"github.com/hasura/graphql-engine/cli/v2"
+	"github.com/hasura/graphql-engine/cli/v2/internal/metadataobject/remoteschemas"
+	"github.com/hasura/graphql-engine/cli/v2/internal/metadataobject/sources"
+	"github.com/hasura/graphql-engine/cli/v2/internal/metadataobject/version"
+	cliversion "github.com/hasura/graphql-engine/cli/v2/version"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler_InconsistentMetadataFixes(t *testing.T) {
+	metadataDir := "testdata/fix/metadata"
+	ec := &cli.ExecutionContext{Logger: logrus.New(), Version: cliversion.New()}
+	h := NewHandler(Objects{
+		version.New(ec, metadataDir),
+		sources.New(ec, metadataDir),
+		remoteschemas.New(ec, metadataDir),
+	}, nil, nil, ec.Logger)
+
+	table := func(name string) map[string]interface{} {
+		return map[string]interface{}{"schema": "public", "name": name}
+	}
+	objects := []InconsistentMetadataObject{
+		{
+			Type:       "array_relation",
+			Reason:     `in table "authors": in relationship "comments": table "comments" does not exist`,
+			Definition: map[string]interface{}{"source": "default", "table": table("authors"), "name": "comments"},
+		},
+		{
+			Type:       "select_permission",
+			Reason:     `in table "authors": in permission for role "user": column "email" does not exist`,
+			Definition: map[string]interface{}{"source": "default", "table": table("authors"), "role": "user"},
+		},
+		{
+			Type:       "table",
+			Reason:     `no such table/view exists in source: "comments"`,
+			Definition: table("comments"),
+		},
+		{
+			Type:       "object_relation",
+			Reason:     `in table "articles": in relationship "author": column "author_id" does not exist`,
+			Definition: map[string]interface{}{"source": "default", "table": table("articles"), "name": "author"},
+		},
+		{
+			Type:       "select_permission",
+			Reason:     `in table "articles": in permission for role "user": "author_id" does not exist`,
+			Definition: map[string]interface{}{"source": "default", "table": table("articles"), "role": "user"},
+		},
+		{
+			Type:       "remote_schema",
+			Reason:     "HTTP exception occurred while sending the request to https://countries.trevorblades.com",
+			Definition: map[string]interface{}{"name": "countries"},
+		},
+		{
+			Type:       "cron_trigger",
+			Reason:     "invalid schedule",
+			Definition: map[string]interface{}{"name": "cleanup"},
+		},
+		{
+			Type:       "function",
+			Reason:     "no such function exists",
+			Definition: map[string]interface{}{"source": "default", "function": map[string]interface{}{"schema": "public", "name": "search_articles"}},
+		},
+	}
+	fixes, unfixed, err := h.InconsistentMetadataFixes(metadataDir, objects)
+	require.NoError(t, err)
+
+	var descriptions []string
+	for _, fix := range fixes {
+		descriptions = append(descriptions, fix.Description)
+	}
+	assert.Equal(t, []string{
+		"remove array relationship comments of table public.authors",
+		"comment out column email of select permission of role user of table public.authors",
+		"remove table public.comments",
+		"remove object relationship author of table public.articles",
+		"remove select permission of role user of table public.articles",
+		"remove remote schema countries",
+	}, descriptions)
+	assert.Equal(t, []UnfixedObject{
+		{objects[6], `no fix is known for objects of type cron_trigger, run "hasura metadata inconsistency drop" to drop it from the server`},
+		{objects[7], "function public.search_articles is not in the local metadata"},
+	}, unfixed)
+
+	files, err := FixMetadataFiles(metadataDir, fixes)
+	require.NoError(t, err)
+	got := map[string]string{}
+	for file, content := range files {
+		got[file] = string(content)
+	}
+	assert.Equal(t, map[string]string{
+		"databases/default/tables/tables.yaml": `- "!include public_authors.yaml"
+- "!include public_articles.yaml"
+`,
+		"databases/default/tables/public_authors.yaml": `table:
+  name: authors
+  schema: public
+array_relationships:
+- name: articles
+  using:
+    foreign_key_constraint_on:
+      column: author_id
+      table:
+        name: articles
+        schema: public
+select_permissions:
+- role: user
+  permission:
+    columns:
+    - id
+    - name
+    # - email
+    filter: {}
+`,
+		"databases/default/tables/public_articles.yaml": `table:
+  name: articles
+  schema: public
+`,
+		"remote_schemas.yaml": `[]
+`,
+	}, got)
+}
@@ -25,21 +25,39 @@ type Handler struct {
 	objects       Objects
 	v1MetadataOps hasura.CommonMetadataOperations
 	v2MetadataOps hasura.V2CommonMetadataOperations
+	// overlay is merged over the metadata directory when building metadata
+	overlay *overlay
+	// projectDir is the directory {{ file }} placeholders are relative to
+	projectDir string
+	// keepPlaceholders leaves the placeholders of built metadata unexpanded
+	keepPlaceholders bool
 
 	logger *logrus.Logger
 }
 
 func NewHandler(objects Objects, v1MetadataOps hasura.CommonMetadataOperations, v2MetadataOps hasura.V2CommonMetadataOperations, logger *logrus.Logger) *Handler {
-	return &Handler{objects, v1MetadataOps, v2MetadataOps, logger}
+	return &Handler{objects: objects, v1MetadataOps: v1MetadataOps, v2MetadataOps: v2MetadataOps, logger: logger}
 }
 
 func NewHandlerFromEC(ec *cli.ExecutionContext) *Handler {
 	metadataObjects := GetMetadataObjectsWithDir(ec)
-	return NewHandler(metadataObjects, cli.GetCommonMetadataOps(ec), ec.APIClient.V1Metadata, ec.Logger)
+	var h *Handler
+	if ec.APIClient == nil {
+		// commands working offline, like metadata validate, have no client
+		h = NewHandler(metadataObjects, nil, nil, ec.Logger)
+	} else {
+		h = NewHandler(metadataObjects, cli.GetCommonMetadataOps(ec), ec.APIClient.V1Metadata, ec.Logger)
+	}
+	h.SetOverlayFromEC(ec)
+	h.projectDir = ec.ExecutionDirectory
+	return h
 }
 
+// SetMetadataObjects sets the objects metadata is built from, the overlay
+// of the metadata directory is no longer merged
 func (h *Handler) SetMetadataObjects(objects Objects) {
 	h.objects = objects
+	h.overlay = nil
 }
 
 // WriteMetadata writes the files in the metadata folder
@@ -76,6 +94,11 @@ func (h *Handler) ExportMetadata() (map[string][]byte, error) {
 			return nil, errors.Wrap(err, fmt.Sprintf("cannot export %s from metadata", object.Name()))
 		}
 		for fileName, content := range files {
+			// keep the {{ env }} and {{ file }} placeholders of the project
+			content, err := restorePlaceholders(fileName, content, h.projectDir)
+			if err != nil {
+				return nil, errors.Wrapf(err, "cannot restore placeholders of %s", fileName)
+			}
 			metadataFiles[fileName] = content
 		}
 	}
@@ -95,13 +118,36 @@ func (h *Handler) ReloadMetadata() (io.Reader, error) {
 	return r, err
 }
 
+// KeepPlaceholders makes the handler build metadata with the {{ env }} and
+// {{ file }} placeholders of the files left as they are, for commands which
+// do not send the metadata to the server
+func (h *Handler) KeepPlaceholders() {
+	h.keepPlaceholders = true
+}
+
+// BuildMetadata builds metadata from the files of the project, with the
+// {{ env }} and {{ file }} placeholders of the files expanded
 func (h *Handler) BuildMetadata() (yaml.MapSlice, error) {
+	var metadata yaml.MapSlice
+	var err error
+	if h.overlay != nil {
+		metadata, err = h.buildMetadataWithOverlay()
+	} else {
+		metadata, err = buildMetadata(h.objects, h.logger)
+	}
+	if err != nil || h.keepPlaceholders {
+		return metadata, err
+	}
+	return expandMetadataPlaceholders(metadata, h.projectDir)
+}
+
+func buildMetadata(objects Objects, logger *logrus.Logger) (yaml.MapSlice, error) {
 	var tmpMeta yaml.MapSlice
-	for _, object := range h.objects {
+	for _, object := range objects {
 		err := object.Build(&tmpMeta)
 		if err != nil {
 			if errors.Is(err, fs.ErrNotExist) {
-				h.logger.Debugf("metadata file for %s was not found, assuming an empty file", object.Name())
+				logger.Debugf("metadata file for %s was not found, assuming an empty file", object.Name())
 				continue
 			}
 			return tmpMeta, errors.Wrap(err, fmt.Sprintf("cannot build %s from project", object.Name()))
@@ -0,0 +1,116 @@
+// Package include reads metadata files which include other files with
+// "!include <file>" strings, like the files of the metadata layouts having a
+// file per object. The path of an included file is relative to the directory
+// of the including file.
+package include
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const tag = "!include"
+
+// Tag returns the string including file
+func Tag(file string) string {
+	return fmt.Sprintf("%s %s", tag, filepath.ToSlash(file))
+}
+
+// File returns the file included by s, ok is false if s is not an include
+func File(s string) (file string, ok bool) {
+	if !strings.HasPrefix(s, tag+" ") {
+		return "", false
+	}
+	return strings.Trim(strings.TrimSpace(strings.TrimPrefix(s, tag)), `"`), true
+}
+
+// ReadFile reads the file at path with the files it includes. A YAML file is
+// decoded, keeping the order of the keys of its mappings, other files, like
+// GraphQL schemas, are read as a string.
+func ReadFile(path string) (interface{}, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if ext := filepath.Ext(path); ext != ".yaml" && ext != ".yml" {
+		return string(b), nil
+	}
+	var v orderedValue
+	if err := yaml.Unmarshal(b, &v); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return Resolve(v.value, filepath.Dir(path))
+}
+
+// orderedValue decodes a YAML value, mappings are decoded as map slices
+type orderedValue struct {
+	value interface{}
+}
+
+func (o *orderedValue) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&o.value); err != nil {
+		return err
+	}
+	switch o.value.(type) {
+	case map[interface{}]interface{}:
+		var mapping yaml.MapSlice
+		if err := unmarshal(&mapping); err != nil {
+			return err
+		}
+		o.value = mapping
+	case []interface{}:
+		// elements are decoded one by one to keep the order of the keys of
+		// their mappings
+		var sequence []orderedValue
+		if err := unmarshal(&sequence); err != nil {
+			return err
+		}
+		values := make([]interface{}, 0, len(sequence))
+		for _, element := range sequence {
+			values = append(values, element.value)
+		}
+		o.value = values
+	}
+	return nil
+}
+
+// Resolve replaces the include strings of value, decoded from a YAML file of
+// dir, by the content of the files they include
+func Resolve(value interface{}, dir string) (interface{}, error) {
+	var err error
+	switch v := value.(type) {
+	case string:
+		if file, ok := File(v); ok {
+			included, err := ReadFile(filepath.Join(dir, file))
+			if err != nil {
+				// a missing included file is an error of the including file,
+				// not a missing metadata file
+				return nil, fmt.Errorf("cannot include %s: %v", file, err)
+			}
+			return included, nil
+		}
+	case yaml.MapSlice:
+		for i := range v {
+			if v[i].Value, err = Resolve(v[i].Value, dir); err != nil {
+				return nil, err
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			if v[i], err = Resolve(v[i], dir); err != nil {
+				return nil, err
+			}
+		}
+	case map[interface{}]interface{}:
+		for key, item := range v {
+			if v[key], err = Resolve(item, dir); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return value, nil
+}
@@ -0,0 +1,228 @@
+package metadataobject
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/hasura/graphql-engine/cli/v2"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+	v3yaml "gopkg.in/yaml.v3"
+)
+
+// overlay is a directory mirroring the layout of the metadata directory, eg:
+// metadata/overlays/prod. Its YAML files are deep-merged over the files of
+// the metadata directory when building metadata, its other files replace
+// them.
+type overlay struct {
+	dir     string
+	baseDir string
+	// objects returns the metadata objects of a metadata directory
+	objects func(dir string) Objects
+}
+
+// SetOverlayFromEC merges the overlay of the environment selected by the
+// config of ec over the metadata directory of the project when building
+// metadata. Metadata is exported to the metadata directory, overlays are
+// left as they are.
+func (h *Handler) SetOverlayFromEC(ec *cli.ExecutionContext) {
+	if ec.MetadataOverlayDir == "" {
+		h.overlay = nil
+		return
+	}
+	h.overlay = &overlay{
+		dir:     ec.MetadataOverlayDir,
+		baseDir: ec.MetadataDir,
+		objects: func(dir string) Objects {
+			return GetMetadataObjectsWithDir(ec, dir)
+		},
+	}
+}
+
+// buildMetadataWithOverlay builds metadata from a copy of the metadata
+// directory having the files of the overlay merged in
+func (h *Handler) buildMetadataWithOverlay() (yaml.MapSlice, error) {
+	tmpDir, err := ioutil.TempDir("", "hasura-metadata-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+	if err := mergeOverlay(h.overlay.baseDir, h.overlay.dir, tmpDir); err != nil {
+		return nil, errors.Wrapf(err, "cannot merge metadata overlay %s", h.overlay.dir)
+	}
+	h.logger.Debugf("building metadata with overlay %s", h.overlay.dir)
+	return buildMetadata(h.overlay.objects(tmpDir), h.logger)
+}
+
+// mergeOverlay copies the files of baseDir, except overlays, to dst and
+// merges the files of overlayDir over them
+func mergeOverlay(baseDir, overlayDir, dst string) error {
+	err := filepath.Walk(baseDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(baseDir, path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if rel == cli.MetadataOverlaysDirectory {
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(filepath.Join(dst, rel), os.ModePerm)
+		}
+		return copyFile(path, filepath.Join(dst, rel))
+	})
+	if err != nil {
+		return err
+	}
+	return filepath.Walk(overlayDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(overlayDir, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, os.ModePerm)
+		}
+		ext := filepath.Ext(path)
+		if _, err := os.Stat(target); err != nil || (ext != ".yaml" && ext != ".yml") {
+			return copyFile(path, target)
+		}
+		base, err := ioutil.ReadFile(target)
+		if err != nil {
+			return err
+		}
+		over, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		merged, err := mergeYAML(base, over)
+		if err != nil {
+			return errors.Wrapf(err, "cannot merge %s", rel)
+		}
+		return ioutil.WriteFile(target, merged, 0644)
+	})
+}
+
+func copyFile(src, dst string) error {
+	b, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(dst, b, 0644)
+}
+
+// mergeYAML deep-merges the YAML document over into base. Mappings are merged
+// key by key. Sequences of objects having an identity (see identity) are
+// merged element by element, an element of over which is not in base is
+// added. Other values of over, like a list of columns, replace those of base.
+func mergeYAML(base, over []byte) ([]byte, error) {
+	var baseDoc, overDoc v3yaml.Node
+	if err := v3yaml.Unmarshal(base, &baseDoc); err != nil {
+		return nil, err
+	}
+	if err := v3yaml.Unmarshal(over, &overDoc); err != nil {
+		return nil, err
+	}
+	if len(overDoc.Content) == 0 {
+		return base, nil
+	}
+	if len(baseDoc.Content) == 0 {
+		return over, nil
+	}
+	baseDoc.Content[0] = mergeNode(baseDoc.Content[0], overDoc.Content[0])
+	var b bytes.Buffer
+	encoder := v3yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&baseDoc); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func mergeNode(base, over *v3yaml.Node) *v3yaml.Node {
+	switch {
+	case base.Kind == v3yaml.MappingNode && over.Kind == v3yaml.MappingNode && over.Tag == base.Tag:
+		for i := 0; i+1 < len(over.Content); i += 2 {
+			key, value := over.Content[i], over.Content[i+1]
+			merged := false
+			for j := 0; j+1 < len(base.Content); j += 2 {
+				if base.Content[j].Value == key.Value {
+					base.Content[j+1] = mergeNode(base.Content[j+1], value)
+					merged = true
+					break
+				}
+			}
+			if !merged {
+				base.Content = append(base.Content, key, value)
+			}
+		}
+		return base
+	case base.Kind == v3yaml.SequenceNode && over.Kind == v3yaml.SequenceNode && hasIdentities(over):
+		for _, element := range over.Content {
+			id := identity(element)
+			merged := false
+			for j, baseElement := range base.Content {
+				if identity(baseElement) == id {
+					base.Content[j] = mergeNode(baseElement, element)
+					merged = true
+					break
+				}
+			}
+			if !merged {
+				base.Content = append(base.Content, element)
+			}
+		}
+		return base
+	}
+	return over
+}
+
+func hasIdentities(sequence *v3yaml.Node) bool {
+	for _, element := range sequence.Content {
+		if identity(element) == "" {
+			return false
+		}
+	}
+	return len(sequence.Content) > 0
+}
+
+// identityFields are the fields identifying metadata objects in sequences,
+// like the name of a remote schema, the table of a table or the role of a
+// permission
+var identityFields = []string{"name", "table", "function", "role", "role_name", "collection"}
+
+// identity returns the first identity field of a mapping with its value,
+// empty if the node has none
+func identity(node *v3yaml.Node) string {
+	if node.Kind != v3yaml.MappingNode {
+		return ""
+	}
+	for _, field := range identityFields {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value != field {
+				continue
+			}
+			var value interface{}
+			if err := node.Content[i+1].Decode(&value); err != nil {
+				return ""
+			}
+			b, err := json.Marshal(value)
+			if err != nil {
+				return ""
+			}
+			return field + "=" + string(b)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,150 @@
+package metadataobject
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		base string
+		over string
+		want string
+	}{
+		{
+			"objects are merged by identity",
+			`- name: countries
+  definition:
+    url: http://localhost:4000
+    timeout_seconds: 60
+- name: weather
+  definition:
+    url: http://localhost:5000
+`,
+			`- name: countries
+  definition:
+    url: https://countries.example.com
+- name: payments
+  definition:
+    url: https://payments.example.com
+`,
+			`- name: countries
+  definition:
+    url: https://countries.example.com
+    timeout_seconds: 60
+- name: weather
+  definition:
+    url: http://localhost:5000
+- name: payments
+  definition:
+    url: https://payments.example.com
+`,
+		},
+		{
+			"lists of values are replaced",
+			`table:
+  name: users
+  schema: public
+select_permissions:
+  - role: user
+    permission:
+      columns:
+        - id
+        - email
+      filter: {}
+`,
+			`select_permissions:
+  - role: user
+    permission:
+      columns:
+        - id
+`,
+			`table:
+  name: users
+  schema: public
+select_permissions:
+  - role: user
+    permission:
+      columns:
+        - id
+      filter: {}
+`,
+		},
+		{
+			"tags are kept",
+			`tables: "!include default/tables/tables.yaml"
+cron_triggers: !include cron.yaml
+schedule: '* * * * *'
+`,
+			`schedule: '0 0 * * *'
+`,
+			`tables: "!include default/tables/tables.yaml"
+cron_triggers: !include cron.yaml
+schedule: '0 0 * * *'
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mergeYAML([]byte(tt.base), []byte(tt.over))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestMergeOverlay(t *testing.T) {
+	baseDir, err := ioutil.TempDir("", "metadata-*")
+	require.NoError(t, err)
+	defer os.RemoveAll(baseDir)
+	dst, err := ioutil.TempDir("", "metadata-*")
+	require.NoError(t, err)
+	defer os.RemoveAll(dst)
+
+	files := map[string]string{
+		"version.yaml":                            "version: 3\n",
+		"actions.graphql":                         "type Query { a: String }\n",
+		"actions.yaml":                            "actions:\n- name: a\n  definition:\n    handler: http://localhost:3000\n",
+		"overlays/prod/actions.yaml":              "actions:\n- name: a\n  definition:\n    handler: https://actions.example.com\n",
+		"overlays/prod/cron_triggers.yaml":        "- name: cleanup\n  schedule: 0 0 * * *\n",
+		"overlays/staging/remote_schemas.yaml":    "- name: staging\n",
+		"databases/default/tables/public_t1.yaml": "table:\n  name: t1\n  schema: public\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(baseDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	}
+	require.NoError(t, mergeOverlay(baseDir, filepath.Join(baseDir, "overlays", "prod"), dst))
+
+	want := map[string]string{
+		"version.yaml":       "version: 3\n",
+		"actions.graphql":    "type Query { a: String }\n",
+		"actions.yaml":       "actions:\n  - name: a\n    definition:\n      handler: https://actions.example.com\n",
+		"cron_triggers.yaml": "- name: cleanup\n  schedule: 0 0 * * *\n",
+		"databases/default/tables/public_t1.yaml": "table:\n  name: t1\n  schema: public\n",
+	}
+	got := map[string]string{}
+	require.NoError(t, filepath.Walk(dst, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dst, path)
+		if err != nil {
+			return err
+		}
+		got[filepath.ToSlash(rel)] = string(b)
+		return nil
+	}))
+	assert.Equal(t, want, got)
+}
@@ -0,0 +1,707 @@
+package metadataobject
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	gyaml "github.com/goccy/go-yaml"
+	"github.com/hasura/graphql-engine/cli/v2/internal/hasura"
+	"github.com/hasura/graphql-engine/cli/v2/internal/metadatadiff"
+	"github.com/pkg/errors"
+)
+
+// ObjectSelection selects the metadata objects applied by
+// PartialMetadataOperations
+type ObjectSelection struct {
+	// Files are metadata files relative to the metadata directory, eg:
+	// databases/default/tables/public_orders.yaml
+	Files         []string
+	Sources       []string
+	RemoteSchemas []string
+}
+
+// PartialMetadataOperations returns the metadata API calls which apply the
+// selected objects of the project on the server, computed from the
+// differences between server metadata and project metadata. Objects which
+// are not selected are left as they are on the server.
+func (h *Handler) PartialMetadataOperations(selection ObjectSelection, metadataDir string) ([]hasura.RequestBody, error) {
+	selectors, err := selectors(selection, metadataDir)
+	if err != nil {
+		return nil, err
+	}
+	jbyt, err := h.MakeJSONMetadata()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot build project metadata")
+	}
+	var local interface{}
+	if err := json.Unmarshal(jbyt, &local); err != nil {
+		return nil, err
+	}
+	r, err := h.v1MetadataOps.ExportMetadata()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot export metadata from server")
+	}
+	var server interface{}
+	if err := json.NewDecoder(r).Decode(&server); err != nil {
+		return nil, errors.Wrap(err, "cannot decode server metadata")
+	}
+	var changes []metadatadiff.Change
+	for _, change := range metadatadiff.Diff(server, local) {
+		if isSelected(change.Object, selectors) {
+			changes = append(changes, change)
+		}
+	}
+	return metadataOperations(changes, server, local)
+}
+
+// ApplyMetadataOperations sends the metadata API calls as one bulk request
+func (h *Handler) ApplyMetadataOperations(operations []hasura.RequestBody) error {
+	resp, body, err := h.v1MetadataOps.SendCommonMetadataOperation(hasura.RequestBody{Type: "bulk", Args: operations})
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		b, err := ioutil.ReadAll(body)
+		if err != nil {
+			return err
+		}
+		return errors.New(string(b))
+	}
+	return nil
+}
+
+// selectors returns the paths of the selected objects, an ID without a name
+// selects all the objects of its kind
+func selectors(selection ObjectSelection, metadataDir string) ([][]metadatadiff.ObjectID, error) {
+	var selectors [][]metadatadiff.ObjectID
+	for _, name := range selection.Sources {
+		selectors = append(selectors, []metadatadiff.ObjectID{{Kind: "source", Name: name}})
+	}
+	for _, name := range selection.RemoteSchemas {
+		selectors = append(selectors, []metadatadiff.ObjectID{{Kind: "remote_schema", Name: name}})
+	}
+	for _, file := range selection.Files {
+		selector, err := fileSelector(filepath.ToSlash(filepath.Clean(file)), metadataDir)
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, selector)
+	}
+	return selectors, nil
+}
+
+// fileSelector returns the path of the objects built from a metadata file
+func fileSelector(file, metadataDir string) ([]metadatadiff.ObjectID, error) {
+	parts := strings.Split(file, "/")
+	if len(parts) > 4 && parts[0] == "databases" && parts[2] == "tables" {
+		// a permission or relationship file of a table exported with the
+		// split layout selects the table
+		parts = append(parts[:3], parts[3]+".yaml")
+		file = strings.Join(parts, "/")
+	}
+	switch {
+	case file == "remote_schemas.yaml":
+		return []metadatadiff.ObjectID{{Kind: "remote_schema"}}, nil
+	case len(parts) > 2 && parts[0] == "remote_schemas":
+		return []metadatadiff.ObjectID{{Kind: "remote_schema", Name: parts[1]}}, nil
+	case file == "databases/databases.yaml":
+		return []metadatadiff.ObjectID{{Kind: "source"}}, nil
+	case len(parts) == 4 && parts[0] == "databases" && (parts[2] == "tables" || parts[2] == "functions"):
+		kind, field := "table", "table"
+		if parts[2] == "functions" {
+			kind, field = "function", "function"
+		}
+		source := metadatadiff.ObjectID{Kind: "source", Name: parts[1]}
+		if parts[3] == parts[2]+".yaml" {
+			return []metadatadiff.ObjectID{source, {Kind: kind}}, nil
+		}
+		b, err := ioutil.ReadFile(filepath.Join(metadataDir, file))
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot read metadata file %s", file)
+		}
+		jbyt, err := gyaml.YAMLToJSON(b)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot parse metadata file %s", file)
+		}
+		var object map[string]interface{}
+		if err := json.Unmarshal(jbyt, &object); err != nil {
+			return nil, errors.Wrapf(err, "cannot parse metadata file %s", file)
+		}
+		name := metadatadiff.QualifiedName(object[field])
+		if name == "" {
+			return nil, fmt.Errorf("metadata file %s has no %s", file, field)
+		}
+		return []metadatadiff.ObjectID{source, {Kind: kind, Name: name}}, nil
+	}
+	return nil, fmt.Errorf("cannot apply %s alone: only databases/databases.yaml, the files of tables and functions of databases and the files of remote schemas can be applied partially", file)
+}
+
+func isSelected(path []metadatadiff.ObjectID, selectors [][]metadatadiff.ObjectID) bool {
+	for _, selector := range selectors {
+		if len(selector) > len(path) {
+			continue
+		}
+		matches := true
+		for i, id := range selector {
+			if id.Kind != path[i].Kind || (id.Name != "" && id.Name != path[i].Name) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+// operations are run in phases, so that objects are dropped before they are
+// created again and are created after the objects they depend on
+const (
+	// permissions are dropped before the relationships they use
+	phaseDropPermission = iota
+	phaseDrop
+	phaseSource
+	phaseTable
+	phaseRelationship
+	phasePermission
+)
+
+type operation struct {
+	phase int
+	body  hasura.RequestBody
+}
+
+// operations builds the metadata API calls of changes
+type operations struct {
+	list   []operation
+	server interface{}
+	local  interface{}
+	// recreated are the keys of the table permissions and remote
+	// relationships already dropped or created by the operations
+	recreated map[string]bool
+	// parents are the keys of the functions and remote schemas changed as a
+	// whole, their permissions are dropped and created along with them
+	parents map[string]bool
+}
+
+func (o *operations) add(phase int, kind string, args map[string]interface{}) {
+	o.list = append(o.list, operation{phase, hasura.RequestBody{Type: kind, Args: args}})
+}
+
+// metadataOperations returns the metadata API calls which turn metadata of
+// the server into project metadata for the changes
+func metadataOperations(changes []metadatadiff.Change, server, local interface{}) ([]hasura.RequestBody, error) {
+	o := &operations{server: server, local: local, recreated: map[string]bool{}, parents: map[string]bool{}}
+	for _, change := range changes {
+		switch path := change.Object; {
+		case len(path) == 1 && path[0].Kind == "remote_schema",
+			len(path) == 2 && path[0].Kind == "source" && path[1].Kind == "function":
+			o.parents[objectKey(path)] = true
+		}
+	}
+	for _, change := range changes {
+		if err := o.addChange(change); err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(o.list, func(i, j int) bool { return o.list[i].phase < o.list[j].phase })
+	bodies := []hasura.RequestBody{}
+	for _, op := range o.list {
+		bodies = append(bodies, op.body)
+	}
+	return bodies, nil
+}
+
+// relationshipKinds are the objects of a table other than permissions, which
+// can be used by permissions
+var relationshipKinds = map[string]string{
+	"object_relationships": "object_relationship",
+	"array_relationships":  "array_relationship",
+	"remote_relationships": "remote_relationship",
+	"computed_fields":      "computed_field",
+	"event_triggers":       "event_trigger",
+}
+
+var permissionKinds = map[string]string{
+	"insert_permissions": "insert_permission",
+	"select_permissions": "select_permission",
+	"update_permissions": "update_permission",
+	"delete_permissions": "delete_permission",
+}
+
+func (o *operations) addChange(change metadatadiff.Change) error {
+	path := change.Object
+	before, _ := change.Before.(map[string]interface{})
+	after, _ := change.After.(map[string]interface{})
+	unsupported := fmt.Errorf("cannot apply %s alone, use metadata apply without selecting objects", path[len(path)-1])
+
+	switch path[0].Kind {
+	case "source":
+	case "remote_schema":
+		return o.addRemoteSchemaChange(change, before, after, unsupported)
+	default:
+		return unsupported
+	}
+
+	source := metadatadiff.Find(o.local, path[:1])
+	if source == nil {
+		source = metadatadiff.Find(o.server, path[:1])
+	}
+	prefix, err := sourcePrefix(source)
+	if err != nil {
+		return err
+	}
+	sourceName := path[0].Name
+	if len(path) == 1 {
+		switch change.Type {
+		case metadatadiff.Removed:
+			o.add(phaseDrop, prefix+"_drop_source", map[string]interface{}{"name": sourceName, "cascade": true})
+		case metadatadiff.Added:
+			o.addSource(prefix, after)
+		case metadatadiff.Changed:
+			for _, field := range change.Fields {
+				if !strings.HasPrefix(field, "configuration") {
+					return fmt.Errorf("cannot apply the change of %s of %s, use metadata apply without selecting objects", field, path[0])
+				}
+			}
+			o.add(phaseSource, prefix+"_add_source", map[string]interface{}{
+				"name":                  sourceName,
+				"configuration":         after["configuration"],
+				"replace_configuration": true,
+			})
+		}
+		return nil
+	}
+
+	switch path[1].Kind {
+	case "table":
+		table := metadatadiff.Find(o.local, path[:2])
+		if table == nil {
+			table = metadatadiff.Find(o.server, path[:2])
+		}
+		if len(path) == 2 {
+			return o.addTableChange(prefix, sourceName, change, before, after)
+		}
+		kind := path[2].Kind
+		if strings.HasSuffix(kind, "_permission") || kind == "remote_relationship" {
+			// the permission was recreated along with a relationship it uses,
+			// the remote relationship along with its remote schema
+			key := tableObjectKey(sourceName, table["table"], kind, path[2].Name)
+			if o.recreated[key] {
+				return nil
+			}
+			o.recreated[key] = true
+		}
+		if change.Type == metadatadiff.Changed && kind == "event_trigger" {
+			// an event trigger is replaced, deleting it would drop its
+			// pending events
+			o.createTableObject(prefix, sourceName, table["table"], kind, after, true)
+			return nil
+		}
+		if change.Type != metadatadiff.Added {
+			if kind == "object_relationship" || kind == "array_relationship" || kind == "computed_field" {
+				o.recreateDependentPermissions(prefix, sourceName, before["name"])
+			}
+			o.dropTableObject(prefix, sourceName, table["table"], kind, before)
+		}
+		if change.Type != metadatadiff.Removed {
+			o.createTableObject(prefix, sourceName, table["table"], kind, after, false)
+		}
+	case "function":
+		if len(path) == 2 {
+			if change.Type != metadatadiff.Added {
+				o.add(phaseDrop, prefix+"_untrack_function", map[string]interface{}{"source": sourceName, "function": before["function"]})
+			}
+			if change.Type != metadatadiff.Removed {
+				o.addFunction(prefix, sourceName, after)
+			}
+			return nil
+		}
+		if o.parents[objectKey(path[:2])] {
+			// the permissions are created along with the function
+			return nil
+		}
+		function := metadatadiff.Find(o.local, path[:2])
+		if function == nil {
+			function = metadatadiff.Find(o.server, path[:2])
+		}
+		if change.Type != metadatadiff.Added {
+			o.add(phaseDropPermission, prefix+"_drop_function_permission", map[string]interface{}{
+				"source": sourceName, "function": function["function"], "role": before["role"],
+			})
+		}
+		if change.Type != metadatadiff.Removed {
+			o.add(phasePermission, prefix+"_create_function_permission", map[string]interface{}{
+				"source": sourceName, "function": function["function"], "role": after["role"],
+			})
+		}
+	default:
+		return unsupported
+	}
+	return nil
+}
+
+// sourcePrefix returns the prefix of the metadata API calls of a source, eg: pg
+func sourcePrefix(source map[string]interface{}) (string, error) {
+	kind, _ := source["kind"].(string)
+	switch kind {
+	case "postgres":
+		return "pg", nil
+	case "mssql", "bigquery", "citus":
+		return kind, nil
+	}
+	return "", fmt.Errorf("cannot apply objects of source %v of kind %q alone, use metadata apply without selecting objects", source["name"], kind)
+}
+
+func (o *operations) addSource(prefix string, source map[string]interface{}) {
+	name := source["name"]
+	o.add(phaseSource, prefix+"_add_source", map[string]interface{}{"name": name, "configuration": source["configuration"]})
+	tables, _ := source["tables"].([]interface{})
+	for _, t := range tables {
+		if table, ok := t.(map[string]interface{}); ok {
+			o.addTable(prefix, name, table)
+		}
+	}
+	functions, _ := source["functions"].([]interface{})
+	for _, f := range functions {
+		if function, ok := f.(map[string]interface{}); ok {
+			o.addFunction(prefix, name, function)
+		}
+	}
+}
+
+func (o *operations) addTableChange(prefix, source string, change metadatadiff.Change, before, after map[string]interface{}) error {
+	switch change.Type {
+	case metadatadiff.Removed:
+		o.add(phaseDrop, prefix+"_untrack_table", map[string]interface{}{"source": source, "table": before["table"], "cascade": true})
+	case metadatadiff.Added:
+		o.addTable(prefix, source, after)
+	case metadatadiff.Changed:
+		customization, isEnum := false, false
+		for _, field := range change.Fields {
+			switch {
+			case strings.HasPrefix(field, "configuration"):
+				customization = true
+			case field == "is_enum":
+				isEnum = true
+			default:
+				return fmt.Errorf("cannot apply the change of %s of %s, use metadata apply without selecting objects", field, change.Object[1])
+			}
+		}
+		if customization {
+			configuration := after["configuration"]
+			if configuration == nil {
+				configuration = map[string]interface{}{}
+			}
+			o.add(phaseTable, prefix+"_set_table_customization", map[string]interface{}{"source": source, "table": after["table"], "configuration": configuration})
+		}
+		if isEnum {
+			enum, _ := after["is_enum"].(bool)
+			o.add(phaseTable, prefix+"_set_table_is_enum", map[string]interface{}{"source": source, "table": after["table"], "is_enum": enum})
+		}
+	}
+	return nil
+}
+
+// addTable tracks a table along with its relationships, triggers and
+// permissions
+func (o *operations) addTable(prefix string, source interface{}, table map[string]interface{}) {
+	args := map[string]interface{}{"source": source, "table": table["table"]}
+	if configuration, ok := table["configuration"]; ok {
+		args["configuration"] = configuration
+	}
+	o.add(phaseTable, prefix+"_track_table", args)
+	if enum, _ := table["is_enum"].(bool); enum {
+		o.add(phaseTable, prefix+"_set_table_is_enum", map[string]interface{}{"source": source, "table": table["table"], "is_enum": true})
+	}
+	for _, fields := range []map[string]string{relationshipKinds, permissionKinds} {
+		for _, field := range sortedKeys(fields) {
+			objects, _ := table[field].([]interface{})
+			for _, item := range objects {
+				if object, ok := item.(map[string]interface{}); ok {
+					o.createTableObject(prefix, source, table["table"], fields[field], object, false)
+				}
+			}
+		}
+	}
+}
+
+func (o *operations) addFunction(prefix string, source interface{}, function map[string]interface{}) {
+	args := map[string]interface{}{"source": source, "function": function["function"]}
+	for _, field := range []string{"configuration", "comment"} {
+		if value, ok := function[field]; ok {
+			args[field] = value
+		}
+	}
+	o.add(phaseTable, prefix+"_track_function", args)
+	permissions, _ := function["permissions"].([]interface{})
+	for _, p := range permissions {
+		if permission, ok := p.(map[string]interface{}); ok {
+			o.add(phasePermission, prefix+"_create_function_permission", map[string]interface{}{
+				"source": source, "function": function["function"], "role": permission["role"],
+			})
+		}
+	}
+}
+
+// createTableObject adds the call creating a relationship, a computed field,
+// an event trigger or a permission of a table, an existing event trigger is
+// replaced if replace is set
+func (o *operations) createTableObject(prefix string, source, table interface{}, kind string, object map[string]interface{}, replace bool) {
+	args := map[string]interface{}{"source": source, "table": table}
+	switch kind {
+	case "object_relationship", "array_relationship":
+		copyFields(args, object, "name", "using", "comment")
+		o.add(phaseRelationship, prefix+"_create_"+kind, args)
+	case "remote_relationship":
+		copyFields(args, object, "name")
+		definition, _ := object["definition"].(map[string]interface{})
+		copyFields(args, definition, sortedKeys(definition)...)
+		o.add(phaseRelationship, prefix+"_create_remote_relationship", args)
+	case "computed_field":
+		copyFields(args, object, "name", "definition", "comment")
+		o.add(phaseRelationship, prefix+"_add_computed_field", args)
+	case "event_trigger":
+		copyFields(args, object, "name", "webhook", "webhook_from_env", "headers", "retry_conf")
+		definition, _ := object["definition"].(map[string]interface{})
+		copyFields(args, definition, sortedKeys(definition)...)
+		args["replace"] = replace
+		o.add(phaseRelationship, prefix+"_create_event_trigger", args)
+	default:
+		copyFields(args, object, "role", "permission", "comment")
+		o.add(phasePermission, prefix+"_create_"+kind, args)
+	}
+}
+
+// dropTableObject adds the call dropping an object created by
+// createTableObject
+func (o *operations) dropTableObject(prefix string, source, table interface{}, kind string, object map[string]interface{}) {
+	args := map[string]interface{}{"source": source, "table": table}
+	switch kind {
+	case "object_relationship", "array_relationship":
+		args["relationship"] = object["name"]
+		o.add(phaseDrop, prefix+"_drop_relationship", args)
+	case "remote_relationship":
+		args["name"] = object["name"]
+		o.add(phaseDrop, prefix+"_delete_remote_relationship", args)
+	case "computed_field":
+		args["name"] = object["name"]
+		o.add(phaseDrop, prefix+"_drop_computed_field", args)
+	case "event_trigger":
+		o.add(phaseDrop, prefix+"_delete_event_trigger", map[string]interface{}{"source": source, "name": object["name"]})
+	default:
+		args["role"] = object["role"]
+		o.add(phaseDropPermission, prefix+"_drop_"+kind, args)
+	}
+}
+
+// recreateDependentPermissions drops the permissions on the server which may
+// use a relationship or a computed field of the given name, so that it can be
+// dropped, and creates them again from project metadata. A permission uses it
+// if its name is a key of the permission, permissions of other tables can use
+// it through their relationships.
+func (o *operations) recreateDependentPermissions(prefix, source string, name interface{}) {
+	sourceID := metadatadiff.ObjectID{Kind: "source", Name: source}
+	server := metadatadiff.Find(o.server, []metadatadiff.ObjectID{sourceID})
+	tables, _ := server["tables"].([]interface{})
+	for _, t := range tables {
+		table, ok := t.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		tableID := metadatadiff.ObjectID{Kind: "table", Name: metadatadiff.QualifiedName(table["table"])}
+		for _, field := range sortedKeys(permissionKinds) {
+			kind := permissionKinds[field]
+			permissions, _ := table[field].([]interface{})
+			for _, p := range permissions {
+				permission, ok := p.(map[string]interface{})
+				if !ok || !hasKey(permission["permission"], name) {
+					continue
+				}
+				role, _ := permission["role"].(string)
+				key := tableObjectKey(source, table["table"], kind, role)
+				if o.recreated[key] {
+					continue
+				}
+				o.recreated[key] = true
+				o.dropTableObject(prefix, source, table["table"], kind, permission)
+				if local := metadatadiff.Find(o.local, []metadatadiff.ObjectID{sourceID, tableID, {Kind: kind, Name: role}}); local != nil {
+					o.createTableObject(prefix, source, table["table"], kind, local, false)
+				}
+			}
+		}
+	}
+}
+
+// tableObjectKey identifies a permission or a relationship of a table
+func tableObjectKey(source string, table interface{}, kind, name string) string {
+	return strings.Join([]string{source, metadatadiff.QualifiedName(table), kind, name}, "/")
+}
+
+// objectKey identifies the object at path
+func objectKey(path []metadatadiff.ObjectID) string {
+	var ids []string
+	for _, id := range path {
+		ids = append(ids, id.Kind+":"+id.Name)
+	}
+	return strings.Join(ids, "/")
+}
+
+// hasKey reports whether key is a key of an object of v
+func hasKey(v interface{}, key interface{}) bool {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, value := range v {
+			if k == key || hasKey(value, key) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, value := range v {
+			if hasKey(value, key) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (o *operations) addRemoteSchemaChange(change metadatadiff.Change, before, after map[string]interface{}, unsupported error) error {
+	path := change.Object
+	if len(path) == 1 {
+		switch change.Type {
+		case metadatadiff.Removed:
+			o.add(phaseDrop, "remove_remote_schema", map[string]interface{}{"name": before["name"]})
+		case metadatadiff.Added:
+			o.addRemoteSchema(after)
+		case metadatadiff.Changed:
+			for _, field := range change.Fields {
+				if !strings.HasPrefix(field, "definition") && field != "comment" {
+					return fmt.Errorf("cannot apply the change of %s of %s, use metadata apply without selecting objects", field, path[0])
+				}
+			}
+			// a remote schema cannot be updated in place, it is removed and
+			// added again along with its permissions and the remote
+			// relationships using it
+			if err := o.recreateRemoteRelationships(before["name"]); err != nil {
+				return err
+			}
+			o.add(phaseDrop, "remove_remote_schema", map[string]interface{}{"name": before["name"]})
+			o.addRemoteSchema(after)
+		}
+		return nil
+	}
+	if path[1].Kind != "remote_schema_permission" {
+		return unsupported
+	}
+	if o.parents[objectKey(path[:1])] {
+		// the permissions are added along with the remote schema
+		return nil
+	}
+	if change.Type != metadatadiff.Added {
+		o.add(phaseDropPermission, "drop_remote_schema_permissions", map[string]interface{}{"remote_schema": path[0].Name, "role": before["role"]})
+	}
+	if change.Type != metadatadiff.Removed {
+		o.addRemoteSchemaPermission(path[0].Name, after)
+	}
+	return nil
+}
+
+func (o *operations) addRemoteSchema(remoteSchema map[string]interface{}) {
+	args := map[string]interface{}{}
+	copyFields(args, remoteSchema, "name", "definition", "comment")
+	o.add(phaseSource, "add_remote_schema", args)
+	permissions, _ := remoteSchema["permissions"].([]interface{})
+	for _, p := range permissions {
+		if permission, ok := p.(map[string]interface{}); ok {
+			o.addRemoteSchemaPermission(remoteSchema["name"], permission)
+		}
+	}
+}
+
+// recreateRemoteRelationships drops the remote relationships on the server
+// which use a remote schema, so that it can be removed, and creates them again
+// from project metadata
+func (o *operations) recreateRemoteRelationships(remoteSchema interface{}) error {
+	server, _ := o.server.(map[string]interface{})
+	sources, _ := server["sources"].([]interface{})
+	for _, s := range sources {
+		source, ok := s.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		sourceName, _ := source["name"].(string)
+		sourceID := metadatadiff.ObjectID{Kind: "source", Name: sourceName}
+		tables, _ := source["tables"].([]interface{})
+		for _, t := range tables {
+			table, ok := t.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			tableID := metadatadiff.ObjectID{Kind: "table", Name: metadatadiff.QualifiedName(table["table"])}
+			relationships, _ := table["remote_relationships"].([]interface{})
+			for _, r := range relationships {
+				relationship, ok := r.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				definition, _ := relationship["definition"].(map[string]interface{})
+				if definition["remote_schema"] != remoteSchema {
+					continue
+				}
+				name, _ := relationship["name"].(string)
+				key := tableObjectKey(sourceName, table["table"], "remote_relationship", name)
+				if o.recreated[key] {
+					continue
+				}
+				o.recreated[key] = true
+				prefix, err := sourcePrefix(source)
+				if err != nil {
+					return err
+				}
+				o.dropTableObject(prefix, sourceName, table["table"], "remote_relationship", relationship)
+				if local := metadatadiff.Find(o.local, []metadatadiff.ObjectID{sourceID, tableID, {Kind: "remote_relationship", Name: name}}); local != nil {
+					o.createTableObject(prefix, sourceName, table["table"], "remote_relationship", local, false)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (o *operations) addRemoteSchemaPermission(remoteSchema interface{}, permission map[string]interface{}) {
+	args := map[string]interface{}{"remote_schema": remoteSchema}
+	copyFields(args, permission, "role", "definition", "comment")
+	o.add(phasePermission, "add_remote_schema_permissions", args)
+}
+
+// copyFields copies the fields present in from to args
+func copyFields(args, from map[string]interface{}, fields ...string) {
+	for _, field := range fields {
+		if value, ok := from[field]; ok {
+			args[field] = value
+		}
+	}
+}
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch m := m.(type) {
+	case map[string]string:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]interface{}:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
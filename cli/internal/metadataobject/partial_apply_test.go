@@ -0,0 +1,240 @@
+package metadataobject
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hasura/graphql-engine/cli/v2/internal/metadatadiff"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetadataOperations(t *testing.T) {
+	server := `{"version": 3, "sources": [
+		{"name": "default", "kind": "postgres", "configuration": {}, "tables": [
+			{"table": {"schema": "public", "name": "orders"},
+			 "object_relationships": [{"name": "customer", "using": {"foreign_key_constraint_on": "customer_id"}}],
+			 "select_permissions": [{"role": "user", "permission": {"columns": ["id"], "filter": {}}}]},
+			{"table": {"schema": "public", "name": "logs"}},
+			{"table": {"schema": "public", "name": "users"},
+			 "remote_relationships": [{"name": "country", "definition": {"remote_schema": "countries", "hasura_fields": ["country_id"], "remote_field": {"country": {"arguments": {"id": "$country_id"}}}}}]}
+		]}
+	], "remote_schemas": [{"name": "countries", "definition": {"url": "http://a"},
+		"permissions": [{"role": "user", "definition": {"schema": "type Query { country: Int }"}}]}]}`
+	local := `{"version": 3, "sources": [
+		{"name": "default", "kind": "postgres", "configuration": {}, "tables": [
+			{"table": {"schema": "public", "name": "orders"},
+			 "select_permissions": [{"role": "user", "permission": {"columns": ["id"], "filter": {"owner_id": {"_eq": "X-Hasura-User-Id"}}}}]},
+			{"table": {"schema": "public", "name": "items"},
+			 "array_relationships": [{"name": "orders", "using": {"foreign_key_constraint_on": {"table": "orders", "column": "item_id"}}}],
+			 "insert_permissions": [{"role": "user", "permission": {"check": {}, "columns": ["id"]}}]},
+			{"table": {"schema": "public", "name": "users"}, "is_enum": true,
+			 "remote_relationships": [{"name": "country", "definition": {"remote_schema": "countries", "hasura_fields": ["country_id"], "remote_field": {"country": {"arguments": {"id": "$country_id"}}}}}]}
+		]}
+	], "remote_schemas": [{"name": "countries", "definition": {"url": "http://b"},
+		"permissions": [{"role": "user", "definition": {"schema": "type Query { country(id: Int): Int }"}}]}], "api_limits": {"disabled": true}}`
+
+	tests := []struct {
+		name      string
+		selectors [][]metadatadiff.ObjectID
+		want      string
+		wantErr   string
+	}{
+		{
+			"table file",
+			[][]metadatadiff.ObjectID{{{Kind: "source", Name: "default"}, {Kind: "table", Name: "public.orders"}}},
+			`[
+				{"type": "pg_drop_select_permission", "args": {"source": "default", "table": {"schema": "public", "name": "orders"}, "role": "user"}},
+				{"type": "pg_drop_relationship", "args": {"source": "default", "table": {"schema": "public", "name": "orders"}, "relationship": "customer"}},
+				{"type": "pg_create_select_permission", "args": {"source": "default", "table": {"schema": "public", "name": "orders"}, "role": "user", "permission": {"columns": ["id"], "filter": {"owner_id": {"_eq": "X-Hasura-User-Id"}}}}}
+			]`,
+			"",
+		},
+		{
+			"source",
+			[][]metadatadiff.ObjectID{{{Kind: "source", Name: "default"}}},
+			`[
+				{"type": "pg_drop_select_permission", "args": {"source": "default", "table": {"schema": "public", "name": "orders"}, "role": "user"}},
+				{"type": "pg_drop_relationship", "args": {"source": "default", "table": {"schema": "public", "name": "orders"}, "relationship": "customer"}},
+				{"type": "pg_untrack_table", "args": {"source": "default", "table": {"schema": "public", "name": "logs"}, "cascade": true}},
+				{"type": "pg_set_table_is_enum", "args": {"source": "default", "table": {"schema": "public", "name": "users"}, "is_enum": true}},
+				{"type": "pg_track_table", "args": {"source": "default", "table": {"schema": "public", "name": "items"}}},
+				{"type": "pg_create_array_relationship", "args": {"source": "default", "table": {"schema": "public", "name": "items"}, "name": "orders", "using": {"foreign_key_constraint_on": {"table": "orders", "column": "item_id"}}}},
+				{"type": "pg_create_select_permission", "args": {"source": "default", "table": {"schema": "public", "name": "orders"}, "role": "user", "permission": {"columns": ["id"], "filter": {"owner_id": {"_eq": "X-Hasura-User-Id"}}}}},
+				{"type": "pg_create_insert_permission", "args": {"source": "default", "table": {"schema": "public", "name": "items"}, "role": "user", "permission": {"check": {}, "columns": ["id"]}}}
+			]`,
+			"",
+		},
+		{
+			"remote schema",
+			[][]metadatadiff.ObjectID{{{Kind: "remote_schema", Name: "countries"}}},
+			// the remote schema is added again with its permissions and the
+			// remote relationships using it
+			`[
+				{"type": "pg_delete_remote_relationship", "args": {"source": "default", "table": {"schema": "public", "name": "users"}, "name": "country"}},
+				{"type": "remove_remote_schema", "args": {"name": "countries"}},
+				{"type": "add_remote_schema", "args": {"name": "countries", "definition": {"url": "http://b"}}},
+				{"type": "pg_create_remote_relationship", "args": {"source": "default", "table": {"schema": "public", "name": "users"}, "name": "country", "remote_schema": "countries", "hasura_fields": ["country_id"], "remote_field": {"country": {"arguments": {"id": "$country_id"}}}}},
+				{"type": "add_remote_schema_permissions", "args": {"remote_schema": "countries", "role": "user", "definition": {"schema": "type Query { country(id: Int): Int }"}}}
+			]`,
+			"",
+		},
+		{
+			"nothing selected",
+			[][]metadatadiff.ObjectID{{{Kind: "remote_schema", Name: "weather"}}},
+			`[]`,
+			"",
+		},
+		{
+			"unsupported object",
+			[][]metadatadiff.ObjectID{{{Kind: "api_limits"}}},
+			"",
+			"cannot apply api limits alone, use metadata apply without selecting objects",
+		},
+	}
+	var before, after interface{}
+	require.NoError(t, json.Unmarshal([]byte(server), &before))
+	require.NoError(t, json.Unmarshal([]byte(local), &after))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var changes []metadatadiff.Change
+			for _, change := range metadatadiff.Diff(before, after) {
+				if isSelected(change.Object, tt.selectors) {
+					changes = append(changes, change)
+				}
+			}
+			got, err := metadataOperations(changes, before, after)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			gotJSON, err := json.Marshal(got)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(gotJSON))
+		})
+	}
+}
+
+func TestMetadataOperations_ChangedTableObjects(t *testing.T) {
+	server := `{"version": 3, "sources": [
+		{"name": "default", "kind": "postgres", "configuration": {}, "tables": [
+			{"table": {"schema": "public", "name": "orders"},
+			 "object_relationships": [{"name": "customer", "using": {"foreign_key_constraint_on": "customer_id"}}],
+			 "event_triggers": [{"name": "order_created", "definition": {"insert": {"columns": "*"}}, "webhook": "http://a", "retry_conf": {"num_retries": 0}}],
+			 "select_permissions": [{"role": "user", "permission": {"columns": ["id"], "filter": {"customer": {"id": {"_eq": "X-Hasura-User-Id"}}}}}]},
+			{"table": {"schema": "public", "name": "items"},
+			 "select_permissions": [{"role": "user", "permission": {"columns": ["id"], "filter": {"order": {"customer": {"id": {"_eq": "X-Hasura-User-Id"}}}}}}]},
+			{"table": {"schema": "public", "name": "customers"},
+			 "select_permissions": [{"role": "user", "permission": {"columns": ["id"], "filter": {}}}]}
+		]}
+	]}`
+	local := `{"version": 3, "sources": [
+		{"name": "default", "kind": "postgres", "configuration": {}, "tables": [
+			{"table": {"schema": "public", "name": "orders"},
+			 "object_relationships": [{"name": "customer", "using": {"manual_configuration": {"remote_table": "customers", "column_mapping": {"customer_id": "id"}}}}],
+			 "event_triggers": [{"name": "order_created", "definition": {"insert": {"columns": "*"}}, "webhook": "http://b", "retry_conf": {"num_retries": 0}}],
+			 "select_permissions": [{"role": "user", "permission": {"columns": ["id"], "filter": {"customer": {"id": {"_eq": "X-Hasura-User-Id"}}}}}]},
+			{"table": {"schema": "public", "name": "items"},
+			 "select_permissions": [{"role": "user", "permission": {"columns": ["id"], "filter": {"order": {"customer": {"id": {"_eq": "X-Hasura-User-Id"}}}}}}]},
+			{"table": {"schema": "public", "name": "customers"},
+			 "select_permissions": [{"role": "user", "permission": {"columns": ["id"], "filter": {}}}]}
+		]}
+	]}`
+	var before, after interface{}
+	require.NoError(t, json.Unmarshal([]byte(server), &before))
+	require.NoError(t, json.Unmarshal([]byte(local), &after))
+	got, err := metadataOperations(metadatadiff.Diff(before, after), before, after)
+	require.NoError(t, err)
+	gotJSON, err := json.Marshal(got)
+	require.NoError(t, err)
+	// the unchanged permissions using the changed relationship are dropped
+	// before it and created again after it, the event trigger is replaced
+	assert.JSONEq(t, `[
+		{"type": "pg_drop_select_permission", "args": {"source": "default", "table": {"schema": "public", "name": "orders"}, "role": "user"}},
+		{"type": "pg_drop_select_permission", "args": {"source": "default", "table": {"schema": "public", "name": "items"}, "role": "user"}},
+		{"type": "pg_drop_relationship", "args": {"source": "default", "table": {"schema": "public", "name": "orders"}, "relationship": "customer"}},
+		{"type": "pg_create_event_trigger", "args": {"source": "default", "table": {"schema": "public", "name": "orders"}, "name": "order_created", "insert": {"columns": "*"}, "webhook": "http://b", "retry_conf": {"num_retries": 0}, "replace": true}},
+		{"type": "pg_create_object_relationship", "args": {"source": "default", "table": {"schema": "public", "name": "orders"}, "name": "customer", "using": {"manual_configuration": {"remote_table": "customers", "column_mapping": {"customer_id": "id"}}}}},
+		{"type": "pg_create_select_permission", "args": {"source": "default", "table": {"schema": "public", "name": "orders"}, "role": "user", "permission": {"columns": ["id"], "filter": {"customer": {"id": {"_eq": "X-Hasura-User-Id"}}}}}},
+		{"type": "pg_create_select_permission", "args": {"source": "default", "table": {"schema": "public", "name": "items"}, "role": "user", "permission": {"columns": ["id"], "filter": {"order": {"customer": {"id": {"_eq": "X-Hasura-User-Id"}}}}}}}
+	]`, string(gotJSON))
+}
+
+func TestMetadataOperations_ChangedFunction(t *testing.T) {
+	server := `{"version": 3, "sources": [
+		{"name": "default", "kind": "postgres", "configuration": {}, "functions": [
+			{"function": {"schema": "public", "name": "search"}, "permissions": [{"role": "user"}]}
+		]}
+	]}`
+	local := `{"version": 3, "sources": [
+		{"name": "default", "kind": "postgres", "configuration": {}, "functions": [
+			{"function": {"schema": "public", "name": "search"}, "configuration": {"exposed_as": "query"},
+			 "permissions": [{"role": "user"}, {"role": "admin_viewer"}]}
+		]}
+	]}`
+	var before, after interface{}
+	require.NoError(t, json.Unmarshal([]byte(server), &before))
+	require.NoError(t, json.Unmarshal([]byte(local), &after))
+	got, err := metadataOperations(metadatadiff.Diff(before, after), before, after)
+	require.NoError(t, err)
+	gotJSON, err := json.Marshal(got)
+	require.NoError(t, err)
+	// the permissions are created once, along with the function
+	assert.JSONEq(t, `[
+		{"type": "pg_untrack_function", "args": {"source": "default", "function": {"schema": "public", "name": "search"}}},
+		{"type": "pg_track_function", "args": {"source": "default", "function": {"schema": "public", "name": "search"}, "configuration": {"exposed_as": "query"}}},
+		{"type": "pg_create_function_permission", "args": {"source": "default", "function": {"schema": "public", "name": "search"}, "role": "user"}},
+		{"type": "pg_create_function_permission", "args": {"source": "default", "function": {"schema": "public", "name": "search"}, "role": "admin_viewer"}}
+	]`, string(gotJSON))
+}
+
+func TestFileSelector(t *testing.T) {
+	tests := []struct {
+		file    string
+		want    []metadatadiff.ObjectID
+		wantErr string
+	}{
+		{
+			"databases/default/tables/public_authors.yaml",
+			[]metadatadiff.ObjectID{{Kind: "source", Name: "default"}, {Kind: "table", Name: "public.authors"}},
+			"",
+		},
+		{
+			"databases/default/tables/tables.yaml",
+			[]metadatadiff.ObjectID{{Kind: "source", Name: "default"}, {Kind: "table"}},
+			"",
+		},
+		{
+			"databases/default/tables/public_authors/permissions/user.yaml",
+			[]metadatadiff.ObjectID{{Kind: "source", Name: "default"}, {Kind: "table", Name: "public.authors"}},
+			"",
+		},
+		{
+			"remote_schemas.yaml",
+			[]metadatadiff.ObjectID{{Kind: "remote_schema"}},
+			"",
+		},
+		{
+			"remote_schemas/countries/permissions/user.graphql",
+			[]metadatadiff.ObjectID{{Kind: "remote_schema", Name: "countries"}},
+			"",
+		},
+		{
+			"actions.yaml",
+			nil,
+			"cannot apply actions.yaml alone: only databases/databases.yaml, the files of tables and functions of databases and the files of remote schemas can be applied partially",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			got, err := fileSelector(tt.file, "testdata/validate/metadata")
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -1,31 +1,71 @@
 package remoteschemas
 
 import (
+	"errors"
+	"fmt"
 	"io/ioutil"
+	"net/http"
 	"path/filepath"
 
 	errors2 "github.com/hasura/graphql-engine/cli/v2/internal/metadataobject/errors"
+	"github.com/hasura/graphql-engine/cli/v2/internal/metadataobject/include"
 
 	"github.com/hasura/graphql-engine/cli/v2"
+	"github.com/hasura/graphql-engine/cli/v2/internal/hasura"
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
 )
 
 const (
 	fileName string = "remote_schemas.yaml"
+
+	// files of the directory layout, see exportDirectories
+	remoteSchemasDirectory string = "remote_schemas"
+	definitionFileName     string = "definition.yaml"
+	schemaFileName         string = "schema.graphql"
+	permissionsDirectory   string = "permissions"
 )
 
 type RemoteSchemaConfig struct {
 	MetadataDir string
 
-	logger *logrus.Logger
+	// splitRemoteSchemas exports each remote schema to its own directory
+	splitRemoteSchemas bool
+	// introspect returns the introspection result of a remote schema, the
+	// schema is not exported without it
+	introspect func(name string) ([]byte, error)
+	logger     *logrus.Logger
 }
 
 func New(ec *cli.ExecutionContext, baseDir string) *RemoteSchemaConfig {
-	return &RemoteSchemaConfig{
-		MetadataDir: baseDir,
-		logger:      ec.Logger,
+	r := &RemoteSchemaConfig{
+		MetadataDir:        baseDir,
+		splitRemoteSchemas: ec.Config != nil && ec.Config.MetadataLayout != nil && ec.Config.MetadataLayout.SplitRemoteSchemas,
+		logger:             ec.Logger,
+	}
+	if ec.HasMetadataV3 && ec.APIClient != nil && ec.APIClient.V1Metadata != nil {
+		r.introspect = func(name string) ([]byte, error) {
+			return introspectRemoteSchema(ec.APIClient.V1Metadata, name)
+		}
+	}
+	return r
+}
+
+// introspectRemoteSchema returns the introspection result of a remote schema
+// by the server
+func introspectRemoteSchema(client hasura.V1Metadata, name string) ([]byte, error) {
+	resp, body, err := client.Send(hasura.RequestBody{Type: "introspect_remote_schema", Args: map[string]string{"name": name}})
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(string(b))
 	}
+	return b, nil
 }
 
 func (r *RemoteSchemaConfig) Validate() error {
@@ -46,7 +86,13 @@ func (r *RemoteSchemaConfig) CreateFiles() error {
 }
 
 func (r *RemoteSchemaConfig) Build(metadata *yaml.MapSlice) errors2.ErrParsingMetadataObject {
-	data, err := ioutil.ReadFile(filepath.Join(r.MetadataDir, fileName))
+	// remote schemas exported in the directory layout are included by
+	// remote_schemas.yaml
+	remoteSchemas, err := include.ReadFile(filepath.Join(r.MetadataDir, fileName))
+	if err != nil {
+		return r.Error(err)
+	}
+	data, err := yaml.Marshal(remoteSchemas)
 	if err != nil {
 		return r.Error(err)
 	}
@@ -77,6 +123,13 @@ func (r *RemoteSchemaConfig) Export(metadata yaml.MapSlice) (map[string][]byte,
 	if remoteSchemas == nil {
 		remoteSchemas = make([]interface{}, 0)
 	}
+	if r.splitRemoteSchemas {
+		files, err := r.exportDirectories(remoteSchemas)
+		if err != nil {
+			return nil, r.Error(err)
+		}
+		return files, nil
+	}
 	data, err := yaml.Marshal(remoteSchemas)
 	if err != nil {
 		return nil, r.Error(err)
@@ -86,6 +139,105 @@ func (r *RemoteSchemaConfig) Export(metadata yaml.MapSlice) (map[string][]byte,
 	}, nil
 }
 
+// exportDirectories exports each remote schema to a directory included by
+// remote_schemas.yaml:
+//
+//	remote_schemas/<name>/definition.yaml
+//	remote_schemas/<name>/schema.graphql
+//	remote_schemas/<name>/permissions/<role>.graphql
+//
+// The schema of the permission of each role is included by definition.yaml.
+// schema.graphql is the schema of the remote schema introspected by the
+// server, for reviews, it is not read when metadata is built since the server
+// introspects the remote schema itself.
+func (r *RemoteSchemaConfig) exportDirectories(remoteSchemas interface{}) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	tags := make([]string, 0)
+	list, _ := remoteSchemas.([]interface{})
+	for _, item := range list {
+		remoteSchema, ok := item.(yaml.MapSlice)
+		if !ok {
+			return nil, fmt.Errorf("unexpected remote schema: %v", item)
+		}
+		dir := filepath.Join(remoteSchemasDirectory, fmt.Sprint(value(remoteSchema, "name")))
+		definition := make(yaml.MapSlice, 0, len(remoteSchema))
+		for _, field := range remoteSchema {
+			if field.Key != "permissions" {
+				definition = append(definition, field)
+				continue
+			}
+			permissions, _ := field.Value.([]interface{})
+			included := make([]interface{}, 0, len(permissions))
+			for _, p := range permissions {
+				permission, _ := p.(yaml.MapSlice)
+				schemaFile := filepath.Join(permissionsDirectory, fmt.Sprintf("%v.graphql", value(permission, "role")))
+				permission = withValue(permission, "definition", func(v interface{}) interface{} {
+					permissionDefinition, _ := v.(yaml.MapSlice)
+					return withValue(permissionDefinition, "schema", func(schema interface{}) interface{} {
+						files[filepath.ToSlash(filepath.Join(r.MetadataDir, dir, schemaFile))] = []byte(fmt.Sprint(schema))
+						return include.Tag(schemaFile)
+					})
+				})
+				included = append(included, permission)
+			}
+			definition = append(definition, yaml.MapItem{Key: field.Key, Value: included})
+		}
+		data, err := yaml.Marshal(definition)
+		if err != nil {
+			return nil, err
+		}
+		files[filepath.ToSlash(filepath.Join(r.MetadataDir, dir, definitionFileName))] = data
+		if r.introspect != nil {
+			name := fmt.Sprint(value(remoteSchema, "name"))
+			sdl, err := r.schemaSDL(name)
+			if err != nil {
+				r.logger.Warnf("schema of remote schema %s is not exported: %v", name, err)
+			} else {
+				files[filepath.ToSlash(filepath.Join(r.MetadataDir, dir, schemaFileName))] = []byte(sdl)
+			}
+		}
+		tags = append(tags, include.Tag(filepath.Join(dir, definitionFileName)))
+	}
+	data, err := yaml.Marshal(tags)
+	if err != nil {
+		return nil, err
+	}
+	files[filepath.ToSlash(filepath.Join(r.MetadataDir, fileName))] = data
+	return files, nil
+}
+
+// schemaSDL returns the schema of a remote schema in the GraphQL schema
+// definition language
+func (r *RemoteSchemaConfig) schemaSDL(name string) (string, error) {
+	result, err := r.introspect(name)
+	if err != nil {
+		return "", err
+	}
+	return printSDL(result)
+}
+
+func value(mapping yaml.MapSlice, key string) interface{} {
+	for _, item := range mapping {
+		if item.Key == key {
+			return item.Value
+		}
+	}
+	return nil
+}
+
+// withValue returns a copy of mapping with the value of key replaced by f of
+// it, mapping is returned as it is if it has no key
+func withValue(mapping yaml.MapSlice, key string, f func(interface{}) interface{}) yaml.MapSlice {
+	copied := make(yaml.MapSlice, len(mapping))
+	copy(copied, mapping)
+	for i := range copied {
+		if copied[i].Key == key {
+			copied[i].Value = f(copied[i].Value)
+		}
+	}
+	return copied
+}
+
 func (r *RemoteSchemaConfig) Name() string {
 	return "remote_schemas"
 }
@@ -0,0 +1,183 @@
+package remoteschemas
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestRemoteSchemaConfig_ExportDirectories(t *testing.T) {
+	metadataDir, err := ioutil.TempDir("", "metadata-*")
+	require.NoError(t, err)
+	defer os.RemoveAll(metadataDir)
+
+	remoteSchemasYAML := `- name: countries
+  definition:
+    url: https://countries.example.com
+    timeout_seconds: 60
+  comment: ""
+  permissions:
+  - role: user
+    definition:
+      schema: |
+        type Query {
+          countries: [String]
+        }
+- name: weather
+  definition:
+    url: https://weather.example.com
+`
+	var metadata yaml.MapSlice
+	require.NoError(t, yaml.Unmarshal([]byte("remote_schemas:\n"+remoteSchemasYAML), &metadata))
+
+	r := &RemoteSchemaConfig{
+		MetadataDir:        metadataDir,
+		splitRemoteSchemas: true,
+		introspect: func(name string) ([]byte, error) {
+			if name != "countries" {
+				return nil, errors.New("remote schema is unreachable")
+			}
+			return []byte(`{"data": {"__schema": {"queryType": {"name": "Query"}, "types": [
+				{"kind": "OBJECT", "name": "Query", "fields": [{"name": "countries", "args": [], "type": {"kind": "LIST", "ofType": {"kind": "SCALAR", "name": "String"}}}]},
+				{"kind": "SCALAR", "name": "String"}
+			]}}}`), nil
+		},
+		logger: logrus.New(),
+	}
+	files, err := r.Export(metadata)
+	require.NoError(t, err)
+	got := map[string]string{}
+	for name, content := range files {
+		rel, err := filepath.Rel(metadataDir, name)
+		require.NoError(t, err)
+		got[filepath.ToSlash(rel)] = string(content)
+	}
+	assert.Equal(t, map[string]string{
+		"remote_schemas.yaml": `- '!include remote_schemas/countries/definition.yaml'
+- '!include remote_schemas/weather/definition.yaml'
+`,
+		"remote_schemas/countries/definition.yaml": `name: countries
+definition:
+  url: https://countries.example.com
+  timeout_seconds: 60
+comment: ""
+permissions:
+- role: user
+  definition:
+    schema: '!include permissions/user.graphql'
+`,
+		"remote_schemas/countries/permissions/user.graphql": `type Query {
+  countries: [String]
+}
+`,
+		"remote_schemas/countries/schema.graphql": `type Query {
+  countries: [String]
+}
+`,
+		"remote_schemas/weather/definition.yaml": `name: weather
+definition:
+  url: https://weather.example.com
+`,
+	}, got)
+
+	// remote schemas are built back from the directories
+	for name, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(name), os.ModePerm))
+		require.NoError(t, ioutil.WriteFile(name, content, 0644))
+	}
+	var built yaml.MapSlice
+	require.NoError(t, r.Build(&built))
+	b, err := yaml.Marshal(built)
+	require.NoError(t, err)
+	assert.Equal(t, "remote_schemas:\n"+remoteSchemasYAML, string(b))
+}
+
+func TestPrintSDL(t *testing.T) {
+	result := `{"data": {"__schema": {
+		"queryType": {"name": "query_root"},
+		"mutationType": {"name": "mutation_root"},
+		"subscriptionType": null,
+		"types": [
+			{"kind": "OBJECT", "name": "query_root", "fields": [
+				{"name": "users", "description": "fetch users", "args": [
+					{"name": "limit", "type": {"kind": "SCALAR", "name": "Int"}, "defaultValue": "10"},
+					{"name": "where", "type": {"kind": "INPUT_OBJECT", "name": "UserFilter"}, "defaultValue": null}
+				], "type": {"kind": "NON_NULL", "ofType": {"kind": "LIST", "ofType": {"kind": "NON_NULL", "ofType": {"kind": "OBJECT", "name": "User"}}}}},
+				{"name": "search", "args": [], "type": {"kind": "UNION", "name": "SearchResult"}, "isDeprecated": true, "deprecationReason": "use users"}
+			], "interfaces": []},
+			{"kind": "OBJECT", "name": "mutation_root", "fields": [
+				{"name": "ping", "args": [], "type": {"kind": "SCALAR", "name": "Boolean"}}
+			], "interfaces": []},
+			{"kind": "INTERFACE", "name": "Node", "fields": [
+				{"name": "id", "args": [], "type": {"kind": "NON_NULL", "ofType": {"kind": "SCALAR", "name": "ID"}}}
+			]},
+			{"kind": "OBJECT", "name": "User", "description": "a user\nof the app", "fields": [
+				{"name": "id", "args": [], "type": {"kind": "NON_NULL", "ofType": {"kind": "SCALAR", "name": "ID"}}},
+				{"name": "role", "args": [], "type": {"kind": "ENUM", "name": "Role"}},
+				{"name": "created_at", "args": [], "type": {"kind": "SCALAR", "name": "timestamptz"}}
+			], "interfaces": [{"kind": "INTERFACE", "name": "Node"}]},
+			{"kind": "UNION", "name": "SearchResult", "possibleTypes": [{"kind": "OBJECT", "name": "User"}]},
+			{"kind": "ENUM", "name": "Role", "enumValues": [
+				{"name": "ADMIN", "isDeprecated": false},
+				{"name": "GUEST", "isDeprecated": true, "deprecationReason": "No longer supported"}
+			]},
+			{"kind": "INPUT_OBJECT", "name": "UserFilter", "inputFields": [
+				{"name": "role", "type": {"kind": "ENUM", "name": "Role"}, "defaultValue": "ADMIN"}
+			]},
+			{"kind": "SCALAR", "name": "timestamptz"},
+			{"kind": "SCALAR", "name": "Int"},
+			{"kind": "OBJECT", "name": "__Type", "fields": []}
+		]
+	}}}`
+	got, err := printSDL([]byte(result))
+	require.NoError(t, err)
+	assert.Equal(t, `schema {
+  query: query_root
+  mutation: mutation_root
+}
+
+interface Node {
+  id: ID!
+}
+
+enum Role {
+  ADMIN
+  GUEST @deprecated
+}
+
+union SearchResult = User
+
+"""
+a user
+of the app
+"""
+type User implements Node {
+  id: ID!
+  role: Role
+  created_at: timestamptz
+}
+
+input UserFilter {
+  role: Role = ADMIN
+}
+
+type mutation_root {
+  ping: Boolean
+}
+
+type query_root {
+  """fetch users"""
+  users(limit: Int = 10, where: UserFilter): [User!]!
+  search: SearchResult @deprecated(reason: "use users")
+}
+
+scalar timestamptz
+`, got)
+}
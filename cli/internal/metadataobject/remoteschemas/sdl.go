@@ -0,0 +1,222 @@
+package remoteschemas
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// introspection is the result of the introspection query of a remote schema
+type introspection struct {
+	Data struct {
+		Schema struct {
+			QueryType        *namedType `json:"queryType"`
+			MutationType     *namedType `json:"mutationType"`
+			SubscriptionType *namedType `json:"subscriptionType"`
+			Types            []fullType `json:"types"`
+		} `json:"__schema"`
+	} `json:"data"`
+}
+
+type namedType struct {
+	Name string `json:"name"`
+}
+
+type fullType struct {
+	Kind          string       `json:"kind"`
+	Name          string       `json:"name"`
+	Description   string       `json:"description"`
+	Fields        []field      `json:"fields"`
+	InputFields   []inputValue `json:"inputFields"`
+	Interfaces    []typeRef    `json:"interfaces"`
+	EnumValues    []enumValue  `json:"enumValues"`
+	PossibleTypes []typeRef    `json:"possibleTypes"`
+}
+
+type field struct {
+	Name              string       `json:"name"`
+	Description       string       `json:"description"`
+	Args              []inputValue `json:"args"`
+	Type              typeRef      `json:"type"`
+	IsDeprecated      bool         `json:"isDeprecated"`
+	DeprecationReason string       `json:"deprecationReason"`
+}
+
+type inputValue struct {
+	Name         string  `json:"name"`
+	Description  string  `json:"description"`
+	Type         typeRef `json:"type"`
+	DefaultValue *string `json:"defaultValue"`
+}
+
+type enumValue struct {
+	Name              string `json:"name"`
+	Description       string `json:"description"`
+	IsDeprecated      bool   `json:"isDeprecated"`
+	DeprecationReason string `json:"deprecationReason"`
+}
+
+type typeRef struct {
+	Kind   string   `json:"kind"`
+	Name   string   `json:"name"`
+	OfType *typeRef `json:"ofType"`
+}
+
+func (t typeRef) String() string {
+	switch t.Kind {
+	case "NON_NULL":
+		if t.OfType != nil {
+			return t.OfType.String() + "!"
+		}
+	case "LIST":
+		if t.OfType != nil {
+			return "[" + t.OfType.String() + "]"
+		}
+	}
+	return t.Name
+}
+
+var builtinScalars = map[string]bool{"String": true, "Int": true, "Float": true, "Boolean": true, "ID": true}
+
+// printSDL prints the schema of the result of an introspection query in the
+// GraphQL schema definition language. Types are sorted by name, built-in
+// scalars and directives are left out.
+func printSDL(result []byte) (string, error) {
+	var i introspection
+	if err := json.Unmarshal(result, &i); err != nil {
+		return "", fmt.Errorf("cannot decode introspection result: %w", err)
+	}
+	schema := i.Data.Schema
+	if schema.QueryType == nil {
+		return "", fmt.Errorf("introspection result has no query type")
+	}
+	var b strings.Builder
+	// the schema definition is left out if the root types are named after
+	// their operations
+	roots := []struct {
+		operation, defaultName string
+		t                      *namedType
+	}{
+		{"query", "Query", schema.QueryType},
+		{"mutation", "Mutation", schema.MutationType},
+		{"subscription", "Subscription", schema.SubscriptionType},
+	}
+	custom := false
+	for _, root := range roots {
+		custom = custom || (root.t != nil && root.t.Name != root.defaultName)
+	}
+	if custom {
+		b.WriteString("schema {\n")
+		for _, root := range roots {
+			if root.t != nil {
+				fmt.Fprintf(&b, "  %s: %s\n", root.operation, root.t.Name)
+			}
+		}
+		b.WriteString("}\n")
+	}
+
+	types := append([]fullType{}, schema.Types...)
+	sort.Slice(types, func(i, j int) bool { return types[i].Name < types[j].Name })
+	for _, t := range types {
+		if strings.HasPrefix(t.Name, "__") || (t.Kind == "SCALAR" && builtinScalars[t.Name]) {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		printDescription(&b, t.Description, "")
+		switch t.Kind {
+		case "SCALAR":
+			fmt.Fprintf(&b, "scalar %s\n", t.Name)
+		case "OBJECT", "INTERFACE":
+			keyword := "type"
+			if t.Kind == "INTERFACE" {
+				keyword = "interface"
+			}
+			fmt.Fprintf(&b, "%s %s", keyword, t.Name)
+			if len(t.Interfaces) > 0 {
+				var names []string
+				for _, i := range t.Interfaces {
+					names = append(names, i.Name)
+				}
+				fmt.Fprintf(&b, " implements %s", strings.Join(names, " & "))
+			}
+			b.WriteString(" {\n")
+			for _, f := range t.Fields {
+				printDescription(&b, f.Description, "  ")
+				fmt.Fprintf(&b, "  %s%s: %s%s\n", f.Name, printArgs(f.Args), f.Type, deprecated(f.IsDeprecated, f.DeprecationReason))
+			}
+			b.WriteString("}\n")
+		case "UNION":
+			var names []string
+			for _, p := range t.PossibleTypes {
+				names = append(names, p.Name)
+			}
+			fmt.Fprintf(&b, "union %s = %s\n", t.Name, strings.Join(names, " | "))
+		case "ENUM":
+			fmt.Fprintf(&b, "enum %s {\n", t.Name)
+			for _, v := range t.EnumValues {
+				printDescription(&b, v.Description, "  ")
+				fmt.Fprintf(&b, "  %s%s\n", v.Name, deprecated(v.IsDeprecated, v.DeprecationReason))
+			}
+			b.WriteString("}\n")
+		case "INPUT_OBJECT":
+			fmt.Fprintf(&b, "input %s {\n", t.Name)
+			for _, f := range t.InputFields {
+				printDescription(&b, f.Description, "  ")
+				fmt.Fprintf(&b, "  %s\n", printInputValue(f))
+			}
+			b.WriteString("}\n")
+		default:
+			return "", fmt.Errorf("unknown kind %s of type %s", t.Kind, t.Name)
+		}
+	}
+	return b.String(), nil
+}
+
+func printArgs(args []inputValue) string {
+	if len(args) == 0 {
+		return ""
+	}
+	var printed []string
+	for _, arg := range args {
+		printed = append(printed, printInputValue(arg))
+	}
+	return "(" + strings.Join(printed, ", ") + ")"
+}
+
+func printInputValue(v inputValue) string {
+	s := fmt.Sprintf("%s: %s", v.Name, v.Type)
+	if v.DefaultValue != nil {
+		s += " = " + *v.DefaultValue
+	}
+	return s
+}
+
+func printDescription(b *strings.Builder, description, indent string) {
+	if description == "" {
+		return
+	}
+	description = strings.Replace(description, `"""`, `\"""`, -1)
+	if !strings.Contains(description, "\n") {
+		fmt.Fprintf(b, "%s\"\"\"%s\"\"\"\n", indent, description)
+		return
+	}
+	fmt.Fprintf(b, "%s\"\"\"\n", indent)
+	for _, line := range strings.Split(description, "\n") {
+		fmt.Fprintf(b, "%s%s\n", indent, line)
+	}
+	fmt.Fprintf(b, "%s\"\"\"\n", indent)
+}
+
+func deprecated(isDeprecated bool, reason string) string {
+	if !isDeprecated {
+		return ""
+	}
+	if reason == "" || reason == "No longer supported" {
+		return " @deprecated"
+	}
+	b, _ := json.Marshal(reason)
+	return fmt.Sprintf(" @deprecated(reason: %s)", b)
+}
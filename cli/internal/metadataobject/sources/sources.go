@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 
 	errors2 "github.com/hasura/graphql-engine/cli/v2/internal/metadataobject/errors"
 
@@ -38,12 +39,16 @@ type Source struct {
 type SourceConfig struct {
 	MetadataDir string
 
-	logger *logrus.Logger
+	// splitTables exports the permissions and relationships of tables to
+	// their own files, see splitTable
+	splitTables bool
+	logger      *logrus.Logger
 }
 
 func New(ec *cli.ExecutionContext, baseDir string) *SourceConfig {
 	return &SourceConfig{
 		MetadataDir: baseDir,
+		splitTables: ec.Config != nil && ec.Config.MetadataLayout != nil && ec.Config.MetadataLayout.SplitTables,
 		logger:      ec.Logger,
 	}
 }
@@ -107,6 +112,13 @@ func (t *SourceConfig) Build(metadata *goyaml.MapSlice) errors2.ErrParsingMetada
 			if err != nil {
 				return t.Error(err)
 			}
+			// tables exported in the split layout include their permissions
+			// and relationships
+			for _, table := range entries(tablesKey) {
+				if err := joinTable(table); err != nil {
+					return t.Error(err)
+				}
+			}
 			source.Tables = tablesKey
 		} else {
 			t.logger.Debugf("building metadata: table node not found for %s", source.Name)
@@ -216,12 +228,26 @@ func (t *SourceConfig) Export(metadata goyaml.MapSlice) (map[string][]byte, erro
 			tableIncludeTag := fmt.Sprintf("%s %s", "!include", tableFileName)
 			tableTags = append(tableTags, tableIncludeTag)
 
+			tablesDir := filepath.Join(t.MetadataDir, sourcesDirectory, source.Name, tablesDirectory)
+			rawTable := rawTables[idx]
+			if t.splitTables {
+				// build <source>/tables/<table_primary_key>/{permissions,relationships}/<name>.yaml
+				var tableFiles map[string]yaml.MapSlice
+				rawTable, tableFiles = splitTable(rawTable, strings.TrimSuffix(tableFileName, ".yaml"))
+				for name, content := range tableFiles {
+					b, err := yaml.Marshal(content)
+					if err != nil {
+						return nil, t.Error(err)
+					}
+					files[filepath.ToSlash(filepath.Join(tablesDir, name))] = b
+				}
+			}
 			// build <source>/tables/<table_primary_key>.yaml
-			b, err := yaml.Marshal(rawTables[idx])
+			b, err := yaml.Marshal(rawTable)
 			if err != nil {
 				return nil, t.Error(err)
 			}
-			tableFilePath := filepath.ToSlash(filepath.Join(t.MetadataDir, sourcesDirectory, source.Name, tablesDirectory, tableFileName))
+			tableFilePath := filepath.ToSlash(filepath.Join(tablesDir, tableFileName))
 			files[tableFilePath] = b
 		}
 		tableTagsFilePath := filepath.ToSlash(filepath.Join(t.MetadataDir, sourcesDirectory, source.Name, tablesDirectory, "tables.yaml"))
@@ -0,0 +1,188 @@
+package sources
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+const (
+	permissionsDirectory   string = "permissions"
+	relationshipsDirectory string = "relationships"
+
+	// keys of a table file listing the files of its permissions and
+	// relationships in the split layout
+	permissionsKey   string = "permissions"
+	relationshipsKey string = "relationships"
+)
+
+// permissionKinds are the kinds of permissions of a table in the order they
+// are written to the file of a role
+var permissionKinds = []string{"insert", "select", "update", "delete"}
+
+// relationshipKinds are the kinds of relationships of a table
+var relationshipKinds = []string{"object", "array", "remote"}
+
+// splitTable moves the permissions of table to a file per role and its
+// relationships to a file per relationship, in a directory named dir next to
+// the file of the table. It returns the table, which includes these files,
+// and the files keyed by their path relative to the file of the table.
+//
+// The file of a role has the permissions of the role keyed by kind:
+//
+//	role: user
+//	select:
+//	  permission: ...
+//
+// The file of a relationship has the relationship keyed by kind:
+//
+//	name: author
+//	object:
+//	  using: ...
+func splitTable(table yaml.MapSlice, dir string) (yaml.MapSlice, map[string]yaml.MapSlice) {
+	files := map[string]yaml.MapSlice{}
+	var roles, relationships []string
+	var split yaml.MapSlice
+	var hasPermissions, hasRelationships bool
+	for _, item := range table {
+		key, _ := item.Key.(string)
+		if kind, ok := listKind(key, "_permissions", permissionKinds); ok {
+			for _, entry := range entries(item.Value) {
+				role := fmt.Sprint(entry["role"])
+				name := filepath.ToSlash(filepath.Join(dir, permissionsDirectory, role+".yaml"))
+				if _, ok := files[name]; !ok {
+					files[name] = yaml.MapSlice{{Key: "role", Value: role}}
+					roles = append(roles, name)
+				}
+				files[name] = append(files[name], yaml.MapItem{Key: kind, Value: without(entry, "role")})
+			}
+			if !hasPermissions {
+				split = append(split, yaml.MapItem{Key: permissionsKey})
+				hasPermissions = true
+			}
+			continue
+		}
+		if kind, ok := listKind(key, "_relationships", relationshipKinds); ok {
+			for _, entry := range entries(item.Value) {
+				relationship := fmt.Sprint(entry["name"])
+				name := filepath.ToSlash(filepath.Join(dir, relationshipsDirectory, relationship+".yaml"))
+				files[name] = yaml.MapSlice{
+					{Key: "name", Value: relationship},
+					{Key: kind, Value: without(entry, "name")},
+				}
+				relationships = append(relationships, name)
+			}
+			if !hasRelationships {
+				split = append(split, yaml.MapItem{Key: relationshipsKey})
+				hasRelationships = true
+			}
+			continue
+		}
+		split = append(split, item)
+	}
+	for idx := range split {
+		switch split[idx].Key {
+		case permissionsKey:
+			split[idx].Value = includeTags(roles)
+		case relationshipsKey:
+			split[idx].Value = includeTags(relationships)
+		}
+	}
+	return split, files
+}
+
+// joinTable puts the permissions and relationships included by a table
+// exported by splitTable back in the lists of their kind
+func joinTable(table map[string]interface{}) error {
+	if permissions, ok := table[permissionsKey]; ok {
+		delete(table, permissionsKey)
+		for _, file := range entries(permissions) {
+			role := file["role"]
+			for _, kind := range permissionKinds {
+				permission, ok := file[kind].(map[string]interface{})
+				if !ok {
+					continue
+				}
+				entry := map[string]interface{}{"role": role}
+				for k, v := range permission {
+					entry[k] = v
+				}
+				appendEntry(table, kind+"_permissions", entry)
+			}
+		}
+	}
+	if relationships, ok := table[relationshipsKey]; ok {
+		delete(table, relationshipsKey)
+		for _, file := range entries(relationships) {
+			found := false
+			for _, kind := range relationshipKinds {
+				relationship, ok := file[kind].(map[string]interface{})
+				if !ok {
+					continue
+				}
+				entry := map[string]interface{}{"name": file["name"]}
+				for k, v := range relationship {
+					entry[k] = v
+				}
+				appendEntry(table, kind+"_relationships", entry)
+				found = true
+			}
+			if !found {
+				return fmt.Errorf("relationship %v of table %v has no kind, expected one of %s", file["name"], table["table"], strings.Join(relationshipKinds, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+func appendEntry(table map[string]interface{}, key string, entry map[string]interface{}) {
+	list, _ := table[key].([]interface{})
+	table[key] = append(list, entry)
+}
+
+// entries returns the mappings of a list decoded from YAML
+func entries(list interface{}) []map[string]interface{} {
+	var mappings []map[string]interface{}
+	items, _ := list.([]interface{})
+	for _, item := range items {
+		if mapping, ok := item.(map[string]interface{}); ok {
+			mappings = append(mappings, mapping)
+		}
+	}
+	return mappings
+}
+
+func without(mapping map[string]interface{}, key string) map[string]interface{} {
+	m := map[string]interface{}{}
+	for k, v := range mapping {
+		if k != key {
+			m[k] = v
+		}
+	}
+	return m
+}
+
+func includeTags(files []string) []string {
+	tags := make([]string, 0, len(files))
+	for _, file := range files {
+		tags = append(tags, fmt.Sprintf("%s %s", includeTag, file))
+	}
+	return tags
+}
+
+// listKind returns the kind of a list of permissions or relationships of a
+// table from its key, eg: select for select_permissions
+func listKind(key, suffix string, kinds []string) (string, bool) {
+	if !strings.HasSuffix(key, suffix) {
+		return "", false
+	}
+	kind := strings.TrimSuffix(key, suffix)
+	for _, k := range kinds {
+		if k == kind {
+			return kind, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,149 @@
+package sources
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestSourceConfig_ExportSplitTables(t *testing.T) {
+	metadataDir, err := ioutil.TempDir("", "metadata-*")
+	require.NoError(t, err)
+	defer os.RemoveAll(metadataDir)
+
+	metadataYAML := `version: 3
+sources:
+- name: default
+  kind: postgres
+  configuration:
+    connection_info:
+      database_url:
+        from_env: HASURA_GRAPHQL_DATABASE_URL
+  tables:
+  - table:
+      name: authors
+      schema: public
+    array_relationships:
+    - name: articles
+      using:
+        foreign_key_constraint_on:
+          column: author_id
+          table:
+            name: articles
+            schema: public
+    select_permissions:
+    - permission:
+        columns:
+        - id
+        - name
+        filter: {}
+      role: anonymous
+    - permission:
+        columns:
+        - id
+        filter: {}
+      role: user
+    update_permissions:
+    - permission:
+        columns:
+        - name
+        filter:
+          id:
+            _eq: X-Hasura-User-Id
+      role: user
+  - table:
+      name: articles
+      schema: public
+`
+	var metadata yaml.MapSlice
+	require.NoError(t, yaml.Unmarshal([]byte(metadataYAML), &metadata))
+	tc := &SourceConfig{
+		MetadataDir: metadataDir,
+		splitTables: true,
+		logger:      logrus.New(),
+	}
+	files, err := tc.Export(metadata)
+	require.NoError(t, err)
+
+	got := map[string]string{}
+	for name, content := range files {
+		rel, err := filepath.Rel(metadataDir, name)
+		require.NoError(t, err)
+		if strings.HasPrefix(filepath.ToSlash(rel), "databases/default/tables/") {
+			got[filepath.ToSlash(rel)] = string(content)
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"databases/default/tables/tables.yaml": `- "!include public_authors.yaml"
+- "!include public_articles.yaml"
+`,
+		"databases/default/tables/public_authors.yaml": `table:
+  name: authors
+  schema: public
+relationships:
+- "!include public_authors/relationships/articles.yaml"
+permissions:
+- "!include public_authors/permissions/anonymous.yaml"
+- "!include public_authors/permissions/user.yaml"
+`,
+		"databases/default/tables/public_authors/relationships/articles.yaml": `name: articles
+array:
+  using:
+    foreign_key_constraint_on:
+      column: author_id
+      table:
+        name: articles
+        schema: public
+`,
+		"databases/default/tables/public_authors/permissions/anonymous.yaml": `role: anonymous
+select:
+  permission:
+    columns:
+    - id
+    - name
+    filter: {}
+`,
+		"databases/default/tables/public_authors/permissions/user.yaml": `role: user
+select:
+  permission:
+    columns:
+    - id
+    filter: {}
+update:
+  permission:
+    columns:
+    - name
+    filter:
+      id:
+        _eq: X-Hasura-User-Id
+`,
+		"databases/default/tables/public_articles.yaml": `table:
+  name: articles
+  schema: public
+`,
+	}, got)
+
+	// metadata built from the split layout is the exported metadata
+	for name, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(name), os.ModePerm))
+		require.NoError(t, ioutil.WriteFile(name, content, 0644))
+	}
+	var built yaml.MapSlice
+	require.NoError(t, tc.Build(&built))
+	var want struct {
+		Sources interface{} `yaml:"sources"`
+	}
+	require.NoError(t, yaml.Unmarshal([]byte(metadataYAML), &want))
+	wantBytes, err := yaml.Marshal(want.Sources)
+	require.NoError(t, err)
+	gotBytes, err := yaml.Marshal(built[0].Value)
+	require.NoError(t, err)
+	assert.Equal(t, string(wantBytes), string(gotBytes))
+}
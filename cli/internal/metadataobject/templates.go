@@ -0,0 +1,233 @@
+package metadataobject
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// placeholderRegexp matches the placeholders of metadata files:
+// {{ env "NAME" }} is replaced by the value of an environment variable, which
+// can come from the .env file of the project, and {{ file "path" }} by the
+// content of a file relative to the project directory. Other uses of {{ }},
+// like request transforms, are left as they are.
+var placeholderRegexp = regexp.MustCompile(`{{\s*(env|file)\s+"([^"]*)"\s*}}`)
+
+// expandPlaceholders returns s with its placeholders replaced by their values
+func expandPlaceholders(s, projectDir string) (string, error) {
+	var err error
+	expanded := placeholderRegexp.ReplaceAllStringFunc(s, func(placeholder string) string {
+		match := placeholderRegexp.FindStringSubmatch(placeholder)
+		switch match[1] {
+		case "env":
+			value, ok := os.LookupEnv(match[2])
+			if !ok {
+				err = fmt.Errorf("environment variable %s used in metadata is not set", match[2])
+			}
+			return value
+		default:
+			b, readErr := ioutil.ReadFile(filepath.Join(projectDir, match[2]))
+			if readErr != nil {
+				err = fmt.Errorf("cannot read file %s used in metadata: %w", match[2], readErr)
+			}
+			return strings.TrimSuffix(string(b), "\n")
+		}
+	})
+	return expanded, err
+}
+
+// expandMetadataPlaceholders replaces the placeholders of the strings of
+// built metadata by their values
+func expandMetadataPlaceholders(metadata yaml.MapSlice, projectDir string) (yaml.MapSlice, error) {
+	b, err := yaml.Marshal(metadata)
+	if err != nil {
+		return nil, err
+	}
+	if !placeholderRegexp.Match(b) {
+		return metadata, nil
+	}
+	// objects build metadata from values of various types, a round trip
+	// makes it a tree of strings, slices and map slices
+	var generic yaml.MapSlice
+	if err := yaml.Unmarshal(b, &generic); err != nil {
+		return nil, err
+	}
+	expanded, err := mapStrings(generic, func(s string) (string, error) {
+		return expandPlaceholders(s, projectDir)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return expanded.(yaml.MapSlice), nil
+}
+
+// ExpandJSONPlaceholders returns metadata built as JSON with KeepPlaceholders
+// with its placeholders replaced by their values
+func (h *Handler) ExpandJSONPlaceholders(metadata []byte) ([]byte, error) {
+	var v interface{}
+	if err := json.Unmarshal(metadata, &v); err != nil {
+		return nil, err
+	}
+	expanded, err := mapStrings(v, func(s string) (string, error) {
+		return expandPlaceholders(s, h.projectDir)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(expanded)
+}
+
+// restorePlaceholders puts back the placeholders of the file currently at
+// name in the exported content of the file: a string of content is replaced
+// by the string with placeholders at the same YAML path of the current file
+// if it expands to it
+func restorePlaceholders(name string, content []byte, projectDir string) ([]byte, error) {
+	current, err := ioutil.ReadFile(name)
+	if err != nil || !placeholderRegexp.Match(current) {
+		return content, nil
+	}
+	currentValue, err := unmarshalGeneric(current)
+	if err != nil {
+		// the file will be overwritten anyway
+		return content, nil
+	}
+	templates := map[string]string{}
+	walkStrings(currentValue, "$", func(path, s string) string {
+		if placeholderRegexp.MatchString(s) {
+			templates[path] = s
+		}
+		return s
+	})
+	exported, err := unmarshalGeneric(content)
+	if err != nil {
+		return nil, err
+	}
+	restored := false
+	exported = walkStrings(exported, "$", func(path, s string) string {
+		template, ok := templates[path]
+		if !ok {
+			return s
+		}
+		if expanded, err := expandPlaceholders(template, projectDir); err == nil && expanded == s {
+			restored = true
+			return template
+		}
+		return s
+	})
+	if !restored {
+		return content, nil
+	}
+	return yaml.Marshal(exported)
+}
+
+// walkStrings replaces the strings of a value by f of their YAML path and
+// them. An element of a sequence is identified by its identity field, like
+// overlays merge them (see identityFields), so that an object keeps its path
+// when objects are reordered, or by its index if it has none.
+func walkStrings(v interface{}, path string, f func(path, s string) string) interface{} {
+	switch v := v.(type) {
+	case string:
+		return f(path, v)
+	case yaml.MapSlice:
+		for i := range v {
+			v[i].Value = walkStrings(v[i].Value, fmt.Sprintf("%s.%v", path, v[i].Key), f)
+		}
+	case []yaml.MapSlice:
+		for i := range v {
+			v[i] = walkStrings(v[i], elementPath(path, i, v[i]), f).(yaml.MapSlice)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = walkStrings(v[i], elementPath(path, i, v[i]), f)
+		}
+	case map[interface{}]interface{}:
+		for key, value := range v {
+			v[key] = walkStrings(value, fmt.Sprintf("%s.%v", path, key), f)
+		}
+	}
+	return v
+}
+
+// elementPath returns the path of the element at index i of the sequence at path
+func elementPath(path string, i int, element interface{}) string {
+	if mapping, ok := element.(yaml.MapSlice); ok {
+		for _, field := range identityFields {
+			for _, item := range mapping {
+				if item.Key == field {
+					return fmt.Sprintf("%s[%s=%v]", path, field, item.Value)
+				}
+			}
+		}
+	}
+	return fmt.Sprintf("%s[%d]", path, i)
+}
+
+// unmarshalGeneric unmarshals a metadata file, which is a mapping or a
+// sequence, keeping the order of the keys of mappings
+func unmarshalGeneric(b []byte) (interface{}, error) {
+	var value interface{}
+	if err := yaml.Unmarshal(b, &value); err != nil {
+		return nil, err
+	}
+	switch value.(type) {
+	case map[interface{}]interface{}:
+		var mapping yaml.MapSlice
+		err := yaml.Unmarshal(b, &mapping)
+		return mapping, err
+	case []interface{}:
+		var sequence []yaml.MapSlice
+		if err := yaml.Unmarshal(b, &sequence); err == nil {
+			return sequence, nil
+		}
+	}
+	return value, nil
+}
+
+// mapStrings replaces the strings of a value by f of them, keys of mappings
+// are left as they are
+func mapStrings(v interface{}, f func(string) (string, error)) (interface{}, error) {
+	var err error
+	switch v := v.(type) {
+	case string:
+		return f(v)
+	case yaml.MapSlice:
+		for i := range v {
+			if v[i].Value, err = mapStrings(v[i].Value, f); err != nil {
+				return nil, err
+			}
+		}
+	case []yaml.MapSlice:
+		for i := range v {
+			var item interface{}
+			if item, err = mapStrings(v[i], f); err != nil {
+				return nil, err
+			}
+			v[i] = item.(yaml.MapSlice)
+		}
+	case []interface{}:
+		for i := range v {
+			if v[i], err = mapStrings(v[i], f); err != nil {
+				return nil, err
+			}
+		}
+	case map[string]interface{}:
+		for key, value := range v {
+			if v[key], err = mapStrings(value, f); err != nil {
+				return nil, err
+			}
+		}
+	case map[interface{}]interface{}:
+		for key, value := range v {
+			if v[key], err = mapStrings(value, f); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return v, nil
+}
@@ -0,0 +1,167 @@
+package metadataobject
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestExpandMetadataPlaceholders(t *testing.T) {
+	projectDir, err := ioutil.TempDir("", "project-*")
+	require.NoError(t, err)
+	defer os.RemoveAll(projectDir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(projectDir, "token"), []byte("s3cr3t\n"), 0644))
+	os.Setenv("TEST_METADATA_CRON_URL", "https://cron.example.com")
+	defer os.Unsetenv("TEST_METADATA_CRON_URL")
+
+	tests := []struct {
+		name     string
+		metadata string
+		want     string
+		wantErr  string
+	}{
+		{
+			"env and file placeholders are expanded",
+			`cron_triggers:
+- name: cleanup
+  webhook: '{{ env "TEST_METADATA_CRON_URL" }}/cleanup'
+  payload:
+    token: '{{file "token"}}'
+    template: '{{$body.input}}'
+`,
+			`cron_triggers:
+- name: cleanup
+  webhook: https://cron.example.com/cleanup
+  payload:
+    token: s3cr3t
+    template: '{{$body.input}}'
+`,
+			"",
+		},
+		{
+			"unset environment variable",
+			`rest_endpoints:
+- name: users
+  url: '{{ env "TEST_METADATA_UNSET" }}'
+`,
+			"",
+			"environment variable TEST_METADATA_UNSET used in metadata is not set",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var metadata yaml.MapSlice
+			require.NoError(t, yaml.Unmarshal([]byte(tt.metadata), &metadata))
+			got, err := expandMetadataPlaceholders(metadata, projectDir)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			b, err := yaml.Marshal(got)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(b))
+		})
+	}
+}
+
+func TestRestorePlaceholders(t *testing.T) {
+	dir, err := ioutil.TempDir("", "metadata-*")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	os.Setenv("TEST_METADATA_CRON_URL", "https://cron.example.com")
+	defer os.Unsetenv("TEST_METADATA_CRON_URL")
+	name := filepath.Join(dir, "cron_triggers.yaml")
+	require.NoError(t, ioutil.WriteFile(name, []byte(`- name: cleanup
+  webhook: '{{ env "TEST_METADATA_CRON_URL" }}/cleanup'
+  schedule: 0 0 * * *
+`), 0644))
+
+	exported := []byte(`- name: report
+  webhook: https://cron.example.com/cleanup
+  schedule: 0 0 * * 0
+- name: cleanup
+  webhook: https://cron.example.com/cleanup
+  schedule: 0 1 * * *
+`)
+	got, err := restorePlaceholders(name, exported, dir)
+	require.NoError(t, err)
+	// the placeholder is restored at its path only, a string of another
+	// object expanding to the same value is left as it is
+	assert.Equal(t, `- name: report
+  webhook: https://cron.example.com/cleanup
+  schedule: 0 0 * * 0
+- name: cleanup
+  webhook: '{{ env "TEST_METADATA_CRON_URL" }}/cleanup'
+  schedule: 0 1 * * *
+`, string(got))
+
+	// an empty value or a value used as a name elsewhere does not replace
+	// the strings equal to it
+	os.Setenv("TEST_METADATA_ROLE", "user")
+	defer os.Unsetenv("TEST_METADATA_ROLE")
+	os.Setenv("TEST_METADATA_EMPTY", "")
+	defer os.Unsetenv("TEST_METADATA_EMPTY")
+	tablesFile := filepath.Join(dir, "public_orders.yaml")
+	require.NoError(t, ioutil.WriteFile(tablesFile, []byte(`table:
+  name: orders
+  schema: public
+select_permissions:
+- role: admin
+  permission:
+    filter:
+      role:
+        _eq: '{{ env "TEST_METADATA_ROLE" }}'
+    columns: []
+  comment: '{{ env "TEST_METADATA_EMPTY" }}'
+`), 0644))
+	got, err = restorePlaceholders(tablesFile, []byte(`table:
+  name: orders
+  schema: public
+select_permissions:
+- role: user
+  permission:
+    filter:
+      role:
+        _eq: user
+    columns: []
+  comment: ""
+- role: admin
+  permission:
+    filter:
+      role:
+        _eq: user
+    columns: []
+  comment: ""
+`), dir)
+	require.NoError(t, err)
+	assert.Equal(t, `table:
+  name: orders
+  schema: public
+select_permissions:
+- role: user
+  permission:
+    filter:
+      role:
+        _eq: user
+    columns: []
+  comment: ""
+- role: admin
+  permission:
+    filter:
+      role:
+        _eq: '{{ env "TEST_METADATA_ROLE" }}'
+    columns: []
+  comment: '{{ env "TEST_METADATA_EMPTY" }}'
+`, string(got))
+
+	// files without placeholders are exported as they are
+	got, err = restorePlaceholders(filepath.Join(dir, "missing.yaml"), exported, dir)
+	require.NoError(t, err)
+	assert.Equal(t, string(exported), string(got))
+}
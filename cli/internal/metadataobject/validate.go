@@ -0,0 +1,274 @@
+package metadataobject
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/hasura/graphql-engine/cli/v2/internal/jsonschema"
+	"github.com/pkg/errors"
+)
+
+//go:embed schema/metadata.schema.json
+var metadataSchema []byte
+
+// ValidationError is a violation of the metadata schema
+type ValidationError struct {
+	// File is the metadata file where the violation is, relative to the
+	// metadata directory. It is empty for violations of the whole metadata.
+	File string `json:"file"`
+	// Path is the YAML path of the value in File
+	Path    string `json:"path"`
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+func (e ValidationError) String() string {
+	if e.File == "" {
+		return fmt.Sprintf("%s: %s", e.Path, e.Message)
+	}
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %s: %s", e.File, e.Path, e.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s: %s", e.File, e.Line, e.Path, e.Message)
+}
+
+// metadataFile is the file a top level key of metadata is built from
+type metadataFile struct {
+	name string
+	// path of the value in the file, the value is the whole file if it is empty
+	path []string
+}
+
+var metadataFiles = map[string]metadataFile{
+	"version":                      {"version.yaml", []string{"version"}},
+	"sources":                      {filepath.Join("databases", "databases.yaml"), nil},
+	"tables":                       {"tables.yaml", nil},
+	"functions":                    {"functions.yaml", nil},
+	"actions":                      {"actions.yaml", []string{"actions"}},
+	"custom_types":                 {"actions.yaml", []string{"custom_types"}},
+	"remote_schemas":               {"remote_schemas.yaml", nil},
+	"query_collections":            {"query_collections.yaml", nil},
+	"allowlist":                    {"allow_list.yaml", nil},
+	"cron_triggers":                {"cron_triggers.yaml", nil},
+	"rest_endpoints":               {"rest_endpoints.yaml", nil},
+	"inherited_roles":              {"inherited_roles.yaml", nil},
+	"api_limits":                   {"api_limits.yaml", nil},
+	"graphql_schema_introspection": {"graphql_schema_introspection.yaml", nil},
+}
+
+// ValidateMetadata builds metadata from the project and validates it against
+// the metadata schema embedded in the CLI, without a server. The violations
+// are located in the files of metadataDir they were built from.
+func (h *Handler) ValidateMetadata(metadataDir string) ([]ValidationError, error) {
+	jbyt, err := h.MakeJSONMetadata()
+	if err != nil {
+		return nil, err
+	}
+	var metadata interface{}
+	if err := json.Unmarshal(jbyt, &metadata); err != nil {
+		return nil, err
+	}
+	schema, err := jsonschema.Compile(metadataSchema)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot compile metadata schema")
+	}
+	l := &locator{metadataDir: metadataDir, files: map[string]ast.Node{}}
+	var violations []ValidationError
+	for _, e := range schema.Validate(metadata) {
+		violation, err := l.locate(e, metadata)
+		if err != nil {
+			return nil, err
+		}
+		violations = append(violations, violation)
+	}
+	sort.SliceStable(violations, func(i, j int) bool {
+		if violations[i].File != violations[j].File {
+			return violations[i].File < violations[j].File
+		}
+		return violations[i].Line < violations[j].Line
+	})
+	return violations, nil
+}
+
+// locator finds the file and the YAML path a value of the built metadata
+// was read from, by walking the files the way they are built and following
+// !include tags
+type locator struct {
+	metadataDir string
+	// files caches the parsed files by path
+	files map[string]ast.Node
+}
+
+func (l *locator) locate(e jsonschema.Error, metadata interface{}) (ValidationError, error) {
+	violation, _, err := l.find(e, metadata)
+	return violation, err
+}
+
+// find locates the value at the path of e, found is false if the value was
+// located only partially, like a value built from a file it cannot read
+func (l *locator) find(e jsonschema.Error, metadata interface{}) (violation ValidationError, found bool, err error) {
+	violation = ValidationError{Path: e.PathString(), Message: e.Message}
+	if len(e.Path) == 0 {
+		return violation, false, nil
+	}
+	key, _ := e.Path[0].(string)
+	file, ok := metadataFiles[key]
+	if !ok {
+		return violation, false, nil
+	}
+	value := metadata.(map[string]interface{})[key]
+	violation.File, violation.Path = file.name, "$"
+	node, err := l.parse(file.name)
+	if err != nil || node == nil {
+		return violation, false, err
+	}
+	for _, p := range file.path {
+		if node = mappingValue(node, p); node == nil {
+			return violation, false, nil
+		}
+		violation.Path += "." + p
+	}
+	violation.Line = line(node)
+
+	for _, p := range e.Path[1:] {
+		included, name, err := l.resolveInclude(node, violation.File)
+		if err != nil || included == nil {
+			return violation, false, err
+		}
+		if name != violation.File {
+			violation.File, violation.Path, violation.Line = name, "$", line(included)
+		}
+		node = included
+		switch p := p.(type) {
+		case string:
+			key := mappingKey(node, p)
+			if key == nil {
+				return violation, false, nil
+			}
+			node = key.Value
+			violation.Path += "." + p
+			violation.Line = line(key.Key)
+			obj, _ := value.(map[string]interface{})
+			value = obj[p]
+		case int:
+			elements, _ := value.([]interface{})
+			if p >= len(elements) {
+				return violation, false, nil
+			}
+			index := sequenceIndex(node, p, elements[p])
+			if index < 0 {
+				return violation, false, nil
+			}
+			node = node.(*ast.SequenceNode).Values[index]
+			violation.Path += fmt.Sprintf("[%d]", index)
+			violation.Line = line(node)
+			value = elements[p]
+		}
+	}
+	return violation, true, nil
+}
+
+// resolveInclude returns the root of the file included by node if it is an
+// !include tag, the path of the file is relative to the directory of the
+// including file
+func (l *locator) resolveInclude(node ast.Node, file string) (ast.Node, string, error) {
+	var include string
+	switch n := node.(type) {
+	case *ast.TagNode:
+		if n.Start.Value != "!include" || n.Value == nil {
+			return node, file, nil
+		}
+		include = n.Value.GetToken().Value
+	case *ast.StringNode:
+		if !strings.HasPrefix(n.Value, "!include ") {
+			return node, file, nil
+		}
+		include = strings.Trim(strings.TrimSpace(strings.TrimPrefix(n.Value, "!include")), `"`)
+	default:
+		return node, file, nil
+	}
+	included := filepath.Join(filepath.Dir(file), include)
+	root, err := l.parse(included)
+	return root, included, err
+}
+
+func (l *locator) parse(name string) (ast.Node, error) {
+	if node, ok := l.files[name]; ok {
+		return node, nil
+	}
+	b, err := ioutil.ReadFile(filepath.Join(l.metadataDir, name))
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read metadata file %s", name)
+	}
+	f, err := parser.ParseBytes(b, 0)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot parse metadata file %s", name)
+	}
+	var root ast.Node
+	if len(f.Docs) > 0 {
+		root = f.Docs[0].Body
+	}
+	l.files[name] = root
+	return root, nil
+}
+
+// mappingKey returns the entry of key if node is a mapping
+func mappingKey(node ast.Node, key string) *ast.MappingValueNode {
+	var entries []*ast.MappingValueNode
+	switch n := node.(type) {
+	case *ast.MappingNode:
+		entries = n.Values
+	case *ast.MappingValueNode:
+		entries = []*ast.MappingValueNode{n}
+	}
+	for _, entry := range entries {
+		if k, ok := entry.Key.(ast.ScalarNode); ok && fmt.Sprint(k.GetValue()) == key {
+			return entry
+		}
+	}
+	return nil
+}
+
+func mappingValue(node ast.Node, key string) ast.Node {
+	if entry := mappingKey(node, key); entry != nil {
+		return entry.Value
+	}
+	return nil
+}
+
+// sequenceIndex returns the index in the sequence node of the element built
+// as value at index. Elements having a name are matched by name, since some
+// objects, like actions, are not built in the order of their file.
+func sequenceIndex(node ast.Node, index int, value interface{}) int {
+	seq, ok := node.(*ast.SequenceNode)
+	if !ok {
+		return -1
+	}
+	if obj, ok := value.(map[string]interface{}); ok {
+		if name, ok := obj["name"].(string); ok {
+			for i, element := range seq.Values {
+				if n, ok := mappingValue(element, "name").(ast.ScalarNode); ok && fmt.Sprint(n.GetValue()) == name {
+					return i
+				}
+			}
+		}
+	}
+	if index < len(seq.Values) {
+		return index
+	}
+	return -1
+}
+
+func line(node ast.Node) int {
+	if node == nil || node.GetToken() == nil {
+		return 0
+	}
+	return node.GetToken().Position.Line
+}
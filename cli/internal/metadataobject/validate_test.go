@@ -0,0 +1,58 @@
+package metadataobject
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/hasura/graphql-engine/cli/v2"
+	crontriggers "github.com/hasura/graphql-engine/cli/v2/internal/metadataobject/cron_triggers"
+	"github.com/hasura/graphql-engine/cli/v2/internal/metadataobject/remoteschemas"
+	"github.com/hasura/graphql-engine/cli/v2/internal/metadataobject/sources"
+	"github.com/hasura/graphql-engine/cli/v2/internal/metadataobject/version"
+	cliversion "github.com/hasura/graphql-engine/cli/v2/version"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_ValidateMetadata(t *testing.T) {
+	metadataDir := "testdata/validate/metadata"
+	ec := &cli.ExecutionContext{Logger: logrus.New(), Version: cliversion.New()}
+	h := NewHandler(Objects{
+		version.New(ec, metadataDir),
+		sources.New(ec, metadataDir),
+		remoteschemas.New(ec, metadataDir),
+		crontriggers.New(ec, metadataDir),
+	}, nil, nil, ec.Logger)
+	h.KeepPlaceholders()
+
+	got, err := h.ValidateMetadata(metadataDir)
+	assert.NoError(t, err)
+	assert.Equal(t, []ValidationError{
+		{"cron_triggers.yaml", "$[1]", 10, `missing required field "schedule"`},
+		{"databases/default/tables/public_articles.yaml", "$.select_permisions", 4, `unknown field "select_permisions", did you mean "select_permissions"?`},
+		{"databases/default/tables/public_articles.yaml", "$.update_permissions[0].permission.columns", 12, `value "title" is not one of "*"`},
+		{"remote_schemas.yaml", "$[0].definition.forward_client_headers", 5, "expected boolean, got string"},
+	}, got)
+}
+
+func TestHandler_ValidateMetadataWithOverlay(t *testing.T) {
+	metadataDir := "testdata/validate/metadata"
+	ec := &cli.ExecutionContext{
+		Logger:             logrus.New(),
+		Version:            cliversion.New(),
+		Config:             &cli.Config{Version: cli.V3},
+		HasMetadataV3:      true,
+		MetadataDir:        metadataDir,
+		MetadataOverlayDir: filepath.Join(metadataDir, "overlays", "prod"),
+	}
+	h := NewHandlerFromEC(ec)
+	h.KeepPlaceholders()
+
+	got, err := h.ValidateMetadata(metadataDir)
+	assert.NoError(t, err)
+	// the overlay fixes forward_client_headers of remote_schemas.yaml
+	for _, violation := range got {
+		assert.NotEqual(t, "remote_schemas.yaml", violation.File)
+	}
+	assert.NotEmpty(t, got)
+}
@@ -0,0 +1,280 @@
+// Package pgdiff compares the tables of Postgres schemas and generates
+// the SQL statements which change one set of tables into the other.
+package pgdiff
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hasura/graphql-engine/cli/v2/internal/hasura"
+	"github.com/pkg/errors"
+)
+
+// Database is a Postgres database connected to Hasura
+type Database struct {
+	SourceOps hasura.PGSourceOps
+	PGDump    hasura.PGDump
+	// Source is the name of the database on Hasura
+	Source string
+}
+
+// Column is a column of a table
+type Column struct {
+	Name    string
+	Type    string
+	NotNull bool
+	// Default is the default expression of the column, empty if it has none
+	Default string
+}
+
+// Table is a table and the objects which belong to it
+type Table struct {
+	Schema  string
+	Name    string
+	Columns []Column
+	// Constraints maps constraint names to their definitions
+	Constraints map[string]string
+	// Indexes maps names of indexes, which do not back a constraint, to their definitions
+	Indexes map[string]string
+}
+
+// QualifiedName returns the quoted schema qualified name of the table
+func (t *Table) QualifiedName() string {
+	return quoteIdent(t.Schema) + "." + quoteIdent(t.Name)
+}
+
+func (t *Table) column(name string) (Column, bool) {
+	for _, c := range t.Columns {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Column{}, false
+}
+
+// Catalog is the set of tables of one or more schemas
+type Catalog struct {
+	// Tables maps schema qualified table names to tables
+	Tables map[string]*Table
+	// Dump returns the statements which create the given tables of the catalog
+	Dump func(tables []*Table) (string, error)
+}
+
+func (c *Catalog) sortedTables() []*Table {
+	var tables []*Table
+	for _, t := range c.Tables {
+		tables = append(tables, t)
+	}
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].QualifiedName() < tables[j].QualifiedName()
+	})
+	return tables
+}
+
+// all definitions are read with an empty search_path, so that every
+// reference to an object in a user schema is schema qualified
+const emptySearchPath = "SELECT pg_catalog.set_config('search_path', '', true);\n"
+
+const tablesQuery = `SELECT c.relname
+FROM pg_catalog.pg_class c
+JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+WHERE n.nspname = %s AND c.relkind IN ('r', 'p')
+ORDER BY 1`
+
+const columnsQuery = `SELECT c.relname, a.attname, pg_catalog.format_type(a.atttypid, a.atttypmod),
+  CASE WHEN a.attnotnull THEN 'true' ELSE 'false' END,
+  COALESCE(pg_catalog.pg_get_expr(d.adbin, d.adrelid), '')
+FROM pg_catalog.pg_attribute a
+JOIN pg_catalog.pg_class c ON c.oid = a.attrelid
+JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+LEFT JOIN pg_catalog.pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+WHERE n.nspname = %s AND c.relkind IN ('r', 'p') AND a.attnum > 0 AND NOT a.attisdropped
+ORDER BY c.relname, a.attnum`
+
+const constraintsQuery = `SELECT c.relname, con.conname, pg_catalog.pg_get_constraintdef(con.oid)
+FROM pg_catalog.pg_constraint con
+JOIN pg_catalog.pg_class c ON c.oid = con.conrelid
+JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+WHERE n.nspname = %s AND c.relkind IN ('r', 'p') AND con.contype IN ('p', 'u', 'f', 'c', 'x')
+ORDER BY 1, 2`
+
+const indexesQuery = `SELECT t.relname, i.relname, pg_catalog.pg_get_indexdef(i.oid)
+FROM pg_catalog.pg_index x
+JOIN pg_catalog.pg_class i ON i.oid = x.indexrelid
+JOIN pg_catalog.pg_class t ON t.oid = x.indrelid
+JOIN pg_catalog.pg_namespace n ON n.oid = t.relnamespace
+WHERE n.nspname = %s AND t.relkind IN ('r', 'p')
+  AND NOT EXISTS (
+    SELECT 1 FROM pg_catalog.pg_constraint con
+    WHERE con.conindid = x.indexrelid AND con.contype IN ('p', 'u', 'x')
+  )
+ORDER BY 1, 2`
+
+// ReadCatalog reads the tables of schemas in db. A schema is added to the
+// catalog under the name it is mapped to in rename, if any, which allows
+// comparing a scratch schema with the schema it stands in for.
+func ReadCatalog(db *Database, schemas []string, rename map[string]string) (*Catalog, error) {
+	catalog := &Catalog{Tables: map[string]*Table{}}
+	// actual maps the schema names in the catalog back to the names in db
+	actual := map[string]string{}
+	for _, schema := range schemas {
+		as := schema
+		if name, ok := rename[schema]; ok {
+			as = name
+		}
+		actual[as] = schema
+		if err := readSchema(db, catalog, schema, as); err != nil {
+			return nil, errors.Wrapf(err, "reading tables of schema %s", schema)
+		}
+	}
+	catalog.Dump = func(tables []*Table) (string, error) {
+		return dumpTables(db, tables, actual)
+	}
+	return catalog, nil
+}
+
+func readSchema(db *Database, catalog *Catalog, schema, as string) error {
+	key := func(table string) string {
+		return quoteIdent(as) + "." + quoteIdent(table)
+	}
+	rename := func(definition string) string {
+		return renameSchema(definition, schema, as)
+	}
+
+	rows, err := query(db, tablesQuery, schema)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		catalog.Tables[key(row[0])] = &Table{
+			Schema:      as,
+			Name:        row[0],
+			Constraints: map[string]string{},
+			Indexes:     map[string]string{},
+		}
+	}
+
+	rows, err = query(db, columnsQuery, schema)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if t, ok := catalog.Tables[key(row[0])]; ok {
+			t.Columns = append(t.Columns, Column{
+				Name:    row[1],
+				Type:    rename(row[2]),
+				NotNull: row[3] == "true",
+				Default: rename(row[4]),
+			})
+		}
+	}
+
+	rows, err = query(db, constraintsQuery, schema)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if t, ok := catalog.Tables[key(row[0])]; ok {
+			t.Constraints[row[1]] = rename(row[2])
+		}
+	}
+
+	rows, err = query(db, indexesQuery, schema)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if t, ok := catalog.Tables[key(row[0])]; ok {
+			t.Indexes[row[1]] = rename(row[2])
+		}
+	}
+	return nil
+}
+
+// query runs a catalog query for schema and returns the result rows
+// without the header row
+func query(db *Database, format, schema string) ([][]string, error) {
+	resp, err := db.SourceOps.PGRunSQL(hasura.PGRunSQLInput{
+		SQL:      emptySearchPath + fmt.Sprintf(format, quoteLiteral(schema)),
+		Source:   db.Source,
+		ReadOnly: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.ResultType != hasura.TuplesOK || len(resp.Result) == 0 {
+		return nil, fmt.Errorf("unexpected result of catalog query: %v", resp.ResultType)
+	}
+	return resp.Result[1:], nil
+}
+
+// dumpTables returns the statements which create tables using pg_dump
+func dumpTables(db *Database, tables []*Table, actual map[string]string) (string, error) {
+	if len(tables) == 0 {
+		return "", nil
+	}
+	opts := []string{"--no-owner", "--no-acl", "--schema-only"}
+	for _, t := range tables {
+		schema := t.Schema
+		if name, ok := actual[schema]; ok {
+			schema = name
+		}
+		opts = append(opts, "--table", quoteIdent(schema)+"."+quoteIdent(t.Name))
+	}
+	resp, err := db.PGDump.Send(hasura.PGDumpRequest{
+		Opts:        opts,
+		CleanOutput: true,
+		SourceName:  db.Source,
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "cannot dump tables")
+	}
+	dump, err := ioutil.ReadAll(resp)
+	if err != nil {
+		return "", errors.Wrap(err, "cannot read dump of tables")
+	}
+	out := string(dump)
+	for as, schema := range actual {
+		out = renameSchema(out, schema, as)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+var unquotedIdent = regexp.MustCompile(`^[a-z_][a-z0-9_$]*$`)
+
+func quoteIdent(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+func quoteLiteral(value string) string {
+	return `'` + strings.Replace(value, `'`, `''`, -1) + `'`
+}
+
+// renameSchema replaces references to objects of schema from in
+// definition with references to objects of schema to
+func renameSchema(definition, from, to string) string {
+	if from == to {
+		return definition
+	}
+	replacement := to
+	if !unquotedIdent.MatchString(to) {
+		replacement = quoteIdent(to)
+	}
+	definition = strings.Replace(definition, quoteIdent(from)+".", replacement+".", -1)
+	if unquotedIdent.MatchString(from) {
+		bare := regexp.MustCompile(`(^|[^\w$"])` + regexp.QuoteMeta(from) + `\.`)
+		definition = bare.ReplaceAllString(definition, "${1}"+strings.Replace(replacement, "$", "$$", -1)+".")
+	}
+	return definition
+}
+
+// RedirectSchema rewrites the SQL of a migration so that it creates the
+// objects of schema from in schema to: references qualified with schema
+// from are replaced and schema to is put first on the search_path, schema
+// from stays on the search_path to resolve extensions installed in it.
+func RedirectSchema(sql, from, to string) string {
+	return fmt.Sprintf("SET LOCAL search_path TO %s, %s;\n", quoteIdent(to), quoteIdent(from)) + renameSchema(sql, from, to)
+}
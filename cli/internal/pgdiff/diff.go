@@ -0,0 +1,174 @@
+package pgdiff
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Diff returns the SQL statements which change the tables of catalog from
+// into the tables of catalog to. Tables, columns, constraints and indexes
+// are compared, tables missing in from are created with the Dump of to.
+func Diff(from, to *Catalog) ([]string, error) {
+	var (
+		dropConstraints []string
+		dropIndexes     []string
+		alterColumns    []string
+		addConstraints  []string
+		createIndexes   []string
+		createTables    []*Table
+		dropTables      []string
+	)
+
+	for _, t := range to.sortedTables() {
+		if _, ok := from.Tables[t.QualifiedName()]; !ok {
+			createTables = append(createTables, t)
+		}
+	}
+	for _, old := range from.sortedTables() {
+		t, ok := to.Tables[old.QualifiedName()]
+		if !ok {
+			dropTables = append(dropTables, old.QualifiedName())
+			continue
+		}
+		table := t.QualifiedName()
+
+		for _, name := range sortedKeys(old.Constraints) {
+			if definition, ok := t.Constraints[name]; !ok || definition != old.Constraints[name] {
+				dropConstraints = append(dropConstraints, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s;", table, quoteIdent(name)))
+			}
+		}
+		for _, name := range sortedKeys(t.Constraints) {
+			if definition, ok := old.Constraints[name]; !ok || definition != t.Constraints[name] {
+				addConstraints = append(addConstraints, fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s %s;", table, quoteIdent(name), t.Constraints[name]))
+			}
+		}
+
+		for _, name := range sortedKeys(old.Indexes) {
+			if definition, ok := t.Indexes[name]; !ok || definition != old.Indexes[name] {
+				dropIndexes = append(dropIndexes, fmt.Sprintf("DROP INDEX %s.%s;", quoteIdent(t.Schema), quoteIdent(name)))
+			}
+		}
+		for _, name := range sortedKeys(t.Indexes) {
+			if definition, ok := old.Indexes[name]; !ok || definition != t.Indexes[name] {
+				createIndexes = append(createIndexes, t.Indexes[name]+";")
+			}
+		}
+
+		alterColumns = append(alterColumns, diffColumns(old, t)...)
+	}
+
+	var statements []string
+	statements = append(statements, dropConstraints...)
+	statements = append(statements, dropIndexes...)
+	if len(createTables) > 0 {
+		dump, err := to.Dump(createTables)
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, dump)
+	}
+	statements = append(statements, alterColumns...)
+	statements = append(statements, addConstraints...)
+	statements = append(statements, createIndexes...)
+	if len(dropTables) > 0 {
+		// tables are dropped by a single statement so that
+		// references between the dropped tables do not matter
+		statements = append(statements, fmt.Sprintf("DROP TABLE %s;", strings.Join(dropTables, ", ")))
+	}
+	return statements, nil
+}
+
+func diffColumns(old, t *Table) []string {
+	var statements []string
+	table := t.QualifiedName()
+	for _, c := range t.Columns {
+		oldColumn, ok := old.column(c.Name)
+		if !ok {
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;", table, columnDefinition(t, c)))
+			continue
+		}
+		column := quoteIdent(c.Name)
+		if oldColumn.Type != c.Type {
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s USING %s::%s;", table, column, c.Type, column, c.Type))
+		}
+		if oldColumn.Default != c.Default {
+			if c.Default == "" {
+				statements = append(statements, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP DEFAULT;", table, column))
+			} else {
+				statements = append(statements, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET DEFAULT %s;", table, column, c.Default))
+			}
+		}
+		if oldColumn.NotNull != c.NotNull {
+			if c.NotNull {
+				statements = append(statements, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET NOT NULL;", table, column))
+			} else {
+				statements = append(statements, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP NOT NULL;", table, column))
+			}
+		}
+	}
+	for _, oldColumn := range old.Columns {
+		if _, ok := t.column(oldColumn.Name); !ok {
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", table, quoteIdent(oldColumn.Name)))
+		}
+	}
+	return statements
+}
+
+var serialTypes = map[string]string{
+	"smallint": "smallserial",
+	"integer":  "serial",
+	"bigint":   "bigserial",
+}
+
+var nextval = regexp.MustCompile(`^nextval\('(.*)'::regclass\)$`)
+
+// columnDefinition returns the definition of a column added to an existing
+// table, a column using the sequence created for a serial column is added
+// as a serial column so that the sequence is created along with it
+func columnDefinition(t *Table, c Column) string {
+	definition := quoteIdent(c.Name) + " " + c.Type
+	if serial, ok := serialTypes[c.Type]; ok {
+		if m := nextval.FindStringSubmatch(c.Default); m != nil && isSerialSequence(m[1], t, c) {
+			definition = quoteIdent(c.Name) + " " + serial
+			if c.NotNull {
+				definition += " NOT NULL"
+			}
+			return definition
+		}
+	}
+	if c.NotNull {
+		definition += " NOT NULL"
+	}
+	if c.Default != "" {
+		definition += " DEFAULT " + c.Default
+	}
+	return definition
+}
+
+// isSerialSequence reports if sequence is the name postgres
+// gives to the sequence of a serial column
+func isSerialSequence(sequence string, t *Table, c Column) bool {
+	name := t.Name + "_" + c.Name + "_seq"
+	for _, candidate := range []string{
+		t.Schema + "." + name,
+		quoteIdent(t.Schema) + "." + name,
+		t.Schema + "." + quoteIdent(name),
+		quoteIdent(t.Schema) + "." + quoteIdent(name),
+	} {
+		if sequence == candidate {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string]string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
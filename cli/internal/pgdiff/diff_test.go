@@ -0,0 +1,172 @@
+package pgdiff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newCatalog(tables ...*Table) *Catalog {
+	c := &Catalog{Tables: map[string]*Table{}}
+	for _, t := range tables {
+		if t.Constraints == nil {
+			t.Constraints = map[string]string{}
+		}
+		if t.Indexes == nil {
+			t.Indexes = map[string]string{}
+		}
+		c.Tables[t.QualifiedName()] = t
+	}
+	c.Dump = func(tables []*Table) (string, error) {
+		var names []string
+		for _, t := range tables {
+			names = append(names, t.QualifiedName())
+		}
+		return fmt.Sprintf("-- dump of %s", strings.Join(names, ", ")), nil
+	}
+	return c
+}
+
+func usersTable() *Table {
+	return &Table{
+		Schema: "public",
+		Name:   "users",
+		Columns: []Column{
+			{Name: "id", Type: "integer", NotNull: true, Default: "nextval('public.users_id_seq'::regclass)"},
+			{Name: "name", Type: "text", NotNull: true},
+		},
+		Constraints: map[string]string{"users_pkey": "PRIMARY KEY (id)"},
+		Indexes:     map[string]string{},
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		from *Catalog
+		to   func() *Catalog
+		want []string
+	}{
+		{
+			"no changes",
+			newCatalog(usersTable()),
+			func() *Catalog { return newCatalog(usersTable()) },
+			nil,
+		},
+		{
+			"create and drop tables",
+			newCatalog(&Table{Schema: "public", Name: "a"}, &Table{Schema: "public", Name: "b"}),
+			func() *Catalog { return newCatalog(usersTable()) },
+			[]string{
+				`-- dump of "public"."users"`,
+				`DROP TABLE "public"."a", "public"."b";`,
+			},
+		},
+		{
+			"columns",
+			newCatalog(usersTable()),
+			func() *Catalog {
+				users := usersTable()
+				users.Columns = []Column{
+					users.Columns[0],
+					{Name: "name", Type: "character varying(50)", Default: "'anonymous'::text"},
+					{Name: "email", Type: "text", NotNull: true, Default: "''::text"},
+				}
+				return newCatalog(users)
+			},
+			[]string{
+				`ALTER TABLE "public"."users" ALTER COLUMN "name" TYPE character varying(50) USING "name"::character varying(50);`,
+				`ALTER TABLE "public"."users" ALTER COLUMN "name" SET DEFAULT 'anonymous'::text;`,
+				`ALTER TABLE "public"."users" ALTER COLUMN "name" DROP NOT NULL;`,
+				`ALTER TABLE "public"."users" ADD COLUMN "email" text NOT NULL DEFAULT ''::text;`,
+			},
+		},
+		{
+			"serial column",
+			newCatalog(&Table{Schema: "public", Name: "users", Columns: []Column{{Name: "name", Type: "text"}}}),
+			func() *Catalog { return newCatalog(usersTable()) },
+			[]string{
+				`ALTER TABLE "public"."users" ADD COLUMN "id" serial NOT NULL;`,
+				`ALTER TABLE "public"."users" ALTER COLUMN "name" SET NOT NULL;`,
+				`ALTER TABLE "public"."users" ADD CONSTRAINT "users_pkey" PRIMARY KEY (id);`,
+			},
+		},
+		{
+			"drop column",
+			newCatalog(usersTable()),
+			func() *Catalog {
+				users := usersTable()
+				users.Columns = users.Columns[:1]
+				return newCatalog(users)
+			},
+			[]string{
+				`ALTER TABLE "public"."users" DROP COLUMN "name";`,
+			},
+		},
+		{
+			"constraints and indexes",
+			newCatalog(func() *Table {
+				users := usersTable()
+				users.Constraints["users_name_check"] = "CHECK ((name <> ''::text))"
+				users.Indexes["users_name_idx"] = "CREATE INDEX users_name_idx ON public.users USING btree (name)"
+				return users
+			}()),
+			func() *Catalog {
+				users := usersTable()
+				users.Constraints["users_name_check"] = "CHECK ((length(name) > 1))"
+				users.Constraints["users_name_key"] = "UNIQUE (name)"
+				users.Indexes["users_lower_name_idx"] = "CREATE INDEX users_lower_name_idx ON public.users USING btree (lower(name))"
+				return newCatalog(users)
+			},
+			[]string{
+				`ALTER TABLE "public"."users" DROP CONSTRAINT "users_name_check";`,
+				`DROP INDEX "public"."users_name_idx";`,
+				`ALTER TABLE "public"."users" ADD CONSTRAINT "users_name_check" CHECK ((length(name) > 1));`,
+				`ALTER TABLE "public"."users" ADD CONSTRAINT "users_name_key" UNIQUE (name);`,
+				`CREATE INDEX users_lower_name_idx ON public.users USING btree (lower(name));`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Diff(tt.from, tt.to())
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRenameSchema(t *testing.T) {
+	tests := []struct {
+		name       string
+		definition string
+		from, to   string
+		want       string
+	}{
+		{
+			"qualified references",
+			`FOREIGN KEY (author_id) REFERENCES hdb_cli_diff_1.authors(id)`,
+			"hdb_cli_diff_1", "public",
+			`FOREIGN KEY (author_id) REFERENCES public.authors(id)`,
+		},
+		{
+			"quoted references",
+			`CREATE TABLE "public"."articles" (author_id integer REFERENCES public.authors(id));`,
+			"public", "hdb_cli_diff_1",
+			`CREATE TABLE hdb_cli_diff_1."articles" (author_id integer REFERENCES hdb_cli_diff_1.authors(id));`,
+		},
+		{
+			"names ending with the schema name are not replaced",
+			`SELECT my_public.f(), "not public".g()`,
+			"public", "scratch",
+			`SELECT my_public.f(), "not public".g()`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, renameSchema(tt.definition, tt.from, tt.to))
+		})
+	}
+}
@@ -0,0 +1,416 @@
+package pgsql
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	createTable    = regexp.MustCompile(`(?i)^CREATE\s+(?:(?:GLOBAL|LOCAL)\s+)?(?:(?:TEMP|TEMPORARY|UNLOGGED)\s+)?TABLE\s+(IF\s+NOT\s+EXISTS\s+)?(` + QualifiedName + `)\s*`)
+	dropTable      = regexp.MustCompile(`(?i)^DROP\s+TABLE\s+(?:IF\s+EXISTS\s+)?(.*?)(?:\s+(?:CASCADE|RESTRICT))?$`)
+	alterTable     = regexp.MustCompile(`(?i)^ALTER\s+TABLE\s+(?:IF\s+EXISTS\s+)?(?:ONLY\s+)?(` + QualifiedName + `)\s*\*?\s+(.*)$`)
+	createIndex    = regexp.MustCompile(`(?i)^CREATE\s+(?:UNIQUE\s+)?INDEX\s+(CONCURRENTLY\s+)?(?:IF\s+NOT\s+EXISTS\s+)?(?:(` + Identifier + `)\s+)?ON\s+(?:ONLY\s+)?(` + QualifiedName + `)`)
+	dropIndex      = regexp.MustCompile(`(?i)^DROP\s+INDEX\s+(?:CONCURRENTLY\s+)?(?:IF\s+EXISTS\s+)?(.*?)(?:\s+(?:CASCADE|RESTRICT))?$`)
+	createView     = regexp.MustCompile(`(?i)^CREATE\s+(OR\s+REPLACE\s+)?(?:(?:TEMP|TEMPORARY)\s+)?(?:RECURSIVE\s+)?(MATERIALIZED\s+)?VIEW\s+(?:IF\s+NOT\s+EXISTS\s+)?(` + QualifiedName + `)`)
+	dropView       = regexp.MustCompile(`(?i)^DROP\s+(?:MATERIALIZED\s+)?VIEW\s+(?:IF\s+EXISTS\s+)?(.*?)(?:\s+(?:CASCADE|RESTRICT))?$`)
+	createFunction = regexp.MustCompile(`(?i)^CREATE\s+(OR\s+REPLACE\s+)?(FUNCTION|PROCEDURE)\s+(` + QualifiedName + `)\s*\(`)
+	dropFunction   = regexp.MustCompile(`(?i)^DROP\s+(?:FUNCTION|PROCEDURE)\s+(?:IF\s+EXISTS\s+)?(` + QualifiedName + `)`)
+
+	addColumn        = regexp.MustCompile(`(?i)^ADD\s+(COLUMN\s+)?(?:IF\s+NOT\s+EXISTS\s+)?(` + Identifier + `)\s+(.*)$`)
+	dropColumn       = regexp.MustCompile(`(?i)^DROP\s+(COLUMN\s+)?(?:IF\s+EXISTS\s+)?(` + Identifier + `)`)
+	alterColumnType  = regexp.MustCompile(`(?i)^ALTER\s+(?:COLUMN\s+)?(` + Identifier + `)\s+(?:SET\s+DATA\s+)?TYPE\s+(.*?)(?:\s+(?:COLLATE|USING)\s+.*)?$`)
+	renameColumn     = regexp.MustCompile(`(?i)^RENAME\s+(?:COLUMN\s+)?(` + Identifier + `)\s+TO\s+(` + Identifier + `)$`)
+	renameTable      = regexp.MustCompile(`(?i)^RENAME\s+TO\s+(` + Identifier + `)$`)
+	addConstraint    = regexp.MustCompile(`(?i)^ADD\s+CONSTRAINT\s+(` + Identifier + `)\s+`)
+	dropConstraint   = regexp.MustCompile(`(?i)^DROP\s+CONSTRAINT\s+(?:IF\s+EXISTS\s+)?(` + Identifier + `)`)
+	columnDefinition = regexp.MustCompile(`(?i)^(` + Identifier + `)\s+(.*)$`)
+	constraintPrefix = regexp.MustCompile(`(?i)^(?:CONSTRAINT|PRIMARY|UNIQUE|FOREIGN|CHECK|EXCLUDE|LIKE)\b`)
+	columnConstraint = regexp.MustCompile(`(?i)^(?:CONSTRAINT|NOT|NULL|DEFAULT|PRIMARY|UNIQUE|REFERENCES|CHECK|GENERATED|COLLATE)$`)
+	argumentDefault  = regexp.MustCompile(`(?i)\s+(?:DEFAULT\s+|=\s*).*$`)
+
+	unquotedIdentifier = regexp.MustCompile(`^[a-z_][a-z0-9_$]*$`)
+)
+
+// Irreversible is a statement for which no down statement can be generated
+type Irreversible struct {
+	Line      int
+	Statement string
+	Reason    string
+}
+
+// Schema tracks the tables, indexes, views and functions created by SQL
+// statements, so that objects dropped by later statements can be restored
+type Schema struct {
+	tables    map[string]*table
+	indexes   map[string]string
+	views     map[string]string
+	functions map[string]string
+}
+
+type table struct {
+	// elements are the column and table constraint definitions
+	elements []string
+	columns  []column
+}
+
+type column struct {
+	name string
+	typ  string
+}
+
+// NewSchema returns an empty schema
+func NewSchema() *Schema {
+	return &Schema{
+		tables:    map[string]*table{},
+		indexes:   map[string]string{},
+		views:     map[string]string{},
+		functions: map[string]string{},
+	}
+}
+
+// Apply records the objects created and dropped by sql
+func (s *Schema) Apply(sql string) {
+	for _, statement := range Split(sql) {
+		s.apply(statement)
+	}
+}
+
+// Down returns the statements which revert the statements of up, in reverse
+// order, along with the statements which cannot be reverted. Objects dropped
+// by up are restored using their definitions recorded by Apply, the objects
+// created and dropped by up are recorded as well.
+func (s *Schema) Down(up string) (string, []Irreversible) {
+	var inverses [][]string
+	var irreversible []Irreversible
+	for _, statement := range Split(up) {
+		inverse, reason := s.inverse(statement)
+		if reason != "" {
+			irreversible = append(irreversible, Irreversible{Line: statement.Line, Statement: statement.Text, Reason: reason})
+			inverse = []string{fmt.Sprintf("-- could not revert the statement on line %d: %s", statement.Line, reason)}
+		}
+		inverses = append(inverses, inverse)
+		s.apply(statement)
+	}
+	var down []string
+	for i := len(inverses) - 1; i >= 0; i-- {
+		down = append(down, inverses[i]...)
+	}
+	if len(down) == 0 {
+		return "", irreversible
+	}
+	return strings.Join(down, "\n") + "\n", irreversible
+}
+
+func (s *Schema) inverse(statement Statement) ([]string, string) {
+	text := statement.Text
+	if m := createTable.FindStringSubmatch(text); m != nil {
+		if m[1] != "" && s.tables[NormalizeName(m[2])] != nil {
+			// CREATE TABLE IF NOT EXISTS of an existing table does nothing
+			return nil, ""
+		}
+		return []string{fmt.Sprintf("DROP TABLE %s;", m[2])}, ""
+	}
+	if m := dropTable.FindStringSubmatch(text); m != nil {
+		var inverse []string
+		for _, name := range SplitTopLevel(m[1]) {
+			t := s.tables[NormalizeName(name)]
+			if t == nil {
+				return nil, fmt.Sprintf("definition of table %s is not known", name)
+			}
+			inverse = append(inverse, fmt.Sprintf("CREATE TABLE %s (%s);", name, strings.Join(t.elements, ", ")))
+		}
+		return inverse, ""
+	}
+	if m := alterTable.FindStringSubmatch(text); m != nil {
+		return s.inverseAlterTable(m[1], SplitTopLevel(m[2]))
+	}
+	if m := createIndex.FindStringSubmatch(text); m != nil {
+		if m[2] == "" {
+			return nil, "index has no name"
+		}
+		return []string{fmt.Sprintf("DROP INDEX %s%s;", m[1], qualify(m[3], m[2]))}, ""
+	}
+	if m := dropIndex.FindStringSubmatch(text); m != nil {
+		return restore(s.indexes, "index", SplitTopLevel(m[1]))
+	}
+	if m := createView.FindStringSubmatch(text); m != nil {
+		kind := "VIEW"
+		if m[2] != "" {
+			kind = "MATERIALIZED VIEW"
+		}
+		inverse := []string{fmt.Sprintf("DROP %s %s;", kind, m[3])}
+		if previous, ok := s.views[NormalizeName(m[3])]; ok && m[1] != "" {
+			inverse = append(inverse, previous+";")
+		}
+		return inverse, ""
+	}
+	if m := dropView.FindStringSubmatch(text); m != nil {
+		return restore(s.views, "view", SplitTopLevel(m[1]))
+	}
+	if m := createFunction.FindStringSubmatch(text); m != nil {
+		arguments, _, ok := Parenthesized(text, len(m[0])-1)
+		if !ok {
+			return nil, "cannot parse function arguments"
+		}
+		inverse := []string{fmt.Sprintf("DROP %s %s(%s);", strings.ToUpper(m[2]), m[3], argumentTypes(arguments))}
+		if previous, ok := s.functions[NormalizeName(m[3])]; ok && m[1] != "" {
+			inverse = append(inverse, previous+";")
+		}
+		return inverse, ""
+	}
+	if m := dropFunction.FindStringSubmatch(text); m != nil {
+		return restore(s.functions, "function", []string{m[1]})
+	}
+	return nil, "statement is not reversible"
+}
+
+func (s *Schema) inverseAlterTable(name string, actions []string) ([]string, string) {
+	t := s.tables[NormalizeName(name)]
+	var inverse []string
+	for _, action := range actions {
+		if m := addColumn.FindStringSubmatch(action); m != nil && (m[1] != "" || !constraintPrefix.MatchString(m[2])) {
+			inverse = append(inverse, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", name, m[2]))
+			continue
+		}
+		if m := addConstraint.FindStringSubmatch(action); m != nil {
+			inverse = append(inverse, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s;", name, m[1]))
+			continue
+		}
+		if m := dropColumn.FindStringSubmatch(action); m != nil && (m[1] != "" || !strings.EqualFold(m[2], "CONSTRAINT")) {
+			var typ string
+			if t != nil {
+				if c := t.column(m[2]); c != nil {
+					typ = c.typ
+				}
+			}
+			if typ == "" {
+				return nil, fmt.Sprintf("type of column %s of table %s is not known", m[2], name)
+			}
+			inverse = append(inverse, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;", name, m[2], typ))
+			continue
+		}
+		return nil, fmt.Sprintf("ALTER TABLE action %q is not reversible", action)
+	}
+	// actions are reverted in reverse order
+	for i, j := 0, len(inverse)-1; i < j; i, j = i+1, j-1 {
+		inverse[i], inverse[j] = inverse[j], inverse[i]
+	}
+	return inverse, ""
+}
+
+// restore returns the recorded definitions of the named objects
+func restore(definitions map[string]string, kind string, names []string) ([]string, string) {
+	var inverse []string
+	for _, name := range names {
+		definition, ok := definitions[NormalizeName(name)]
+		if !ok {
+			return nil, fmt.Sprintf("definition of %s %s is not known", kind, name)
+		}
+		inverse = append(inverse, definition+";")
+	}
+	return inverse, ""
+}
+
+func (s *Schema) apply(statement Statement) {
+	text := statement.Text
+	if m := createTable.FindStringSubmatch(text); m != nil {
+		name := NormalizeName(m[2])
+		if m[1] != "" && s.tables[name] != nil {
+			return
+		}
+		t := &table{}
+		if rest := text[len(m[0]):]; strings.HasPrefix(rest, "(") {
+			if elements, _, ok := Parenthesized(rest, 0); ok {
+				for _, element := range SplitTopLevel(elements) {
+					t.add(element)
+				}
+				s.tables[name] = t
+				return
+			}
+		}
+		// columns of tables created with AS or PARTITION OF are not known
+		delete(s.tables, name)
+		return
+	}
+	if m := dropTable.FindStringSubmatch(text); m != nil {
+		for _, name := range SplitTopLevel(m[1]) {
+			delete(s.tables, NormalizeName(name))
+		}
+		return
+	}
+	if m := alterTable.FindStringSubmatch(text); m != nil {
+		name := NormalizeName(m[1])
+		if t := s.tables[name]; t != nil {
+			for _, action := range SplitTopLevel(m[2]) {
+				if renamed := t.alter(action); renamed != "" {
+					delete(s.tables, name)
+					s.tables[NormalizeName(qualify(m[1], renamed))] = t
+				}
+			}
+		}
+		return
+	}
+	if m := createIndex.FindStringSubmatch(text); m != nil {
+		if m[2] != "" {
+			s.indexes[NormalizeName(qualify(m[3], m[2]))] = statement.Raw
+		}
+		return
+	}
+	if m := dropIndex.FindStringSubmatch(text); m != nil {
+		for _, name := range SplitTopLevel(m[1]) {
+			delete(s.indexes, NormalizeName(name))
+		}
+		return
+	}
+	if m := createView.FindStringSubmatch(text); m != nil {
+		s.views[NormalizeName(m[3])] = statement.Raw
+		return
+	}
+	if m := dropView.FindStringSubmatch(text); m != nil {
+		for _, name := range SplitTopLevel(m[1]) {
+			delete(s.views, NormalizeName(name))
+		}
+		return
+	}
+	if m := createFunction.FindStringSubmatch(text); m != nil {
+		s.functions[NormalizeName(m[3])] = statement.Raw
+		return
+	}
+	if m := dropFunction.FindStringSubmatch(text); m != nil {
+		delete(s.functions, NormalizeName(m[1]))
+	}
+}
+
+// add adds a column or table constraint definition to the table
+func (t *table) add(element string) {
+	t.elements = append(t.elements, element)
+	if constraintPrefix.MatchString(element) {
+		return
+	}
+	if m := columnDefinition.FindStringSubmatch(element); m != nil {
+		t.columns = append(t.columns, column{name: NormalizeIdentifier(m[1]), typ: columnType(m[2])})
+	}
+}
+
+// alter applies an ALTER TABLE action, the new name
+// of the table is returned if the table is renamed
+func (t *table) alter(action string) string {
+	switch {
+	case addColumn.MatchString(action):
+		m := addColumn.FindStringSubmatch(action)
+		if m[1] != "" || !constraintPrefix.MatchString(m[2]) {
+			t.add(m[2] + " " + m[3])
+			return ""
+		}
+		t.elements = append(t.elements, strings.TrimSpace(action[len("ADD"):]))
+	case dropConstraint.MatchString(action):
+		name := NormalizeIdentifier(dropConstraint.FindStringSubmatch(action)[1])
+		for i, element := range t.elements {
+			if m := addConstraint.FindStringSubmatch("ADD " + element); m != nil && NormalizeIdentifier(m[1]) == name {
+				t.elements = append(t.elements[:i], t.elements[i+1:]...)
+				break
+			}
+		}
+	case dropColumn.MatchString(action):
+		m := dropColumn.FindStringSubmatch(action)
+		t.remove(NormalizeIdentifier(m[2]))
+	case alterColumnType.MatchString(action):
+		m := alterColumnType.FindStringSubmatch(action)
+		if c := t.column(m[1]); c != nil {
+			c.typ = m[2]
+			t.replace(c.name, quoteIdentifier(c.name)+" "+c.typ)
+		}
+	case renameColumn.MatchString(action):
+		m := renameColumn.FindStringSubmatch(action)
+		if c := t.column(m[1]); c != nil {
+			name, typ := c.name, c.typ
+			t.remove(name)
+			t.add(m[2] + " " + typ)
+		}
+	case renameTable.MatchString(action):
+		return renameTable.FindStringSubmatch(action)[1]
+	}
+	return ""
+}
+
+func (t *table) column(name string) *column {
+	name = NormalizeIdentifier(name)
+	for i := range t.columns {
+		if t.columns[i].name == name {
+			return &t.columns[i]
+		}
+	}
+	return nil
+}
+
+// remove removes a column and its definition
+func (t *table) remove(name string) {
+	for i, c := range t.columns {
+		if c.name == name {
+			t.columns = append(t.columns[:i], t.columns[i+1:]...)
+			break
+		}
+	}
+	t.replace(name, "")
+}
+
+// replace replaces the definition of a column, it is removed if definition is empty
+func (t *table) replace(name, definition string) {
+	for i, element := range t.elements {
+		if constraintPrefix.MatchString(element) {
+			continue
+		}
+		if m := columnDefinition.FindStringSubmatch(element); m != nil && NormalizeIdentifier(m[1]) == name {
+			if definition == "" {
+				t.elements = append(t.elements[:i], t.elements[i+1:]...)
+			} else {
+				t.elements[i] = definition
+			}
+			return
+		}
+	}
+}
+
+// columnType returns the type of a column definition,
+// which is followed by the constraints of the column
+func columnType(definition string) string {
+	var typ []string
+	for _, word := range strings.Fields(definition) {
+		if columnConstraint.MatchString(word) {
+			break
+		}
+		typ = append(typ, word)
+	}
+	return strings.Join(typ, " ")
+}
+
+// argumentTypes removes the defaults of function arguments,
+// so that they can be used to identify the function
+func argumentTypes(arguments string) string {
+	if strings.TrimSpace(arguments) == "" {
+		return ""
+	}
+	var types []string
+	for _, argument := range SplitTopLevel(arguments) {
+		types = append(types, argumentDefault.ReplaceAllString(argument, ""))
+	}
+	return strings.Join(types, ", ")
+}
+
+// qualify qualifies name with the schema of qualifiedName, if it has one.
+// It is used for indexes which are created in the schema of their table.
+func qualify(qualifiedName, name string) string {
+	parts := identifierPart.FindAllString(qualifiedName, -1)
+	if len(parts) == 2 {
+		return parts[0] + "." + name
+	}
+	return name
+}
+
+func quoteIdentifier(name string) string {
+	if unquotedIdentifier.MatchString(name) {
+		return name
+	}
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
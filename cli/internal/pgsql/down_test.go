@@ -0,0 +1,83 @@
+package pgsql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchema_Down(t *testing.T) {
+	history := `CREATE TABLE public.authors (
+  id serial PRIMARY KEY,
+  name text NOT NULL DEFAULT '',
+  CONSTRAINT authors_name_check CHECK (name <> 'x')
+);
+ALTER TABLE authors ADD COLUMN bio varchar(100) NOT NULL, ADD COLUMN age int;
+ALTER TABLE authors ALTER COLUMN age TYPE bigint USING age::bigint;
+ALTER TABLE authors DROP COLUMN bio;
+CREATE INDEX authors_name_idx ON public.authors (name);
+CREATE VIEW author_names AS SELECT name FROM authors;
+CREATE FUNCTION add(a int, b int) RETURNS int AS $$ SELECT a + b $$ LANGUAGE sql;`
+
+	tests := []struct {
+		name             string
+		up               string
+		wantDown         string
+		wantIrreversible []Irreversible
+	}{
+		{
+			"create and drop table",
+			"CREATE TABLE articles (id serial PRIMARY KEY);\nDROP TABLE authors;",
+			"CREATE TABLE authors (id serial PRIMARY KEY, name text NOT NULL DEFAULT '', CONSTRAINT authors_name_check CHECK (name <> 'x'), age bigint);\nDROP TABLE articles;\n",
+			nil,
+		},
+		{
+			"add and drop columns",
+			"ALTER TABLE authors ADD COLUMN email text, DROP COLUMN age;\nALTER TABLE authors DROP COLUMN name;",
+			"ALTER TABLE authors ADD COLUMN name text;\nALTER TABLE authors ADD COLUMN age bigint;\nALTER TABLE authors DROP COLUMN email;\n",
+			nil,
+		},
+		{
+			"indexes, views and functions",
+			`CREATE UNIQUE INDEX CONCURRENTLY authors_email_key ON public.authors (email);
+DROP INDEX public.authors_name_idx;
+CREATE OR REPLACE VIEW author_names AS SELECT upper(name) AS name FROM authors;
+DROP VIEW author_names;
+CREATE OR REPLACE FUNCTION add(a int, b int DEFAULT 1) RETURNS int AS $$ SELECT a + b + 1 $$ LANGUAGE sql;
+DROP FUNCTION add(int, int);`,
+			`CREATE OR REPLACE FUNCTION add(a int, b int DEFAULT 1) RETURNS int AS $$ SELECT a + b + 1 $$ LANGUAGE sql;
+DROP FUNCTION add(a int, b int);
+CREATE FUNCTION add(a int, b int) RETURNS int AS $$ SELECT a + b $$ LANGUAGE sql;
+CREATE OR REPLACE VIEW author_names AS SELECT upper(name) AS name FROM authors;
+DROP VIEW author_names;
+CREATE VIEW author_names AS SELECT name FROM authors;
+CREATE INDEX authors_name_idx ON public.authors (name);
+DROP INDEX CONCURRENTLY public.authors_email_key;
+`,
+			nil,
+		},
+		{
+			"irreversible statements",
+			"DROP TABLE unknown;\n\nALTER TABLE authors DROP COLUMN missing;\nUPDATE authors SET name = 'a';\nCREATE TABLE t (id int);",
+			`DROP TABLE t;
+-- could not revert the statement on line 4: statement is not reversible
+-- could not revert the statement on line 3: type of column missing of table authors is not known
+-- could not revert the statement on line 1: definition of table unknown is not known
+`,
+			[]Irreversible{
+				{1, "DROP TABLE unknown", "definition of table unknown is not known"},
+				{3, "ALTER TABLE authors DROP COLUMN missing", "type of column missing of table authors is not known"},
+				{4, "UPDATE authors SET name = 'a'", "statement is not reversible"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSchema()
+			s.Apply(history)
+			down, irreversible := s.Down(tt.up)
+			assert.Equal(t, tt.wantDown, down)
+			assert.Equal(t, tt.wantIrreversible, irreversible)
+		})
+	}
+}
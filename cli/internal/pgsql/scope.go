@@ -0,0 +1,117 @@
+package pgsql
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	globalObject    = regexp.MustCompile(`(?i)^(?:(?:CREATE|ALTER|DROP)(?:\s+OR\s+REPLACE)?(?:\s+TRUSTED)?(?:\s+PROCEDURAL)?|COMMENT\s+ON|SECURITY\s+LABEL(?:\s+FOR\s+` + Identifier + `)?\s+ON)\s+(EXTENSION|SCHEMA|ROLE|USER|GROUP|DATABASE|TABLESPACE|PUBLICATION|SUBSCRIPTION|EVENT\s+TRIGGER|FOREIGN\s+DATA\s+WRAPPER|SERVER|LANGUAGE|CAST)\b`)
+	globalStatement = regexp.MustCompile(`(?i)^(?:ALTER\s+SYSTEM|ALTER\s+DEFAULT\s+PRIVILEGES|REASSIGN\s+OWNED|DROP\s+OWNED)\b`)
+	grant           = regexp.MustCompile(`(?i)^(?:GRANT|REVOKE)\b`)
+	onKeyword       = regexp.MustCompile(`(?i)\bON\b`)
+	grantOn         = regexp.MustCompile(`(?i)\bON\s+(?:(?:TABLE|SEQUENCE|FUNCTION|PROCEDURE|ROUTINE|TYPE|DOMAIN|FOREIGN\s+TABLE)\s+)?(` + QualifiedName + `)`)
+	grantInSchema   = regexp.MustCompile(`(?i)\bON\s+(?:SCHEMA|ALL\s+(?:TABLES|SEQUENCES|FUNCTIONS|PROCEDURES|ROUTINES)\s+IN\s+SCHEMA)\s+(` + Identifier + `)`)
+	schemaObject    = regexp.MustCompile(`(?i)^(CREATE|ALTER|DROP)(?:\s+OR\s+REPLACE)?(?:\s+(?:GLOBAL|LOCAL|TEMP|TEMPORARY|UNLOGGED|UNIQUE|RECURSIVE|MATERIALIZED|FOREIGN|CONSTRAINT))*\s+(TABLE|VIEW|SEQUENCE|TYPE|DOMAIN|FUNCTION|PROCEDURE|ROUTINE|AGGREGATE|INDEX|TRIGGER|POLICY|RULE|COLLATION|STATISTICS)\s+(?:CONCURRENTLY\s+)?(?:IF\s+(?:NOT\s+)?EXISTS\s+)?(?:ONLY\s+)?`)
+	onTable         = regexp.MustCompile(`(?i)\bON\s+(?:ONLY\s+)?(` + QualifiedName + `)`)
+	setSchema       = regexp.MustCompile(`(?i)\bSET\s+SCHEMA\s+(` + Identifier + `)`)
+	commentOn       = regexp.MustCompile(`(?i)^COMMENT\s+ON\s+(?:MATERIALIZED\s+|FOREIGN\s+)?(?:TABLE|VIEW|SEQUENCE|TYPE|DOMAIN|FUNCTION|PROCEDURE|ROUTINE|AGGREGATE|INDEX|CONSTRAINT\s+` + Identifier + `\s+ON|TRIGGER\s+` + Identifier + `\s+ON|POLICY\s+` + Identifier + `\s+ON|RULE\s+` + Identifier + `\s+ON)\s+(` + QualifiedName + `)`)
+	commentOnColumn = regexp.MustCompile(`(?i)^COMMENT\s+ON\s+COLUMN\s+(` + QualifiedName + `(?:\s*\.\s*` + Identifier + `)?)`)
+	dataChange      = regexp.MustCompile(`(?i)^(?:INSERT\s+INTO|UPDATE(?:\s+ONLY)?|DELETE\s+FROM(?:\s+ONLY)?|COPY)\s+(` + QualifiedName + `)`)
+	truncate        = regexp.MustCompile(`(?i)^TRUNCATE\s+(?:TABLE\s+)?(.*?)(?:\s+(?:RESTART|CONTINUE)\s+IDENTITY)?(?:\s+(?:CASCADE|RESTRICT))?$`)
+	leadingName     = regexp.MustCompile(`(?i)^(?:ONLY\s+)?(` + QualifiedName + `)`)
+)
+
+// ChangesOutsideSchema returns the statements of sql which change objects
+// that are not in schema: database and cluster wide objects, like schemas,
+// extensions and roles, and objects qualified with another schema.
+// Unqualified names are assumed to be in schema. Statements running code,
+// like DO blocks or function calls, are not checked.
+func ChangesOutsideSchema(sql, schema string) []Statement {
+	var statements []Statement
+	for _, statement := range Split(sql) {
+		if changesOutsideSchema(statement.Text, schema) {
+			statements = append(statements, statement)
+		}
+	}
+	return statements
+}
+
+func changesOutsideSchema(text, schema string) bool {
+	if globalObject.MatchString(text) || globalStatement.MatchString(text) {
+		return true
+	}
+	if grant.MatchString(text) {
+		// GRANT role TO role changes the members of a role
+		if !onKeyword.MatchString(text) {
+			return true
+		}
+		if m := grantInSchema.FindStringSubmatch(text); m != nil {
+			return NormalizeIdentifier(m[1]) != schema
+		}
+		if m := grantOn.FindStringSubmatch(text); m != nil {
+			return outsideSchema(m[1], schema)
+		}
+		return false
+	}
+	var names []string
+	if m := schemaObject.FindStringSubmatch(text); m != nil {
+		rest := text[len(m[0]):]
+		kind := strings.ToUpper(m[2])
+		switch {
+		case strings.ToUpper(m[1]) == "DROP" && kind != "TRIGGER" && kind != "POLICY" && kind != "RULE":
+			for _, part := range SplitTopLevel(rest) {
+				names = append(names, leading(part))
+			}
+		case kind != "INDEX" || strings.ToUpper(m[1]) != "CREATE":
+			// the name of a created index is not qualified, it is created in the schema of its table
+			names = append(names, leading(rest))
+		}
+		if kind == "INDEX" || kind == "TRIGGER" || kind == "POLICY" || kind == "RULE" {
+			if on := onTable.FindStringSubmatch(rest); on != nil {
+				names = append(names, on[1])
+			}
+		}
+		if s := setSchema.FindStringSubmatch(rest); s != nil && NormalizeIdentifier(s[1]) != schema {
+			return true
+		}
+	} else if m := commentOnColumn.FindStringSubmatch(text); m != nil {
+		// a column is qualified with its table, and with the schema of the table
+		if parts := identifierPart.FindAllString(m[1], -1); len(parts) == 3 {
+			names = append(names, parts[0]+"."+parts[1])
+		}
+	} else if m := commentOn.FindStringSubmatch(text); m != nil {
+		names = append(names, m[1])
+	} else if m := dataChange.FindStringSubmatch(text); m != nil {
+		names = append(names, m[1])
+	} else if m := truncate.FindStringSubmatch(text); m != nil {
+		for _, part := range SplitTopLevel(m[1]) {
+			names = append(names, leading(part))
+		}
+	}
+	for _, name := range names {
+		if outsideSchema(name, schema) {
+			return true
+		}
+	}
+	return false
+}
+
+// leading returns the name at the start of s
+func leading(s string) string {
+	if m := leadingName.FindStringSubmatch(s); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// outsideSchema reports whether name is qualified with a schema other than
+// schema, temporary objects are not outside of schema
+func outsideSchema(name, schema string) bool {
+	parts := identifierPart.FindAllString(name, -1)
+	if len(parts) < 2 {
+		return false
+	}
+	qualifier := NormalizeIdentifier(parts[0])
+	return qualifier != schema && qualifier != "pg_temp"
+}
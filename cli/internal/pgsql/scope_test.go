@@ -0,0 +1,46 @@
+package pgsql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChangesOutsideSchema(t *testing.T) {
+	tests := []struct {
+		statement string
+		outside   bool
+	}{
+		{`CREATE TABLE authors (id serial PRIMARY KEY, name text DEFAULT other.f())`, false},
+		{`CREATE TABLE public.authors (id serial PRIMARY KEY, owner_id int REFERENCES other.owners (id))`, false},
+		{`CREATE TABLE IF NOT EXISTS "Other".authors (id int)`, true},
+		{`CREATE UNIQUE INDEX authors_name ON ONLY public.authors (name)`, false},
+		{`CREATE INDEX ON other.authors (name)`, true},
+		{`ALTER TABLE ONLY other.authors ADD COLUMN age int`, true},
+		{`ALTER TABLE authors SET SCHEMA other`, true},
+		{`DROP TABLE authors, other.articles CASCADE`, true},
+		{`DROP TRIGGER audit ON other.authors`, true},
+		{`CREATE OR REPLACE FUNCTION other.f() RETURNS int AS $$ SELECT 1 $$ LANGUAGE sql`, true},
+		{`CREATE VIEW authors_view AS SELECT a.id FROM authors a JOIN other.x x ON x.id = a.id`, false},
+		{`INSERT INTO other.settings VALUES (1)`, true},
+		{`UPDATE public.authors SET name = 'x'`, false},
+		{`TRUNCATE authors, other.articles`, true},
+		{`COMMENT ON COLUMN authors.name IS 'name'`, false},
+		{`COMMENT ON COLUMN other.authors.name IS 'name'`, true},
+		{`COMMENT ON TABLE other.authors IS 'authors'`, true},
+		{`CREATE EXTENSION IF NOT EXISTS pgcrypto`, true},
+		{`CREATE SCHEMA app`, true},
+		{`DROP SCHEMA public CASCADE`, true},
+		{`CREATE ROLE reader`, true},
+		{`GRANT reader TO writer`, true},
+		{`GRANT SELECT ON authors TO reader`, false},
+		{`GRANT USAGE ON SCHEMA other TO reader`, true},
+		{`GRANT SELECT ON ALL TABLES IN SCHEMA public TO reader`, false},
+		{`CREATE TEMP TABLE pg_temp.scratch (id int)`, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.statement, func(t *testing.T) {
+			assert.Equal(t, tc.outside, len(ChangesOutsideSchema(tc.statement, "public")) > 0)
+		})
+	}
+}
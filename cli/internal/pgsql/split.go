@@ -0,0 +1,194 @@
+// Package pgsql has helpers to work with the text of postgres SQL statements
+// without a full parser, like splitting migration files into statements.
+package pgsql
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Statement is a single SQL statement of a file
+type Statement struct {
+	// Text is the statement with comments removed and whitespace collapsed
+	Text string
+	// Raw is the statement as written, without the terminating semicolon
+	Raw string
+	// Line is the line of the file on which the statement starts
+	Line int
+}
+
+var dollarQuoteTag = regexp.MustCompile(`^\$([A-Za-z_][A-Za-z0-9_]*)?\$`)
+
+// Split splits SQL into statements, semicolons inside
+// quoted strings, quoted identifiers and comments are skipped
+func Split(sql string) []Statement {
+	var statements []Statement
+	var b strings.Builder
+	line, start, offset := 1, 0, -1
+
+	write := func(i int, s string) {
+		if start == 0 && strings.TrimSpace(s) != "" {
+			start = line
+			offset = i
+		}
+		b.WriteString(s)
+		line += strings.Count(s, "\n")
+	}
+	skip := func(s string) {
+		b.WriteString(" ")
+		line += strings.Count(s, "\n")
+	}
+	flush := func(end int) {
+		if text := strings.Join(strings.Fields(b.String()), " "); text != "" {
+			statements = append(statements, Statement{
+				Text: text,
+				Raw:  strings.TrimSpace(sql[offset:end]),
+				Line: start,
+			})
+		}
+		b.Reset()
+		start, offset = 0, -1
+	}
+	// quoted returns the length of the quoted token starting at i,
+	// a doubled quote character is an escaped quote
+	quoted := func(i int, quote byte) int {
+		j := i + 1
+		for j < len(sql) {
+			if sql[j] == quote {
+				if j+1 < len(sql) && sql[j+1] == quote {
+					j += 2
+					continue
+				}
+				return j + 1 - i
+			}
+			j++
+		}
+		return len(sql) - i
+	}
+
+	for i := 0; i < len(sql); {
+		rest := sql[i:]
+		switch {
+		case strings.HasPrefix(rest, "--"):
+			end := strings.Index(rest, "\n")
+			if end < 0 {
+				end = len(rest)
+			}
+			skip(rest[:end])
+			i += end
+		case strings.HasPrefix(rest, "/*"):
+			end := strings.Index(rest[2:], "*/")
+			if end < 0 {
+				end = len(rest)
+			} else {
+				end += 4
+			}
+			skip(rest[:end])
+			i += end
+		case rest[0] == '\'' || rest[0] == '"':
+			n := quoted(i, rest[0])
+			write(i, rest[:n])
+			i += n
+		case rest[0] == '$' && dollarQuoteTag.MatchString(rest):
+			tag := dollarQuoteTag.FindString(rest)
+			end := strings.Index(rest[len(tag):], tag)
+			n := len(rest)
+			if end >= 0 {
+				n = len(tag) + end + len(tag)
+			}
+			write(i, rest[:n])
+			i += n
+		case rest[0] == ';':
+			flush(i)
+			i++
+		default:
+			write(i, rest[:1])
+			i++
+		}
+	}
+	flush(len(sql))
+	return statements
+}
+
+// Identifier matches a quoted or unquoted identifier
+const Identifier = `(?:"(?:[^"]|"")+"|[A-Za-z_][A-Za-z0-9_$]*)`
+
+// QualifiedName matches an optionally schema qualified name
+const QualifiedName = Identifier + `(?:\s*\.\s*` + Identifier + `)?`
+
+var identifierPart = regexp.MustCompile(Identifier)
+
+// NormalizeName returns a schema qualified name in which unquoted
+// identifiers are folded to lower case, as postgres does. Names
+// without a schema are assumed to be in the public schema.
+func NormalizeName(name string) string {
+	var parts []string
+	for _, part := range identifierPart.FindAllString(name, -1) {
+		parts = append(parts, NormalizeIdentifier(part))
+	}
+	if len(parts) == 1 {
+		parts = append([]string{"public"}, parts...)
+	}
+	return strings.Join(parts, ".")
+}
+
+// NormalizeIdentifier unquotes a quoted identifier and
+// folds an unquoted identifier to lower case
+func NormalizeIdentifier(identifier string) string {
+	if strings.HasPrefix(identifier, `"`) {
+		return strings.Replace(identifier[1:len(identifier)-1], `""`, `"`, -1)
+	}
+	return strings.ToLower(identifier)
+}
+
+// SplitTopLevel splits s on commas which are not inside parentheses or quotes
+func SplitTopLevel(s string) []string {
+	var parts []string
+	depth, last := 0, 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			parts = append(parts, strings.TrimSpace(s[last:i]))
+			last = i + 1
+		}
+	}
+	return append(parts, strings.TrimSpace(s[last:]))
+}
+
+// Parenthesized returns the text inside the parentheses opening at
+// s[open] and the index following the closing parenthesis
+func Parenthesized(s string, open int) (inner string, end int, ok bool) {
+	depth := 0
+	var quote byte
+	for i := open; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return s[open+1 : i], i + 1, true
+			}
+		}
+	}
+	return "", 0, false
+}
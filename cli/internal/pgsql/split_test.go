@@ -0,0 +1,42 @@
+package pgsql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplit(t *testing.T) {
+	sql := `-- a comment; with a semicolon
+CREATE TABLE "a;b" (
+  id serial /* ; */ PRIMARY KEY,
+  name text DEFAULT 'x;''y'
+);
+
+CREATE FUNCTION f() RETURNS trigger AS $$
+BEGIN
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+DROP TABLE c`
+	want := []Statement{
+		{
+			Text: `CREATE TABLE "a;b" ( id serial PRIMARY KEY, name text DEFAULT 'x;''y' )`,
+			Raw:  "CREATE TABLE \"a;b\" (\n  id serial /* ; */ PRIMARY KEY,\n  name text DEFAULT 'x;''y'\n)",
+			Line: 2,
+		},
+		{
+			Text: "CREATE FUNCTION f() RETURNS trigger AS $$ BEGIN RETURN NEW; END; $$ LANGUAGE plpgsql",
+			Raw:  "CREATE FUNCTION f() RETURNS trigger AS $$\nBEGIN\n  RETURN NEW;\nEND;\n$$ LANGUAGE plpgsql",
+			Line: 7,
+		},
+		{Text: "DROP TABLE c", Raw: "DROP TABLE c", Line: 12},
+	}
+	assert.Equal(t, want, Split(sql))
+}
+
+func TestNormalizeName(t *testing.T) {
+	assert.Equal(t, "public.users", NormalizeName("Users"))
+	assert.Equal(t, "auth.Users", NormalizeName(`AUTH . "Users"`))
+	assert.Equal(t, `public.a"b`, NormalizeName(`"a""b"`))
+}
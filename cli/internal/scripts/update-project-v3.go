@@ -4,6 +4,7 @@ import (
 	"path/filepath"
 	"regexp"
 
+	"github.com/hasura/graphql-engine/cli/v2/internal/hasura"
 	"github.com/hasura/graphql-engine/cli/v2/internal/metadataobject"
 
 	"github.com/hasura/graphql-engine/cli/v2/internal/metadatautil"
@@ -291,7 +292,7 @@ func CopyState(ec *cli.ExecutionContext, sourceDatabase, destDatabase string) er
 	if err := src.PrepareMigrationsStateStore(sourceDatabase); err != nil {
 		return err
 	}
-	dst := migrations.NewCatalogStateStore(statestore.NewCLICatalogState(ec.APIClient.V1Metadata))
+	dst := migrations.NewCatalogStateStore(statestore.NewCLICatalogState(ec.APIClient.V1Metadata), migrations.NewLocker(ec.APIClient.V2Query, hasura.SourceKindPG, migrations.DefaultSchema, migrations.DefaultMigrationsLockTable))
 	if err := dst.PrepareMigrationsStateStore(destDatabase); err != nil {
 		return err
 	}
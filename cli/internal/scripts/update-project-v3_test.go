@@ -256,11 +256,11 @@ func Test_copyState(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			srcSettings := cli.GetSettingsStateStore(tt.args.ec, "default")
 			assert.NoError(t, srcSettings.PrepareSettingsDriver())
-			srcMigrations := cli.GetMigrationsStateStore(tt.args.ec)
+			srcMigrations := cli.GetMigrationsStateStore(tt.args.ec, hasura.SourceKindPG)
 			assert.NoError(t, srcMigrations.PrepareMigrationsStateStore("default"))
 
 			dstSettings := settings.NewStateStoreCatalog(statestore.NewCLICatalogState(tt.args.ec.APIClient.V1Metadata))
-			dstMigrations := migrations.NewCatalogStateStore(statestore.NewCLICatalogState(tt.args.ec.APIClient.V1Metadata))
+			dstMigrations := migrations.NewCatalogStateStore(statestore.NewCLICatalogState(tt.args.ec.APIClient.V1Metadata), migrations.NewLocker(tt.args.ec.APIClient.V2Query, hasura.SourceKindPG, migrations.DefaultSchema, migrations.DefaultMigrationsLockTable))
 			assert.NoError(t, srcSettings.UpdateSetting("test", "test"))
 			assert.NoError(t, srcMigrations.SetVersion("", 123, false))
 			if err := CopyState(tt.args.ec, "default", tt.args.destdatabase); (err != nil) != tt.wantErr {
@@ -12,7 +12,8 @@ import (
 // from v1.4 clients are expected to make use of the catalog API
 // rather than assuming a SQL backend for metadata storage
 type CatalogStateStore struct {
-	c *statestore.CLICatalogState
+	c      *statestore.CLICatalogState
+	locker Locker
 }
 
 func (m *CatalogStateStore) getCLIState() (*statestore.CLIState, error) {
@@ -32,8 +33,8 @@ func (m *CatalogStateStore) setCLIState(state statestore.CLIState) error {
 	return nil
 }
 
-func NewCatalogStateStore(c *statestore.CLICatalogState) *CatalogStateStore {
-	return &CatalogStateStore{c}
+func NewCatalogStateStore(c *statestore.CLICatalogState, locker Locker) *CatalogStateStore {
+	return &CatalogStateStore{c, locker}
 }
 
 func (m *CatalogStateStore) InsertVersion(database string, version int64) error {
@@ -68,6 +69,32 @@ func (m *CatalogStateStore) RemoveVersion(database string, version int64) error
 	return m.setCLIState(*state)
 }
 
+func (m *CatalogStateStore) SetChecksum(database string, version int64, checksum string) error {
+	state, err := m.getCLIState()
+	if err != nil {
+		return err
+	}
+	versionString := fmt.Sprintf("%d", version)
+	state.SetMigrationChecksum(database, versionString, checksum)
+	return m.setCLIState(*state)
+}
+
+func (m *CatalogStateStore) GetChecksums(database string) (map[uint64]string, error) {
+	state, err := m.getCLIState()
+	if err != nil {
+		return nil, err
+	}
+	var checksums = map[uint64]string{}
+	for version, checksum := range state.GetMigrationChecksumsByDatabase(database) {
+		parsedVersion, err := strconv.ParseUint(version, 10, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing migration version")
+		}
+		checksums[parsedVersion] = checksum
+	}
+	return checksums, nil
+}
+
 func (m *CatalogStateStore) PrepareMigrationsStateStore(_ string) error {
 	return nil
 }
@@ -87,3 +114,27 @@ func (m *CatalogStateStore) GetVersions(database string) (map[uint64]bool, error
 	}
 	return versions, nil
 }
+
+// TryLock takes the lock in the lock table of the database rather than in the
+// catalog state, the catalog state is read and written as a whole and cannot
+// be changed atomically. The lock table is created when it is first needed.
+func (m *CatalogStateStore) TryLock(database string, lock statestore.MigrationsLock) (*statestore.MigrationsLock, error) {
+	if err := m.locker.Prepare(database); err != nil {
+		return nil, err
+	}
+	return m.locker.TryLock(database, lock)
+}
+
+func (m *CatalogStateStore) Unlock(database string, id string) error {
+	if err := m.locker.Prepare(database); err != nil {
+		return err
+	}
+	return m.locker.Unlock(database, id)
+}
+
+func (m *CatalogStateStore) GetLock(database string) (*statestore.MigrationsLock, error) {
+	if err := m.locker.Prepare(database); err != nil {
+		return nil, err
+	}
+	return m.locker.GetLock(database)
+}
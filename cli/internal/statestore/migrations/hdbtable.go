@@ -6,12 +6,15 @@ import (
 
 	"github.com/hasura/graphql-engine/cli/v2/internal/hasura"
 
+	"github.com/hasura/graphql-engine/cli/v2/internal/statestore"
 	"github.com/hasura/graphql-engine/cli/v2/migrate/database"
 )
 
 const (
 	DefaultMigrationsTable = "schema_migrations"
 	DefaultSchema          = "hdb_catalog"
+	// DefaultMigrationsLockTable holds the migrations lock of a database
+	DefaultMigrationsLockTable = DefaultMigrationsTable + "_lock"
 )
 
 // until version 1.4 migration state was stored a special table
@@ -19,10 +22,11 @@ const (
 type MigrationStateStoreHdbTable struct {
 	client        hasura.PGSourceOps
 	schema, table string
+	lock          *PGLock
 }
 
 func NewMigrationStateStoreHdbTable(client hasura.PGSourceOps, schema, table string) *MigrationStateStoreHdbTable {
-	return &MigrationStateStoreHdbTable{client, schema, table}
+	return &MigrationStateStoreHdbTable{client, schema, table, NewPGLock(client, schema, table+"_lock")}
 }
 
 func (m *MigrationStateStoreHdbTable) InsertVersion(sourceName string, version int64) error {
@@ -63,7 +67,48 @@ func (m *MigrationStateStoreHdbTable) RemoveVersion(sourceName string, version i
 	return nil
 }
 
+func (m *MigrationStateStoreHdbTable) SetChecksum(sourceName string, version int64, checksum string) error {
+	query := hasura.PGRunSQLInput{
+		Source: sourceName,
+		SQL:    `UPDATE ` + fmt.Sprintf("%s.%s", m.schema, m.table) + ` SET checksum = '` + checksum + `' WHERE version = ` + strconv.FormatInt(version, 10),
+	}
+	_, err := m.client.PGRunSQL(query)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *MigrationStateStoreHdbTable) GetChecksums(sourceName string) (map[uint64]string, error) {
+	query := hasura.PGRunSQLInput{
+		SQL:    `SELECT version, checksum FROM ` + fmt.Sprintf("%s.%s", m.schema, m.table) + ` WHERE checksum IS NOT NULL`,
+		Source: sourceName,
+	}
+
+	runsqlResp, err := m.client.PGRunSQL(query)
+	if err != nil {
+		return nil, err
+	}
+
+	var checksums = map[uint64]string{}
+	for index, val := range runsqlResp.Result {
+		if index == 0 {
+			continue
+		}
+
+		version, err := strconv.ParseInt(val[0], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		checksums[uint64(version)] = val[1]
+	}
+	return checksums, nil
+}
+
 func (m *MigrationStateStoreHdbTable) PrepareMigrationsStateStore(sourceName string) error {
+	if err := m.lock.Prepare(sourceName); err != nil {
+		return err
+	}
 	// check if migration table exists
 	query := hasura.PGRunSQLInput{
 		Source: sourceName,
@@ -80,13 +125,19 @@ func (m *MigrationStateStoreHdbTable) PrepareMigrationsStateStore(sourceName str
 	}
 	result := runsqlResp.Result
 	if result[1][0] != "0" {
-		return nil
+		// tables created by older versions of the cli do not have the checksum column
+		query = hasura.PGRunSQLInput{
+			Source: sourceName,
+			SQL:    `ALTER TABLE ` + fmt.Sprintf("%s.%s", m.schema, m.table) + ` ADD COLUMN IF NOT EXISTS checksum text`,
+		}
+		_, err = m.client.PGRunSQL(query)
+		return err
 	}
 
 	// Now Create the table
 	query = hasura.PGRunSQLInput{
 		Source: sourceName,
-		SQL:    `CREATE TABLE ` + fmt.Sprintf("%s.%s", m.schema, m.table) + ` (version bigint not null primary key, dirty boolean not null)`,
+		SQL:    `CREATE TABLE ` + fmt.Sprintf("%s.%s", m.schema, m.table) + ` (version bigint not null primary key, dirty boolean not null, checksum text)`,
 	}
 
 	runsqlResp, err = m.client.PGRunSQL(query)
@@ -134,3 +185,15 @@ func (m *MigrationStateStoreHdbTable) GetVersions(sourceName string) (map[uint64
 	}
 	return versions, nil
 }
+
+func (m *MigrationStateStoreHdbTable) TryLock(sourceName string, lock statestore.MigrationsLock) (*statestore.MigrationsLock, error) {
+	return m.lock.TryLock(sourceName, lock)
+}
+
+func (m *MigrationStateStoreHdbTable) Unlock(sourceName string, id string) error {
+	return m.lock.Unlock(sourceName, id)
+}
+
+func (m *MigrationStateStoreHdbTable) GetLock(sourceName string) (*statestore.MigrationsLock, error) {
+	return m.lock.GetLock(sourceName)
+}
@@ -0,0 +1,224 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hasura/graphql-engine/cli/v2/internal/hasura"
+	"github.com/hasura/graphql-engine/cli/v2/internal/statestore"
+)
+
+// migrationsLockKey is the key of the single row of the lock table
+const migrationsLockKey = "migrations"
+
+// Locker holds the migrations lock of a database as a row of a table in the
+// database. The row is inserted by a single statement which does nothing if
+// the row exists, so that only one of concurrent processes can insert it.
+type Locker interface {
+	// Prepare creates the lock table if it does not exist
+	Prepare(database string) error
+	TryLock(database string, lock statestore.MigrationsLock) (holder *statestore.MigrationsLock, err error)
+	Unlock(database string, id string) error
+	GetLock(database string) (*statestore.MigrationsLock, error)
+}
+
+// NewLocker returns the locker of a database of the given kind
+func NewLocker(client hasura.V2Query, kind hasura.SourceKind, schema, table string) Locker {
+	if kind == hasura.SourceKindMSSQL {
+		return NewMSSQLLock(client, schema, table)
+	}
+	return NewPGLock(client, schema, table)
+}
+
+// PGLock is the migrations lock of a postgres database
+type PGLock struct {
+	client        hasura.PGSourceOps
+	schema, table string
+}
+
+func NewPGLock(client hasura.PGSourceOps, schema, table string) *PGLock {
+	return &PGLock{client, schema, table}
+}
+
+func (l *PGLock) lockTable() string {
+	return fmt.Sprintf("%s.%s", l.schema, l.table)
+}
+
+func (l *PGLock) Prepare(database string) error {
+	query := hasura.PGRunSQLInput{
+		Source: database,
+		SQL: `CREATE SCHEMA IF NOT EXISTS ` + l.schema + `; ` +
+			`CREATE TABLE IF NOT EXISTS ` + l.lockTable() + ` (lock_key text not null primary key, id text not null, owner text not null, acquired_at timestamptz not null)`,
+	}
+	_, err := l.client.PGRunSQL(query)
+	return err
+}
+
+// TryLock inserts the lock row unless it exists, the row is
+// inserted and read back in a single statement
+func (l *PGLock) TryLock(database string, lock statestore.MigrationsLock) (*statestore.MigrationsLock, error) {
+	return tryLock(lock, func() (*statestore.MigrationsLock, error) {
+		query := hasura.PGRunSQLInput{
+			Source: database,
+			SQL: `WITH inserted AS (INSERT INTO ` + l.lockTable() + ` (lock_key, id, owner, acquired_at) VALUES (` +
+				strings.Join([]string{quoteLiteral(migrationsLockKey), quoteLiteral(lock.ID), quoteLiteral(lock.Owner), quoteLiteral(lock.AcquiredAt.UTC().Format(time.RFC3339))}, ", ") +
+				`) ON CONFLICT (lock_key) DO NOTHING RETURNING id, owner, acquired_at) ` +
+				`SELECT id, owner, ` + formatTimestamp("acquired_at") + ` FROM inserted UNION ALL ` +
+				`SELECT id, owner, ` + formatTimestamp("acquired_at") + ` FROM ` + l.lockTable() + ` WHERE lock_key = ` + quoteLiteral(migrationsLockKey) + ` AND NOT EXISTS (SELECT 1 FROM inserted)`,
+		}
+		runsqlResp, err := l.client.PGRunSQL(query)
+		if err != nil {
+			return nil, err
+		}
+		return parseLock(runsqlResp.Result)
+	})
+}
+
+func (l *PGLock) Unlock(database string, id string) error {
+	sql := `DELETE FROM ` + l.lockTable() + ` WHERE lock_key = ` + quoteLiteral(migrationsLockKey)
+	if id != "" {
+		sql += ` AND id = ` + quoteLiteral(id)
+	}
+	_, err := l.client.PGRunSQL(hasura.PGRunSQLInput{Source: database, SQL: sql})
+	return err
+}
+
+func (l *PGLock) GetLock(database string) (*statestore.MigrationsLock, error) {
+	query := hasura.PGRunSQLInput{
+		Source: database,
+		SQL:    `SELECT id, owner, ` + formatTimestamp("acquired_at") + ` FROM ` + l.lockTable() + ` WHERE lock_key = ` + quoteLiteral(migrationsLockKey),
+	}
+	runsqlResp, err := l.client.PGRunSQL(query)
+	if err != nil {
+		return nil, err
+	}
+	return parseLock(runsqlResp.Result)
+}
+
+// MSSQLLock is the migrations lock of a SQL Server database, SQL Server has
+// no ON CONFLICT so the row is inserted with a NOT EXISTS check holding a
+// range lock on the key until the insert is done
+type MSSQLLock struct {
+	client        hasura.MSSQLSourceOps
+	schema, table string
+}
+
+func NewMSSQLLock(client hasura.MSSQLSourceOps, schema, table string) *MSSQLLock {
+	return &MSSQLLock{client, schema, table}
+}
+
+func (l *MSSQLLock) lockTable() string {
+	return fmt.Sprintf("%s.%s", l.schema, l.table)
+}
+
+func (l *MSSQLLock) run(database, sql string) ([][]string, error) {
+	runsqlResp, err := l.client.MSSQLRunSQL(hasura.MSSQLRunSQLInput{Source: database, SQL: sql})
+	if err != nil {
+		return nil, err
+	}
+	var result [][]string
+	for _, row := range runsqlResp.Result {
+		var values []string
+		for _, value := range row {
+			values = append(values, fmt.Sprint(value))
+		}
+		result = append(result, values)
+	}
+	return result, nil
+}
+
+func (l *MSSQLLock) Prepare(database string) error {
+	_, err := l.run(database,
+		`IF SCHEMA_ID(`+quoteMSSQLLiteral(l.schema)+`) IS NULL EXEC(`+quoteMSSQLLiteral("CREATE SCHEMA "+l.schema)+`); `+
+			`IF OBJECT_ID(`+quoteMSSQLLiteral(l.lockTable())+`, N'U') IS NULL `+
+			`CREATE TABLE `+l.lockTable()+` (lock_key nvarchar(64) not null primary key, id nvarchar(256) not null, owner nvarchar(max) not null, acquired_at datetime2(0) not null)`)
+	return err
+}
+
+// TryLock inserts the lock row unless it exists and reads back the holder of the lock
+func (l *MSSQLLock) TryLock(database string, lock statestore.MigrationsLock) (*statestore.MigrationsLock, error) {
+	return tryLock(lock, func() (*statestore.MigrationsLock, error) {
+		key := quoteMSSQLLiteral(migrationsLockKey)
+		_, err := l.run(database,
+			`INSERT INTO `+l.lockTable()+` (lock_key, id, owner, acquired_at) SELECT `+
+				strings.Join([]string{key, quoteMSSQLLiteral(lock.ID), quoteMSSQLLiteral(lock.Owner), quoteMSSQLLiteral(lock.AcquiredAt.UTC().Format("2006-01-02T15:04:05"))}, ", ")+
+				` WHERE NOT EXISTS (SELECT 1 FROM `+l.lockTable()+` WITH (UPDLOCK, HOLDLOCK) WHERE lock_key = `+key+`)`)
+		if err != nil {
+			return nil, err
+		}
+		return l.GetLock(database)
+	})
+}
+
+func (l *MSSQLLock) Unlock(database string, id string) error {
+	sql := `DELETE FROM ` + l.lockTable() + ` WHERE lock_key = ` + quoteMSSQLLiteral(migrationsLockKey)
+	if id != "" {
+		sql += ` AND id = ` + quoteMSSQLLiteral(id)
+	}
+	_, err := l.run(database, sql)
+	return err
+}
+
+func (l *MSSQLLock) GetLock(database string) (*statestore.MigrationsLock, error) {
+	result, err := l.run(database, l.selectLock())
+	if err != nil {
+		return nil, err
+	}
+	return parseLock(result)
+}
+
+func (l *MSSQLLock) selectLock() string {
+	return `SELECT id, owner, CONVERT(nvarchar(19), acquired_at, 126) + N'Z' FROM ` + l.lockTable() + ` WHERE lock_key = ` + quoteMSSQLLiteral(migrationsLockKey)
+}
+
+// tryLock returns the holder of the lock after an attempt to take it, nil if
+// the attempt took it. The attempt does not see a row inserted by a process
+// which took the lock while the attempt was waiting for it, or the row can be
+// deleted right after the attempt, then there is no holder and it is retried.
+func tryLock(lock statestore.MigrationsLock, attempt func() (*statestore.MigrationsLock, error)) (*statestore.MigrationsLock, error) {
+	for i := 0; i < 3; i++ {
+		holder, err := attempt()
+		if err != nil {
+			return nil, err
+		}
+		if holder == nil {
+			continue
+		}
+		if holder.ID == lock.ID {
+			return nil, nil
+		}
+		return holder, nil
+	}
+	return nil, errors.New("migrations lock was released while it was being taken")
+}
+
+// parseLock reads the lock from the id, owner and acquired_at
+// columns of a run_sql result, it is nil if there are no rows
+func parseLock(result [][]string) (*statestore.MigrationsLock, error) {
+	if len(result) < 2 {
+		return nil, nil
+	}
+	row := result[1]
+	if len(row) != 3 {
+		return nil, fmt.Errorf("unexpected migrations lock row: %v", row)
+	}
+	acquiredAt, err := time.Parse(time.RFC3339, row[2])
+	if err != nil {
+		return nil, fmt.Errorf("parsing migrations lock time: %w", err)
+	}
+	return &statestore.MigrationsLock{ID: row[0], Owner: row[1], AcquiredAt: acquiredAt}, nil
+}
+
+func formatTimestamp(column string) string {
+	return `to_char(` + column + ` AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"')`
+}
+
+func quoteLiteral(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+func quoteMSSQLLiteral(s string) string {
+	return "N" + quoteLiteral(s)
+}
@@ -0,0 +1,180 @@
+package migrations
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hasura/graphql-engine/cli/v2/internal/hasura"
+	"github.com/hasura/graphql-engine/cli/v2/internal/statestore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCatalogState keeps the catalog state in memory
+type fakeCatalogState struct {
+	state json.RawMessage
+}
+
+func (f *fakeCatalogState) Set(key string, state interface{}) (io.Reader, error) {
+	b, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	f.state = b
+	return bytes.NewReader(nil), nil
+}
+
+func (f *fakeCatalogState) Get() (io.Reader, error) {
+	state := f.state
+	if state == nil {
+		state = json.RawMessage(`{}`)
+	}
+	b, err := json.Marshal(map[string]json.RawMessage{"cli_state": state})
+	return bytes.NewReader(b), err
+}
+
+var (
+	insertedLock = regexp.MustCompile(`VALUES \('migrations', '([^']*)', '([^']*)', '([^']*)'\) ON CONFLICT \(lock_key\) DO NOTHING`)
+	deletedLock  = regexp.MustCompile(`AND id = '([^']*)'`)
+)
+
+// fakeLockTable keeps the lock table of each database in memory, statements
+// run one at a time like the single statements run_sql runs on postgres
+type fakeLockTable struct {
+	mu   sync.Mutex
+	rows map[string][]string
+}
+
+func newFakeLockTable() *fakeLockTable {
+	return &fakeLockTable{rows: map[string][]string{}}
+}
+
+func (f *fakeLockTable) PGRunSQL(input hasura.PGRunSQLInput) (*hasura.PGRunSQLOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	result := [][]string{{"id", "owner", "acquired_at"}}
+	switch {
+	case strings.HasPrefix(input.SQL, "CREATE"):
+		return &hasura.PGRunSQLOutput{ResultType: hasura.CommandOK}, nil
+	case strings.HasPrefix(input.SQL, "DELETE"):
+		if m := deletedLock.FindStringSubmatch(input.SQL); m == nil || (f.rows[input.Source] != nil && f.rows[input.Source][0] == m[1]) {
+			delete(f.rows, input.Source)
+		}
+		return &hasura.PGRunSQLOutput{ResultType: hasura.CommandOK}, nil
+	case strings.HasPrefix(input.SQL, "WITH inserted"):
+		m := insertedLock.FindStringSubmatch(input.SQL)
+		if m == nil {
+			return nil, fmt.Errorf("unexpected statement: %s", input.SQL)
+		}
+		if f.rows[input.Source] == nil {
+			f.rows[input.Source] = m[1:]
+		}
+	}
+	if row := f.rows[input.Source]; row != nil {
+		result = append(result, row)
+	}
+	return &hasura.PGRunSQLOutput{ResultType: hasura.TuplesOK, Result: result}, nil
+}
+
+func TestCatalogStateStore_TryLock(t *testing.T) {
+	store := NewCatalogStateStore(statestore.NewCLICatalogState(&fakeCatalogState{}), NewPGLock(newFakeLockTable(), DefaultSchema, DefaultMigrationsLockTable))
+	first := statestore.MigrationsLock{ID: "1", Owner: "ci@runner-1 pid 10", AcquiredAt: time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)}
+	second := statestore.MigrationsLock{ID: "2", Owner: "ci@runner-2 pid 20", AcquiredAt: time.Date(2021, 6, 1, 10, 1, 0, 0, time.UTC)}
+
+	holder, err := store.TryLock("default", first)
+	require.NoError(t, err)
+	assert.Nil(t, holder)
+
+	// the lock of a database does not affect other databases
+	holder, err = store.TryLock("other", second)
+	require.NoError(t, err)
+	assert.Nil(t, holder)
+	require.NoError(t, store.Unlock("other", second.ID))
+
+	holder, err = store.TryLock("default", second)
+	require.NoError(t, err)
+	assert.Equal(t, &first, holder)
+
+	// the lock can be released only by its holder
+	require.NoError(t, store.Unlock("default", second.ID))
+	holder, err = store.GetLock("default")
+	require.NoError(t, err)
+	assert.Equal(t, &first, holder)
+
+	// versions recorded while the lock is held keep the lock
+	require.NoError(t, store.SetVersion("default", 1, false))
+	holder, err = store.GetLock("default")
+	require.NoError(t, err)
+	assert.Equal(t, &first, holder)
+	require.NoError(t, store.Unlock("default", first.ID))
+	holder, err = store.GetLock("default")
+	require.NoError(t, err)
+	assert.Nil(t, holder)
+
+	holder, err = store.TryLock("default", second)
+	require.NoError(t, err)
+	assert.Nil(t, holder)
+	require.NoError(t, store.Unlock("default", ""))
+	holder, err = store.GetLock("default")
+	require.NoError(t, err)
+	assert.Nil(t, holder)
+}
+
+func TestCatalogStateStore_TryLockConcurrently(t *testing.T) {
+	locks := newFakeLockTable()
+	var wg sync.WaitGroup
+	holders := make([]*statestore.MigrationsLock, 10)
+	errs := make([]error, 10)
+	for i := range holders {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// every process has its own store, like concurrent migrate apply runs
+			store := NewCatalogStateStore(statestore.NewCLICatalogState(&fakeCatalogState{}), NewPGLock(locks, DefaultSchema, DefaultMigrationsLockTable))
+			lock := statestore.MigrationsLock{ID: fmt.Sprint(i), Owner: fmt.Sprintf("ci@runner-%d pid %d", i, i), AcquiredAt: time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)}
+			holders[i], errs[i] = store.TryLock("default", lock)
+		}(i)
+	}
+	wg.Wait()
+
+	var taken []int
+	for i := range holders {
+		require.NoError(t, errs[i])
+		if holders[i] == nil {
+			taken = append(taken, i)
+		}
+	}
+	require.Len(t, taken, 1)
+	for i, holder := range holders {
+		if i != taken[0] {
+			assert.Equal(t, fmt.Sprint(taken[0]), holder.ID)
+		}
+	}
+}
+
+func TestTryLock_RetriesWithoutHolder(t *testing.T) {
+	lock := statestore.MigrationsLock{ID: "1"}
+	other := &statestore.MigrationsLock{ID: "2"}
+
+	// the holder inserted the row while the attempt waited for it, the attempt did not see it
+	attempts := []*statestore.MigrationsLock{nil, other}
+	holder, err := tryLock(lock, func() (*statestore.MigrationsLock, error) {
+		holder := attempts[0]
+		attempts = attempts[1:]
+		return holder, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, other, holder)
+
+	_, err = tryLock(lock, func() (*statestore.MigrationsLock, error) {
+		return nil, nil
+	})
+	assert.Error(t, err)
+}
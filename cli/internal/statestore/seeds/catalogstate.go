@@ -0,0 +1,47 @@
+package seeds
+
+import (
+	"github.com/hasura/graphql-engine/cli/v2/internal/statestore"
+)
+
+// CatalogStateStore records the applied seed files in the settings of the
+// catalog state, a setting per database
+type CatalogStateStore struct {
+	c *statestore.CLICatalogState
+}
+
+func NewCatalogStateStore(c *statestore.CLICatalogState) *CatalogStateStore {
+	return &CatalogStateStore{c}
+}
+
+func (s *CatalogStateStore) getCLIState() (*statestore.CLIState, error) {
+	state, err := s.c.Get()
+	if err != nil {
+		return nil, err
+	}
+	state.Init()
+	return state, nil
+}
+
+func (s *CatalogStateStore) GetSeeds(database string) (map[string]statestore.AppliedSeed, error) {
+	state, err := s.getCLIState()
+	if err != nil {
+		return nil, err
+	}
+	return state.GetSeedsByDatabase(database)
+}
+
+func (s *CatalogStateStore) SetSeeds(database string, seeds map[string]statestore.AppliedSeed) error {
+	if len(seeds) == 0 {
+		return nil
+	}
+	state, err := s.getCLIState()
+	if err != nil {
+		return err
+	}
+	if err := state.SetSeeds(database, seeds); err != nil {
+		return err
+	}
+	_, err = s.c.Set(*state)
+	return err
+}
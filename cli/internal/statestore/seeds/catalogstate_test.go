@@ -0,0 +1,68 @@
+package seeds
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/hasura/graphql-engine/cli/v2/internal/statestore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCatalogState keeps the cli catalog state in memory like the server
+type fakeCatalogState struct {
+	state []byte
+	sets  int
+}
+
+func (f *fakeCatalogState) Get() (io.Reader, error) {
+	return bytes.NewReader([]byte(`{"cli_state": ` + string(f.state) + `}`)), nil
+}
+
+func (f *fakeCatalogState) Set(key string, state interface{}) (io.Reader, error) {
+	b, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	f.state = b
+	f.sets++
+	return bytes.NewReader([]byte(`{"message": "success"}`)), nil
+}
+
+func TestCatalogStateStore_SetSeeds(t *testing.T) {
+	catalogState := &fakeCatalogState{state: []byte(`{"settings": {"migration_mode": "true"}}`)}
+	store := NewCatalogStateStore(statestore.NewCLICatalogState(catalogState))
+	appliedAt := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	// reading the applied seed files does not write the catalog state
+	seeds, err := store.GetSeeds("default")
+	require.NoError(t, err)
+	assert.Empty(t, seeds)
+	assert.Equal(t, 0, catalogState.sets)
+
+	require.NoError(t, store.SetSeeds("default", map[string]statestore.AppliedSeed{
+		"1_authors.sql":       {Checksum: "a", AppliedAt: appliedAt, Dirty: true},
+		"public.articles.csv": {Checksum: "b", AppliedAt: appliedAt},
+	}))
+	// a seed file applied again is recorded with its new state
+	require.NoError(t, store.SetSeeds("default", map[string]statestore.AppliedSeed{
+		"1_authors.sql": {Checksum: "c", AppliedAt: appliedAt.Add(time.Hour)},
+	}))
+	seeds, err = store.GetSeeds("default")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]statestore.AppliedSeed{
+		"1_authors.sql":       {Checksum: "c", AppliedAt: appliedAt.Add(time.Hour)},
+		"public.articles.csv": {Checksum: "b", AppliedAt: appliedAt},
+	}, seeds)
+
+	// the seed files of a database do not affect other databases or settings
+	seeds, err = store.GetSeeds("other")
+	require.NoError(t, err)
+	assert.Empty(t, seeds)
+	state, err := statestore.NewCLICatalogState(catalogState).Get()
+	require.NoError(t, err)
+	assert.Equal(t, "true", state.GetSetting("migration_mode"))
+}
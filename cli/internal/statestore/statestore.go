@@ -2,7 +2,9 @@ package statestore
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
+	"time"
 
 	"github.com/hasura/graphql-engine/cli/v2/internal/hasura"
 )
@@ -13,8 +15,48 @@ type MigrationsStateStore interface {
 	RemoveVersion(database string, version int64) error
 	SetVersion(database string, version int64, dirty bool) error
 	GetVersions(database string) (map[uint64]bool, error)
+	// SetChecksum records a hash of the contents of an applied migration
+	SetChecksum(database string, version int64, checksum string) error
+	GetChecksums(database string) (map[uint64]string, error)
 
 	PrepareMigrationsStateStore(database string) error
+
+	// TryLock takes the migrations lock of database. If the lock is held by
+	// another process it is left untouched and its holder is returned.
+	TryLock(database string, lock MigrationsLock) (holder *MigrationsLock, err error)
+	// Unlock releases the migrations lock of database if it is held by the lock
+	// with the given id, the lock is released regardless of its holder if id is empty.
+	Unlock(database string, id string) error
+	// GetLock returns the holder of the migrations lock of database, nil if it is not held
+	GetLock(database string) (*MigrationsLock, error)
+}
+
+// MigrationsLock is held by a process while it applies migrations on a database,
+// so that concurrent processes cannot apply migrations at the same time
+type MigrationsLock struct {
+	// ID identifies the process holding the lock
+	ID string `json:"id" mapstructure:"id"`
+	// Owner describes the process holding the lock for humans, like user@host (pid 123)
+	Owner      string    `json:"owner" mapstructure:"owner"`
+	AcquiredAt time.Time `json:"acquiredAt" mapstructure:"acquiredAt"`
+}
+
+// Abstraction for the storage layer of the state of the applied seed files
+type SeedsStateStore interface {
+	// GetSeeds returns the seed files applied on database by name
+	GetSeeds(database string) (map[string]AppliedSeed, error)
+	// SetSeeds records the seed files applied on database
+	SetSeeds(database string, seeds map[string]AppliedSeed) error
+}
+
+// AppliedSeed is a seed file applied on a database
+type AppliedSeed struct {
+	// Checksum is a hash of the contents of the file when it was applied
+	Checksum  string    `json:"checksum" mapstructure:"checksum"`
+	AppliedAt time.Time `json:"appliedAt" mapstructure:"appliedAt"`
+	// Dirty is set while the file is applied, it stays set if applying the
+	// file or recording it as applied failed
+	Dirty bool `json:"dirty,omitempty" mapstructure:"dirty,omitempty"`
 }
 
 // Abstraction for storage layer of CLI settings
@@ -58,9 +100,19 @@ func (c *CLICatalogState) Set(state CLIState) (io.Reader, error) {
 //		"12321312321321321": true
 type MigrationsState map[string]map[string]bool
 
+//
+// "default:
+//		Version			     Checksum
+//		--------------------------
+//		"12321312321321321": "9f86d081884c7d659a2feaa0c55ad015..."
+type MigrationChecksums map[string]map[string]string
+
 type CLIState struct {
 	Migrations MigrationsState   `json:"migrations,omitempty" mapstructure:"migrations,omitempty"`
 	Settings   map[string]string `json:"settings" mapstructure:"settings"`
+	// MigrationChecksums holds a hash of the contents of each applied migration
+	// this is used to find migrations which were modified after being applied
+	MigrationChecksums MigrationChecksums `json:"migrationChecksums,omitempty" mapstructure:"migrationChecksums,omitempty"`
 	// IsStateCopyCompleted is a utility variable
 	// pre config v3 state was stored in users database connected to hasura in `hdb_catalog.*` tables
 	// this variable is set to true when state copy happens from hdb_catalog.* tables
@@ -76,6 +128,9 @@ func (c *CLIState) Init() {
 	if c.Settings == nil {
 		c.Settings = map[string]string{}
 	}
+	if c.MigrationChecksums == nil {
+		c.MigrationChecksums = map[string]map[string]string{}
+	}
 }
 func (c *CLIState) SetMigration(database, key string, value bool) {
 	if c.Migrations[database] == nil {
@@ -86,6 +141,21 @@ func (c *CLIState) SetMigration(database, key string, value bool) {
 
 func (c *CLIState) UnsetMigration(database, key string) {
 	delete(c.Migrations[database], key)
+	delete(c.MigrationChecksums[database], key)
+}
+
+func (c *CLIState) SetMigrationChecksum(database, key, checksum string) {
+	if c.MigrationChecksums == nil {
+		c.MigrationChecksums = map[string]map[string]string{}
+	}
+	if c.MigrationChecksums[database] == nil {
+		c.MigrationChecksums[database] = map[string]string{}
+	}
+	c.MigrationChecksums[database][key] = checksum
+}
+
+func (c *CLIState) GetMigrationChecksumsByDatabase(database string) map[string]string {
+	return c.MigrationChecksums[database]
 }
 
 func (c *CLIState) GetMigrationsByDatabase(database string) map[string]bool {
@@ -115,6 +185,39 @@ func (c *CLIState) GetSettings() map[string]string {
 	return c.Settings
 }
 
+// seedsSettingPrefix prefixes the name of the setting holding the seed files
+// applied on a database, as a JSON object of the files by name
+const seedsSettingPrefix = "seeds."
+
+// GetSeedsByDatabase returns the seed files applied on database by name
+func (c *CLIState) GetSeedsByDatabase(database string) (map[string]AppliedSeed, error) {
+	seeds := map[string]AppliedSeed{}
+	if v := c.GetSetting(seedsSettingPrefix + database); len(v) > 0 {
+		if err := json.Unmarshal([]byte(v), &seeds); err != nil {
+			return nil, fmt.Errorf("reading the seed files applied on %s: %w", database, err)
+		}
+	}
+	return seeds, nil
+}
+
+// SetSeeds records seeds as applied on database, along with the seed files
+// recorded already
+func (c *CLIState) SetSeeds(database string, seeds map[string]AppliedSeed) error {
+	applied, err := c.GetSeedsByDatabase(database)
+	if err != nil {
+		return err
+	}
+	for name, seed := range seeds {
+		applied[name] = seed
+	}
+	b, err := json.Marshal(applied)
+	if err != nil {
+		return err
+	}
+	c.SetSetting(seedsSettingPrefix+database, string(b))
+	return nil
+}
+
 func CopyMigrationState(src, dest MigrationsStateStore, srcdatabase, destdatabase string) error {
 	versions, err := src.GetVersions(srcdatabase)
 	if err != nil {
@@ -123,6 +226,13 @@ func CopyMigrationState(src, dest MigrationsStateStore, srcdatabase, destdatabas
 	for k, v := range versions {
 		dest.SetVersion(destdatabase, int64(k), v)
 	}
+	checksums, err := src.GetChecksums(srcdatabase)
+	if err != nil {
+		return err
+	}
+	for k, v := range checksums {
+		dest.SetChecksum(destdatabase, int64(k), v)
+	}
 	return nil
 }
 
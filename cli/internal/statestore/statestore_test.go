@@ -118,3 +118,39 @@ func TestCLICatalogState_Set(t *testing.T) {
 		})
 	}
 }
+
+func TestCLIState_MigrationChecksums(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(c *CLIState)
+		want  map[string]string
+	}{
+		{
+			"can set checksum of a migration",
+			func(c *CLIState) {
+				c.SetMigration("test", "123", false)
+				c.SetMigrationChecksum("test", "123", "abc")
+			},
+			map[string]string{"123": "abc"},
+		},
+		{
+			"unsetting a migration removes its checksum",
+			func(c *CLIState) {
+				c.SetMigration("test", "123", false)
+				c.SetMigrationChecksum("test", "123", "abc")
+				c.SetMigration("test", "456", false)
+				c.SetMigrationChecksum("test", "456", "def")
+				c.UnsetMigration("test", "123")
+			},
+			map[string]string{"456": "def"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &CLIState{}
+			c.Init()
+			tt.setup(c)
+			assert.Equal(t, tt.want, c.GetMigrationChecksumsByDatabase("test"))
+		})
+	}
+}
@@ -0,0 +1,149 @@
+// Package watcher watches the directories of a project and reports the files
+// changed in them in batches, once the files stopped changing.
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watcher watches directories recursively. Changes are debounced: the files
+// changed in a burst of events, like the files written by a metadata export,
+// are reported together once no file changed for the debounce delay.
+type Watcher struct {
+	// Changes receives the paths of the files changed in a batch, sorted
+	Changes chan []string
+	// Errors receives the errors of the watch
+	Errors chan error
+
+	fsw  *fsnotify.Watcher
+	done chan struct{}
+}
+
+// New watches dirs and the directories created in them, the directories
+// which do not exist are ignored
+func New(debounce time.Duration, dirs ...string) (*Watcher, error) {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{
+		Changes: make(chan []string),
+		Errors:  make(chan error),
+		fsw:     fsw,
+		done:    make(chan struct{}),
+	}
+	for _, dir := range dirs {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			continue
+		}
+		if err := w.addRecursive(dir); err != nil {
+			fsw.Close()
+			return nil, err
+		}
+	}
+	events := make(chan string)
+	go w.read(events)
+	go debounceEvents(events, debounce, w.Changes, w.done)
+	return w, nil
+}
+
+// Close stops the watch
+func (w *Watcher) Close() error {
+	close(w.done)
+	return w.fsw.Close()
+}
+
+func (w *Watcher) addRecursive(dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return w.fsw.Add(path)
+		}
+		return nil
+	})
+}
+
+// read forwards the paths of the events of the watch to events, directories
+// created while watching are watched too
+func (w *Watcher) read(events chan<- string) {
+	for {
+		select {
+		case event, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			if event.Op&fsnotify.Create == fsnotify.Create {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if err := w.addRecursive(event.Name); err != nil {
+						w.sendError(err)
+					}
+				}
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			select {
+			case events <- event.Name:
+			case <-w.done:
+				return
+			}
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+			w.sendError(err)
+		case <-w.done:
+			return
+		}
+	}
+}
+
+func (w *Watcher) sendError(err error) {
+	select {
+	case w.Errors <- err:
+	case <-w.done:
+	}
+}
+
+// debounceEvents collects the paths received on events and sends them to
+// changes once no path was received for delay
+func debounceEvents(events <-chan string, delay time.Duration, changes chan<- []string, done <-chan struct{}) {
+	pending := map[string]bool{}
+	timer := time.NewTimer(delay)
+	timer.Stop()
+	for {
+		select {
+		case path := <-events:
+			pending[path] = true
+			if !timer.Stop() {
+				// drain the timer if it fired before the event was received
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(delay)
+		case <-timer.C:
+			batch := make([]string, 0, len(pending))
+			for path := range pending {
+				batch = append(batch, path)
+			}
+			sort.Strings(batch)
+			pending = map[string]bool{}
+			select {
+			case changes <- batch:
+			case <-done:
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package watcher
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebounceEvents(t *testing.T) {
+	events := make(chan string)
+	changes := make(chan []string)
+	done := make(chan struct{})
+	defer close(done)
+	go debounceEvents(events, 50*time.Millisecond, changes, done)
+
+	events <- "metadata/tables.yaml"
+	events <- "metadata/actions.yaml"
+	events <- "metadata/tables.yaml"
+	select {
+	case batch := <-changes:
+		assert.Equal(t, []string{"metadata/actions.yaml", "metadata/tables.yaml"}, batch)
+	case <-time.After(time.Second):
+		t.Fatal("expected a batch of changes")
+	}
+
+	events <- "migrations/default/1_init/up.sql"
+	select {
+	case batch := <-changes:
+		assert.Equal(t, []string{"migrations/default/1_init/up.sql"}, batch)
+	case <-time.After(time.Second):
+		t.Fatal("expected a batch of changes")
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watcher-*")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	w, err := New(50*time.Millisecond, dir, filepath.Join(dir, "missing"))
+	require.NoError(t, err)
+	defer w.Close()
+
+	// files of directories created while watching are reported
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "default"), os.ModePerm))
+	assert.Equal(t, []string{filepath.Join(dir, "default")}, <-w.Changes)
+	name := filepath.Join(dir, "default", "seed.sql")
+	require.NoError(t, ioutil.WriteFile(name, []byte("select 1;"), 0644))
+	select {
+	case batch := <-w.Changes:
+		assert.Equal(t, []string{name}, batch)
+	case err := <-w.Errors:
+		t.Fatal(err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected a batch of changes")
+	}
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -222,11 +223,21 @@ func DownCmd(m *migrate.Migrate, limit int64) error {
 	}
 }
 
-func SquashCmd(m *migrate.Migrate, from uint64, version int64, name, directory string) (versions []int64, err error) {
+// SquashCmd squashes the migrations from version from up to the latest one into
+// a new migration. When generateDown is set and none of the squashed migrations
+// has down SQL, the down SQL is generated from the squashed up SQL.
+func SquashCmd(m *migrate.Migrate, from uint64, version int64, name, directory string, generateDown bool) (versions []int64, err error) {
 	versions, upMeta, upSql, downMeta, downSql, err := m.Squash(from)
 	if err != nil {
 		return
 	}
+	if generateDown && len(bytes.TrimSpace(downSql)) == 0 && len(bytes.TrimSpace(upSql)) != 0 {
+		down, err := GenerateDownSQL(m.SourceDriver(), from, string(upSql), m.Logger)
+		if err != nil {
+			return versions, errors.Wrap(err, "cannot generate down migration")
+		}
+		downSql = []byte(down)
+	}
 
 	createOptions := New(version, name, directory)
 	if len(upMeta) != 0 {
@@ -0,0 +1,259 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hasura/graphql-engine/cli/v2/internal/hasura"
+	"github.com/hasura/graphql-engine/cli/v2/internal/pgdiff"
+	"github.com/hasura/graphql-engine/cli/v2/internal/pgsql"
+	"github.com/hasura/graphql-engine/cli/v2/migrate"
+	"github.com/hasura/graphql-engine/cli/v2/migrate/source"
+	"github.com/hasura/graphql-engine/cli/v2/migrate/source/file"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// scratchSchemaPrefix is the prefix of the temporary schema
+// into which local migrations are replayed
+const scratchSchemaPrefix = "hdb_cli_diff_"
+
+type SchemaDiffOptions struct {
+	// Directory is the migrations directory of the database
+	Directory string
+	Parser    source.Parser
+	// Database is the database whose schema is compared with the local migrations
+	Database *pgdiff.Database
+	// Shadow is an empty database into which the local migrations are replayed,
+	// when it is nil they are replayed into a temporary schema of Database.
+	// The schemas replayed into the shadow database are dropped after the diff.
+	Shadow  *pgdiff.Database
+	Schemas []string
+	Logger  *log.Logger
+}
+
+// DiffSchema replays the local migrations and compares the resulting tables with
+// the tables of the database. The returned up statements change the schema of the
+// replayed migrations into the schema of the database, down statements revert them.
+// Both are empty if there are no differences. Migrations are replayed into a
+// temporary schema of the database only if they do not change objects outside of
+// the diffed schema, like extensions, roles or tables of other schemas, which would
+// be changed for real.
+func DiffSchema(opts SchemaDiffOptions) (up, down string, err error) {
+	migrations, err := readMigrations(opts)
+	if err != nil {
+		return "", "", err
+	}
+	replay := opts.Shadow
+	schemas := opts.Schemas
+	var rename map[string]string
+	var redirect func(string) string
+	if replay == nil {
+		if len(opts.Schemas) != 1 {
+			return "", "", errors.New("local migrations can be replayed into a temporary schema only when diffing a single schema, use a shadow database to diff multiple schemas")
+		}
+		if err := checkScratchReplay(migrations, opts.Schemas[0]); err != nil {
+			return "", "", err
+		}
+		scratch := fmt.Sprintf("%s%d", scratchSchemaPrefix, time.Now().UnixNano()/int64(time.Millisecond))
+		if err := runSQL(opts.Database, fmt.Sprintf(`CREATE SCHEMA "%s";`, scratch)); err != nil {
+			return "", "", errors.Wrap(err, "cannot create temporary schema")
+		}
+		defer func() {
+			if dropErr := runSQL(opts.Database, fmt.Sprintf(`DROP SCHEMA "%s" CASCADE;`, scratch)); dropErr != nil {
+				opts.Logger.Warnf("cannot drop temporary schema %s: %v", scratch, dropErr)
+			}
+		}()
+		replay = opts.Database
+		schemas = []string{scratch}
+		rename = map[string]string{scratch: opts.Schemas[0]}
+		redirect = func(sql string) string {
+			return pgdiff.RedirectSchema(sql, opts.Schemas[0], scratch)
+		}
+	} else {
+		shadow, err := pgdiff.ReadCatalog(opts.Shadow, opts.Schemas, nil)
+		if err != nil {
+			return "", "", err
+		}
+		if len(shadow.Tables) > 0 {
+			return "", "", fmt.Errorf("shadow database %s has to be empty, found %d tables in schemas %s", opts.Shadow.Source, len(shadow.Tables), strings.Join(opts.Schemas, ", "))
+		}
+		existing, err := readSchemas(opts.Shadow)
+		if err != nil {
+			return "", "", errors.Wrap(err, "cannot read schemas of shadow database")
+		}
+		defer func() {
+			if cleanErr := cleanShadow(opts.Shadow, opts.Schemas, existing); cleanErr != nil {
+				opts.Logger.Warnf("cannot drop the replayed migrations from shadow database %s: %v", opts.Shadow.Source, cleanErr)
+			}
+		}()
+	}
+
+	if err := replayMigrations(opts, migrations, replay, redirect); err != nil {
+		return "", "", err
+	}
+	local, err := pgdiff.ReadCatalog(replay, schemas, rename)
+	if err != nil {
+		return "", "", err
+	}
+	remote, err := pgdiff.ReadCatalog(opts.Database, opts.Schemas, nil)
+	if err != nil {
+		return "", "", err
+	}
+	upStatements, err := pgdiff.Diff(local, remote)
+	if err != nil {
+		return "", "", err
+	}
+	downStatements, err := pgdiff.Diff(remote, local)
+	if err != nil {
+		return "", "", err
+	}
+	return joinStatements(upStatements), joinStatements(downStatements), nil
+}
+
+// migrationSQL is the up SQL of a local migration
+type migrationSQL struct {
+	fileName string
+	sql      string
+}
+
+// readMigrations reads the up SQL of all local migrations in order
+func readMigrations(opts SchemaDiffOptions) ([]migrationSQL, error) {
+	f, err := file.New(migrate.GetFilePath(opts.Directory).String(), opts.Logger)
+	if err != nil {
+		return nil, err
+	}
+	f.DefaultParser(opts.Parser)
+	if err := f.Scan(); err != nil {
+		return nil, err
+	}
+	var migrations []migrationSQL
+	for _, version := range f.Migrations.Index {
+		r, _, fileName, err := f.ReadUp(version)
+		if err != nil {
+			if os.IsNotExist(errors.Cause(err)) {
+				continue
+			}
+			return nil, err
+		}
+		body, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot read migration %s", fileName)
+		}
+		if strings.TrimSpace(string(body)) == "" {
+			continue
+		}
+		migrations = append(migrations, migrationSQL{fileName, string(body)})
+	}
+	return migrations, nil
+}
+
+// checkScratchReplay returns an error if a migration changes objects outside
+// of schema, they would be changed for real when the migrations are replayed
+// into a temporary schema of the database
+func checkScratchReplay(migrations []migrationSQL, schema string) error {
+	var outside []string
+	for _, m := range migrations {
+		for _, statement := range pgsql.ChangesOutsideSchema(m.sql, schema) {
+			outside = append(outside, fmt.Sprintf("%s:%d: %s", m.fileName, statement.Line, statement.Text))
+		}
+	}
+	if len(outside) == 0 {
+		return nil
+	}
+	return fmt.Errorf("local migrations cannot be replayed into a temporary schema of the database, these statements change objects outside of schema %s, use a shadow database to replay them instead:\n%s", schema, strings.Join(outside, "\n"))
+}
+
+// replayMigrations runs the up SQL of the migrations in order on db
+func replayMigrations(opts SchemaDiffOptions, migrations []migrationSQL, db *pgdiff.Database, redirect func(string) string) error {
+	for _, m := range migrations {
+		sql := m.sql
+		if redirect != nil {
+			sql = redirect(sql)
+		}
+		opts.Logger.Debugf("replaying migration: %s", m.fileName)
+		if err := runSQL(db, sql); err != nil {
+			return errors.Wrapf(err, "cannot replay migration %s", m.fileName)
+		}
+	}
+	return nil
+}
+
+const schemasQuery = `SELECT nspname FROM pg_catalog.pg_namespace
+WHERE nspname NOT LIKE 'pg\_%' AND nspname <> 'information_schema'
+ORDER BY 1`
+
+// readSchemas returns the names of the user schemas of db
+func readSchemas(db *pgdiff.Database) (map[string]bool, error) {
+	resp, err := db.SourceOps.PGRunSQL(hasura.PGRunSQLInput{
+		SQL:      schemasQuery,
+		Source:   db.Source,
+		ReadOnly: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.ResultType != hasura.TuplesOK || len(resp.Result) == 0 {
+		return nil, fmt.Errorf("unexpected result of schemas query: %v", resp.ResultType)
+	}
+	schemas := map[string]bool{}
+	for _, row := range resp.Result[1:] {
+		schemas[row[0]] = true
+	}
+	return schemas, nil
+}
+
+// cleanShadow drops what the replayed migrations created in the shadow
+// database, so that it is empty for the next diff: the diffed schemas are
+// recreated empty and the schemas which did not exist before are dropped
+func cleanShadow(db *pgdiff.Database, schemas []string, existing map[string]bool) error {
+	current, err := readSchemas(db)
+	if err != nil {
+		return err
+	}
+	diffed := map[string]bool{}
+	var create []string
+	for _, schema := range schemas {
+		diffed[schema] = true
+		if existing[schema] {
+			create = append(create, schema)
+		}
+	}
+	var created []string
+	for schema := range current {
+		if !existing[schema] && !diffed[schema] {
+			created = append(created, schema)
+		}
+	}
+	sort.Strings(created)
+	var statements []string
+	for _, schema := range append(append([]string{}, schemas...), created...) {
+		statements = append(statements, fmt.Sprintf(`DROP SCHEMA IF EXISTS "%s" CASCADE;`, schema))
+	}
+	for _, schema := range create {
+		statements = append(statements, fmt.Sprintf(`CREATE SCHEMA "%s";`, schema))
+	}
+	return runSQL(db, strings.Join(statements, "\n"))
+}
+
+func runSQL(db *pgdiff.Database, sql string) error {
+	checkMetadataConsistency := false
+	_, err := db.SourceOps.PGRunSQL(hasura.PGRunSQLInput{
+		SQL:                      sql,
+		Source:                   db.Source,
+		CheckMetadataConsistency: &checkMetadataConsistency,
+	})
+	return err
+}
+
+func joinStatements(statements []string) string {
+	if len(statements) == 0 {
+		return ""
+	}
+	return strings.Join(statements, "\n") + "\n"
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hasura/graphql-engine/cli/v2/internal/hasura"
+	"github.com/hasura/graphql-engine/cli/v2/internal/pgdiff"
+	"github.com/hasura/graphql-engine/cli/v2/migrate/source"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingSourceOps records the SQL run on the database
+type recordingSourceOps struct {
+	sql []string
+}
+
+func (r *recordingSourceOps) PGRunSQL(input hasura.PGRunSQLInput) (*hasura.PGRunSQLOutput, error) {
+	r.sql = append(r.sql, input.SQL)
+	return nil, errors.New("not implemented")
+}
+
+func TestDiffSchema_ChangesOutsideSchema(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestDiffSchema")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"100_a/up.sql": "CREATE EXTENSION IF NOT EXISTS pgcrypto;\nCREATE TABLE authors (id uuid DEFAULT gen_random_uuid());",
+		"200_b/up.sql": "CREATE TABLE audit.authors_log (id int);",
+	}
+	for name, body := range files {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(body), 0644))
+	}
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+
+	ops := &recordingSourceOps{}
+	_, _, err = DiffSchema(SchemaDiffOptions{
+		Directory: dir,
+		Parser:    source.DefaultParsev2,
+		Database:  &pgdiff.Database{SourceOps: ops, Source: "default"},
+		Schemas:   []string{"public"},
+		Logger:    logger,
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "100_a/up.sql:1: CREATE EXTENSION IF NOT EXISTS pgcrypto")
+	assert.Contains(t, err.Error(), "200_b/up.sql:1: CREATE TABLE audit.authors_log (id int)")
+	// nothing is run on the database
+	assert.Empty(t, ops.sql)
+}
+
+var (
+	createSchema   = regexp.MustCompile(`CREATE SCHEMA "?(\w+)"?`)
+	dropSchema     = regexp.MustCompile(`DROP SCHEMA IF EXISTS "(\w+)" CASCADE`)
+	createTable    = regexp.MustCompile(`CREATE TABLE (?:(\w+)\.)?(\w+)`)
+	catalogSchema  = regexp.MustCompile(`n\.nspname = '(\w+)'`)
+	tablesOfSchema = "SELECT c.relname\nFROM pg_catalog.pg_class c"
+)
+
+// fakeDatabase keeps the schemas and tables created by the SQL run on a
+// database, the catalog queries return the tables without any columns
+type fakeDatabase struct {
+	schemas map[string][]string
+	// failOn fails the SQL containing it
+	failOn string
+}
+
+func newFakeDatabase(tables ...string) *fakeDatabase {
+	db := &fakeDatabase{schemas: map[string][]string{"public": nil}}
+	db.schemas["public"] = append(db.schemas["public"], tables...)
+	return db
+}
+
+func (f *fakeDatabase) PGRunSQL(input hasura.PGRunSQLInput) (*hasura.PGRunSQLOutput, error) {
+	if f.failOn != "" && strings.Contains(input.SQL, f.failOn) {
+		return nil, errors.New("failed")
+	}
+	if input.SQL == schemasQuery {
+		result := [][]string{{"nspname"}}
+		for _, schema := range f.schemaNames() {
+			result = append(result, []string{schema})
+		}
+		return &hasura.PGRunSQLOutput{ResultType: hasura.TuplesOK, Result: result}, nil
+	}
+	if m := catalogSchema.FindStringSubmatch(input.SQL); m != nil {
+		result := [][]string{{"relname"}}
+		if strings.Contains(input.SQL, tablesOfSchema) {
+			for _, table := range f.schemas[m[1]] {
+				result = append(result, []string{table})
+			}
+		}
+		return &hasura.PGRunSQLOutput{ResultType: hasura.TuplesOK, Result: result}, nil
+	}
+	for _, statement := range strings.Split(input.SQL, ";") {
+		if m := dropSchema.FindStringSubmatch(statement); m != nil {
+			delete(f.schemas, m[1])
+		} else if m := createSchema.FindStringSubmatch(statement); m != nil {
+			f.schemas[m[1]] = nil
+		} else if m := createTable.FindStringSubmatch(statement); m != nil {
+			schema := m[1]
+			if schema == "" {
+				schema = "public"
+			}
+			f.schemas[schema] = append(f.schemas[schema], m[2])
+		}
+	}
+	return &hasura.PGRunSQLOutput{ResultType: hasura.CommandOK}, nil
+}
+
+func (f *fakeDatabase) schemaNames() []string {
+	var schemas []string
+	for schema := range f.schemas {
+		schemas = append(schemas, schema)
+	}
+	sort.Strings(schemas)
+	return schemas
+}
+
+func TestDiffSchema_ShadowDatabaseIsCleaned(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestDiffSchema")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"100_a/up.sql": "CREATE TABLE authors (id int);",
+		"200_b/up.sql": "CREATE SCHEMA audit;\nCREATE TABLE audit.authors_log (id int);",
+	}
+	for name, body := range files {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(body), 0644))
+	}
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+
+	shadow := newFakeDatabase()
+	opts := SchemaDiffOptions{
+		Directory: dir,
+		Parser:    source.DefaultParsev2,
+		Database:  &pgdiff.Database{SourceOps: newFakeDatabase("authors"), Source: "default"},
+		Shadow:    &pgdiff.Database{SourceOps: shadow, Source: "shadow"},
+		Schemas:   []string{"public"},
+		Logger:    logger,
+	}
+
+	// the migrations replayed before a migration fails are dropped as well
+	shadow.failOn = "audit.authors_log"
+	_, _, err = DiffSchema(opts)
+	require.Error(t, err)
+	assert.Equal(t, map[string][]string{"public": nil}, shadow.schemas)
+
+	shadow.failOn = ""
+	for i := 0; i < 2; i++ {
+		up, down, err := DiffSchema(opts)
+		require.NoError(t, err)
+		assert.Empty(t, up)
+		assert.Empty(t, down)
+		assert.Equal(t, map[string][]string{"public": nil}, shadow.schemas)
+	}
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/hasura/graphql-engine/cli/v2/internal/pgsql"
+	"github.com/hasura/graphql-engine/cli/v2/migrate/source"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// GenerateDownSQL returns SQL which reverts the statements of up. Tables,
+// indexes, views and functions dropped by up are restored from their
+// definitions in the up SQL of the migrations of drv older than version
+// before, all migrations are read if before is 0. Statements which cannot
+// be reverted are logged as warnings and left as comments in the down SQL.
+// The migrations are read in the order of drv, which is not the order of the
+// versions when migrations declare depends_on, so every migration is visited.
+func GenerateDownSQL(drv source.Driver, before uint64, up string, logger *log.Logger) (string, error) {
+	schema := pgsql.NewSchema()
+	version, err := drv.First()
+	for ; err == nil; version, err = drv.Next(version) {
+		if before != 0 && version >= before {
+			continue
+		}
+		r, _, fileName, readErr := drv.ReadUp(version)
+		if readErr == nil {
+			body, readErr := ioutil.ReadAll(r)
+			r.Close()
+			if readErr != nil {
+				return "", errors.Wrapf(readErr, "cannot read migration %s", fileName)
+			}
+			schema.Apply(string(body))
+		} else if !os.IsNotExist(errors.Cause(readErr)) {
+			return "", readErr
+		}
+	}
+	if err != nil && !os.IsNotExist(errors.Cause(err)) {
+		return "", err
+	}
+
+	down, irreversible := schema.Down(up)
+	for _, statement := range irreversible {
+		logger.Warnf("cannot generate down SQL for the statement on line %d of the up migration (%s): %s", statement.Line, statement.Reason, statement.Statement)
+	}
+	return down, nil
+}
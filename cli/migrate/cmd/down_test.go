@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hasura/graphql-engine/cli/v2/migrate"
+	"github.com/hasura/graphql-engine/cli/v2/migrate/source"
+	"github.com/hasura/graphql-engine/cli/v2/migrate/source/file"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateDownSQL(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestGenerateDownSQL")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"100_a/up.sql": "CREATE TABLE authors (id serial PRIMARY KEY, name text);",
+		"200_b/up.sql": "ALTER TABLE authors ADD COLUMN bio text;",
+		"300_c/up.sql": "ALTER TABLE authors DROP COLUMN bio;",
+		// 250 is applied after 350, which it depends on
+		"250_d/up.sql": "-- depends_on: 350_e\nALTER TABLE authors ADD COLUMN rating int;",
+		"350_e/up.sql": "CREATE TABLE books (id serial PRIMARY KEY);",
+	}
+	for name, body := range files {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(body), 0644))
+	}
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	f, err := file.New(migrate.GetFilePath(dir).String(), logger)
+	require.NoError(t, err)
+	f.DefaultParser(source.DefaultParsev2)
+	require.NoError(t, f.Scan())
+
+	// migrations from 300 on are squashed, the column dropped by them is known from 200
+	down, err := GenerateDownSQL(f, 300, "ALTER TABLE authors DROP COLUMN bio;", logger)
+	require.NoError(t, err)
+	assert.Equal(t, "ALTER TABLE authors ADD COLUMN bio text;\n", down)
+
+	// migrations older than 300 are read after the migrations from 300 on in the order of depends_on
+	down, err = GenerateDownSQL(f, 300, "ALTER TABLE authors DROP COLUMN rating;", logger)
+	require.NoError(t, err)
+	assert.Equal(t, "ALTER TABLE authors ADD COLUMN rating int;\n", down)
+
+	down, err = GenerateDownSQL(f, 0, "ALTER TABLE authors DROP COLUMN bio;\nDROP TABLE authors;", logger)
+	require.NoError(t, err)
+	assert.Equal(t, "CREATE TABLE authors (id serial PRIMARY KEY, name text, rating int);\n-- could not revert the statement on line 1: type of column bio of table authors is not known\n", down)
+}
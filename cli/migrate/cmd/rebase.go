@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/hasura/graphql-engine/cli/v2/migrate"
+	"github.com/hasura/graphql-engine/cli/v2/migrate/source"
+	"github.com/hasura/graphql-engine/cli/v2/migrate/source/file"
+	"github.com/pkg/errors"
+)
+
+// RenameMigrations renames the migrations of directory from the old
+// versions to the new versions in renames and rewrites depends_on
+// references to the renamed versions in all migrations of directory.
+func RenameMigrations(directory string, parser source.Parser, renames map[uint64]uint64) error {
+	f, err := file.New(migrate.GetFilePath(directory).String(), nil)
+	if err != nil {
+		return err
+	}
+	f.DefaultParser(parser)
+	if err := f.Scan(); err != nil {
+		return err
+	}
+
+	for from, to := range renames {
+		if _, ok := f.Migrations.Migrations[from]; !ok {
+			return fmt.Errorf("cannot find migration %d in %s", from, directory)
+		}
+		if _, ok := f.Migrations.Migrations[to]; ok {
+			return fmt.Errorf("cannot rename migration %d to %d, a migration with version %d already exists", from, to, to)
+		}
+	}
+
+	rewrittenDirs := map[string]bool{}
+	for _, version := range f.Migrations.Index {
+		for _, m := range f.Migrations.Migrations[version] {
+			if m.Direction == source.Up {
+				if err := rewriteFile(filepath.Join(directory, m.Raw), renames, source.RenameDependsOnHeader); err != nil {
+					return err
+				}
+			}
+			if m.IsDir && !rewrittenDirs[filepath.Dir(m.Raw)] {
+				err := rewriteFile(filepath.Join(directory, filepath.Dir(m.Raw), source.DependsOnFile), renames, source.RenameDependsOnFile)
+				if err != nil && !os.IsNotExist(err) {
+					return err
+				}
+				rewrittenDirs[filepath.Dir(m.Raw)] = true
+			}
+		}
+	}
+
+	for from, to := range renames {
+		migrations := f.Migrations.Migrations[from]
+		renamed := map[string]bool{}
+		for _, m := range migrations {
+			// all files of a migration directory are moved by renaming the directory
+			oldPath := m.Raw
+			if m.IsDir {
+				oldPath = filepath.Dir(m.Raw)
+			}
+			if renamed[oldPath] {
+				continue
+			}
+			newPath := strconv.FormatUint(to, 10) + strings.TrimPrefix(oldPath, strconv.FormatUint(from, 10))
+			if err := os.Rename(filepath.Join(directory, oldPath), filepath.Join(directory, newPath)); err != nil {
+				return errors.Wrapf(err, "cannot rename migration %s", oldPath)
+			}
+			renamed[oldPath] = true
+		}
+	}
+	return nil
+}
+
+func rewriteFile(path string, renames map[uint64]uint64, rewrite func([]byte, map[uint64]uint64) []byte) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	updated := rewrite(data, renames)
+	if string(updated) == string(data) {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, updated, info.Mode())
+}
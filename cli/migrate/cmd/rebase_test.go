@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hasura/graphql-engine/cli/v2/migrate/source"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenameMigrations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestRenameMigrations")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"100_a/up.sql":          "1 up",
+		"100_a/down.sql":        "1 down",
+		"200_b/up.sql":          "-- depends_on: 100_a\n2 up",
+		"300_c/up.sql":          "3 up",
+		"300_c/depends_on.yaml": "- 100_a\n- 200_b\n",
+	}
+	for name, body := range files {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(body), 0644))
+	}
+
+	err = RenameMigrations(dir, source.DefaultParsev2, map[uint64]uint64{100: 400, 200: 401})
+	require.NoError(t, err)
+
+	want := map[string]string{
+		"400_a/up.sql":          "1 up",
+		"400_a/down.sql":        "1 down",
+		"401_b/up.sql":          "-- depends_on: 400_a\n2 up",
+		"300_c/up.sql":          "3 up",
+		"300_c/depends_on.yaml": "- 400_a\n- 401_b\n",
+	}
+	for name, body := range want {
+		got, err := ioutil.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		assert.Equal(t, body, string(got))
+	}
+	for _, name := range []string{"100_a", "200_b"} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		assert.True(t, os.IsNotExist(err))
+	}
+
+	err = RenameMigrations(dir, source.DefaultParsev2, map[uint64]uint64{400: 300})
+	assert.Error(t, err)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"sync"
+	"time"
 
 	nurl "net/url"
 
@@ -54,9 +55,10 @@ type Driver interface {
 
 	// Lock should acquire a database lock so that only one migration process
 	// can run at a time. Migrate will call this function before Run is called.
+	// If the lock is held by another process, wait at most timeout for it.
 	// If the implementation can't provide this functionality, return nil.
 	// Return database.ErrLocked if database is already locked.
-	Lock() error
+	Lock(timeout time.Duration) error
 
 	// Unlock should release the lock. Migrate will call this function after
 	// all migrations have been run.
@@ -83,6 +85,10 @@ type Driver interface {
 	// version must be >= -1. -1 means NilVersion.
 	RemoveVersion(version int64) error
 
+	// SetChecksum saves a hash of the contents of an applied migration.
+	// Migrate will call this function after a migration is applied.
+	SetChecksum(version int64, checksum string) error
+
 	// Version returns the currently active version and if the database is dirty.
 	// When no migration has been applied, it must return version -1.
 	// Dirty means, a previous migration failed and user interaction is required.
@@ -117,6 +123,8 @@ type Driver interface {
 
 	SettingsDriver
 
+	TransactionDriver
+
 	Query(data interface{}) error
 }
 
@@ -4,6 +4,7 @@ import (
 	"crypto/tls"
 	"io"
 	"testing"
+	"time"
 
 	"github.com/hasura/graphql-engine/cli/v2/internal/hasura"
 
@@ -31,7 +32,7 @@ func (m *mockDriver) UnLock() error {
 func (m *mockDriver) Run(migration io.Reader, fileType, fileName string) error {
 	return nil
 }
-func (m *mockDriver) Lock() error {
+func (m *mockDriver) Lock(timeout time.Duration) error {
 	return nil
 }
 
@@ -59,6 +60,10 @@ func (m *mockDriver) RemoveVersion(version int64) error {
 	return nil
 }
 
+func (m *mockDriver) SetChecksum(version int64, checksum string) error {
+	return nil
+}
+
 func (m *mockDriver) First() (migrationVersion *MigrationVersion, ok bool) {
 	return nil, false
 }
@@ -110,6 +115,14 @@ func (m *mockDriver) UpdateSetting(name string, value string) error {
 	return nil
 }
 
+func (m *mockDriver) BeginTransaction() error {
+	return nil
+}
+
+func (m *mockDriver) CommitTransaction() error {
+	return nil
+}
+
 func (m *mockDriver) ApplySeed(interface{}) error {
 	return nil
 }
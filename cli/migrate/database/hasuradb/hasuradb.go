@@ -7,8 +7,13 @@ import (
 	"io/ioutil"
 	"net/http"
 	nurl "net/url"
+	"os"
+	"os/user"
 	"path"
 	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
 
 	"github.com/hasura/graphql-engine/cli/v2/internal/statestore"
 
@@ -74,8 +79,14 @@ type HasuraDB struct {
 	migrationQuery HasuraInterfaceBulk
 	jsonPath       map[string]string
 	isLocked       bool
-	logger         *log.Logger
-	hasuraOpts     *database.HasuraOpts
+	// lockID identifies this instance as the holder of the migrations lock
+	lockID string
+	// isTransaction is set when migrations passed to Run should be buffered
+	// and sent as a single bulk request
+	isTransaction            bool
+	hasMetadataInTransaction bool
+	logger                   *log.Logger
+	hasuraOpts               *database.HasuraOpts
 
 	metadataops          hasura.CommonMetadataOperations
 	v2metadataops        hasura.V2CommonMetadataOperations
@@ -201,10 +212,13 @@ func (h *HasuraDB) Scan() error {
 	return h.getVersions()
 }
 
-func (h *HasuraDB) Lock() error {
+func (h *HasuraDB) Lock(timeout time.Duration) error {
 	if h.isLocked {
 		return database.ErrLocked
 	}
+	if err := h.acquireMigrationsLock(timeout); err != nil {
+		return err
+	}
 
 	h.migrationQuery = HasuraInterfaceBulk{
 		Type: "bulk",
@@ -223,14 +237,71 @@ func (h *HasuraDB) UnLock() error {
 	defer func() {
 		h.isLocked = false
 	}()
+	if err := h.migrationsStateStore.Unlock(h.hasuraOpts.SourceName, h.lockID); err != nil {
+		return errors.Wrap(err, "releasing migrations lock")
+	}
 	return nil
 }
 
+// lockRetryInterval is the time between attempts to take
+// a migrations lock held by another process
+var lockRetryInterval = time.Second
+
+// acquireMigrationsLock takes the migrations lock of the database in the state
+// store, so that migrations are not applied by concurrent processes
+func (h *HasuraDB) acquireMigrationsLock(timeout time.Duration) error {
+	if h.lockID == "" {
+		id, err := uuid.NewV4()
+		if err != nil {
+			return errors.Wrap(err, "generating migrations lock id")
+		}
+		h.lockID = id.String()
+	}
+	lock := statestore.MigrationsLock{
+		ID:         h.lockID,
+		Owner:      lockOwner(),
+		AcquiredAt: time.Now().UTC(),
+	}
+	deadline := time.Now().Add(timeout)
+	for waiting := false; ; waiting = true {
+		holder, err := h.migrationsStateStore.TryLock(h.hasuraOpts.SourceName, lock)
+		if err != nil {
+			return errors.Wrap(err, "taking migrations lock")
+		}
+		if holder == nil {
+			return nil
+		}
+		if !waiting {
+			h.logger.Infof("waiting for the migrations lock held by %s since %s", holder.Owner, holder.AcquiredAt.Local().Format(time.RFC1123))
+		}
+		if time.Now().Add(lockRetryInterval).After(deadline) {
+			return fmt.Errorf("%w: timed out after %s waiting for the migrations lock held by %s since %s, if that process is not running anymore release the lock with 'hasura migrate unlock'",
+				database.ErrLocked, timeout, holder.Owner, holder.AcquiredAt.Local().Format(time.RFC1123))
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// lockOwner describes the current process for the holder of a lock
+func lockOwner() string {
+	owner := fmt.Sprintf("pid %d", os.Getpid())
+	if hostname, err := os.Hostname(); err == nil {
+		owner = hostname + " " + owner
+	}
+	if u, err := user.Current(); err == nil {
+		owner = u.Username + "@" + owner
+	}
+	return owner
+}
+
 func (h *HasuraDB) Run(migration io.Reader, fileType, fileName string) error {
 	migr, err := ioutil.ReadAll(migration)
 	if err != nil {
 		return err
 	}
+	if h.isTransaction {
+		return h.addToTransaction(migr, fileType)
+	}
 	body := string(migr[:])
 	switch fileType {
 	case "sql":
@@ -361,6 +432,8 @@ func sendMetadataMigrations(hasuradb *HasuraDB, requests []interface{}) error {
 
 func (h *HasuraDB) ResetQuery() {
 	h.migrationQuery.ResetArgs()
+	h.isTransaction = false
+	h.hasMetadataInTransaction = false
 }
 
 func (h *HasuraDB) InsertVersion(version int64) error {
@@ -375,14 +448,22 @@ func (h *HasuraDB) RemoveVersion(version int64) error {
 	return h.migrationsStateStore.RemoveVersion(h.hasuraOpts.SourceName, version)
 }
 
+func (h *HasuraDB) SetChecksum(version int64, checksum string) error {
+	return h.migrationsStateStore.SetChecksum(h.hasuraOpts.SourceName, version, checksum)
+}
+
 func (h *HasuraDB) getVersions() (err error) {
 
 	v, err := h.migrationsStateStore.GetVersions(h.hasuraOpts.SourceName)
 	if err != nil {
 		return err
 	}
+	checksums, err := h.migrationsStateStore.GetChecksums(h.hasuraOpts.SourceName)
+	if err != nil {
+		return err
+	}
 	for version, dirty := range v {
-		h.migrations.Append(database.MigrationVersion{Version: version, Dirty: dirty})
+		h.migrations.Append(database.MigrationVersion{Version: version, Dirty: dirty, Checksum: checksums[version]})
 	}
 	return nil
 }
@@ -0,0 +1,91 @@
+package hasuradb
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/goccy/go-yaml"
+	"github.com/hasura/graphql-engine/cli/v2/internal/hasura"
+	"github.com/pkg/errors"
+)
+
+func (h *HasuraDB) BeginTransaction() error {
+	h.migrationQuery = HasuraInterfaceBulk{
+		Type: "bulk",
+		Args: make([]interface{}, 0),
+	}
+	h.hasMetadataInTransaction = false
+	h.isTransaction = true
+	return nil
+}
+
+func (h *HasuraDB) CommitTransaction() error {
+	defer func() {
+		h.isTransaction = false
+		h.hasMetadataInTransaction = false
+		h.migrationQuery.ResetArgs()
+	}()
+	if len(h.migrationQuery.Args) == 0 {
+		return nil
+	}
+	// with metadata v3, run_sql requests go to /v2/query and metadata requests
+	// go to /v1/metadata, these cannot be part of the same transaction
+	if h.hasuraOpts.HasMetadataV3 && h.hasMetadataInTransaction {
+		return fmt.Errorf("sql and metadata migrations cannot be applied atomically on a server with metadata v3")
+	}
+	resp, body, err := h.genericQueryRequest(h.migrationQuery)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		b, err := ioutil.ReadAll(body)
+		if err != nil {
+			return err
+		}
+		return errors.New(string(b))
+	}
+	return nil
+}
+
+// addToTransaction adds the requests of a migration file to the
+// bulk request which is sent on CommitTransaction
+func (h *HasuraDB) addToTransaction(body []byte, fileType string) error {
+	switch fileType {
+	case "sql":
+		if len(body) == 0 {
+			return nil
+		}
+		sqlInput := hasura.PGRunSQLInput{
+			SQL:    string(body),
+			Source: h.hasuraOpts.SourceName,
+		}
+		if h.config.enableCheckMetadataConsistency {
+			sqlInput.CheckMetadataConsistency = func() *bool { b := false; return &b }()
+		}
+		var requestType string
+		switch h.hasuraOpts.SourceKind {
+		case hasura.SourceKindPG:
+			requestType = RunSQL
+		case hasura.SourceKindMSSQL:
+			requestType = "mssql_run_sql"
+		default:
+			return fmt.Errorf("unsupported source kind, source name: %v kind: %v", h.hasuraOpts.SourceName, h.hasuraOpts.SourceKind)
+		}
+		h.migrationQuery.Args = append(h.migrationQuery.Args, hasura.RequestBody{
+			Type: requestType,
+			Args: sqlInput,
+		})
+	case "meta":
+		var metadataRequests []interface{}
+		if err := yaml.Unmarshal(body, &metadataRequests); err != nil {
+			return err
+		}
+		if len(metadataRequests) == 0 {
+			return nil
+		}
+		h.migrationQuery.Args = append(h.migrationQuery.Args, metadataRequests...)
+		h.hasMetadataInTransaction = true
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package hasuradb
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hasura/graphql-engine/cli/v2/internal/hasura"
+	"github.com/hasura/graphql-engine/cli/v2/internal/httpc"
+	"github.com/hasura/graphql-engine/cli/v2/migrate/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHasuraDB_Transaction(t *testing.T) {
+	type migration struct {
+		body     string
+		fileType string
+	}
+	tests := []struct {
+		name          string
+		hasuraOpts    *database.HasuraOpts
+		migrations    []migration
+		statusCode    int
+		wantRequests  int
+		wantBulk      string
+		wantCommitErr bool
+	}{
+		{
+			"sends sql migrations as a single bulk request",
+			&database.HasuraOpts{SourceName: "s1", SourceKind: hasura.SourceKindPG, HasMetadataV3: true},
+			[]migration{
+				{"CREATE TABLE a (id int);", "sql"},
+				{"", "sql"},
+				{"CREATE TABLE b (id int);", "sql"},
+			},
+			http.StatusOK,
+			1,
+			`{"type":"bulk","args":[{"type":"run_sql","args":{"sql":"CREATE TABLE a (id int);","source":"s1"}},{"type":"run_sql","args":{"sql":"CREATE TABLE b (id int);","source":"s1"}}]}`,
+			false,
+		},
+		{
+			"uses mssql_run_sql for mssql sources",
+			&database.HasuraOpts{SourceName: "s1", SourceKind: hasura.SourceKindMSSQL, HasMetadataV3: true},
+			[]migration{
+				{"CREATE TABLE a (id int);", "sql"},
+			},
+			http.StatusOK,
+			1,
+			`{"type":"bulk","args":[{"type":"mssql_run_sql","args":{"sql":"CREATE TABLE a (id int);","source":"s1"}}]}`,
+			false,
+		},
+		{
+			"mixes sql and metadata migrations on servers without metadata v3",
+			&database.HasuraOpts{SourceKind: hasura.SourceKindPG},
+			[]migration{
+				{"CREATE TABLE a (id int);", "sql"},
+				{"- type: track_table\n  args:\n    name: a\n", "meta"},
+			},
+			http.StatusOK,
+			1,
+			`{"type":"bulk","args":[{"type":"run_sql","args":{"sql":"CREATE TABLE a (id int);"}},{"args":{"name":"a"},"type":"track_table"}]}`,
+			false,
+		},
+		{
+			"refuses to mix sql and metadata migrations on servers with metadata v3",
+			&database.HasuraOpts{SourceName: "s1", SourceKind: hasura.SourceKindPG, HasMetadataV3: true},
+			[]migration{
+				{"CREATE TABLE a (id int);", "sql"},
+				{"- type: track_table\n  args:\n    name: a\n", "meta"},
+			},
+			http.StatusOK,
+			0,
+			"",
+			true,
+		},
+		{
+			"returns error when the bulk request fails",
+			&database.HasuraOpts{SourceName: "s1", SourceKind: hasura.SourceKindPG, HasMetadataV3: true},
+			[]migration{
+				{"CREATE TABLE a (id int);", "sql"},
+			},
+			http.StatusBadRequest,
+			1,
+			`{"type":"bulk","args":[{"type":"run_sql","args":{"sql":"CREATE TABLE a (id int);","source":"s1"}}]}`,
+			true,
+		},
+		{
+			"does not send a request when there is nothing to apply",
+			&database.HasuraOpts{SourceName: "s1", SourceKind: hasura.SourceKindPG, HasMetadataV3: true},
+			nil,
+			http.StatusOK,
+			0,
+			"",
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []string
+			h := &HasuraDB{
+				config:     &Config{},
+				hasuraOpts: tt.hasuraOpts,
+				genericQueryRequest: func(requestBody interface{}) (*httpc.Response, io.Reader, error) {
+					b, err := json.Marshal(requestBody)
+					require.NoError(t, err)
+					requests = append(requests, string(b))
+					return &httpc.Response{Response: &http.Response{StatusCode: tt.statusCode}}, bytes.NewBufferString(`{"error":"failed"}`), nil
+				},
+			}
+			require.NoError(t, h.BeginTransaction())
+			for _, m := range tt.migrations {
+				require.NoError(t, h.Run(strings.NewReader(m.body), m.fileType, ""))
+			}
+			err := h.CommitTransaction()
+			if tt.wantCommitErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Len(t, requests, tt.wantRequests)
+			if tt.wantRequests > 0 {
+				assert.JSONEq(t, tt.wantBulk, requests[0])
+			}
+			assert.False(t, h.isTransaction)
+			assert.Len(t, h.migrationQuery.Args, 0)
+		})
+	}
+}
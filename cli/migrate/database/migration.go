@@ -84,6 +84,9 @@ func (m *Migrations) findPos(version uint64) int {
 type MigrationVersion struct {
 	Version uint64
 	Dirty   bool
+	// Checksum is the hash of the migration contents recorded when it was applied,
+	// it is empty for migrations applied before checksums were recorded
+	Checksum string
 }
 
 type migrationVersions []MigrationVersion
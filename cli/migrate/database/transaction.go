@@ -0,0 +1,11 @@
+package database
+
+type TransactionDriver interface {
+	// BeginTransaction makes the driver buffer every migration passed to Run
+	// instead of applying it right away.
+	BeginTransaction() error
+
+	// CommitTransaction applies all buffered migrations in a single
+	// transaction. Either all of them are applied or none of them are.
+	CommitTransaction() error
+}
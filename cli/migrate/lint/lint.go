@@ -0,0 +1,171 @@
+// Package lint checks the SQL of migrations for statements which are
+// known to cause problems, like taking long locks on big tables.
+package lint
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hasura/graphql-engine/cli/v2/migrate/source"
+	"github.com/pkg/errors"
+)
+
+// Severity decides how a violation of a rule is reported
+type Severity string
+
+const (
+	// SeverityOff disables a rule
+	SeverityOff Severity = "off"
+	// SeverityWarn reports violations of a rule as warnings
+	SeverityWarn Severity = "warn"
+	// SeverityError reports violations of a rule as errors, which fail the lint
+	SeverityError Severity = "error"
+)
+
+func (s Severity) validate() error {
+	switch s {
+	case SeverityOff, SeverityWarn, SeverityError:
+		return nil
+	}
+	return fmt.Errorf("invalid severity %q, allowed values: %s, %s, %s", s, SeverityOff, SeverityWarn, SeverityError)
+}
+
+// Migration is the SQL of a migration version
+type Migration struct {
+	Version uint64
+	// UpFile and DownFile are the paths of the up and down SQL files,
+	// they are empty if the migration does not have the file
+	UpFile   string
+	Up       string
+	DownFile string
+	Down     string
+}
+
+// Violation is a statement of a migration which violates a rule
+type Violation struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	File     string   `json:"file"`
+	Line     int      `json:"line"`
+	Message  string   `json:"message"`
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s:%d: %s: %s [%s]", v.File, v.Line, v.Severity, v.Message, v.Rule)
+}
+
+// Linter checks migrations with the built in rules
+type Linter struct {
+	severities map[string]Severity
+}
+
+// New returns a Linter using the default severity of every rule,
+// overridden by severities which maps rule names to severities
+func New(severities map[string]string) (*Linter, error) {
+	l := &Linter{severities: map[string]Severity{}}
+	for _, r := range rules {
+		l.severities[r.Name] = r.Severity
+	}
+	for name, severity := range severities {
+		if _, ok := l.severities[name]; !ok {
+			return nil, fmt.Errorf("unknown lint rule %q", name)
+		}
+		s := Severity(strings.ToLower(severity))
+		if err := s.validate(); err != nil {
+			return nil, errors.Wrapf(err, "lint rule %s", name)
+		}
+		l.severities[name] = s
+	}
+	return l, nil
+}
+
+// Lint returns the violations of the rules in migrations,
+// ordered by file and line
+func (l *Linter) Lint(migrations []*Migration) []Violation {
+	var violations []Violation
+	for _, m := range migrations {
+		parsed := parseMigration(m)
+		for _, r := range rules {
+			severity := l.severities[r.Name]
+			if severity == SeverityOff {
+				continue
+			}
+			for _, v := range r.check(parsed) {
+				v.Rule = r.Name
+				v.Severity = severity
+				violations = append(violations, v)
+			}
+		}
+	}
+	sort.SliceStable(violations, func(i, j int) bool {
+		if violations[i].File != violations[j].File {
+			return violations[i].File < violations[j].File
+		}
+		return violations[i].Line < violations[j].Line
+	})
+	return violations
+}
+
+// HasErrors reports if any of violations has error severity
+func HasErrors(violations []Violation) bool {
+	for _, v := range violations {
+		if v.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadMigrations reads the SQL of the given versions from a scanned source
+// driver, all versions are read if versions is empty. File paths are
+// reported relative to directory.
+func ReadMigrations(drv source.Driver, versions []uint64, directory string) ([]*Migration, error) {
+	if len(versions) == 0 {
+		version, err := drv.First()
+		for err == nil {
+			versions = append(versions, version)
+			version, err = drv.Next(version)
+		}
+		if !os.IsNotExist(errors.Cause(err)) {
+			return nil, err
+		}
+	}
+	var migrations []*Migration
+	for _, version := range versions {
+		m := &Migration{Version: version}
+		var err error
+		m.UpFile, m.Up, err = readFile(drv.ReadUp, version, directory)
+		if err != nil {
+			return nil, err
+		}
+		m.DownFile, m.Down, err = readFile(drv.ReadDown, version, directory)
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, m)
+	}
+	return migrations, nil
+}
+
+type readFunc func(version uint64) (r io.ReadCloser, identifier string, fileName string, err error)
+
+func readFile(read readFunc, version uint64, directory string) (string, string, error) {
+	r, _, fileName, err := read(version)
+	if err != nil {
+		if os.IsNotExist(errors.Cause(err)) {
+			return "", "", nil
+		}
+		return "", "", err
+	}
+	defer r.Close()
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", "", errors.Wrapf(err, "cannot read migration %s", fileName)
+	}
+	return filepath.Join(directory, fileName), string(body), nil
+}
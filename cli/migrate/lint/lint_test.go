@@ -0,0 +1,111 @@
+package lint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLinter_Lint(t *testing.T) {
+	tests := []struct {
+		name       string
+		severities map[string]string
+		migration  *Migration
+		want       []Violation
+	}{
+		{
+			"add column with volatile default",
+			nil,
+			&Migration{
+				UpFile: "1_a/up.sql",
+				Up: `ALTER TABLE users ADD COLUMN created_at timestamptz DEFAULT now();
+ALTER TABLE users ADD COLUMN uuid uuid DEFAULT gen_random_uuid(),
+  ADD COLUMN seq bigserial, ADD CONSTRAINT users_uuid_key UNIQUE (uuid);`,
+			},
+			[]Violation{
+				{"add-column-default-before-pg11", SeverityWarn, "1_a/up.sql", 1, "column created_at is added to public.users with the default now(), which rewrites the table on Postgres versions before 11"},
+				{"add-column-volatile-default", SeverityWarn, "1_a/up.sql", 2, "column uuid is added to public.users with the volatile default gen_random_uuid(), which rewrites the table"},
+				{"add-column-volatile-default", SeverityWarn, "1_a/up.sql", 2, "column seq is added to public.users with a serial type, which rewrites the table"},
+			},
+		},
+		{
+			"add column with constant and null defaults",
+			nil,
+			&Migration{
+				UpFile: "1_a/up.sql",
+				Up:     `ALTER TABLE users ADD COLUMN active boolean DEFAULT false NOT NULL, ADD COLUMN bio text DEFAULT NULL, ADD COLUMN age int;`,
+			},
+			[]Violation{
+				{"add-column-default-before-pg11", SeverityWarn, "1_a/up.sql", 1, "column active is added to public.users with the default false, which rewrites the table on Postgres versions before 11"},
+			},
+		},
+		{
+			"tables created in the same migration",
+			nil,
+			&Migration{
+				UpFile: "1_a/up.sql",
+				Up: `CREATE TABLE "Users" (id int);
+ALTER TABLE "Users" ADD COLUMN uuid uuid DEFAULT gen_random_uuid();
+ALTER TABLE public."Users" ALTER COLUMN id TYPE bigint;
+CREATE INDEX ON "Users" (uuid);`,
+			},
+			nil,
+		},
+		{
+			"create index and alter column type",
+			nil,
+			&Migration{
+				UpFile: "1_a/up.sql",
+				Up: `CREATE UNIQUE INDEX IF NOT EXISTS users_email_key ON ONLY users (email);
+CREATE INDEX CONCURRENTLY users_name_idx ON users (name);
+
+ALTER TABLE users ALTER COLUMN id SET DATA TYPE bigint;`,
+			},
+			[]Violation{
+				{"create-index-not-concurrently", SeverityWarn, "1_a/up.sql", 1, "index on public.users is created without CONCURRENTLY, which blocks writes to the table"},
+				{"alter-column-type", SeverityWarn, "1_a/up.sql", 4, "type of column id of public.users is changed, which can rewrite the table"},
+			},
+		},
+		{
+			"drops without down",
+			nil,
+			&Migration{
+				UpFile:   "1_a/up.sql",
+				Up:       "ALTER TABLE users DROP COLUMN name, DROP CONSTRAINT users_pkey, DROP email;\nDROP TABLE IF EXISTS authors, articles CASCADE;",
+				DownFile: "1_a/down.sql",
+				Down:     "ALTER TABLE users ADD COLUMN name text;\nCREATE TABLE public.articles (id int);",
+			},
+			[]Violation{
+				{"drop-column-without-down", SeverityError, "1_a/up.sql", 1, "column email of public.users is dropped but not added back by the down migration"},
+				{"drop-table-without-down", SeverityError, "1_a/up.sql", 2, "table public.authors is dropped but not created again by the down migration"},
+			},
+		},
+		{
+			"configured severities",
+			map[string]string{"drop-table-without-down": "warn", "create-index-not-concurrently": "off"},
+			&Migration{
+				UpFile: "1_a/up.sql",
+				Up:     "CREATE INDEX ON users (name);\nDROP TABLE authors;",
+			},
+			[]Violation{
+				{"drop-table-without-down", SeverityWarn, "1_a/up.sql", 2, "table public.authors is dropped but not created again by the down migration"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := New(tt.severities)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, l.Lint([]*Migration{tt.migration}))
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(map[string]string{"no-such-rule": "warn"})
+	assert.EqualError(t, err, `unknown lint rule "no-such-rule"`)
+	_, err = New(map[string]string{"alter-column-type": "fatal"})
+	assert.EqualError(t, err, `lint rule alter-column-type: invalid severity "fatal", allowed values: off, warn, error`)
+	_, err = New(map[string]string{"alter-column-type": "ERROR"})
+	assert.NoError(t, err)
+}
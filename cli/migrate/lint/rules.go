@@ -0,0 +1,300 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hasura/graphql-engine/cli/v2/internal/pgsql"
+)
+
+// Rule is a check run on every migration
+type Rule struct {
+	// Name is used to configure the severity of the rule
+	Name        string
+	Description string
+	// Severity is used when the severity of the rule is not configured
+	Severity Severity
+	check    func(m *parsedMigration) []Violation
+}
+
+// Rules returns the built in rules
+func Rules() []Rule {
+	return append([]Rule(nil), rules...)
+}
+
+var rules = []Rule{
+	{
+		Name:        "add-column-volatile-default",
+		Description: "adding a column with a volatile default to an existing table rewrites the whole table",
+		Severity:    SeverityWarn,
+		check:       checkAddColumnVolatileDefault,
+	},
+	{
+		Name:        "add-column-default-before-pg11",
+		Description: "adding a column with any default to an existing table rewrites the whole table on Postgres versions before 11",
+		Severity:    SeverityWarn,
+		check:       checkAddColumnDefaultBeforePG11,
+	},
+	{
+		Name:        "create-index-not-concurrently",
+		Description: "creating an index on an existing table without CONCURRENTLY blocks writes to the table",
+		Severity:    SeverityWarn,
+		check:       checkCreateIndexNotConcurrently,
+	},
+	{
+		Name:        "alter-column-type",
+		Description: "changing the type of a column of an existing table can rewrite the table and blocks reads and writes",
+		Severity:    SeverityWarn,
+		check:       checkAlterColumnType,
+	},
+	{
+		Name:        "drop-column-without-down",
+		Description: "a dropped column has to be added back by the down migration",
+		Severity:    SeverityError,
+		check:       checkDropColumnWithoutDown,
+	},
+	{
+		Name:        "drop-table-without-down",
+		Description: "a dropped table has to be created again by the down migration",
+		Severity:    SeverityError,
+		check:       checkDropTableWithoutDown,
+	},
+}
+
+var (
+	createTableStatement = regexp.MustCompile(`(?i)^CREATE\s+(?:(?:GLOBAL|LOCAL)\s+)?(?:(?:TEMP|TEMPORARY|UNLOGGED)\s+)?TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?(` + pgsql.QualifiedName + `)`)
+	createIndexStatement = regexp.MustCompile(`(?i)^CREATE\s+(?:UNIQUE\s+)?INDEX\s+(CONCURRENTLY\s+)?(?:IF\s+NOT\s+EXISTS\s+)?(?:` + pgsql.Identifier + `\s+)?ON\s+(?:ONLY\s+)?(` + pgsql.QualifiedName + `)`)
+	alterTableStatement  = regexp.MustCompile(`(?i)^ALTER\s+TABLE\s+(?:IF\s+EXISTS\s+)?(?:ONLY\s+)?(` + pgsql.QualifiedName + `)\s*\*?\s+(.*)$`)
+	dropTableStatement   = regexp.MustCompile(`(?i)^DROP\s+TABLE\s+(?:IF\s+EXISTS\s+)?(.*?)(?:\s+(?:CASCADE|RESTRICT))?$`)
+
+	addColumnAction        = regexp.MustCompile(`(?i)^ADD\s+(COLUMN\s+)?(?:IF\s+NOT\s+EXISTS\s+)?(` + pgsql.Identifier + `)\s+(.*)$`)
+	dropColumnAction       = regexp.MustCompile(`(?i)^DROP\s+(COLUMN\s+)?(?:IF\s+EXISTS\s+)?(` + pgsql.Identifier + `)`)
+	alterColumnTypeAction  = regexp.MustCompile(`(?i)^ALTER\s+(?:COLUMN\s+)?(` + pgsql.Identifier + `)\s+(?:SET\s+DATA\s+)?TYPE\s+`)
+	columnDefault          = regexp.MustCompile(`(?i)\bDEFAULT\s+(.*)$`)
+	serialType             = regexp.MustCompile(`(?i)^(?:small|big)?serial[248]?\b`)
+	volatileFunctionCall   = regexp.MustCompile(`(?i)\b(random|gen_random_uuid|uuid_generate_v1|uuid_generate_v1mc|uuid_generate_v4|clock_timestamp|timeofday|nextval)\s*\(`)
+	columnConstraintStart  = regexp.MustCompile(`(?i)\s+(?:NOT\s+NULL|NULL|CONSTRAINT|CHECK|UNIQUE|PRIMARY|REFERENCES|GENERATED|COLLATE)\b`)
+	nullDefault            = regexp.MustCompile(`(?i)^NULL(?:\s*::.*)?$`)
+	constraintActionPrefix = regexp.MustCompile(`(?i)^(?:CONSTRAINT|PRIMARY|UNIQUE|FOREIGN|CHECK|EXCLUDE)\b`)
+)
+
+// parsedMigration is a migration split into statements
+type parsedMigration struct {
+	*Migration
+	up   []pgsql.Statement
+	down []pgsql.Statement
+	// createdTables are the tables created by the up migration
+	createdTables map[string]bool
+}
+
+func parseMigration(m *Migration) *parsedMigration {
+	p := &parsedMigration{
+		Migration:     m,
+		up:            pgsql.Split(m.Up),
+		down:          pgsql.Split(m.Down),
+		createdTables: map[string]bool{},
+	}
+	for _, table := range createdTables(p.up) {
+		p.createdTables[table] = true
+	}
+	return p
+}
+
+func createdTables(statements []pgsql.Statement) []string {
+	var tables []string
+	for _, s := range statements {
+		if match := createTableStatement.FindStringSubmatch(s.Text); match != nil {
+			tables = append(tables, pgsql.NormalizeName(match[1]))
+		}
+	}
+	return tables
+}
+
+// tableAction is an action of an ALTER TABLE statement
+type tableAction struct {
+	table  string
+	action string
+	line   int
+}
+
+func tableActions(statements []pgsql.Statement) []tableAction {
+	var actions []tableAction
+	for _, s := range statements {
+		match := alterTableStatement.FindStringSubmatch(s.Text)
+		if match == nil {
+			continue
+		}
+		for _, action := range pgsql.SplitTopLevel(match[2]) {
+			actions = append(actions, tableAction{table: pgsql.NormalizeName(match[1]), action: action, line: s.Line})
+		}
+	}
+	return actions
+}
+
+// addedColumn returns the column and its definition if action adds a column
+func addedColumn(action string) (column, definition string, ok bool) {
+	match := addColumnAction.FindStringSubmatch(action)
+	if match == nil || (match[1] == "" && constraintActionPrefix.MatchString(match[2])) {
+		return "", "", false
+	}
+	return pgsql.NormalizeIdentifier(match[2]), match[3], true
+}
+
+// droppedColumn returns the column if action drops a column
+func droppedColumn(action string) (string, bool) {
+	match := dropColumnAction.FindStringSubmatch(action)
+	if match == nil || (match[1] == "" && strings.EqualFold(match[2], "CONSTRAINT")) {
+		return "", false
+	}
+	return pgsql.NormalizeIdentifier(match[2]), true
+}
+
+func checkAddColumnVolatileDefault(m *parsedMigration) []Violation {
+	var violations []Violation
+	for _, a := range tableActions(m.up) {
+		if m.createdTables[a.table] {
+			continue
+		}
+		column, definition, ok := addedColumn(a.action)
+		if !ok {
+			continue
+		}
+		var reason string
+		if serialType.MatchString(definition) {
+			reason = "a serial type"
+		} else if match := columnDefault.FindStringSubmatch(definition); match != nil {
+			if call := volatileFunctionCall.FindStringSubmatch(match[1]); call != nil {
+				reason = fmt.Sprintf("the volatile default %s()", strings.ToLower(call[1]))
+			}
+		}
+		if reason != "" {
+			violations = append(violations, Violation{
+				File:    m.UpFile,
+				Line:    a.line,
+				Message: fmt.Sprintf("column %s is added to %s with %s, which rewrites the table", column, a.table, reason),
+			})
+		}
+	}
+	return violations
+}
+
+// checkAddColumnDefaultBeforePG11 flags the defaults which are not flagged by
+// add-column-volatile-default: from Postgres 11 a non volatile default, like
+// now() or a constant, is stored in the catalog without rewriting the table
+func checkAddColumnDefaultBeforePG11(m *parsedMigration) []Violation {
+	var violations []Violation
+	for _, a := range tableActions(m.up) {
+		if m.createdTables[a.table] {
+			continue
+		}
+		column, definition, ok := addedColumn(a.action)
+		if !ok || serialType.MatchString(definition) {
+			continue
+		}
+		match := columnDefault.FindStringSubmatch(definition)
+		if match == nil || volatileFunctionCall.MatchString(match[1]) {
+			continue
+		}
+		expression := match[1]
+		if loc := columnConstraintStart.FindStringIndex(expression); loc != nil {
+			expression = expression[:loc[0]]
+		}
+		if nullDefault.MatchString(expression) {
+			continue
+		}
+		violations = append(violations, Violation{
+			File:    m.UpFile,
+			Line:    a.line,
+			Message: fmt.Sprintf("column %s is added to %s with the default %s, which rewrites the table on Postgres versions before 11", column, a.table, expression),
+		})
+	}
+	return violations
+}
+
+func checkCreateIndexNotConcurrently(m *parsedMigration) []Violation {
+	var violations []Violation
+	for _, s := range m.up {
+		match := createIndexStatement.FindStringSubmatch(s.Text)
+		if match == nil || match[1] != "" {
+			continue
+		}
+		table := pgsql.NormalizeName(match[2])
+		if m.createdTables[table] {
+			continue
+		}
+		violations = append(violations, Violation{
+			File:    m.UpFile,
+			Line:    s.Line,
+			Message: fmt.Sprintf("index on %s is created without CONCURRENTLY, which blocks writes to the table", table),
+		})
+	}
+	return violations
+}
+
+func checkAlterColumnType(m *parsedMigration) []Violation {
+	var violations []Violation
+	for _, a := range tableActions(m.up) {
+		if m.createdTables[a.table] {
+			continue
+		}
+		match := alterColumnTypeAction.FindStringSubmatch(a.action)
+		if match == nil {
+			continue
+		}
+		violations = append(violations, Violation{
+			File:    m.UpFile,
+			Line:    a.line,
+			Message: fmt.Sprintf("type of column %s of %s is changed, which can rewrite the table", pgsql.NormalizeIdentifier(match[1]), a.table),
+		})
+	}
+	return violations
+}
+
+func checkDropColumnWithoutDown(m *parsedMigration) []Violation {
+	restored := map[string]bool{}
+	for _, a := range tableActions(m.down) {
+		if column, _, ok := addedColumn(a.action); ok {
+			restored[a.table+"."+column] = true
+		}
+	}
+	var violations []Violation
+	for _, a := range tableActions(m.up) {
+		column, ok := droppedColumn(a.action)
+		if !ok || restored[a.table+"."+column] {
+			continue
+		}
+		violations = append(violations, Violation{
+			File:    m.UpFile,
+			Line:    a.line,
+			Message: fmt.Sprintf("column %s of %s is dropped but not added back by the down migration", column, a.table),
+		})
+	}
+	return violations
+}
+
+func checkDropTableWithoutDown(m *parsedMigration) []Violation {
+	restored := map[string]bool{}
+	for _, table := range createdTables(m.down) {
+		restored[table] = true
+	}
+	var violations []Violation
+	for _, s := range m.up {
+		match := dropTableStatement.FindStringSubmatch(s.Text)
+		if match == nil {
+			continue
+		}
+		for _, name := range pgsql.SplitTopLevel(match[1]) {
+			table := pgsql.NormalizeName(name)
+			if restored[table] {
+				continue
+			}
+			violations = append(violations, Violation{
+				File:    m.UpFile,
+				Line:    s.Line,
+				Message: fmt.Sprintf("table %s is dropped but not created again by the down migration", table),
+			})
+		}
+	}
+	return violations
+}
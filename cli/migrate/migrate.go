@@ -8,9 +8,12 @@ package migrate
 import (
 	"bytes"
 	"container/list"
+	"crypto/sha256"
 	"crypto/tls"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"sync"
 	"text/tabwriter"
@@ -103,6 +106,26 @@ type Migrate struct {
 
 	SkipExecution bool
 	DryRun        bool
+	// Atomic applies all migrations read in a run as a single transaction,
+	// versions are recorded only when the whole batch is applied.
+	Atomic bool
+	// Quiet disables printing the migrations of a dry run to stdout
+	Quiet bool
+
+	// Applied lists the migrations applied by the runs of this instance,
+	// for a dry run these are the migrations which would have been applied.
+	Applied []AppliedMigration
+}
+
+// AppliedMigration is a migration version applied by a run
+type AppliedMigration struct {
+	Version   uint64 `json:"version"`
+	Name      string `json:"name"`
+	Direction string `json:"direction"`
+	// Duration is the time spent executing the files of the migration,
+	// it is not recorded for atomic runs where the transaction is
+	// executed as a whole.
+	Duration time.Duration `json:"-"`
 }
 
 type NewMigrateOpts struct {
@@ -118,7 +141,10 @@ type NewMigrateOpts struct {
 // The URL scheme is defined by each driver.
 func New(opts NewMigrateOpts) (*Migrate, error) {
 	m := newCommon(opts.cmd)
-	sourceName, err := schemeFromUrl(opts.sourceUrl)
+	sourceName, err := source.SchemeFromURL(opts.sourceUrl)
+	if err == nil && len(sourceName) == 0 {
+		err = errNoScheme
+	}
 	if err != nil {
 		log.Debug(err)
 		return nil, err
@@ -223,25 +249,21 @@ func (m *Migrate) readStatusFromSource() (err error) {
 		return err
 	}
 	m.status.Append(m.newMigrationStatus(firstVersion, "source", false))
-	from := int64(firstVersion)
-
-	lastVersion, err := m.sourceDrv.GetLocalVersion()
-	if err != nil {
-		return err
-	}
-	m.status.Append(m.newMigrationStatus(lastVersion, "source", false))
-	to := int64(lastVersion)
+	from := firstVersion
 
-	for from < to {
-		next, err := m.sourceDrv.Next(suint64(from))
+	// source versions are not necessarily in increasing order
+	// when migrations declare dependencies, so walk till the end
+	for {
+		next, err := m.sourceDrv.Next(from)
+		if os.IsNotExist(err) {
+			return nil
+		}
 		if err != nil {
 			return err
 		}
 		m.status.Append(m.newMigrationStatus(next, "source", false))
-		from = int64(next)
+		from = next
 	}
-
-	return nil
 }
 
 func (m *Migrate) readStatusFromDatabase() (err error) {
@@ -249,14 +271,14 @@ func (m *Migrate) readStatusFromDatabase() (err error) {
 	if !ok {
 		return nil
 	}
-	m.status.Append(m.newMigrationStatus(firstVersion.Version, "database", firstVersion.Dirty))
+	m.status.Append(m.newDatabaseMigrationStatus(firstVersion))
 	from := int64(firstVersion.Version)
 
 	lastVersion, ok := m.databaseDrv.Last()
 	if !ok {
 		return nil
 	}
-	m.status.Append(m.newMigrationStatus(lastVersion.Version, "database", lastVersion.Dirty))
+	m.status.Append(m.newDatabaseMigrationStatus(lastVersion))
 	to := int64(lastVersion.Version)
 
 	for from < to {
@@ -264,7 +286,7 @@ func (m *Migrate) readStatusFromDatabase() (err error) {
 		if !ok {
 			return nil
 		}
-		m.status.Append(m.newMigrationStatus(next.Version, "database", next.Dirty))
+		m.status.Append(m.newDatabaseMigrationStatus(next))
 		from = int64(next.Version)
 	}
 	return err
@@ -293,14 +315,94 @@ func (m *Migrate) newMigrationStatus(version uint64, driverType string, dirty bo
 	return migrStatus
 }
 
+func (m *Migrate) newDatabaseMigrationStatus(migrationVersion *database.MigrationVersion) *MigrationStatus {
+	migrStatus := m.newMigrationStatus(migrationVersion.Version, "database", migrationVersion.Dirty)
+	migrStatus.AppliedChecksum = migrationVersion.Checksum
+	return migrStatus
+}
+
 func (m *Migrate) GetStatus() (*Status, error) {
 	err := m.calculateStatus()
 	if err != nil {
 		return nil, err
 	}
+	err = m.markModifiedMigrations()
+	if err != nil {
+		return nil, err
+	}
 	return m.status, nil
 }
 
+// markModifiedMigrations compares the checksum recorded when a migration was
+// applied with the checksum of the local migration files and marks
+// the migrations which were modified after being applied
+func (m *Migrate) markModifiedMigrations() error {
+	for _, version := range m.status.Index {
+		migrStatus := m.status.Migrations[version]
+		if !migrStatus.IsApplied || !migrStatus.IsPresent || migrStatus.AppliedChecksum == "" {
+			continue
+		}
+		checksum, err := m.checksum(version)
+		if err != nil {
+			return errors.Wrapf(err, "cannot compute checksum of migration %d", version)
+		}
+		migrStatus.IsModified = checksum != migrStatus.AppliedChecksum
+	}
+	return nil
+}
+
+// checksum returns the sha256 hash of the contents of the up migration
+// files (sql and yaml) of a version. The depends_on header of the sql file
+// is left out, it is rewritten when the migrations it refers to are renamed.
+func (m *Migrate) checksum(version uint64) (string, error) {
+	h := sha256.New()
+	readers := []func(uint64) (io.ReadCloser, string, string, error){
+		m.sourceDrv.ReadUp,
+		m.sourceDrv.ReadMetaUp,
+	}
+	for idx, read := range readers {
+		r, _, _, err := read(version)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		body, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return "", err
+		}
+		if idx == 0 {
+			body = source.StripDependsOnHeader(body)
+		}
+		h.Write(body)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// appliedChecksum returns the checksum recorded in the database when
+// version was applied
+func (m *Migrate) appliedChecksum(version uint64) string {
+	migrationVersion, ok := m.databaseDrv.First()
+	for ok {
+		if migrationVersion.Version == version {
+			return migrationVersion.Checksum
+		}
+		migrationVersion, ok = m.databaseDrv.Next(migrationVersion.Version)
+	}
+	return ""
+}
+
+// setChecksum records the checksum of an up migration in the database
+func (m *Migrate) setChecksum(version uint64) error {
+	checksum, err := m.checksum(version)
+	if err != nil {
+		return err
+	}
+	return m.databaseDrv.SetChecksum(int64(version), checksum)
+}
+
 func (m *Migrate) GetSetting(name string) (string, error) {
 	val, err := m.databaseDrv.GetSetting(name)
 	if err != nil {
@@ -326,6 +428,11 @@ func (m *Migrate) Version() (version uint64, dirty bool, err error) {
 	return suint64(v), d, nil
 }
 
+// SourceDriver returns the driver from which migrations are read
+func (m *Migrate) SourceDriver() source.Driver {
+	return m.sourceDrv
+}
+
 func (m *Migrate) GetUnappliedMigrations(version uint64) []uint64 {
 	return m.sourceDrv.GetUnappliedMigrations(version)
 }
@@ -354,6 +461,44 @@ func (m *Migrate) RemoveVersions(versions []uint64) error {
 	return m.unlockErr(nil)
 }
 
+// MoveVersions records each renamed version as applied in place of its
+// old version, versions which are not applied are left untouched.
+// It has to be called after the source is rescanned with the renamed migrations.
+func (m *Migrate) MoveVersions(renames map[uint64]uint64) error {
+	mode, err := m.databaseDrv.GetSetting("migration_mode")
+	if err != nil {
+		return err
+	}
+
+	if mode != "true" {
+		return ErrNoMigrationMode
+	}
+
+	if err := m.lock(); err != nil {
+		return err
+	}
+
+	for from, to := range renames {
+		if !m.databaseDrv.Read(from) {
+			continue
+		}
+		if err := m.databaseDrv.SetVersion(int64(to), false); err != nil {
+			return m.unlockErr(err)
+		}
+		// the checksum recorded when the migration was applied is kept, so
+		// the changes made to it since are still reported by migrate verify
+		if checksum := m.appliedChecksum(from); checksum != "" {
+			if err := m.databaseDrv.SetChecksum(int64(to), checksum); err != nil {
+				return m.unlockErr(err)
+			}
+		}
+		if err := m.databaseDrv.RemoveVersion(int64(from)); err != nil {
+			return m.unlockErr(err)
+		}
+	}
+	return m.unlockErr(nil)
+}
+
 func (m *Migrate) Query(data interface{}) error {
 	mode, err := m.databaseDrv.GetSetting("migration_mode")
 	if err != nil {
@@ -601,11 +746,11 @@ func (m *Migrate) Up() error {
 
 	curVersion, dirty, err := m.databaseDrv.Version()
 	if err != nil {
-		return err
+		return m.unlockErr(err)
 	}
 
 	if dirty {
-		return ErrDirty{curVersion}
+		return m.unlockErr(ErrDirty{curVersion})
 	}
 
 	ret := make(chan interface{}, m.PrefetchMigrations)
@@ -637,11 +782,11 @@ func (m *Migrate) Down() error {
 
 	curVersion, dirty, err := m.databaseDrv.Version()
 	if err != nil {
-		return err
+		return m.unlockErr(err)
 	}
 
 	if dirty {
-		return ErrDirty{curVersion}
+		return m.unlockErr(ErrDirty{curVersion})
 	}
 
 	ret := make(chan interface{}, m.PrefetchMigrations)
@@ -760,15 +905,13 @@ func (m *Migrate) squashDown(version uint64, ret chan<- interface{}) {
 		return
 	}
 
+	// the versions are squashed down in the reverse of the order of the
+	// source, from the last version to version
 	for {
 		if m.stop() {
 			return
 		}
 
-		if from < version {
-			return
-		}
-
 		prev, err := m.sourceDrv.Prev(from)
 		if os.IsNotExist(err) {
 			migr, err := m.metanewMigration(from, -1)
@@ -815,6 +958,9 @@ func (m *Migrate) squashDown(version uint64, ret chan<- interface{}) {
 			m.Logger.Warnf("%v", err)
 		}
 
+		if from == version {
+			return
+		}
 		from = prev
 	}
 }
@@ -1051,7 +1197,8 @@ func (m *Migrate) readUp(limit int64, ret chan<- interface{}) {
 	}
 }
 
-// readDown reads down migrations from `from` limitted by `limit`.
+// readDown reads down migrations of the applied versions limitted by `limit`,
+// in the reverse of the order of the source.
 // limit can be -1, implying no limit and reading until there are no more migrations.
 // Each migration is then written to the ret channel.
 // If an error occurs during reading, that error is written to the ret channel, too.
@@ -1064,65 +1211,41 @@ func (m *Migrate) readDown(limit int64, ret chan<- interface{}) {
 		return
 	}
 
-	from, _, err := m.databaseDrv.Version()
+	versions, err := m.appliedVersionsDown()
 	if err != nil {
 		ret <- err
 		return
 	}
 
 	// no change if already at nil version
-	if from == -1 && limit == -1 {
-		ret <- ErrNoChange
-		return
-	}
-
-	// can't go over limit if already at nil version
-	if from == -1 && limit > 0 {
+	if len(versions) == 0 {
 		ret <- ErrNoChange
 		return
 	}
 
 	count := int64(0)
-	for count < limit || limit == -1 {
+	for i, version := range versions {
+		if count == limit {
+			return
+		}
 		if m.stop() {
 			return
 		}
 
-		err = m.versionDownExists(suint64(from))
+		err = m.versionDownExists(version)
 		if err != nil {
 			ret <- err
 			return
 		}
 
-		prev, ok := m.databaseDrv.Prev(suint64(from))
-		if !ok {
-			// no limit or haven't reached limit, apply "first" migration
-			if limit == -1 || limit-count > 0 {
-				migr, err := m.metanewMigration(suint64(from), -1)
-				if err != nil {
-					ret <- err
-					return
-				}
-				ret <- migr
-				go migr.Buffer()
-
-				migr, err = m.newMigration(suint64(from), -1)
-				if err != nil {
-					ret <- err
-					return
-				}
-				ret <- migr
-				go migr.Buffer()
-				count++
-			}
-
-			if count < limit {
-				ret <- ErrShortLimit{suint64(limit - count)}
-			}
-			return
+		// the target version is the version migrated down next, the nil
+		// version for the last one
+		prev := database.NilVersion
+		if i+1 < len(versions) {
+			prev = int64(versions[i+1])
 		}
 
-		migr, err := m.metanewMigration(suint64(from), int64(prev.Version))
+		migr, err := m.metanewMigration(version, prev)
 		if err != nil {
 			ret <- err
 			return
@@ -1131,7 +1254,7 @@ func (m *Migrate) readDown(limit int64, ret chan<- interface{}) {
 		ret <- migr
 		go migr.Buffer()
 
-		migr, err = m.newMigration(suint64(from), int64(prev.Version))
+		migr, err = m.newMigration(version, prev)
 		if err != nil {
 			ret <- err
 			return
@@ -1139,9 +1262,40 @@ func (m *Migrate) readDown(limit int64, ret chan<- interface{}) {
 
 		ret <- migr
 		go migr.Buffer()
-		from = int64(prev.Version)
 		count++
 	}
+
+	if count < limit {
+		ret <- ErrShortLimit{suint64(limit - count)}
+	}
+}
+
+// appliedVersionsDown returns the applied versions in the order they are
+// migrated down, the reverse of the order of the source which follows the
+// depends_on declarations. Applied versions missing from the source come
+// first, latest first.
+func (m *Migrate) appliedVersionsDown() ([]uint64, error) {
+	var versions []uint64
+	inSource := map[uint64]bool{}
+	version, err := m.sourceDrv.First()
+	for err == nil {
+		inSource[version] = true
+		if m.databaseDrv.Read(version) {
+			versions = append([]uint64{version}, versions...)
+		}
+		version, err = m.sourceDrv.Next(version)
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	var missing []uint64
+	for applied, ok := m.databaseDrv.First(); ok; applied, ok = m.databaseDrv.Next(applied.Version) {
+		if !inSource[applied.Version] {
+			missing = append([]uint64{applied.Version}, missing...)
+		}
+	}
+	return append(missing, versions...), nil
 }
 
 // runMigrations reads *Migration and error from a channel. Any other type
@@ -1151,6 +1305,9 @@ func (m *Migrate) readDown(limit int64, ret chan<- interface{}) {
 // to stop execution because it might have received a stop signal on the
 // GracefulStop channel.
 func (m *Migrate) runMigrations(ret <-chan interface{}) error {
+	if m.Atomic {
+		return m.runMigrationsAtomic(ret)
+	}
 	for r := range ret {
 		if m.stop() {
 			return nil
@@ -1164,11 +1321,14 @@ func (m *Migrate) runMigrations(ret <-chan interface{}) error {
 		case *Migration:
 			migr := r.(*Migration)
 			if migr.Body != nil {
+				var took time.Duration
 				if !m.SkipExecution {
 					m.Logger.Debugf("applying migration: %s", migr.FileName)
+					start := time.Now()
 					if err := m.databaseDrv.Run(migr.BufferedBody, migr.FileType, migr.FileName); err != nil {
 						return err
 					}
+					took = time.Since(start)
 				}
 				version := int64(migr.Version)
 				// Insert Version number into the table
@@ -1180,13 +1340,81 @@ func (m *Migrate) runMigrations(ret <-chan interface{}) error {
 					if err := m.databaseDrv.RemoveVersion(version); err != nil {
 						return err
 					}
+				} else if err := m.setChecksum(migr.Version); err != nil {
+					return err
 				}
+				m.recordApplied(migr, took)
 			}
 		}
 	}
 	return nil
 }
 
+// runMigrationsAtomic is similar to runMigrations, but instead of applying
+// each migration as it is received, all migrations are buffered by the
+// database driver and applied in a single transaction. Versions are written
+// to the state store only after the transaction succeeds.
+func (m *Migrate) runMigrationsAtomic(ret <-chan interface{}) error {
+	type versionChange struct {
+		version       int64
+		targetVersion int64
+	}
+	var changes []versionChange
+	var applied []*Migration
+
+	if err := m.databaseDrv.BeginTransaction(); err != nil {
+		return err
+	}
+	for r := range ret {
+		if m.stop() {
+			m.databaseDrv.ResetQuery()
+			return nil
+		}
+
+		switch r.(type) {
+		case error:
+			m.databaseDrv.ResetQuery()
+			return r.(error)
+		case *Migration:
+			migr := r.(*Migration)
+			if migr.Body != nil {
+				if !m.SkipExecution {
+					m.Logger.Debugf("adding migration to transaction: %s", migr.FileName)
+					if err := m.databaseDrv.Run(migr.BufferedBody, migr.FileType, migr.FileName); err != nil {
+						m.databaseDrv.ResetQuery()
+						return err
+					}
+				}
+				changes = append(changes, versionChange{int64(migr.Version), migr.TargetVersion})
+				applied = append(applied, migr)
+			}
+		}
+	}
+
+	m.Logger.Debugf("applying %d migration files in a single transaction", len(changes))
+	if err := m.databaseDrv.CommitTransaction(); err != nil {
+		return err
+	}
+	for _, change := range changes {
+		// Insert Version number into the table
+		if err := m.databaseDrv.SetVersion(change.version, false); err != nil {
+			return err
+		}
+		if change.version != change.targetVersion {
+			// Delete Version number from the table
+			if err := m.databaseDrv.RemoveVersion(change.version); err != nil {
+				return err
+			}
+		} else if err := m.setChecksum(suint64(change.version)); err != nil {
+			return err
+		}
+	}
+	for _, migr := range applied {
+		m.recordApplied(migr, 0)
+	}
+	return nil
+}
+
 func (m *Migrate) runDryRun(ret <-chan interface{}) error {
 	migrations := make([]*Migration, 0)
 	var lastInsertVersion int64
@@ -1206,13 +1434,35 @@ func (m *Migrate) runDryRun(ret <-chan interface{}) error {
 					migrations = append(migrations, migr)
 					lastInsertVersion = version
 				}
+				m.recordApplied(migr, 0)
 			}
 		}
 	}
-	fmt.Fprintf(os.Stdout, "%s", printDryRunStatus(migrations))
+	if !m.Quiet {
+		fmt.Fprintf(os.Stdout, "%s", printDryRunStatus(migrations))
+	}
 	return nil
 }
 
+// recordApplied adds a migration file to Applied, the files of
+// a version are recorded as a single entry
+func (m *Migrate) recordApplied(migr *Migration, took time.Duration) {
+	direction := "up"
+	if int64(migr.Version) != migr.TargetVersion {
+		direction = "down"
+	}
+	if n := len(m.Applied); n > 0 && m.Applied[n-1].Version == migr.Version && m.Applied[n-1].Direction == direction {
+		m.Applied[n-1].Duration += took
+		return
+	}
+	m.Applied = append(m.Applied, AppliedMigration{
+		Version:   migr.Version,
+		Name:      migr.Identifier,
+		Direction: direction,
+		Duration:  took,
+	})
+}
+
 func (m *Migrate) squashMigrations(retUp <-chan interface{}, retDown <-chan interface{}, dataUp chan<- interface{}, dataDown chan<- interface{}, versions chan<- int64) error {
 	var latestVersion int64
 	go func() {
@@ -1370,11 +1620,13 @@ func (m *Migrate) versionDownExists(version uint64) error {
 
 // newMigration is a helper func that returns a *Migration for the
 // specified version and targetVersion (sql).
-// will return the down migration
+// will return the down migration unless targetVersion is version, the
+// target of a down migration can be a higher version when the migrations
+// are ordered by depends_on
 func (m *Migrate) newMigration(version uint64, targetVersion int64) (*Migration, error) {
 	var migr *Migration
 
-	if targetVersion >= int64(version) {
+	if targetVersion == int64(version) {
 		r, identifier, fileName, err := m.sourceDrv.ReadUp(version)
 		if os.IsNotExist(err) {
 			// create "empty" migration
@@ -1429,7 +1681,7 @@ func (m *Migrate) newMigration(version uint64, targetVersion int64) (*Migration,
 func (m *Migrate) metanewMigration(version uint64, targetVersion int64) (*Migration, error) {
 	var migr *Migration
 
-	if targetVersion >= int64(version) {
+	if targetVersion == int64(version) {
 		r, identifier, fileName, err := m.sourceDrv.ReadMetaUp(version)
 		if os.IsNotExist(err) {
 			// create "empty" migration
@@ -1507,45 +1759,19 @@ func (m *Migrate) lock() error {
 		return ErrLocked
 	}
 
-	// create done channel, used in the timeout goroutine
-	done := make(chan bool, 1)
-	defer func() {
-		done <- true
-	}()
-
-	// use errchan to signal error back to this context
-	errchan := make(chan error, 2)
-
-	// start timeout goroutine
-	timeout := time.After(m.LockTimeout)
-	go func() {
-		for {
-			select {
-			case <-done:
-				return
-			case <-timeout:
-				errchan <- ErrLockTimeout
-				return
-			}
-		}
-	}()
-
-	// now try to acquire the lock
-	go func() {
-		if err := m.databaseDrv.Lock(); err != nil {
-			errchan <- err
-		} else {
-			errchan <- nil
+	if err := m.databaseDrv.Lock(m.LockTimeout); err != nil {
+		return err
+	}
+	// other processes may have applied migrations while the lock was awaited,
+	// the migrations to run are read from the state when the lock is acquired
+	if err := m.ReScan(); err != nil {
+		if unlockErr := m.databaseDrv.UnLock(); unlockErr != nil {
+			return NewMultiError(err, unlockErr)
 		}
-		return
-	}()
-
-	// wait until we either recieve ErrLockTimeout or error from Lock operation
-	err := <-errchan
-	if err == nil {
-		m.isLocked = true
+		return err
 	}
-	return err
+	m.isLocked = true
+	return nil
 }
 
 // unlock is a thread safe helper function to unlock the database.
@@ -1598,11 +1824,22 @@ func (m *Migrate) GotoVersion(gotoVersion int64) error {
 		return err
 	}
 
+	// the migrations are ordered by the source, a version which is not
+	// applied yet is reached by going up and an applied version by going
+	// down the versions applied after it, if there are
+	down := gotoVersion == database.NilVersion
+	if !down && m.databaseDrv.Read(suint64(gotoVersion)) {
+		versions, err := m.appliedVersionsDown()
+		if err != nil {
+			return m.unlockErr(err)
+		}
+		down = len(versions) > 0 && versions[0] != suint64(gotoVersion)
+	}
 	ret := make(chan interface{})
-	if currVersion <= gotoVersion {
+	if down {
+		go m.readDownFromVersion(gotoVersion, ret)
+	} else {
 		go m.readUpFromVersion(-1, gotoVersion, ret)
-	} else if currVersion > gotoVersion {
-		go m.readDownFromVersion(currVersion, gotoVersion, ret)
 	}
 
 	if m.DryRun {
@@ -1714,70 +1951,46 @@ func (m *Migrate) readUpFromVersion(from int64, to int64, ret chan<- interface{}
 	}
 }
 
-// readDownFromVersion reads down migrations from `from` limitted by `limit`. (modified version of readDown)
-// limit can be -1, implying no limit and reading until there are no more migrations.
+// readDownFromVersion reads down migrations of the applied versions until `to`,
+// in the reverse of the order of the source. (modified version of readDown)
+// to can be -1, implying reading until there are no more migrations.
 // Each migration is then written to the ret channel.
 // If an error occurs during reading, that error is written to the ret channel, too.
 // Once readDownFromVersion is done reading it will close the ret channel.
-func (m *Migrate) readDownFromVersion(from int64, to int64, ret chan<- interface{}) {
+func (m *Migrate) readDownFromVersion(to int64, ret chan<- interface{}) {
 	defer close(ret)
-	var err error
+
+	versions, err := m.appliedVersionsDown()
+	if err != nil {
+		ret <- err
+		return
+	}
+	if to != database.NilVersion && !m.databaseDrv.Read(suint64(to)) {
+		ret <- fmt.Errorf("%v not applied on database", to)
+		return
+	}
+
 	var noOfAppliedMigrations int
-	for {
+	for i, version := range versions {
 		if m.stop() {
 			return
 		}
-
-		if from == to {
-			if noOfAppliedMigrations == 0 {
-				ret <- ErrNoChange
-			}
-			return
+		if int64(version) == to {
+			break
 		}
 
-		err = m.versionDownExists(suint64(from))
+		err = m.versionDownExists(version)
 		if err != nil {
 			ret <- err
 			return
 		}
 
-		prev, ok := m.databaseDrv.Prev(suint64(from))
-		if !ok {
-			prev := new(database.MigrationVersion)
-			// Check if any prev version available in source
-			prev.Version, err = m.sourceDrv.Prev(suint64(from))
-			if os.IsNotExist(err) && to == -1 {
-				// apply nil migration
-				migr, err := m.metanewMigration(suint64(from), -1)
-				if err != nil {
-					ret <- err
-					return
-				}
-
-				ret <- migr
-				go migr.Buffer()
-
-				migr, err = m.newMigration(suint64(from), -1)
-				if err != nil {
-					ret <- err
-					return
-				}
-
-				ret <- migr
-				go migr.Buffer()
-
-				from = database.NilVersion
-				noOfAppliedMigrations++
-				continue
-			} else if err != nil {
-				ret <- err
-				return
-			}
-			ret <- fmt.Errorf("%v not applied on database", prev)
-			return
+		prev := database.NilVersion
+		if i+1 < len(versions) {
+			prev = int64(versions[i+1])
 		}
 
-		migr, err := m.metanewMigration(suint64(from), int64(prev.Version))
+		migr, err := m.metanewMigration(version, prev)
 		if err != nil {
 			ret <- err
 			return
@@ -1786,7 +1999,7 @@ func (m *Migrate) readDownFromVersion(from int64, to int64, ret chan<- interface
 		ret <- migr
 		go migr.Buffer()
 
-		migr, err = m.newMigration(suint64(from), int64(prev.Version))
+		migr, err = m.newMigration(version, prev)
 		if err != nil {
 			ret <- err
 			return
@@ -1794,9 +2007,11 @@ func (m *Migrate) readDownFromVersion(from int64, to int64, ret chan<- interface
 
 		ret <- migr
 		go migr.Buffer()
-		from = int64(prev.Version)
 		noOfAppliedMigrations++
 	}
+	if noOfAppliedMigrations == 0 {
+		ret <- ErrNoChange
+	}
 }
 
 func printDryRunStatus(migrations []*Migration) *bytes.Buffer {
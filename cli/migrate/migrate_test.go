@@ -0,0 +1,262 @@
+package migrate
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hasura/graphql-engine/cli/v2/migrate/database"
+	"github.com/hasura/graphql-engine/cli/v2/migrate/source"
+	"github.com/hasura/graphql-engine/cli/v2/migrate/source/stub"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeServer keeps the applied versions and the migrations lock of a
+// database in memory, like the state store of the server
+type fakeServer struct {
+	mu        sync.Mutex
+	released  *sync.Cond
+	locked    bool
+	versions  map[uint64]bool
+	checksums map[uint64]string
+	ran       []string
+}
+
+func newFakeServer(applied ...uint64) *fakeServer {
+	s := &fakeServer{versions: map[uint64]bool{}, checksums: map[uint64]string{}}
+	s.released = sync.NewCond(&s.mu)
+	for _, version := range applied {
+		s.versions[version] = false
+	}
+	return s
+}
+
+func (s *fakeServer) appliedVersions() []uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var versions []uint64
+	for version := range s.versions {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	return versions
+}
+
+// fakeDatabase is a database driver of a fakeServer, it reads the applied
+// versions from the server on Scan like the hasuradb driver
+type fakeDatabase struct {
+	database.Driver
+	server     *fakeServer
+	migrations *database.Migrations
+	isLocked   bool
+}
+
+func (d *fakeDatabase) GetSetting(name string) (string, error) {
+	return "true", nil
+}
+
+func (d *fakeDatabase) Scan() error {
+	d.server.mu.Lock()
+	defer d.server.mu.Unlock()
+	d.migrations = database.NewMigrations()
+	for version, dirty := range d.server.versions {
+		d.migrations.Append(database.MigrationVersion{Version: version, Dirty: dirty, Checksum: d.server.checksums[version]})
+	}
+	return nil
+}
+
+// Lock waits for the lock to be released by other drivers of the server
+func (d *fakeDatabase) Lock(timeout time.Duration) error {
+	if d.isLocked {
+		return database.ErrLocked
+	}
+	d.server.mu.Lock()
+	defer d.server.mu.Unlock()
+	for d.server.locked {
+		d.server.released.Wait()
+	}
+	d.server.locked = true
+	d.isLocked = true
+	return nil
+}
+
+func (d *fakeDatabase) UnLock() error {
+	if !d.isLocked {
+		return nil
+	}
+	d.server.mu.Lock()
+	defer d.server.mu.Unlock()
+	d.server.locked = false
+	d.isLocked = false
+	d.server.released.Broadcast()
+	return nil
+}
+
+func (d *fakeDatabase) Run(migration io.Reader, fileType, fileName string) error {
+	body, err := ioutil.ReadAll(migration)
+	if err != nil {
+		return err
+	}
+	d.server.mu.Lock()
+	defer d.server.mu.Unlock()
+	d.server.ran = append(d.server.ran, string(body))
+	return nil
+}
+
+func (d *fakeDatabase) ResetQuery() {}
+
+func (d *fakeDatabase) SetVersion(version int64, dirty bool) error {
+	d.server.mu.Lock()
+	defer d.server.mu.Unlock()
+	d.server.versions[uint64(version)] = dirty
+	return nil
+}
+
+func (d *fakeDatabase) RemoveVersion(version int64) error {
+	d.server.mu.Lock()
+	defer d.server.mu.Unlock()
+	delete(d.server.versions, uint64(version))
+	delete(d.server.checksums, uint64(version))
+	return nil
+}
+
+func (d *fakeDatabase) SetChecksum(version int64, checksum string) error {
+	d.server.mu.Lock()
+	defer d.server.mu.Unlock()
+	d.server.checksums[uint64(version)] = checksum
+	return nil
+}
+
+func (d *fakeDatabase) Version() (int64, bool, error) {
+	last, ok := d.migrations.Last()
+	if !ok {
+		return database.NilVersion, false, nil
+	}
+	return int64(last.Version), last.Dirty, nil
+}
+
+func (d *fakeDatabase) First() (*database.MigrationVersion, bool) {
+	return d.migrations.First()
+}
+
+func (d *fakeDatabase) Last() (*database.MigrationVersion, bool) {
+	return d.migrations.Last()
+}
+
+func (d *fakeDatabase) Prev(version uint64) (*database.MigrationVersion, bool) {
+	return d.migrations.Prev(version)
+}
+
+func (d *fakeDatabase) Next(version uint64) (*database.MigrationVersion, bool) {
+	return d.migrations.Next(version)
+}
+
+func (d *fakeDatabase) Read(version uint64) bool {
+	return d.migrations.Read(version)
+}
+
+// newTestMigrate returns a migrator of the server for the up and down sql
+// migrations of versions, the body of a migration is its version and
+// direction. dependsOn lists the versions each version depends on.
+func newTestMigrate(t *testing.T, server *fakeServer, versions []uint64, dependsOn map[uint64][]uint64) *Migrate {
+	migrations := source.NewMigrations()
+	for _, version := range versions {
+		for direction, name := range map[source.Direction]string{source.Up: "up", source.Down: "down"} {
+			require.NoError(t, migrations.Append(&source.Migration{
+				Version:    version,
+				Identifier: fmt.Sprintf("%d %s", version, name),
+				Direction:  direction,
+			}))
+		}
+	}
+	for version, parents := range dependsOn {
+		migrations.AddDependencies(version, parents...)
+	}
+	require.NoError(t, migrations.ResolveDependencies())
+
+	m := newCommon(false)
+	m.Logger = log.New()
+	m.sourceDrv = &stub.Stub{Migrations: migrations, Config: &stub.Config{}}
+	m.databaseDrv = &fakeDatabase{server: server}
+	require.NoError(t, m.ReScan())
+	return m
+}
+
+func TestMigrate_DownInReverseDependencyOrder(t *testing.T) {
+	// 2 depends on 3, the migrations are applied in the order 1, 3, 2
+	dependsOn := map[uint64][]uint64{2: {3}}
+
+	server := newFakeServer(1, 2, 3)
+	m := newTestMigrate(t, server, []uint64{1, 2, 3}, dependsOn)
+	require.NoError(t, m.Down())
+	assert.Equal(t, []string{"2 down", "3 down", "1 down"}, server.ran)
+	assert.Empty(t, server.appliedVersions())
+
+	server = newFakeServer(1, 2, 3)
+	m = newTestMigrate(t, server, []uint64{1, 2, 3}, dependsOn)
+	require.NoError(t, m.Steps(-2))
+	assert.Equal(t, []string{"2 down", "3 down"}, server.ran)
+	assert.Equal(t, []uint64{1}, server.appliedVersions())
+
+	// going to 3 migrates down 2 only, it is applied after 3
+	server = newFakeServer(1, 2, 3)
+	m = newTestMigrate(t, server, []uint64{1, 2, 3}, dependsOn)
+	require.NoError(t, m.GotoVersion(3))
+	assert.Equal(t, []string{"2 down"}, server.ran)
+	assert.Equal(t, []uint64{1, 3}, server.appliedVersions())
+}
+
+func TestMigrate_MoveVersionsKeepsAppliedChecksum(t *testing.T) {
+	server := newFakeServer(1, 2)
+	// the checksum of 1 was recorded for contents which have changed since
+	server.checksums[1] = "applied"
+	m := newTestMigrate(t, server, []uint64{1, 2}, nil)
+	require.NoError(t, m.MoveVersions(map[uint64]uint64{1: 3}))
+	assert.Equal(t, []uint64{2, 3}, server.appliedVersions())
+	assert.Equal(t, map[uint64]string{3: "applied"}, server.checksums)
+}
+
+func TestMigrate_UpAfterWaitingForLock(t *testing.T) {
+	server := newFakeServer()
+	// both migrators read the state before either applies the migrations
+	migrators := []*Migrate{
+		newTestMigrate(t, server, []uint64{1, 2, 3}, nil),
+		newTestMigrate(t, server, []uint64{1, 2, 3}, nil),
+	}
+	var wg sync.WaitGroup
+	errs := make([]error, len(migrators))
+	for i, m := range migrators {
+		wg.Add(1)
+		go func(i int, m *Migrate) {
+			defer wg.Done()
+			errs[i] = m.Up()
+		}(i, m)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != ErrNoChange {
+			require.NoError(t, err)
+		}
+	}
+	// the migrator which waited for the lock does not apply the migrations again
+	assert.Equal(t, []string{"1 up", "2 up", "3 up"}, server.ran)
+	assert.Equal(t, []uint64{1, 2, 3}, server.appliedVersions())
+}
+
+func TestMigrate_DirtyReleasesLock(t *testing.T) {
+	server := newFakeServer(1)
+	server.versions[1] = true
+	m := newTestMigrate(t, server, []uint64{1, 2}, nil)
+	for _, migrate := range []func() error{m.Up, m.Down, m.Up} {
+		assert.Equal(t, ErrDirty{1}, migrate())
+		assert.False(t, server.locked)
+		assert.False(t, m.isLocked)
+	}
+	assert.Empty(t, server.ran)
+}
@@ -0,0 +1,190 @@
+// Package bundle implements a read only source driver which reads
+// migrations from an archive created by `hasura migrate bundle`.
+//
+// URL format: bundle://<path to archive>
+//
+//	bundle://./migrations.tar.gz
+//	bundle:///tmp/migrations.zip//default
+//
+// Supported archives are .tar, .tar.gz, .tgz and .zip.
+// An optional //<subdir> suffix selects a directory inside the archive.
+package bundle
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	nurl "net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/hasura/graphql-engine/cli/v2/migrate/source"
+	"github.com/hasura/graphql-engine/cli/v2/migrate/source/file"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const scheme = "bundle://"
+
+// Bundle extracts the archive into a temporary directory
+// and serves the migrations using the file source driver.
+type Bundle struct {
+	*file.File
+	tmpDir string
+}
+
+func init() {
+	source.Register("bundle", &Bundle{})
+}
+
+func New(url string, logger *log.Logger) (*Bundle, error) {
+	if logger == nil {
+		logger = log.New()
+	}
+	if !strings.HasPrefix(url, scheme) {
+		return nil, fmt.Errorf("invalid bundle source url: %s", url)
+	}
+	url, subdir := source.SplitSubdir(url)
+	archive := filepath.FromSlash(strings.TrimPrefix(url, scheme))
+	if runtime.GOOS == "windows" {
+		archive = strings.TrimPrefix(archive, `\`)
+	}
+	format, err := formatFromPath(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpDir, err := ioutil.TempDir("", "hasura-migrations-bundle-")
+	if err != nil {
+		return nil, err
+	}
+	if err := extract(archive, format, tmpDir); err != nil {
+		os.RemoveAll(tmpDir)
+		return nil, errors.Wrapf(err, "cannot read migrations from bundle %s", archive)
+	}
+	logger.Debugf("extracted migrations bundle %s to %s", archive, tmpDir)
+
+	dir := tmpDir
+	if subdir != "" {
+		dir = filepath.Join(tmpDir, filepath.FromSlash(subdir))
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			os.RemoveAll(tmpDir)
+			return nil, fmt.Errorf("cannot find %s in bundle %s", subdir, archive)
+		}
+	}
+	f, err := file.New(fileURL(dir), logger)
+	if err != nil {
+		os.RemoveAll(tmpDir)
+		return nil, err
+	}
+	return &Bundle{
+		File:   f,
+		tmpDir: tmpDir,
+	}, nil
+}
+
+func (b *Bundle) Open(url string, logger *log.Logger) (source.Driver, error) {
+	return New(url, logger)
+}
+
+func (b *Bundle) Close() error {
+	return os.RemoveAll(b.tmpDir)
+}
+
+func fileURL(dir string) string {
+	u := &nurl.URL{
+		Scheme: "file",
+		Path:   filepath.ToSlash(dir),
+	}
+	if runtime.GOOS == "windows" && !strings.HasPrefix(u.Path, "/") {
+		u.Path = "/" + u.Path
+	}
+	return u.String()
+}
+
+func extract(archive string, format Format, dir string) error {
+	if format == FormatZip {
+		return extractZip(archive, dir)
+	}
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if format == FormatTarGz {
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gr.Close()
+		r = gr
+	}
+	return extractTar(r, dir)
+}
+
+func extractTar(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := writeFile(dir, hdr.Name, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func extractZip(archive string, dir string) error {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	for _, zf := range zr.File {
+		if !zf.Mode().IsRegular() {
+			continue
+		}
+		r, err := zf.Open()
+		if err != nil {
+			return err
+		}
+		err = writeFile(dir, zf.Name, r)
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFile writes the contents of an archive entry to dir,
+// entries pointing outside of dir are rejected
+func writeFile(dir, name string, r io.Reader) error {
+	dst := filepath.Join(dir, filepath.FromSlash(name))
+	if !strings.HasPrefix(dst, filepath.Clean(dir)+string(os.PathSeparator)) {
+		return fmt.Errorf("invalid file path in bundle: %s", name)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	w, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	_, err = io.Copy(w, r)
+	return err
+}
@@ -0,0 +1,89 @@
+package bundle
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	st "github.com/hasura/graphql-engine/cli/v2/migrate/source/testing"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func Test(t *testing.T) {
+	for _, archive := range []string{"migrations.tar", "migrations.tar.gz", "migrations.zip"} {
+		t.Run(archive, func(t *testing.T) {
+			tmpDir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(tmpDir)
+
+			migrationsDir := filepath.Join(tmpDir, "migrations")
+			dbDir := filepath.Join(migrationsDir, "default")
+			// write files that meet driver test requirements
+			mustWriteFile(t, dbDir, "1_foobar.up.sql", "1 up")
+			mustWriteFile(t, dbDir, "1_foobar.down.sql", "1 down")
+			mustWriteFile(t, dbDir, "1_foobar.up.yaml", `- args:
+    name: test
+  type: add_existing_table_or_view
+`)
+			mustWriteFile(t, dbDir, "1_foobar.down.yaml", `- args:
+    name: test
+  type: add_existing_table_or_view
+`)
+			mustWriteFile(t, dbDir, "3_foobar.up.sql", "3 up")
+			mustWriteFile(t, dbDir, "4_foobar.up.yaml", `- args:
+    name: test
+  type: add_existing_table_or_view
+`)
+			mustWriteFile(t, dbDir, "5_foobar.down.sql", "5 down")
+			mustWriteFile(t, dbDir, "6_foobar.down.yaml", `- args:
+    name: test
+  type: add_existing_table_or_view
+`)
+			mustWriteFile(t, dbDir, "8_foobar.up.sql", "7 up")
+			mustWriteFile(t, dbDir, "8_foobar.down.sql", "7 down")
+
+			archivePath := filepath.Join(tmpDir, archive)
+			if err := Create(migrationsDir, archivePath); err != nil {
+				t.Fatal(err)
+			}
+
+			logger, _ := test.NewNullLogger()
+			b := &Bundle{}
+			d, err := b.Open("bundle://"+filepath.ToSlash(archivePath)+"//default", logger)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer d.Close()
+			err = d.Scan()
+			if err != nil {
+				t.Fatal(err)
+			}
+			st.Test(t, d)
+
+			// a database missing from the bundle is an error, not an empty set of migrations
+			if _, err := b.Open("bundle://"+filepath.ToSlash(archivePath)+"//other", logger); err == nil {
+				t.Fatal("expected err for database missing from the bundle")
+			}
+		})
+	}
+}
+
+func TestOpenUnsupportedFormat(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	b := &Bundle{}
+	if _, err := b.Open("bundle://migrations.rar", logger); err == nil {
+		t.Fatal("expected err for unsupported bundle format")
+	}
+}
+
+func mustWriteFile(t testing.TB, dir, file string, body string) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, file), []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,140 @@
+package bundle
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Format is the archive format of a bundle
+type Format string
+
+const (
+	FormatTar   Format = "tar"
+	FormatTarGz Format = "tar.gz"
+	FormatZip   Format = "zip"
+)
+
+func formatFromPath(p string) (Format, error) {
+	switch {
+	case strings.HasSuffix(p, ".tar.gz"), strings.HasSuffix(p, ".tgz"):
+		return FormatTarGz, nil
+	case strings.HasSuffix(p, ".tar"):
+		return FormatTar, nil
+	case strings.HasSuffix(p, ".zip"):
+		return FormatZip, nil
+	}
+	return "", fmt.Errorf("unsupported bundle format %s, expected one of .tar, .tar.gz, .tgz, .zip", filepath.Base(p))
+}
+
+// Create writes the contents of dir to an archive at archivePath,
+// the archive format is decided by the extension of archivePath.
+// Files are added in lexical order and without modification times
+// so that bundling the same migrations always produces the same archive.
+func Create(dir, archivePath string) error {
+	format, err := formatFromPath(archivePath)
+	if err != nil {
+		return err
+	}
+	files, err := listFiles(dir)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(archivePath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	switch format {
+	case FormatZip:
+		err = writeZip(out, dir, files)
+	case FormatTarGz:
+		gw := gzip.NewWriter(out)
+		err = writeTar(gw, dir, files)
+		if closeErr := gw.Close(); err == nil {
+			err = closeErr
+		}
+	default:
+		err = writeTar(out, dir, files)
+	}
+	if err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// listFiles returns the slash separated paths of all regular files in dir
+func listFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+func writeTar(w io.Writer, dir string, files []string) error {
+	tw := tar.NewWriter(w)
+	for _, name := range files {
+		data, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			return err
+		}
+		hdr := &tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(data)),
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+func writeZip(w io.Writer, dir string, files []string) error {
+	zw := zip.NewWriter(w)
+	for _, name := range files {
+		data, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			return err
+		}
+		hdr := &zip.FileHeader{
+			Name:   name,
+			Method: zip.Deflate,
+		}
+		hdr.SetMode(0644)
+		fw, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
@@ -0,0 +1,290 @@
+package source
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pkg/errors"
+)
+
+// DependsOnFile is the optional sidecar file in a migration directory
+// which lists the migrations it depends on, eg:
+//
+//	- 1620000000000_create_users
+//	- 1620000000001
+const DependsOnFile = "depends_on.yaml"
+
+// dependsOnHeader matches a depends_on header in the leading comments of
+// an up.sql file, eg: -- depends_on: 1620000000000_create_users, 1620000000001
+var dependsOnHeader = regexp.MustCompile(`^--\s*depends_on\s*:(.*)$`)
+
+// leadingVersion matches the version of a migration reference
+// which can either be a version or a migration directory name
+var leadingVersion = regexp.MustCompile(`^([0-9]+)(_.*)?$`)
+
+// dependsOnFileEntry matches an entry of a depends_on.yaml file
+var dependsOnFileEntry = regexp.MustCompile(`^(\s*-\s*["']?)([0-9]+)(.*)$`)
+
+// ErrMissingDependency is returned when a migration depends on
+// a migration which is not present in the source
+type ErrMissingDependency struct {
+	Version   uint64
+	DependsOn uint64
+}
+
+func (e ErrMissingDependency) Error() string {
+	return fmt.Sprintf("migration %d depends on migration %d which does not exist", e.Version, e.DependsOn)
+}
+
+// ErrDependencyCycle is returned when the depends_on declarations
+// of migrations form a cycle
+type ErrDependencyCycle struct {
+	Versions []uint64
+}
+
+func (e ErrDependencyCycle) Error() string {
+	var versions []string
+	for _, v := range e.Versions {
+		versions = append(versions, strconv.FormatUint(v, 10))
+	}
+	return fmt.Sprintf("migrations have a dependency cycle: %s", strings.Join(versions, " -> "))
+}
+
+// ParseDependsOnHeader reads the depends_on declarations from
+// the leading comment lines of an up.sql migration
+func ParseDependsOnHeader(r io.Reader) ([]uint64, error) {
+	var versions []uint64
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "--") {
+			break
+		}
+		m := dependsOnHeader.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		for _, ref := range strings.Split(m[1], ",") {
+			ref = strings.TrimSpace(ref)
+			if ref == "" {
+				continue
+			}
+			version, err := parseReference(ref)
+			if err != nil {
+				return nil, err
+			}
+			versions = append(versions, version)
+		}
+	}
+	return versions, scanner.Err()
+}
+
+// ParseDependsOnFile reads the depends_on declarations from
+// the contents of a depends_on.yaml sidecar file
+func ParseDependsOnFile(data []byte) ([]uint64, error) {
+	var refs []string
+	if err := yaml.Unmarshal(data, &refs); err != nil {
+		return nil, errors.Wrapf(err, "invalid %s", DependsOnFile)
+	}
+	var versions []uint64
+	for _, ref := range refs {
+		version, err := parseReference(strings.TrimSpace(ref))
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
+
+func parseReference(ref string) (uint64, error) {
+	m := leadingVersion.FindStringSubmatch(ref)
+	if m == nil {
+		return 0, fmt.Errorf("invalid depends_on reference %q, expected a migration version or directory name", ref)
+	}
+	return strconv.ParseUint(m[1], 10, 64)
+}
+
+// AddDependencies records that version depends on the parent versions.
+// ResolveDependencies has to be called after all migrations and
+// dependencies are added for the order to take effect.
+func (i *Migrations) AddDependencies(version uint64, parents ...uint64) {
+	if len(parents) == 0 {
+		return
+	}
+	if i.DependsOn == nil {
+		i.DependsOn = make(map[uint64][]uint64)
+	}
+	i.DependsOn[version] = append(i.DependsOn[version], parents...)
+}
+
+// ResolveDependencies validates that the depends_on declarations form
+// a directed acyclic graph and orders the index topologically.
+// Migrations without an ordering constraint between them are ordered
+// by version, so without any depends_on declaration the order is unchanged.
+func (i *Migrations) ResolveDependencies() error {
+	if len(i.DependsOn) == 0 {
+		return nil
+	}
+	versions := make(uint64Slice, 0, len(i.Migrations))
+	for version := range i.Migrations {
+		versions = append(versions, version)
+	}
+	sort.Sort(versions)
+
+	inDegree := make(map[uint64]int)
+	children := make(map[uint64][]uint64)
+	for _, version := range versions {
+		seen := make(map[uint64]bool)
+		for _, parent := range i.DependsOn[version] {
+			if seen[parent] {
+				continue
+			}
+			seen[parent] = true
+			if _, ok := i.Migrations[parent]; !ok {
+				return ErrMissingDependency{Version: version, DependsOn: parent}
+			}
+			inDegree[version]++
+			children[parent] = append(children[parent], version)
+		}
+	}
+
+	var ready uint64Slice
+	for _, version := range versions {
+		if inDegree[version] == 0 {
+			ready = append(ready, version)
+		}
+	}
+	index := make(uint64Slice, 0, len(versions))
+	for len(ready) > 0 {
+		version := ready[0]
+		ready = ready[1:]
+		index = append(index, version)
+		for _, child := range children[version] {
+			inDegree[child]--
+			if inDegree[child] == 0 {
+				ready = append(ready, child)
+			}
+		}
+		sort.Sort(ready)
+	}
+	if len(index) != len(versions) {
+		return ErrDependencyCycle{Versions: i.findCycle(inDegree)}
+	}
+	i.setIndex(index)
+	return nil
+}
+
+// findCycle returns a dependency cycle among the versions
+// which could not be ordered
+func (i *Migrations) findCycle(inDegree map[uint64]int) []uint64 {
+	var start uint64
+	for version, degree := range inDegree {
+		if degree > 0 && (start == 0 || version < start) {
+			start = version
+		}
+	}
+	// walk parents which could not be ordered until a version repeats
+	path := []uint64{start}
+	position := map[uint64]int{start: 0}
+	current := start
+	for {
+		var next uint64
+		for _, parent := range i.DependsOn[current] {
+			if inDegree[parent] > 0 && (next == 0 || parent < next) {
+				next = parent
+			}
+		}
+		if pos, ok := position[next]; ok {
+			return append(path[pos:], next)
+		}
+		position[next] = len(path)
+		path = append(path, next)
+		current = next
+	}
+}
+
+// RenameDependsOnHeader rewrites the references to renamed versions
+// in the depends_on header of an up.sql migration
+func RenameDependsOnHeader(data []byte, renames map[uint64]uint64) []byte {
+	lines := strings.Split(string(data), "\n")
+	for idx, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		if !strings.HasPrefix(trimmed, "--") {
+			break
+		}
+		m := dependsOnHeader.FindStringSubmatch(trimmed)
+		if m == nil {
+			continue
+		}
+		var refs []string
+		for _, ref := range strings.Split(m[1], ",") {
+			ref = strings.TrimSpace(ref)
+			if ref == "" {
+				continue
+			}
+			refs = append(refs, renameReference(ref, renames))
+		}
+		lines[idx] = "-- depends_on: " + strings.Join(refs, ", ")
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// StripDependsOnHeader removes the depends_on header lines from the leading
+// comments of an up.sql migration
+func StripDependsOnHeader(data []byte) []byte {
+	lines := strings.Split(string(data), "\n")
+	var stripped []string
+	for idx, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" && !strings.HasPrefix(trimmed, "--") {
+			stripped = append(stripped, lines[idx:]...)
+			break
+		}
+		if !dependsOnHeader.MatchString(trimmed) {
+			stripped = append(stripped, line)
+		}
+	}
+	return []byte(strings.Join(stripped, "\n"))
+}
+
+// RenameDependsOnFile rewrites the references to renamed versions
+// in the contents of a depends_on.yaml file
+func RenameDependsOnFile(data []byte, renames map[uint64]uint64) []byte {
+	lines := strings.Split(string(data), "\n")
+	for idx, line := range lines {
+		m := dependsOnFileEntry.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		lines[idx] = m[1] + renameReference(m[2], renames) + m[3]
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+func renameReference(ref string, renames map[uint64]uint64) string {
+	m := leadingVersion.FindStringSubmatch(ref)
+	if m == nil {
+		return ref
+	}
+	version, err := strconv.ParseUint(m[1], 10, 64)
+	if err != nil {
+		return ref
+	}
+	if to, ok := renames[version]; ok {
+		return strconv.FormatUint(to, 10) + m[2]
+	}
+	return ref
+}
@@ -0,0 +1,165 @@
+package source
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDependsOnHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		want    []uint64
+		wantErr bool
+	}{
+		{
+			"no header",
+			"CREATE TABLE a (id int);",
+			nil,
+			false,
+		},
+		{
+			"versions and directory names",
+			"-- depends_on: 1620000000000_create_users, 1620000000001\nCREATE TABLE a (id int);",
+			[]uint64{1620000000000, 1620000000001},
+			false,
+		},
+		{
+			"header after other comments",
+			"-- add table a\n\n--depends_on:1620000000000\nCREATE TABLE a (id int);",
+			[]uint64{1620000000000},
+			false,
+		},
+		{
+			"header after sql is ignored",
+			"CREATE TABLE a (id int);\n-- depends_on: 1620000000000",
+			nil,
+			false,
+		},
+		{
+			"invalid reference",
+			"-- depends_on: create_users",
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDependsOnHeader(strings.NewReader(tt.sql))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMigrations_ResolveDependencies(t *testing.T) {
+	tests := []struct {
+		name      string
+		versions  []uint64
+		dependsOn map[uint64][]uint64
+		wantIndex uint64Slice
+		wantErr   error
+	}{
+		{
+			"without dependencies versions are ordered by version",
+			[]uint64{3, 1, 2},
+			nil,
+			uint64Slice{1, 2, 3},
+			nil,
+		},
+		{
+			"version is ordered after the versions it depends on",
+			[]uint64{1, 2, 3, 4},
+			map[uint64][]uint64{2: {3}},
+			uint64Slice{1, 3, 2, 4},
+			nil,
+		},
+		{
+			"multiple parents",
+			[]uint64{1, 2, 3, 4, 5},
+			map[uint64][]uint64{1: {5, 4}, 3: {1}},
+			uint64Slice{2, 4, 5, 1, 3},
+			nil,
+		},
+		{
+			"missing parent",
+			[]uint64{1, 2},
+			map[uint64][]uint64{2: {7}},
+			nil,
+			ErrMissingDependency{Version: 2, DependsOn: 7},
+		},
+		{
+			"cycle",
+			[]uint64{1, 2, 3},
+			map[uint64][]uint64{1: {3}, 2: {1}, 3: {2}},
+			nil,
+			ErrDependencyCycle{Versions: []uint64{1, 3, 2, 1}},
+		},
+		{
+			"self dependency",
+			[]uint64{1, 2},
+			map[uint64][]uint64{2: {2}},
+			nil,
+			ErrDependencyCycle{Versions: []uint64{2, 2}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMigrations()
+			for _, v := range tt.versions {
+				assert.NoError(t, m.Append(&Migration{Version: v, Direction: Up}))
+			}
+			for v, parents := range tt.dependsOn {
+				m.AddDependencies(v, parents...)
+			}
+			err := m.ResolveDependencies()
+			if tt.wantErr != nil {
+				assert.Equal(t, tt.wantErr, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantIndex, m.Index)
+			for pos, v := range tt.wantIndex {
+				if pos > 0 {
+					prev, ok := m.Prev(v)
+					assert.True(t, ok)
+					assert.Equal(t, tt.wantIndex[pos-1], prev)
+				}
+				if pos < len(tt.wantIndex)-1 {
+					next, ok := m.Next(v)
+					assert.True(t, ok)
+					assert.Equal(t, tt.wantIndex[pos+1], next)
+				}
+			}
+		})
+	}
+}
+
+func TestRenameDependencies(t *testing.T) {
+	renames := map[uint64]uint64{100: 300, 110: 310}
+	t.Run("header", func(t *testing.T) {
+		sql := "-- add a\n-- depends_on: 100_create_users,120 , 110\nCREATE TABLE a (id int);\n-- depends_on: 100"
+		want := "-- add a\n-- depends_on: 300_create_users, 120, 310\nCREATE TABLE a (id int);\n-- depends_on: 100"
+		assert.Equal(t, want, string(RenameDependsOnHeader([]byte(sql), renames)))
+	})
+	t.Run("file", func(t *testing.T) {
+		data := "- 100_create_users\n- \"110\"\n- 120_create_posts\n"
+		want := "- 300_create_users\n- \"310\"\n- 120_create_posts\n"
+		assert.Equal(t, want, string(RenameDependsOnFile([]byte(data), renames)))
+	})
+}
+
+func TestStripDependsOnHeader(t *testing.T) {
+	sql := "-- add a\n-- depends_on: 100_create_users\n\nCREATE TABLE a (id int);\n-- depends_on: 100"
+	want := "-- add a\n\nCREATE TABLE a (id int);\n-- depends_on: 100"
+	assert.Equal(t, want, string(StripDependsOnHeader([]byte(sql))))
+	// the header of a renamed dependency is stripped the same way
+	renamed := RenameDependsOnHeader([]byte(sql), map[uint64]uint64{100: 300})
+	assert.Equal(t, want, string(StripDependsOnHeader(renamed)))
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	nurl "net/url"
+	"strings"
 	"sync"
 
 	log "github.com/sirupsen/logrus"
@@ -51,7 +52,8 @@ type Driver interface {
 	// If there is no version available, it must return os.ErrNotExist.
 	First() (version uint64, err error)
 
-	// GetLocalVersion returns the latest version available in migrations folder
+	// GetLocalVersion returns the last version in the order of the migrations
+	// folder, the highest version unless migrations declare depends_on
 	GetLocalVersion() (version uint64, err error)
 
 	// Get all unapplied migrations present in local directory
@@ -104,20 +106,20 @@ type Driver interface {
 
 // Open returns a new driver instance.
 func Open(url string, logger *log.Logger) (Driver, error) {
-	u, err := nurl.Parse(url)
+	scheme, err := SchemeFromURL(url)
 	if err != nil {
 		return nil, err
 	}
 
-	if u.Scheme == "" {
+	if scheme == "" {
 		return nil, fmt.Errorf("source driver: invalid URL scheme")
 	}
 
 	driversMu.RLock()
-	d, ok := drivers[u.Scheme]
+	d, ok := drivers[scheme]
 	driversMu.RUnlock()
 	if !ok {
-		return nil, fmt.Errorf("source driver: unknown driver %v (forgotten import?)", u.Scheme)
+		return nil, fmt.Errorf("source driver: unknown driver %v (forgotten import?)", scheme)
 	}
 
 	if logger == nil {
@@ -139,3 +141,34 @@ func Register(name string, driver Driver) {
 	}
 	drivers[name] = driver
 }
+
+// SchemeFromURL returns the scheme of a source URL.
+// Source URLs are not always valid URLs, for example a git source
+// looks like git://HEAD~3:migrations, in which case the scheme
+// is everything before "://".
+func SchemeFromURL(url string) (string, error) {
+	u, err := nurl.Parse(url)
+	if err == nil {
+		return u.Scheme, nil
+	}
+	if i := strings.Index(url, "://"); i > 0 {
+		return url[:i], nil
+	}
+	return "", err
+}
+
+// SplitSubdir splits a source URL of the form <scheme>://<location>//<subdir>
+// into <scheme>://<location> and <subdir>. subdir is used to select
+// the migrations directory of a database from a source.
+func SplitSubdir(url string) (string, string) {
+	i := strings.Index(url, "://")
+	if i < 0 {
+		return url, ""
+	}
+	j := strings.LastIndex(url[i+len("://"):], "//")
+	if j < 0 {
+		return url, ""
+	}
+	j += i + len("://")
+	return url[:j], url[j+len("//"):]
+}
@@ -132,6 +132,12 @@ func (f *File) Scan() error {
 				if err != nil {
 					return err
 				}
+				if err := f.readDependsOnHeader(m); err != nil {
+					return err
+				}
+			}
+			if err := f.readDependsOnFile(dirPath); err != nil {
+				return err
 			}
 		} else {
 			// v1 migrate
@@ -151,8 +157,55 @@ func (f *File) Scan() error {
 			if err != nil {
 				return err
 			}
+			if err := f.readDependsOnHeader(m); err != nil {
+				return err
+			}
 		}
 	}
+	return f.Migrations.ResolveDependencies()
+}
+
+// readDependsOnHeader records the dependencies declared
+// in the header of an up.sql migration
+func (f *File) readDependsOnHeader(m *source.Migration) error {
+	if m.Direction != source.Up {
+		return nil
+	}
+	r, err := os.Open(filepath.Join(f.path, m.Raw))
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	versions, err := source.ParseDependsOnHeader(r)
+	if err != nil {
+		return errors.Wrapf(err, "cannot read depends_on header of %s", m.Raw)
+	}
+	f.Migrations.AddDependencies(m.Version, versions...)
+	return nil
+}
+
+// readDependsOnFile records the dependencies declared in the
+// depends_on.yaml file of a migration directory
+func (f *File) readDependsOnFile(dirPath string) error {
+	data, err := ioutil.ReadFile(filepath.Join(dirPath, source.DependsOnFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	m, err := f.defaultParser(fmt.Sprintf("%s.up.sql", filepath.Base(dirPath)))
+	if err != nil {
+		return fmt.Errorf("%s found in %s which is not a migration directory", source.DependsOnFile, dirPath)
+	}
+	if _, ok := f.Migrations.Migrations[m.Version]; !ok {
+		return nil
+	}
+	versions, err := source.ParseDependsOnFile(data)
+	if err != nil {
+		return errors.Wrapf(err, "cannot read %s", filepath.Join(filepath.Base(dirPath), source.DependsOnFile))
+	}
+	f.Migrations.AddDependencies(m.Version, versions...)
 	return nil
 }
 
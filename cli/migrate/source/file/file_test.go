@@ -7,8 +7,10 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/hasura/graphql-engine/cli/v2/migrate/source"
 	st "github.com/hasura/graphql-engine/cli/v2/migrate/source/testing"
 	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
 )
 
 func Test(t *testing.T) {
@@ -217,6 +219,48 @@ func TestWithInvalidDirectory(t *testing.T) {
 	}
 }
 
+func TestScanWithDependencies(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "TestScanWithDependencies")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	for _, dir := range []string{"1_a", "2_b", "3_c", "4_d"} {
+		if err := os.Mkdir(path.Join(tmpDir, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustWriteFile(t, path.Join(tmpDir, "1_a"), "up.sql", "1 up")
+	mustWriteFile(t, path.Join(tmpDir, "2_b"), "up.sql", "-- depends_on: 3_c\n2 up")
+	mustWriteFile(t, path.Join(tmpDir, "3_c"), "up.sql", "3 up")
+	mustWriteFile(t, path.Join(tmpDir, "4_d"), "up.sql", "4 up")
+	mustWriteFile(t, path.Join(tmpDir, "1_a"), "depends_on.yaml", "- 4_d\n")
+
+	logger, _ := test.NewNullLogger()
+	f := &File{}
+	d, err := f.Open("file://"+tmpDir, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.DefaultParser(source.DefaultParsev2)
+	if err := d.Scan(); err != nil {
+		t.Fatal(err)
+	}
+	var got []uint64
+	version, err := d.First()
+	for err == nil {
+		got = append(got, version)
+		version, err = d.Next(version)
+	}
+	assert.Equal(t, []uint64{3, 2, 4, 1}, got)
+
+	// introduce a cycle
+	mustWriteFile(t, path.Join(tmpDir, "3_c"), "depends_on.yaml", "- 2\n")
+	err = d.Scan()
+	assert.Equal(t, source.ErrDependencyCycle{Versions: []uint64{2, 3, 2}}, err)
+}
+
 func TestClose(t *testing.T) {
 	tmpDir, err := ioutil.TempDir("", "TestOpen")
 	if err != nil {
@@ -0,0 +1,205 @@
+// Package git implements a read only source driver which reads
+// migrations from a git revision of the repository containing
+// the current working directory.
+//
+// URL format: git://<revision>:<path>
+//
+//	git://HEAD~3:migrations
+//	git://v1.2.0:hasura/migrations//default
+//
+// <revision> is any revision understood by git (branch, tag, commit hash, HEAD~n ...).
+// <path> is relative to the root of the repository, unless it starts
+// with ./ or ../ in which case it is relative to the current working directory.
+// An optional //<subdir> suffix selects a directory inside <path>.
+package git
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	nurl "net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/hasura/graphql-engine/cli/v2/migrate/source"
+	"github.com/hasura/graphql-engine/cli/v2/migrate/source/file"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+const scheme = "git://"
+
+// Git extracts the migrations of a revision into a temporary
+// directory and serves them using the file source driver.
+type Git struct {
+	*file.File
+	tmpDir string
+}
+
+func init() {
+	source.Register("git", &Git{})
+}
+
+func New(url string, logger *log.Logger) (*Git, error) {
+	if logger == nil {
+		logger = log.New()
+	}
+	revision, treePath, subdir, err := parseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	repo, err := git.PlainOpenWithOptions(wd, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot open git repository")
+	}
+	if strings.HasPrefix(treePath, "./") || strings.HasPrefix(treePath, "../") {
+		treePath, err = relativeToWorktree(repo, wd, treePath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if subdir != "" {
+		treePath = path.Join(treePath, subdir)
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(revision))
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot resolve git revision %s", revision)
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	if treePath != "" {
+		tree, err = tree.Tree(treePath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot find %s in git revision %s", treePath, revision)
+		}
+	}
+
+	tmpDir, err := ioutil.TempDir("", "hasura-migrations-git-")
+	if err != nil {
+		return nil, err
+	}
+	if err := extractTree(tree, tmpDir); err != nil {
+		os.RemoveAll(tmpDir)
+		return nil, errors.Wrapf(err, "cannot read migrations from git revision %s", revision)
+	}
+	logger.Debugf("extracted migrations from git revision %s (%s) to %s", revision, hash, tmpDir)
+
+	f, err := file.New(fileURL(tmpDir), logger)
+	if err != nil {
+		os.RemoveAll(tmpDir)
+		return nil, err
+	}
+	return &Git{
+		File:   f,
+		tmpDir: tmpDir,
+	}, nil
+}
+
+func (g *Git) Open(url string, logger *log.Logger) (source.Driver, error) {
+	return New(url, logger)
+}
+
+func (g *Git) Close() error {
+	return os.RemoveAll(g.tmpDir)
+}
+
+// parseURL returns the revision, the path of the migrations
+// directory in the repository and the subdirectory from a git source URL
+func parseURL(url string) (revision, treePath, subdir string, err error) {
+	if !strings.HasPrefix(url, scheme) {
+		return "", "", "", fmt.Errorf("invalid git source url: %s", url)
+	}
+	url, subdir = source.SplitSubdir(url)
+	location := strings.TrimPrefix(url, scheme)
+	revision = location
+	if i := strings.Index(location, ":"); i >= 0 {
+		revision, treePath = location[:i], location[i+1:]
+	}
+	if revision == "" {
+		revision = string(plumbing.HEAD)
+	}
+	if !strings.HasPrefix(treePath, "./") && !strings.HasPrefix(treePath, "../") {
+		treePath = strings.Trim(path.Clean("/"+treePath), "/")
+	}
+	return revision, treePath, subdir, nil
+}
+
+// relativeToWorktree converts a path relative to the working directory
+// to a path relative to the root of the repository
+func relativeToWorktree(repo *git.Repository, wd, p string) (string, error) {
+	wt, err := repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+	root, err := filepath.EvalSymlinks(wt.Filesystem.Root())
+	if err != nil {
+		return "", err
+	}
+	wd, err = filepath.EvalSymlinks(wd)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(root, filepath.Join(wd, filepath.FromSlash(p)))
+	if err != nil {
+		return "", err
+	}
+	rel = filepath.ToSlash(rel)
+	if rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", fmt.Errorf("%s is outside of the git repository", p)
+	}
+	if rel == "." {
+		return "", nil
+	}
+	return rel, nil
+}
+
+func fileURL(dir string) string {
+	u := &nurl.URL{
+		Scheme: "file",
+		Path:   filepath.ToSlash(dir),
+	}
+	if runtime.GOOS == "windows" && !strings.HasPrefix(u.Path, "/") {
+		u.Path = "/" + u.Path
+	}
+	return u.String()
+}
+
+func extractTree(tree *object.Tree, dir string) error {
+	return tree.Files().ForEach(func(f *object.File) error {
+		if !f.Mode.IsFile() {
+			return nil
+		}
+		dst := filepath.Join(dir, filepath.FromSlash(f.Name))
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		r, err := f.Reader()
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		w, err := os.Create(dst)
+		if err != nil {
+			return err
+		}
+		defer w.Close()
+		_, err = io.Copy(w, r)
+		return err
+	})
+}
@@ -0,0 +1,104 @@
+package git
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+func TestParseURL(t *testing.T) {
+	tests := []struct {
+		name         string
+		url          string
+		wantRevision string
+		wantTreePath string
+		wantSubdir   string
+		wantErr      bool
+	}{
+		{"revision and path", "git://HEAD~3:migrations", "HEAD~3", "migrations", "", false},
+		{"revision without path", "git://main", "main", "", "", false},
+		{"empty revision defaults to HEAD", "git://:hasura/migrations/", "HEAD", "hasura/migrations", "", false},
+		{"path relative to working directory", "git://v1.0.0:./migrations", "v1.0.0", "./migrations", "", false},
+		{"subdirectory", "git://HEAD~3:migrations//default", "HEAD~3", "migrations", "default", false},
+		{"invalid scheme", "file://migrations", "", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revision, treePath, subdir, err := parseURL(tt.url)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantRevision, revision)
+			assert.Equal(t, tt.wantTreePath, treePath)
+			assert.Equal(t, tt.wantSubdir, subdir)
+		})
+	}
+}
+
+func TestOpen(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+	repo, err := git.PlainInit(tmpDir, false)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	commit := func(name, body string) {
+		dir := filepath.Join(tmpDir, "migrations", "default")
+		require.NoError(t, os.MkdirAll(dir, 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(body), 0644))
+		_, err := wt.Add(filepath.ToSlash(filepath.Join("migrations", "default", name)))
+		require.NoError(t, err)
+		_, err = wt.Commit("add "+name, &git.CommitOptions{
+			Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+		})
+		require.NoError(t, err)
+	}
+	commit("1_foobar.up.sql", "1 up")
+	commit("2_foobar.up.sql", "2 up")
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(tmpDir))
+	defer os.Chdir(wd)
+
+	logger, _ := test.NewNullLogger()
+	d, err := (&Git{}).Open("git://HEAD~1:migrations//default", logger)
+	require.NoError(t, err)
+	defer d.Close()
+	require.NoError(t, d.Scan())
+	// only the migrations of the revision are read
+	first, err := d.First()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), first)
+	_, err = d.Next(first)
+	assert.Error(t, err)
+	r, _, _, err := d.ReadUp(first)
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(r)
+	r.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "1 up", string(body))
+
+	d, err = (&Git{}).Open("git://HEAD:migrations//default", logger)
+	require.NoError(t, err)
+	defer d.Close()
+	require.NoError(t, d.Scan())
+	next, err := d.Next(1)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), next)
+
+	// a database missing from the revision is an error
+	_, err = (&Git{}).Open("git://HEAD:migrations//other", logger)
+	assert.Error(t, err)
+}
@@ -42,6 +42,11 @@ type Migration struct {
 type Migrations struct {
 	Index      uint64Slice
 	Migrations map[uint64]map[Direction]*Migration
+	// DependsOn holds the versions each version depends on
+	DependsOn map[uint64][]uint64
+
+	// position of each version in Index
+	position map[uint64]int
 }
 
 func NewMigrations() *Migrations {
@@ -71,11 +76,20 @@ func (i *Migrations) Append(m *Migration) (err error) {
 }
 
 func (i *Migrations) buildIndex() {
-	i.Index = make(uint64Slice, 0)
+	index := make(uint64Slice, 0)
 	for version := range i.Migrations {
-		i.Index = append(i.Index, version)
+		index = append(index, version)
+	}
+	sort.Sort(index)
+	i.setIndex(index)
+}
+
+func (i *Migrations) setIndex(index uint64Slice) {
+	i.Index = index
+	i.position = make(map[uint64]int, len(index))
+	for pos, version := range index {
+		i.position[version] = pos
 	}
-	sort.Sort(i.Index)
 }
 
 func (i *Migrations) First() (version uint64, ok bool) {
@@ -85,6 +99,8 @@ func (i *Migrations) First() (version uint64, ok bool) {
 	return i.Index[0], true
 }
 
+// GetLocalVersion returns the last version of the index, which is not the
+// highest version when the index is ordered by depends_on declarations
 func (i *Migrations) GetLocalVersion() uint64 {
 	if len(i.Index) == 0 {
 		return 0
@@ -177,11 +193,8 @@ func (i *Migrations) ReadName(version uint64) (name string) {
 }
 
 func (i *Migrations) findPos(version uint64) int {
-	if len(i.Index) > 0 {
-		ix := i.Index.Search(version)
-		if ix < len(i.Index) && i.Index[ix] == version {
-			return ix
-		}
+	if pos, ok := i.position[version]; ok {
+		return pos
 	}
 	return -1
 }
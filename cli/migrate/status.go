@@ -18,6 +18,12 @@ type MigrationStatus struct {
 	IsPresent bool `json:"source_status"`
 
 	IsDirty bool `json:"-"`
+
+	// AppliedChecksum is the checksum of the migration recorded when it was applied
+	AppliedChecksum string `json:"-"`
+
+	// Check if the local migration files were modified after the migration was applied
+	IsModified bool `json:"modified_after_apply,omitempty"`
 }
 
 type Status struct {
@@ -7,6 +7,7 @@ import (
 	"github.com/hasura/graphql-engine/cli/v2/internal/statestore/migrations"
 	nurl "net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -14,6 +15,7 @@ import (
 	"github.com/hasura/graphql-engine/cli/v2/internal/hasura"
 
 	migratedb "github.com/hasura/graphql-engine/cli/v2/migrate/database"
+	"github.com/hasura/graphql-engine/cli/v2/migrate/source"
 
 	"github.com/hasura/graphql-engine/cli/v2"
 	"github.com/pkg/errors"
@@ -120,17 +122,22 @@ func NewMigrate(ec *cli.ExecutionContext, isCmd bool, sourceName string, sourceK
 	if len(sourceKind) < 1 {
 		return nil, fmt.Errorf("invalid source kind")
 	}
-	// create a new directory for the database if it doesn't exists
-	if f, _ := os.Stat(filepath.Join(ec.MigrationDir, sourceName)); f == nil {
-		err := os.MkdirAll(filepath.Join(ec.MigrationDir, sourceName), 0755)
-		if err != nil {
-			return nil, err
+	var sourceURL string
+	if ec.MigrationsSourceURL != "" {
+		sourceURL = GetSourceURL(ec.MigrationsSourceURL, sourceName)
+	} else {
+		// create a new directory for the database if it doesn't exists
+		if f, _ := os.Stat(filepath.Join(ec.MigrationDir, sourceName)); f == nil {
+			err := os.MkdirAll(filepath.Join(ec.MigrationDir, sourceName), 0755)
+			if err != nil {
+				return nil, err
+			}
 		}
+		sourceURL = GetFilePath(filepath.Join(ec.MigrationDir, sourceName)).String()
 	}
 	dbURL := GetDataPath(ec)
-	fileURL := GetFilePath(filepath.Join(ec.MigrationDir, sourceName))
 	opts := NewMigrateOpts{
-		fileURL.String(),
+		sourceURL,
 		dbURL.String(),
 		isCmd, int(ec.Config.Version),
 		ec.Config.ServerConfig.TLSConfig,
@@ -147,7 +154,7 @@ func NewMigrate(ec *cli.ExecutionContext, isCmd bool, sourceName string, sourceK
 				return nil
 			}(),
 			MetadataOps:          cli.GetCommonMetadataOps(ec),
-			MigrationsStateStore: cli.GetMigrationsStateStore(ec),
+			MigrationsStateStore: cli.GetMigrationsStateStore(ec, sourceKind),
 			SettingsStateStore:   cli.GetSettingsStateStore(ec, sourceName),
 		},
 	}
@@ -213,6 +220,16 @@ func GetFilePath(dir string) *nurl.URL {
 	return host
 }
 
+// GetSourceURL returns the URL of the migrations directory of a database
+// inside a source, the database directory is selected using the //<subdir> suffix
+func GetSourceURL(url string, sourceName string) string {
+	if sourceName == "" {
+		return url
+	}
+	url, subdir := source.SplitSubdir(url)
+	return url + "//" + path.Join(subdir, sourceName)
+}
+
 func IsMigrationsSupported(kind hasura.SourceKind) bool {
 	switch kind {
 	case hasura.SourceKindMSSQL, hasura.SourceKindPG:
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// OutputFormat is the format in which commands report their result
+type OutputFormat string
+
+const (
+	// OutputFormatText reports the result of a command as human readable logs and tables
+	OutputFormatText OutputFormat = ""
+	// OutputFormatJSON reports the result of a command as a single JSON document on stdout
+	OutputFormatJSON OutputFormat = "json"
+)
+
+func (f OutputFormat) validate() error {
+	switch f {
+	case OutputFormatText, OutputFormatJSON:
+		return nil
+	}
+	return fmt.Errorf("output format '%v' is not supported. supported formats: %v", f, OutputFormatJSON)
+}
+
+// commandOutput is the document written to stdout at the end of a
+// command when the output format is json
+type commandOutput struct {
+	Command string      `json:"command"`
+	Success bool        `json:"success"`
+	Error   string      `json:"error,omitempty"`
+	Result  interface{} `json:"result,omitempty"`
+}
+
+// IsJSONOutput reports if the command should report its result
+// as a JSON document instead of logs and tables
+func (ec *ExecutionContext) IsJSONOutput() bool {
+	return ec.OutputFormat == OutputFormatJSON
+}
+
+// SetOutput sets the result of the command, it is written as part of the
+// JSON document by WriteOutput. Commands should set their result even if
+// they fail, so that partial progress (eg: migrations applied before the
+// failing one) is reported.
+func (ec *ExecutionContext) SetOutput(result interface{}) {
+	ec.output = result
+}
+
+// WriteOutput writes the JSON document for the command to stdout
+// if the output format is json, it is a no-op otherwise.
+func (ec *ExecutionContext) WriteOutput(command string, err error) error {
+	if !ec.IsJSONOutput() {
+		return nil
+	}
+	out := commandOutput{
+		Command: command,
+		Success: err == nil,
+		Result:  ec.output,
+	}
+	if err != nil {
+		out.Error = err.Error()
+	}
+	b, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding command output as json: %w", err)
+	}
+	_, err = fmt.Fprintln(ec.Stdout, string(b))
+	return err
+}
@@ -73,6 +73,24 @@ func (p *ProjectMetadata) Diff() (io.Reader, error) {
 	return w, nil
 }
 
+// Plan returns the plan of the changes applying the project would make on the
+// server as JSON, it can be saved and applied with metadata apply --plan
+func (p *ProjectMetadata) Plan() (io.Reader, error) {
+	opts := &commands.MetadataPlanOptions{
+		EC:     p.ec,
+		Output: io.Discard,
+	}
+	plan, err := opts.Run()
+	if err != nil {
+		return nil, err
+	}
+	b := new(bytes.Buffer)
+	if err := json.NewEncoder(b).Encode(plan); err != nil {
+		return nil, fmt.Errorf("encoding plan: %w", err)
+	}
+	return b, nil
+}
+
 type ProjectMetadataOption func(*ProjectMetadata)
 
 func WithAdminSecret(adminSecret string) ProjectMetadataOption {
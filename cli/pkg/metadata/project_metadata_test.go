@@ -1,6 +1,7 @@
 package metadata
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"testing"
@@ -164,6 +165,25 @@ func TestProjectMetadataOps_Diff(t *testing.T) {
 	}
 }
 
+func TestProjectMetadata_Plan(t *testing.T) {
+	port, teardown := testutil.StartHasura(t, testutil.HasuraDockerImage)
+	hgeEndpoint := fmt.Sprintf("http://localhost:%s", port)
+	defer teardown()
+	p, err := NewProjectMetadata("testdata/projectv3", WithEndpoint(hgeEndpoint), WithAdminSecret(testutil.TestAdminSecret))
+	require.NoError(t, err)
+	got, err := p.Plan()
+	require.NoError(t, err)
+	var plan struct {
+		ResourceVersion int               `json:"resource_version"`
+		Changes         []json.RawMessage `json:"changes"`
+		Metadata        json.RawMessage   `json:"metadata"`
+	}
+	require.NoError(t, json.NewDecoder(got).Decode(&plan))
+	require.NotZero(t, plan.ResourceVersion)
+	require.NotEmpty(t, plan.Changes)
+	require.NotEmpty(t, plan.Metadata)
+}
+
 func TestProjectMetadata_Reload(t *testing.T) {
 	port, teardown := testutil.StartHasura(t, testutil.HasuraDockerImage)
 	hgeEndpoint := fmt.Sprintf("http://localhost:%s", port)
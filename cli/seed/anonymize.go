@@ -0,0 +1,160 @@
+package seed
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v2"
+)
+
+// Kinds of the fake values replacing the values of anonymized columns
+const (
+	AnonymizeEmail = "email"
+	AnonymizeName  = "name"
+	AnonymizePhone = "phone"
+	AnonymizeText  = "text"
+)
+
+// AnonymizeConfig lists the anonymized columns of each table, with the kind
+// of fake value replacing their values, eg:
+//
+//   public.users:
+//     email: email
+//     full_name: name
+//     phone: phone
+//
+// A table name without schema is in the public schema.
+type AnonymizeConfig map[string]map[string]string
+
+// ReadAnonymizeConfig reads the anonymization config from the YAML file at
+// path
+func ReadAnonymizeConfig(fs afero.Fs, path string) (AnonymizeConfig, error) {
+	b, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return nil, fmt.Errorf("reading anonymization config: %w", err)
+	}
+	var config AnonymizeConfig
+	if err := yaml.Unmarshal(b, &config); err != nil {
+		return nil, fmt.Errorf("parsing anonymization config %s: %w", path, err)
+	}
+	normalized := AnonymizeConfig{}
+	for table, columns := range config {
+		for column, kind := range columns {
+			switch kind {
+			case AnonymizeEmail, AnonymizeName, AnonymizePhone, AnonymizeText:
+			default:
+				return nil, fmt.Errorf("anonymization config %s: unknown kind %q of column %s of table %s, expected one of %s, %s, %s or %s", path, kind, column, table, AnonymizeEmail, AnonymizeName, AnonymizePhone, AnonymizeText)
+			}
+		}
+		normalized[newTableName(table).String()] = columns
+	}
+	return normalized, nil
+}
+
+// anonymizer replaces values by fake values of the same format. A value is
+// always replaced by the same fake value, so that the foreign keys of the
+// exported rows still match. The salt is random for each export, the fake
+// values cannot be matched with the values of another export.
+type anonymizer struct {
+	salt []byte
+}
+
+func newAnonymizer() (*anonymizer, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return &anonymizer{salt}, nil
+}
+
+var (
+	firstNames = []string{"Alex", "Ana", "Ben", "Carla", "Chen", "Dana", "Elif", "Femi", "Grace", "Hugo", "Ines", "Jon", "Kai", "Lena", "Malik", "Nora", "Omar", "Priya", "Quinn", "Rosa", "Sam", "Tara", "Umar", "Vera", "Wei", "Yara", "Zoe"}
+	lastNames  = []string{"Adams", "Bauer", "Costa", "Diaz", "Evans", "Fischer", "Garcia", "Hansen", "Ito", "Jensen", "Kim", "Lopez", "Moreau", "Novak", "Okafor", "Patel", "Rossi", "Silva", "Tanaka", "Umeh", "Varga", "Weber", "Young", "Zhang"}
+)
+
+// fake returns the fake value of kind replacing value
+func (a *anonymizer) fake(kind, value string) string {
+	r := a.random(kind, value)
+	switch kind {
+	case AnonymizeEmail:
+		at := strings.LastIndex(value, "@")
+		if at < 0 {
+			return a.fake(AnonymizeText, value)
+		}
+		return fmt.Sprintf("%s.%s@example.com", strings.ToLower(firstNames[r.intn(len(firstNames))]), r.hex(4))
+	case AnonymizeName:
+		words := strings.Fields(value)
+		for i := range words {
+			names := lastNames
+			if i == 0 && len(words) > 1 {
+				names = firstNames
+			}
+			words[i] = names[r.intn(len(names))]
+		}
+		return strings.Join(words, " ")
+	case AnonymizePhone:
+		// digits are replaced, the separators and the leading + are kept
+		return strings.Map(func(c rune) rune {
+			if unicode.IsDigit(c) {
+				return rune('0' + r.intn(10))
+			}
+			return c
+		}, value)
+	default:
+		return strings.Map(func(c rune) rune {
+			switch {
+			case unicode.IsDigit(c):
+				return rune('0' + r.intn(10))
+			case unicode.IsUpper(c):
+				return rune('A' + r.intn(26))
+			case unicode.IsLetter(c):
+				return rune('a' + r.intn(26))
+			}
+			return c
+		}, value)
+	}
+}
+
+// random returns the random numbers generating the fake value of kind
+// replacing value
+func (a *anonymizer) random(kind, value string) *hashRandom {
+	h := sha256.New()
+	h.Write(a.salt)
+	fmt.Fprintf(h, "%s\x00%s", kind, value)
+	return &hashRandom{seed: h.Sum(nil)}
+}
+
+// hashRandom generates random numbers from a seed, by hashing the seed with
+// a counter
+type hashRandom struct {
+	seed    []byte
+	counter uint64
+	buf     []byte
+}
+
+func (r *hashRandom) bytes(n int) []byte {
+	for len(r.buf) < n {
+		h := sha256.New()
+		h.Write(r.seed)
+		binary.Write(h, binary.BigEndian, r.counter)
+		r.counter++
+		r.buf = append(r.buf, h.Sum(nil)...)
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *hashRandom) intn(n int) int {
+	return int(binary.BigEndian.Uint64(r.bytes(8)) % uint64(n))
+}
+
+func (r *hashRandom) hex(n int) string {
+	return hex.EncodeToString(r.bytes(n))
+}
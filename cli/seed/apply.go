@@ -4,8 +4,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/hasura/graphql-engine/cli/v2/internal/hasura"
+	"github.com/hasura/graphql-engine/cli/v2/internal/statestore"
 
 	"github.com/hasura/graphql-engine/cli/v2"
 
@@ -16,61 +19,50 @@ import (
 
 func hasAllowedSeedFileExtensions(filename string) error {
 	extension := filepath.Ext(filename)
-	allowedExtensions := []string{".sql", ".SQL"}
+	allowedExtensions := []string{".sql", ".csv", ".json", ".ndjson"}
 	for _, allowedExtension := range allowedExtensions {
-		if allowedExtension == extension {
+		if strings.EqualFold(allowedExtension, extension) {
 			return nil
 		}
 	}
 	return fmt.Errorf("expected extension to be one of %v but got %s on file %s", allowedExtensions, extension, filename)
 }
 
-// ApplySeedsToDatabase will read all .sql files in the given
-// directory and apply it to hasura
-func (d *Driver) ApplySeedsToDatabase(fs afero.Fs, rootSeedsDirectory string, filenames []string, source cli.Source) error {
-	seedsDirectory := rootSeedsDirectory
-	if len(source.Name) > 0 {
-		seedsDirectory = filepath.Join(rootSeedsDirectory, source.Name)
-	}
+// IsSeedFile reports whether filename has the extension of a seed file
+func IsSeedFile(filename string) bool {
+	return hasAllowedSeedFileExtensions(filename) == nil
+}
+
+// ApplySeedsToDatabase will read all seed files in the given
+// directory and apply it to hasura, the applied files are returned
+// relative to the seeds directory of the source. The rows of .csv, .json
+// and .ndjson files are inserted into the table the file is named after.
+// The applied files are recorded if the driver tracks them, as dirty while
+// they are applied.
+func (d *Driver) ApplySeedsToDatabase(fs afero.Fs, rootSeedsDirectory string, filenames []string, source cli.Source) ([]string, error) {
+	seedsDirectory := seedsDirectoryOf(rootSeedsDirectory, source)
 	getSourceKind := func(source cli.Source) hasura.SourceKind {
 		if len(source.Name) == 0 {
 			return hasura.SourceKindPG
 		}
 		return source.Kind
 	}
+	sourceKind := getSourceKind(source)
+	files, err := readSeedFiles(fs, seedsDirectory, filenames)
+	if err != nil {
+		return nil, err
+	}
 	var sqlAsBytes [][]byte
-	if len(filenames) > 0 {
-		for _, filename := range filenames {
-			absFilename := filepath.Join(seedsDirectory, filename)
-			if err := hasAllowedSeedFileExtensions(absFilename); err != nil {
-				return err
-			}
-			b, err := afero.ReadFile(fs, absFilename)
-			if err != nil {
-				return errors.Wrap(err, "error opening file")
-			}
-			sqlAsBytes = append(sqlAsBytes, b)
-		}
-	} else {
-		err := afero.Walk(fs, seedsDirectory, func(path string, file os.FileInfo, err error) error {
-			if file == nil || err != nil {
-				return err
-			}
-			if err := hasAllowedSeedFileExtensions(file.Name()); err == nil && !file.IsDir() {
-				b, err := afero.ReadFile(fs, path)
-				if err != nil {
-					return errors.Wrap(err, "error opening file")
-				}
-				sqlAsBytes = append(sqlAsBytes, b)
-			}
-			return nil
-		})
+	var appliedFiles []string
+	for _, file := range files {
+		sql, err := seedSQL(file.name, file.content, sourceKind)
 		if err != nil {
-			return errors.Wrap(err, "error walking the directory path")
+			return nil, err
 		}
+		sqlAsBytes = append(sqlAsBytes, []byte(sql))
+		appliedFiles = append(appliedFiles, file.name)
 	}
 	var args []hasura.RequestBody
-	sourceKind := getSourceKind(source)
 	switch sourceKind {
 	case hasura.SourceKindPG:
 		for _, sql := range sqlAsBytes {
@@ -95,15 +87,92 @@ func (d *Driver) ApplySeedsToDatabase(fs afero.Fs, rootSeedsDirectory string, fi
 			args = append(args, request)
 		}
 	default:
-		return fmt.Errorf("database %s of kind %s is not supported", source.Name, source.Kind)
+		return nil, fmt.Errorf("database %s of kind %s is not supported", source.Name, source.Kind)
 	}
 
 	if len(args) == 0 {
-		return fmt.Errorf("no SQL files found in %s", seedsDirectory)
+		return nil, fmt.Errorf("no seed files found in %s", seedsDirectory)
 	}
-	_, err := d.SendBulk(args)
+	// the seeds are applied by the query API and recorded by the metadata
+	// API, so the files are recorded as dirty until they are applied, like
+	// migrations, rather than being applied again if recording them fails
+	if err := d.recordSeeds(source, files, true); err != nil {
+		return nil, err
+	}
+	_, err = d.SendBulk(args)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.recordSeeds(source, files, false); err != nil {
+		return nil, fmt.Errorf("seeds were applied but recording them failed, they are left dirty: %w", err)
+	}
+	return appliedFiles, nil
+}
+
+// recordSeeds records files as applied on source if the driver tracks the
+// applied seed files
+func (d *Driver) recordSeeds(source cli.Source, files []seedFile, dirty bool) error {
+	if d.StateStore == nil {
+		return nil
+	}
+	applied := map[string]statestore.AppliedSeed{}
+	now := time.Now().UTC()
+	for _, file := range files {
+		applied[seedName(file.name)] = statestore.AppliedSeed{Checksum: checksum(file.content), AppliedAt: now, Dirty: dirty}
+	}
+	return d.StateStore.SetSeeds(source.Name, applied)
+}
+
+func seedsDirectoryOf(rootSeedsDirectory string, source cli.Source) string {
+	if len(source.Name) > 0 {
+		return filepath.Join(rootSeedsDirectory, source.Name)
+	}
+	return rootSeedsDirectory
+}
+
+// seedFile is a seed file read from the seeds directory of a database
+type seedFile struct {
+	// name of the file relative to the seeds directory
+	name    string
+	content []byte
+}
+
+// readSeedFiles reads filenames from seedsDirectory, all the seed files of
+// the directory if filenames is empty
+func readSeedFiles(fs afero.Fs, seedsDirectory string, filenames []string) ([]seedFile, error) {
+	var files []seedFile
+	if len(filenames) > 0 {
+		for _, filename := range filenames {
+			absFilename := filepath.Join(seedsDirectory, filename)
+			if err := hasAllowedSeedFileExtensions(absFilename); err != nil {
+				return nil, err
+			}
+			b, err := afero.ReadFile(fs, absFilename)
+			if err != nil {
+				return nil, errors.Wrap(err, "error opening file")
+			}
+			files = append(files, seedFile{filename, b})
+		}
+		return files, nil
+	}
+	err := afero.Walk(fs, seedsDirectory, func(path string, file os.FileInfo, err error) error {
+		if file == nil || err != nil {
+			return err
+		}
+		if err := hasAllowedSeedFileExtensions(file.Name()); err == nil && !file.IsDir() {
+			b, err := afero.ReadFile(fs, path)
+			if err != nil {
+				return errors.Wrap(err, "error opening file")
+			}
+			if rel, err := filepath.Rel(seedsDirectory, path); err == nil {
+				path = rel
+			}
+			files = append(files, seedFile{path, b})
+		}
+		return nil
+	})
 	if err != nil {
-		return err
+		return nil, errors.Wrap(err, "error walking the directory path")
 	}
-	return nil
+	return files, nil
 }
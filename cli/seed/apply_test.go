@@ -119,7 +119,7 @@ func TestDriver_ApplySeedsToDatabase(t *testing.T) {
 			if tt.before != nil {
 				tt.before(t)
 			}
-			if err := d.ApplySeedsToDatabase(tt.args.fs, tt.args.rootSeedsDirectory, tt.args.filenames, tt.args.source); (err != nil) != tt.wantErr {
+			if _, err := d.ApplySeedsToDatabase(tt.args.fs, tt.args.rootSeedsDirectory, tt.args.filenames, tt.args.source); (err != nil) != tt.wantErr {
 				t.Errorf("ApplySeedsToDatabase() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
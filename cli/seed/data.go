@@ -0,0 +1,274 @@
+package seed
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/hasura/graphql-engine/cli/v2/internal/hasura"
+)
+
+// rowsPerInsert is the number of rows inserted by a statement of a data seed
+// file, SQL Server accepts at most 1000 rows in a VALUES clause
+const rowsPerInsert = 1000
+
+// defaultValue is the value of a column missing from a row of a JSON seed
+// file, the column takes its default value
+type defaultValue struct{}
+
+// sqlLiteral is a value written as it is in the inserts, like a number or a
+// binary literal
+type sqlLiteral string
+
+// dataSeed is the content of a CSV, JSON or NDJSON seed file, the rows of the
+// table the file is named after
+type dataSeed struct {
+	table   string
+	columns []dataColumn
+	// rows have a value per column: nil for NULL, a string, a json.Number, a
+	// bool, a json.RawMessage for JSON objects and arrays, defaultValue or a
+	// sqlLiteral
+	rows [][]interface{}
+}
+
+// dataColumn is a column of a data seed file, its values are cast to type
+// if the column is named <column>:<type> in the file
+type dataColumn struct {
+	name string
+	typ  string
+}
+
+func newDataColumn(header string) dataColumn {
+	parts := strings.SplitN(header, ":", 2)
+	column := dataColumn{name: strings.TrimSpace(parts[0])}
+	if len(parts) == 2 {
+		column.typ = strings.TrimSpace(parts[1])
+	}
+	return column
+}
+
+// seedSQL returns the SQL applying the seed file filename, the content of an
+// .sql file or the inserts of the rows of a data seed file
+func seedSQL(filename string, b []byte, kind hasura.SourceKind) (string, error) {
+	var seed *dataSeed
+	var err error
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		seed, err = readCSVSeed(b)
+	case ".json", ".ndjson":
+		seed, err = readJSONSeed(b)
+	default:
+		return string(b), nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading seed file %s: %w", filename, err)
+	}
+	seed.table = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	return seed.insertSQL(kind), nil
+}
+
+// readCSVSeed reads a CSV file whose first row is the header naming the
+// columns, empty values are NULL
+func readCSVSeed(b []byte) (*dataSeed, error) {
+	records, err := csv.NewReader(bytes.NewReader(b)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("missing header row")
+	}
+	seed := &dataSeed{}
+	for _, header := range records[0] {
+		seed.columns = append(seed.columns, newDataColumn(header))
+	}
+	for _, record := range records[1:] {
+		row := make([]interface{}, 0, len(record))
+		for _, value := range record {
+			if value == "" {
+				row = append(row, nil)
+			} else {
+				row = append(row, value)
+			}
+		}
+		seed.rows = append(seed.rows, row)
+	}
+	return seed, nil
+}
+
+// readJSONSeed reads a JSON array of objects or newline delimited JSON
+// objects, the keys of the objects name the columns in the order they
+// first appear
+func readJSONSeed(b []byte) (*dataSeed, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	array := len(bytes.TrimSpace(b)) > 0 && bytes.TrimSpace(b)[0] == '['
+	if array {
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+	}
+	var objects []map[string]interface{}
+	indexes := map[string]int{}
+	seed := &dataSeed{}
+	for dec.More() {
+		keys, object, err := readObject(dec)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", len(objects)+1, err)
+		}
+		for _, key := range keys {
+			if _, ok := indexes[key]; !ok {
+				indexes[key] = len(seed.columns)
+				seed.columns = append(seed.columns, newDataColumn(key))
+			}
+		}
+		objects = append(objects, object)
+	}
+	if array {
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+	}
+	for _, object := range objects {
+		row := make([]interface{}, len(seed.columns))
+		for i := range row {
+			row[i] = defaultValue{}
+		}
+		for key, value := range object {
+			row[indexes[key]] = value
+		}
+		seed.rows = append(seed.rows, row)
+	}
+	return seed, nil
+}
+
+// readObject reads a JSON object from dec, keeping the order of its keys
+func readObject(dec *json.Decoder) ([]string, map[string]interface{}, error) {
+	if t, err := dec.Token(); err != nil {
+		return nil, nil, err
+	} else if t != json.Delim('{') {
+		return nil, nil, fmt.Errorf("expected an object, got %v", t)
+	}
+	var keys []string
+	object := map[string]interface{}{}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		key, _ := t.(string)
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, nil, err
+		}
+		var value interface{}
+		d := json.NewDecoder(bytes.NewReader(raw))
+		d.UseNumber()
+		if err := d.Decode(&value); err != nil {
+			return nil, nil, err
+		}
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			var compact bytes.Buffer
+			if err := json.Compact(&compact, raw); err != nil {
+				return nil, nil, err
+			}
+			value = json.RawMessage(compact.Bytes())
+		}
+		if _, ok := object[key]; !ok {
+			keys = append(keys, key)
+		}
+		object[key] = value
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, nil, err
+	}
+	return keys, object, nil
+}
+
+// insertSQL returns the statements inserting the rows of the seed in batches
+// of rowsPerInsert
+func (s *dataSeed) insertSQL(kind hasura.SourceKind) string {
+	columns := make([]string, 0, len(s.columns))
+	for _, column := range s.columns {
+		columns = append(columns, quoteIdentifier(column.name, kind))
+	}
+	var b strings.Builder
+	for start := 0; start < len(s.rows); start += rowsPerInsert {
+		end := start + rowsPerInsert
+		if end > len(s.rows) {
+			end = len(s.rows)
+		}
+		fmt.Fprintf(&b, "INSERT INTO %s (%s) VALUES\n", quoteTable(s.table, kind), strings.Join(columns, ", "))
+		for i, row := range s.rows[start:end] {
+			values := make([]string, 0, len(s.columns))
+			for j, column := range s.columns {
+				var value interface{}
+				if j < len(row) {
+					value = row[j]
+				}
+				values = append(values, sqlValue(value, column.typ, kind))
+			}
+			separator := ",\n"
+			if start+i == end-1 {
+				separator = ";\n"
+			}
+			fmt.Fprintf(&b, "(%s)%s", strings.Join(values, ", "), separator)
+		}
+	}
+	return b.String()
+}
+
+func sqlValue(value interface{}, typ string, kind hasura.SourceKind) string {
+	var literal string
+	switch v := value.(type) {
+	case defaultValue:
+		return "DEFAULT"
+	case sqlLiteral:
+		literal = string(v)
+	case nil:
+		literal = "NULL"
+	case json.Number:
+		literal = v.String()
+	case bool:
+		literal = strings.ToUpper(fmt.Sprint(v))
+		if kind == hasura.SourceKindMSSQL {
+			literal = map[bool]string{true: "1", false: "0"}[v]
+		}
+	case json.RawMessage:
+		literal = quoteString(string(v), kind)
+	default:
+		literal = quoteString(fmt.Sprint(v), kind)
+	}
+	if typ == "" {
+		return literal
+	}
+	return fmt.Sprintf("CAST(%s AS %s)", literal, typ)
+}
+
+func quoteString(s string, kind hasura.SourceKind) string {
+	quoted := "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	if kind == hasura.SourceKindMSSQL {
+		return "N" + quoted
+	}
+	return quoted
+}
+
+func quoteIdentifier(name string, kind hasura.SourceKind) string {
+	if kind == hasura.SourceKindMSSQL {
+		return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// quoteTable quotes a table name, which is qualified by its schema if it is
+// like <schema>.<table>
+func quoteTable(table string, kind hasura.SourceKind) string {
+	parts := strings.SplitN(table, ".", 2)
+	for i := range parts {
+		parts[i] = quoteIdentifier(parts[i], kind)
+	}
+	return strings.Join(parts, ".")
+}
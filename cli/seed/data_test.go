@@ -0,0 +1,115 @@
+package seed
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hasura/graphql-engine/cli/v2/internal/hasura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeedSQL(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		content  string
+		kind     hasura.SourceKind
+		want     string
+		wantErr  bool
+	}{
+		{
+			"sql files are applied as they are",
+			"1234_authors.sql",
+			"INSERT INTO authors (name) VALUES ('Jane');",
+			hasura.SourceKindPG,
+			"INSERT INTO authors (name) VALUES ('Jane');",
+			false,
+		},
+		{
+			"csv rows are inserted with the columns of the header",
+			"public.authors.csv",
+			"id,name,joined_at:timestamptz\n1,Jane,2021-01-01\n2,O'Brien,\n",
+			hasura.SourceKindPG,
+			`INSERT INTO "public"."authors" ("id", "name", "joined_at") VALUES
+('1', 'Jane', CAST('2021-01-01' AS timestamptz)),
+('2', 'O''Brien', CAST(NULL AS timestamptz));
+`,
+			false,
+		},
+		{
+			"csv rows must have a value per column",
+			"authors.csv",
+			"id,name\n1\n",
+			hasura.SourceKindPG,
+			"",
+			true,
+		},
+		{
+			"json objects are inserted with their keys as columns",
+			"authors.json",
+			`[
+  {"id": 1, "name": "Jane", "active": true, "tags": ["a", "b"]},
+  {"id": 2, "name": null}
+]`,
+			hasura.SourceKindPG,
+			`INSERT INTO "authors" ("id", "name", "active", "tags") VALUES
+(1, 'Jane', TRUE, '["a","b"]'),
+(2, NULL, DEFAULT, DEFAULT);
+`,
+			false,
+		},
+		{
+			"ndjson objects are inserted into mssql tables",
+			"dbo.authors.ndjson",
+			`{"id": 1, "name": "Jane", "active": true}
+{"id": 2, "name": "Zoë", "active": false}
+`,
+			hasura.SourceKindMSSQL,
+			`INSERT INTO [dbo].[authors] ([id], [name], [active]) VALUES
+(1, N'Jane', 1),
+(2, N'Zoë', 0);
+`,
+			false,
+		},
+		{
+			"json rows must be objects",
+			"authors.json",
+			`[1, 2]`,
+			hasura.SourceKindPG,
+			"",
+			true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := seedSQL(tc.filename, []byte(tc.content), tc.kind)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestSeedSQL_Batches(t *testing.T) {
+	content := "id\n"
+	for i := 0; i < rowsPerInsert+1; i++ {
+		content += "1\n"
+	}
+	got, err := seedSQL("authors.csv", []byte(content), hasura.SourceKindMSSQL)
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(got, "INSERT INTO [authors] ([id]) VALUES"))
+	assert.Equal(t, rowsPerInsert+1, strings.Count(got, "(N'1')"))
+}
+
+func TestIsSeedFile(t *testing.T) {
+	for _, filename := range []string{"1234_authors.sql", "1234_authors.SQL", "public.authors.csv", "Users.CSV", "authors.Json", "authors.ndjson"} {
+		assert.True(t, IsSeedFile(filename), filename)
+	}
+	for _, filename := range []string{"authors.txt", "authors", "authors.csv.bak"} {
+		assert.False(t, IsSeedFile(filename), filename)
+	}
+}
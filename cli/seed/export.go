@@ -0,0 +1,600 @@
+package seed
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/hasura/graphql-engine/cli/v2/internal/hasura"
+)
+
+// ExportDataOptions select the rows exported by ExportData and anonymize
+// their values
+type ExportDataOptions struct {
+	// Where is the condition selecting the rows of each table by table name
+	Where map[string]string
+	// Limit is the maximum number of rows exported from each table, all rows
+	// are exported if it is 0
+	Limit int
+	// SamplePercent is the percentage of the rows of each table randomly
+	// sampled, all rows are exported if it is 0
+	SamplePercent float64
+	Anonymize     AnonymizeConfig
+	// WithDependencies exports the rows of the tables referenced by the
+	// exported rows, through foreign keys, along with them
+	WithDependencies bool
+}
+
+// IsZero reports whether no option is set, all the rows of the tables are
+// exported as they are
+func (o ExportDataOptions) IsZero() bool {
+	return len(o.Where) == 0 && o.Limit == 0 && o.SamplePercent == 0 && len(o.Anonymize) == 0 && !o.WithDependencies
+}
+
+// tableName is the name of a postgres table, in the public schema if it is
+// not qualified
+type tableName struct {
+	schema, name string
+}
+
+func newTableName(table string) tableName {
+	return parseTableName(table, "public")
+}
+
+// parseTableName parses a table name, in defaultSchema if it is not qualified
+func parseTableName(table, defaultSchema string) tableName {
+	if split := strings.SplitN(table, ".", 2); len(split) == 2 {
+		return tableName{split[0], split[1]}
+	}
+	return tableName{defaultSchema, table}
+}
+
+func (t tableName) String() string {
+	return t.schema + "." + t.name
+}
+
+func (t tableName) quoted() string {
+	return quoteIdentifier(t.schema, hasura.SourceKindPG) + "." + quoteIdentifier(t.name, hasura.SourceKindPG)
+}
+
+// WhereConditions returns the conditions of --where by table. A condition
+// is for the table it is prefixed with, like "public.users:id < 100", or for
+// all tables if it has no prefix naming one of tableNames.
+func WhereConditions(tableNames []string, conditions []string) (map[string]string, error) {
+	where := map[string]string{}
+	add := func(table, condition string) error {
+		if _, ok := where[table]; ok {
+			return fmt.Errorf("several conditions for table %s", table)
+		}
+		where[table] = condition
+		return nil
+	}
+	for _, condition := range conditions {
+		prefixed := false
+		if split := strings.SplitN(condition, ":", 2); len(split) == 2 {
+			for _, table := range tableNames {
+				if strings.TrimSpace(split[0]) == table {
+					if err := add(newTableName(table).String(), strings.TrimSpace(split[1])); err != nil {
+						return nil, err
+					}
+					prefixed = true
+				}
+			}
+		}
+		if prefixed {
+			continue
+		}
+		for _, table := range tableNames {
+			if err := add(newTableName(table).String(), condition); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return where, nil
+}
+
+// foreignKey is a foreign key between exported tables
+type foreignKey struct {
+	table, references tableName
+	columns, refs     []string
+}
+
+// ExportData exports the rows of the postgres tables selected by opts as
+// insert statements. The tables are exported in a single query, a table
+// referencing another exported table is exported after it and only with the
+// rows referencing the exported rows of the other table, so that the inserts
+// do not break foreign keys. With dependencies, the tables referenced by the
+// tables, directly or not, are exported too, and the rows referenced by the
+// exported rows are exported along with them rather than the referencing rows
+// being left out. The values of anonymized columns, and of the columns
+// referencing them, are replaced by fake values.
+func (d *Driver) ExportData(tableNames []string, sourceName string, opts ExportDataOptions) (io.Reader, error) {
+	var tables []tableName
+	requested := map[tableName]bool{}
+	for _, table := range tableNames {
+		tables = append(tables, newTableName(table))
+		requested[newTableName(table)] = true
+	}
+	if opts.WithDependencies {
+		dependencies, err := d.dependencies(tables, sourceName)
+		if err != nil {
+			return nil, err
+		}
+		for _, table := range dependencies {
+			if !requested[table] {
+				tables = append(tables, table)
+			}
+		}
+	}
+	columns, foreignKeys, err := d.describeTables(tables, sourceName)
+	if err != nil {
+		return nil, err
+	}
+	for _, table := range tables {
+		if len(columns[table]) == 0 {
+			return nil, fmt.Errorf("table %s not found", table)
+		}
+	}
+	tables = sortByDependencies(tables, foreignKeys)
+
+	query, err := exportQuery(tables, requested, columns, foreignKeys, opts)
+	if err != nil {
+		return nil, err
+	}
+	results, err := d.runSQL(sourceName, query)
+	if err != nil {
+		return nil, err
+	}
+	seeds := make([]*dataSeed, len(tables))
+	for i, table := range tables {
+		seeds[i] = &dataSeed{table: table.String()}
+		for _, column := range columns[table] {
+			seeds[i].columns = append(seeds[i].columns, dataColumn{name: column})
+		}
+	}
+	for _, row := range results[0] {
+		if len(row) != 2 {
+			return nil, fmt.Errorf("unexpected row of exported data: %v", row)
+		}
+		var index int
+		if _, err := fmt.Sscan(row[0], &index); err != nil || index >= len(seeds) {
+			return nil, fmt.Errorf("unexpected row of exported data: %v", row)
+		}
+		var values []*string
+		if err := json.Unmarshal([]byte(row[1]), &values); err != nil {
+			return nil, fmt.Errorf("decoding exported row: %w", err)
+		}
+		seedRow := make([]interface{}, len(values))
+		for j, value := range values {
+			if value != nil {
+				seedRow[j] = *value
+			}
+		}
+		seeds[index].rows = append(seeds[index].rows, seedRow)
+	}
+
+	if len(opts.Anonymize) > 0 {
+		a, err := newAnonymizer()
+		if err != nil {
+			return nil, err
+		}
+		anonymized := anonymizedColumns(opts.Anonymize, foreignKeys)
+		for i, table := range tables {
+			for j, column := range columns[table] {
+				kind, ok := anonymized[table][column]
+				if !ok {
+					continue
+				}
+				for _, row := range seeds[i].rows {
+					if value, ok := row[j].(string); ok {
+						row[j] = a.fake(kind, value)
+					}
+				}
+			}
+		}
+	}
+
+	var b bytes.Buffer
+	for _, seed := range seeds {
+		b.WriteString(seed.insertSQL(hasura.SourceKindPG))
+	}
+	return &b, nil
+}
+
+// dependencies returns the tables referenced by tables through foreign keys,
+// directly or through other tables
+func (d *Driver) dependencies(tables []tableName, sourceName string) ([]tableName, error) {
+	results, err := d.runSQL(sourceName, fmt.Sprintf(`WITH RECURSIVE dependencies(oid) AS (
+  SELECT c.oid FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace WHERE (n.nspname, c.relname) IN (%s)
+  UNION
+  SELECT con.confrelid FROM pg_constraint con JOIN dependencies d ON con.conrelid = d.oid WHERE con.contype = 'f'
+)
+SELECT n.nspname, c.relname
+FROM dependencies d
+JOIN pg_class c ON c.oid = d.oid
+JOIN pg_namespace n ON n.oid = c.relnamespace
+ORDER BY n.nspname, c.relname`, tableList(tables)))
+	if err != nil {
+		return nil, err
+	}
+	var dependencies []tableName
+	for _, row := range results[0] {
+		dependencies = append(dependencies, tableName{row[0], row[1]})
+	}
+	return dependencies, nil
+}
+
+// tableList returns the list of the (schema, name) pairs of tables in SQL
+func tableList(tables []tableName) string {
+	var names []string
+	for _, table := range tables {
+		names = append(names, fmt.Sprintf("(%s, %s)", quoteString(table.schema, hasura.SourceKindPG), quoteString(table.name, hasura.SourceKindPG)))
+	}
+	return strings.Join(names, ", ")
+}
+
+// describeTables returns the columns of tables and the foreign keys between
+// them, generated columns are left out since they cannot be inserted into
+func (d *Driver) describeTables(tables []tableName, sourceName string) (map[tableName][]string, []foreignKey, error) {
+	in := tableList(tables)
+	results, err := d.runSQL(sourceName,
+		fmt.Sprintf(`SELECT n.nspname, c.relname, a.attname
+FROM pg_attribute a
+JOIN pg_class c ON c.oid = a.attrelid
+JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE a.attnum > 0 AND NOT a.attisdropped AND a.attgenerated = '' AND (n.nspname, c.relname) IN (%s)
+ORDER BY n.nspname, c.relname, a.attnum`, in),
+		fmt.Sprintf(`SELECT tn.nspname, t.relname, rn.nspname, r.relname,
+  (SELECT array_to_json(array_agg(a.attname ORDER BY k.i)) FROM unnest(con.conkey) WITH ORDINALITY k(n, i) JOIN pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.n)::text,
+  (SELECT array_to_json(array_agg(a.attname ORDER BY k.i)) FROM unnest(con.confkey) WITH ORDINALITY k(n, i) JOIN pg_attribute a ON a.attrelid = con.confrelid AND a.attnum = k.n)::text
+FROM pg_constraint con
+JOIN pg_class t ON t.oid = con.conrelid
+JOIN pg_namespace tn ON tn.oid = t.relnamespace
+JOIN pg_class r ON r.oid = con.confrelid
+JOIN pg_namespace rn ON rn.oid = r.relnamespace
+WHERE con.contype = 'f' AND (tn.nspname, t.relname) IN (%[1]s) AND (rn.nspname, r.relname) IN (%[1]s)
+ORDER BY con.conname`, in),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	columns := map[tableName][]string{}
+	for _, row := range results[0] {
+		table := tableName{row[0], row[1]}
+		columns[table] = append(columns[table], row[2])
+	}
+	var foreignKeys []foreignKey
+	for _, row := range results[1] {
+		fk := foreignKey{table: tableName{row[0], row[1]}, references: tableName{row[2], row[3]}}
+		if err := json.Unmarshal([]byte(row[4]), &fk.columns); err != nil {
+			return nil, nil, err
+		}
+		if err := json.Unmarshal([]byte(row[5]), &fk.refs); err != nil {
+			return nil, nil, err
+		}
+		foreignKeys = append(foreignKeys, fk)
+	}
+	return columns, foreignKeys, nil
+}
+
+// runSQL runs the read only queries in a bulk request and returns their
+// rows, without the header row
+func (d *Driver) runSQL(sourceName string, queries ...string) ([][][]string, error) {
+	var args []hasura.RequestBody
+	for _, query := range queries {
+		args = append(args, hasura.RequestBody{
+			Type: "run_sql",
+			Args: hasura.PGRunSQLInput{
+				SQL:      query,
+				Source:   sourceName,
+				ReadOnly: true,
+			},
+		})
+	}
+	r, err := d.SendBulk(args)
+	if err != nil {
+		return nil, err
+	}
+	var outputs []hasura.PGRunSQLOutput
+	if err := json.NewDecoder(r).Decode(&outputs); err != nil {
+		return nil, fmt.Errorf("decoding run_sql response: %w", err)
+	}
+	if len(outputs) != len(queries) {
+		return nil, fmt.Errorf("expected %d run_sql results, got %d", len(queries), len(outputs))
+	}
+	results := make([][][]string, len(outputs))
+	for i, output := range outputs {
+		if len(output.Result) > 0 {
+			results[i] = output.Result[1:]
+		}
+	}
+	return results, nil
+}
+
+// sortByDependencies sorts tables so that a table comes after the tables it
+// references. Tables referencing each other are kept in their order.
+func sortByDependencies(tables []tableName, foreignKeys []foreignKey) []tableName {
+	var sorted []tableName
+	added := map[tableName]bool{}
+	for len(sorted) < len(tables) {
+		progress := false
+		for _, table := range tables {
+			if added[table] {
+				continue
+			}
+			ready := true
+			for _, fk := range foreignKeys {
+				if fk.table == table && fk.references != table && !added[fk.references] {
+					ready = false
+				}
+			}
+			if ready {
+				sorted = append(sorted, table)
+				added[table] = true
+				progress = true
+			}
+		}
+		if !progress {
+			// a cycle of references, the first remaining table is added
+			for _, table := range tables {
+				if !added[table] {
+					sorted = append(sorted, table)
+					added[table] = true
+					break
+				}
+			}
+		}
+	}
+	return sorted
+}
+
+// exportQuery returns the query exporting the rows of tables, sorted by
+// dependencies. Each table is selected in a CTE, a row is returned as the
+// index of its table and the text of its values in a JSON array.
+func exportQuery(tables []tableName, requested map[tableName]bool, columns map[tableName][]string, foreignKeys []foreignKey, opts ExportDataOptions) (string, error) {
+	index := map[tableName]int{}
+	for i, table := range tables {
+		index[table] = i
+	}
+	with := "WITH"
+	var ctes []string
+	if opts.WithDependencies {
+		var recursive bool
+		var err error
+		ctes, recursive, err = dependenciesCTEs(tables, requested, index, foreignKeys, opts)
+		if err != nil {
+			return "", err
+		}
+		if recursive {
+			with = "WITH RECURSIVE"
+		}
+	} else {
+		// the CTE of a table uses the CTEs of the tables it references to
+		// select only the rows referencing their selected rows
+		defined := map[tableName]bool{}
+		for i, table := range tables {
+			ctes = append(ctes, fmt.Sprintf("%s AS (%s)", cteName(i), requestedTableQuery(table, defined, index, foreignKeys, opts)))
+			defined[table] = true
+		}
+	}
+	var selects []string
+	for i, table := range tables {
+		var values []string
+		for _, column := range columns[table] {
+			values = append(values, quoteIdentifier(column, hasura.SourceKindPG)+"::text")
+		}
+		selects = append(selects, fmt.Sprintf("SELECT %d, array_to_json(ARRAY[%s]::text[])::text FROM %s", i, strings.Join(values, ", "), cteName(i)))
+	}
+	return fmt.Sprintf("%s %s\n%s\nORDER BY 1", with, strings.Join(ctes, ",\n"), strings.Join(selects, "\nUNION ALL\n")), nil
+}
+
+// requestedTableQuery selects the rows of a requested table matching opts
+// and referencing the selected rows of the defined tables
+func requestedTableQuery(table tableName, defined map[tableName]bool, index map[tableName]int, foreignKeys []foreignKey, opts ExportDataOptions) string {
+	var conditions []string
+	if condition, ok := opts.Where[table.String()]; ok {
+		conditions = append(conditions, "("+condition+")")
+	}
+	for _, fk := range foreignKeys {
+		if fk.table != table || !defined[fk.references] || fk.references == table {
+			continue
+		}
+		var nulls []string
+		for _, column := range fk.columns {
+			nulls = append(nulls, quoteIdentifier(column, hasura.SourceKindPG)+" IS NULL")
+		}
+		conditions = append(conditions, fmt.Sprintf("(%s OR (%s) IN (SELECT %s FROM %s))",
+			strings.Join(nulls, " OR "),
+			quoteIdentifiers(fk.columns),
+			quoteIdentifiers(fk.refs),
+			cteName(index[fk.references]),
+		))
+	}
+	query := "SELECT * FROM " + table.quoted()
+	if opts.SamplePercent > 0 {
+		query += fmt.Sprintf(" TABLESAMPLE BERNOULLI (%g)", opts.SamplePercent)
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	if opts.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", opts.Limit)
+	}
+	return query
+}
+
+// dependenciesCTEs returns the CTEs of tables exported with their
+// dependencies. The rows of a table are its rows matching opts if it is
+// requested, the rows referenced by the selected rows of the other tables,
+// and the rows referenced by these rows through the foreign keys of the table
+// to itself, selected by a recursive CTE. The CTE of a table uses the CTEs of
+// the tables referencing it so they are defined from the last table to the
+// first, tables referencing each other, directly or through other tables,
+// are not supported. It also returns
+// whether one of the CTEs is recursive.
+func dependenciesCTEs(tables []tableName, requested map[tableName]bool, index map[tableName]int, foreignKeys []foreignKey, opts ExportDataOptions) ([]string, bool, error) {
+	for _, fk := range foreignKeys {
+		if fk.table != fk.references && index[fk.table] < index[fk.references] {
+			return nil, false, fmt.Errorf("tables %s and %s reference each other, directly or through other tables, the rows of tables referencing each other cannot be exported with dependencies", fk.table, fk.references)
+		}
+	}
+	var ctes []string
+	recursive := false
+	for i := len(tables) - 1; i >= 0; i-- {
+		table := tables[i]
+		var referenced []string
+		var keys [][]string
+		var self []foreignKey
+		for _, fk := range foreignKeys {
+			switch {
+			case fk.table == table && fk.references == table:
+				self = append(self, fk)
+			case fk.references == table:
+				referenced = append(referenced, fmt.Sprintf("(%s) IN (SELECT %s FROM %s)",
+					quoteIdentifiers(fk.refs),
+					quoteIdentifiers(fk.columns),
+					cteName(index[fk.table]),
+				))
+				keys = append(keys, fk.refs)
+			}
+		}
+		name := cteName(i)
+		if len(self) > 0 {
+			name = partCTEName(i, "rows")
+		}
+
+		var query string
+		switch {
+		case requested[table] && len(referenced) == 0:
+			query = requestedTableQuery(table, nil, index, nil, opts)
+		case requested[table]:
+			// the referenced rows which are not selected already
+			selected := partCTEName(i, "selected")
+			ctes = append(ctes, fmt.Sprintf("%s AS (%s)", selected, requestedTableQuery(table, nil, index, nil, opts)))
+			query = fmt.Sprintf("SELECT * FROM %s UNION ALL SELECT * FROM %s x WHERE (%s) AND NOT EXISTS (SELECT 1 FROM %s s WHERE %s)",
+				selected, table.quoted(), strings.Join(referenced, " OR "), selected, sameRow(keys))
+		case len(referenced) > 0:
+			query = fmt.Sprintf("SELECT * FROM %s WHERE %s", table.quoted(), strings.Join(referenced, " OR "))
+		default:
+			query = fmt.Sprintf("SELECT * FROM %s WHERE FALSE", table.quoted())
+		}
+		ctes = append(ctes, fmt.Sprintf("%s AS (%s)", name, query))
+		if len(self) == 0 {
+			continue
+		}
+
+		// the keys referenced by the rows, and by the rows they reference,
+		// through the foreign keys of the table to itself
+		key := self[0].refs
+		for _, fk := range self[1:] {
+			if quoteIdentifiers(fk.refs) != quoteIdentifiers(key) {
+				return nil, false, fmt.Errorf("table %s references itself through several keys, its rows cannot be exported with dependencies", table)
+			}
+		}
+		var references, notNull []string
+		for _, fk := range self {
+			references = append(references, "("+qualifiedIdentifiers("y", fk.columns)+")")
+		}
+		for _, column := range key {
+			notNull = append(notNull, "v."+quoteIdentifier(column, hasura.SourceKindPG)+" IS NOT NULL")
+		}
+		values := fmt.Sprintf("CROSS JOIN LATERAL (VALUES %s) v(%s) WHERE %s", strings.Join(references, ", "), quoteIdentifiers(key), strings.Join(notNull, " AND "))
+		referencedKeys := partCTEName(i, "keys")
+		ctes = append(ctes, fmt.Sprintf("%s(%s) AS (SELECT v.* FROM %s y %s UNION SELECT v.* FROM %s y JOIN %s r ON (%s) = (%s) %s)",
+			referencedKeys, quoteIdentifiers(key),
+			name, values,
+			table.quoted(), referencedKeys, qualifiedIdentifiers("y", key), qualifiedIdentifiers("r", key), values))
+		ctes = append(ctes, fmt.Sprintf("%s AS (SELECT * FROM %s UNION ALL SELECT * FROM %s x WHERE (%s) IN (SELECT %s FROM %s) AND NOT EXISTS (SELECT 1 FROM %s s WHERE %s))",
+			cteName(i), name, table.quoted(), qualifiedIdentifiers("x", key), quoteIdentifiers(key), referencedKeys, name, sameRow([][]string{key})))
+		recursive = true
+	}
+	return ctes, recursive, nil
+}
+
+// sameRow returns the condition of the row s being the row x, by the values
+// of one of their unique keys
+func sameRow(keys [][]string) string {
+	var conditions []string
+	seen := map[string]bool{}
+	for _, key := range keys {
+		condition := fmt.Sprintf("(%s) = (%s)", qualifiedIdentifiers("s", key), qualifiedIdentifiers("x", key))
+		if !seen[condition] {
+			conditions = append(conditions, condition)
+			seen[condition] = true
+		}
+	}
+	return strings.Join(conditions, " OR ")
+}
+
+func cteName(index int) string {
+	return fmt.Sprintf(`"t%d"`, index)
+}
+
+// partCTEName is the name of a CTE selecting a part of the rows of a table
+func partCTEName(index int, part string) string {
+	return fmt.Sprintf(`"t%d_%s"`, index, part)
+}
+
+func quoteIdentifiers(names []string) string {
+	quoted := make([]string, 0, len(names))
+	for _, name := range names {
+		quoted = append(quoted, quoteIdentifier(name, hasura.SourceKindPG))
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// qualifiedIdentifiers returns the names qualified with alias
+func qualifiedIdentifiers(alias string, names []string) string {
+	qualified := make([]string, 0, len(names))
+	for _, name := range names {
+		qualified = append(qualified, alias+"."+quoteIdentifier(name, hasura.SourceKindPG))
+	}
+	return strings.Join(qualified, ", ")
+}
+
+// anonymizedColumns returns the kind of the anonymized columns by table, the
+// columns of a foreign key are anonymized like the columns they reference,
+// and the other way round
+func anonymizedColumns(config AnonymizeConfig, foreignKeys []foreignKey) map[tableName]map[string]string {
+	anonymized := map[tableName]map[string]string{}
+	set := func(table tableName, column, kind string) bool {
+		if anonymized[table] == nil {
+			anonymized[table] = map[string]string{}
+		}
+		if _, ok := anonymized[table][column]; ok {
+			return false
+		}
+		anonymized[table][column] = kind
+		return true
+	}
+	var names []string
+	for table := range config {
+		names = append(names, table)
+	}
+	sort.Strings(names)
+	for _, table := range names {
+		for column, kind := range config[table] {
+			set(newTableName(table), column, kind)
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, fk := range foreignKeys {
+			for i, ref := range fk.refs {
+				if i >= len(fk.columns) {
+					break
+				}
+				if kind, ok := anonymized[fk.references][ref]; ok {
+					changed = set(fk.table, fk.columns[i], kind) || changed
+				}
+				if kind, ok := anonymized[fk.table][fk.columns[i]]; ok {
+					changed = set(fk.references, ref, kind) || changed
+				}
+			}
+		}
+	}
+	return anonymized
+}
@@ -0,0 +1,209 @@
+package seed
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hasura/graphql-engine/cli/v2/internal/hasura"
+)
+
+// mssqlColumn is a column of an exported SQL Server table
+type mssqlColumn struct {
+	name     string
+	typ      string
+	identity bool
+}
+
+// ExportMSSQLData exports all the rows of the SQL Server tables as insert
+// statements, with mssql_run_sql since there is no pg_dump for SQL Server.
+// A table is exported after the tables it references, the values of identity
+// columns are inserted with IDENTITY_INSERT. Computed and rowversion columns
+// are not exported, SQL Server sets their values.
+func (d *Driver) ExportMSSQLData(tableNames []string, sourceName string) (io.Reader, error) {
+	var tables []tableName
+	for _, table := range tableNames {
+		tables = append(tables, parseTableName(table, "dbo"))
+	}
+	columns, foreignKeys, err := d.describeMSSQLTables(tables, sourceName)
+	if err != nil {
+		return nil, err
+	}
+	for _, table := range tables {
+		if len(columns[table]) == 0 {
+			return nil, fmt.Errorf("table %s not found", table)
+		}
+	}
+	tables = sortByDependencies(tables, foreignKeys)
+
+	var queries []string
+	for _, table := range tables {
+		var values []string
+		for _, column := range columns[table] {
+			expression, _ := mssqlExportValue(column)
+			values = append(values, expression)
+		}
+		queries = append(queries, fmt.Sprintf("SELECT %s FROM %s", strings.Join(values, ", "), quoteTable(table.String(), hasura.SourceKindMSSQL)))
+	}
+	results, err := d.runMSSQL(sourceName, queries...)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	for i, table := range tables {
+		seed := &dataSeed{table: table.String()}
+		identity := false
+		for _, column := range columns[table] {
+			seed.columns = append(seed.columns, dataColumn{name: column.name})
+			identity = identity || column.identity
+		}
+		for _, row := range results[i] {
+			if len(row) != len(columns[table]) {
+				return nil, fmt.Errorf("unexpected row of exported data of table %s: %v", table, row)
+			}
+			seedRow := make([]interface{}, len(row))
+			for j, value := range row {
+				if value == nil {
+					continue
+				}
+				text := fmt.Sprint(value)
+				if _, literal := mssqlExportValue(columns[table][j]); literal {
+					seedRow[j] = sqlLiteral(text)
+				} else {
+					seedRow[j] = text
+				}
+			}
+			seed.rows = append(seed.rows, seedRow)
+		}
+		if len(seed.rows) == 0 {
+			continue
+		}
+		quoted := quoteTable(table.String(), hasura.SourceKindMSSQL)
+		if identity {
+			fmt.Fprintf(&b, "SET IDENTITY_INSERT %s ON;\n", quoted)
+		}
+		b.WriteString(seed.insertSQL(hasura.SourceKindMSSQL))
+		if identity {
+			fmt.Fprintf(&b, "SET IDENTITY_INSERT %s OFF;\n", quoted)
+		}
+	}
+	return &b, nil
+}
+
+// mssqlExportValue returns the expression selecting the value of column as
+// text, in a format SQL Server converts back to the type of the column, and
+// whether the text is written as it is in the inserts rather than quoted
+func mssqlExportValue(column mssqlColumn) (string, bool) {
+	quoted := quoteIdentifier(column.name, hasura.SourceKindMSSQL)
+	switch strings.ToLower(column.typ) {
+	case "binary", "varbinary", "image":
+		// 0x prefixed hexadecimal
+		return fmt.Sprintf("CONVERT(varchar(max), CONVERT(varbinary(max), %s), 1)", quoted), true
+	case "date", "time", "datetime", "datetime2", "smalldatetime", "datetimeoffset":
+		// ISO 8601, with all the fractional seconds and the offset
+		return fmt.Sprintf("CONVERT(nvarchar(max), %s, 126)", quoted), false
+	case "float", "real":
+		// 17 digits, converted back without loss
+		return fmt.Sprintf("CONVERT(nvarchar(max), %s, 3)", quoted), true
+	case "money", "smallmoney":
+		// 4 decimal digits, without thousands separators
+		return fmt.Sprintf("CONVERT(nvarchar(max), %s, 2)", quoted), true
+	case "bit", "tinyint", "smallint", "int", "bigint", "decimal", "numeric":
+		return fmt.Sprintf("CONVERT(nvarchar(max), %s)", quoted), true
+	default:
+		// strings, uniqueidentifier, xml and other types, quoted as N''
+		return fmt.Sprintf("CONVERT(nvarchar(max), %s)", quoted), false
+	}
+}
+
+// describeMSSQLTables returns the exported columns of the SQL Server tables
+// and their foreign keys referencing each other
+func (d *Driver) describeMSSQLTables(tables []tableName, sourceName string) (map[tableName][]mssqlColumn, []foreignKey, error) {
+	var conditions []string
+	for _, table := range tables {
+		conditions = append(conditions, fmt.Sprintf("(s.name = %s AND t.name = %s)", quoteString(table.schema, hasura.SourceKindMSSQL), quoteString(table.name, hasura.SourceKindMSSQL)))
+	}
+	results, err := d.runMSSQL(sourceName,
+		fmt.Sprintf(`SELECT s.name, t.name, c.name,
+  CASE WHEN ty.is_user_defined = 1 AND ty.is_assembly_type = 0 THEN TYPE_NAME(ty.system_type_id) ELSE ty.name END,
+  CONVERT(nvarchar(1), c.is_identity)
+FROM sys.columns c
+JOIN sys.tables t ON t.object_id = c.object_id
+JOIN sys.schemas s ON s.schema_id = t.schema_id
+JOIN sys.types ty ON ty.user_type_id = c.user_type_id
+WHERE c.is_computed = 0 AND ty.name <> N'timestamp' AND (%s)
+ORDER BY s.name, t.name, c.column_id`, strings.Join(conditions, " OR ")),
+		`SELECT ps.name, pt.name, rs.name, rt.name
+FROM sys.foreign_keys fk
+JOIN sys.tables pt ON pt.object_id = fk.parent_object_id
+JOIN sys.schemas ps ON ps.schema_id = pt.schema_id
+JOIN sys.tables rt ON rt.object_id = fk.referenced_object_id
+JOIN sys.schemas rs ON rs.schema_id = rt.schema_id`,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	columns := map[tableName][]mssqlColumn{}
+	for _, row := range results[0] {
+		if len(row) != 5 {
+			return nil, nil, fmt.Errorf("unexpected row of table columns: %v", row)
+		}
+		table := tableName{fmt.Sprint(row[0]), fmt.Sprint(row[1])}
+		columns[table] = append(columns[table], mssqlColumn{
+			name:     fmt.Sprint(row[2]),
+			typ:      fmt.Sprint(row[3]),
+			identity: fmt.Sprint(row[4]) == "1",
+		})
+	}
+	var foreignKeys []foreignKey
+	for _, row := range results[1] {
+		if len(row) != 4 {
+			return nil, nil, fmt.Errorf("unexpected row of foreign keys: %v", row)
+		}
+		fk := foreignKey{
+			table:      tableName{fmt.Sprint(row[0]), fmt.Sprint(row[1])},
+			references: tableName{fmt.Sprint(row[2]), fmt.Sprint(row[3])},
+		}
+		if columns[fk.table] != nil && columns[fk.references] != nil {
+			foreignKeys = append(foreignKeys, fk)
+		}
+	}
+	return columns, foreignKeys, nil
+}
+
+// runMSSQL runs the read only queries in a bulk request and returns their
+// rows, without the header row
+func (d *Driver) runMSSQL(sourceName string, queries ...string) ([][][]interface{}, error) {
+	var args []hasura.RequestBody
+	for _, query := range queries {
+		args = append(args, hasura.RequestBody{
+			Type: "mssql_run_sql",
+			Args: hasura.MSSQLRunSQLInput{
+				SQL:      query,
+				Source:   sourceName,
+				ReadOnly: true,
+			},
+		})
+	}
+	r, err := d.SendBulk(args)
+	if err != nil {
+		return nil, err
+	}
+	var outputs []hasura.MSSQLRunSQLOutput
+	if err := json.NewDecoder(r).Decode(&outputs); err != nil {
+		return nil, fmt.Errorf("decoding mssql_run_sql response: %w", err)
+	}
+	if len(outputs) != len(queries) {
+		return nil, fmt.Errorf("expected %d mssql_run_sql results, got %d", len(queries), len(outputs))
+	}
+	results := make([][][]interface{}, len(outputs))
+	for i, output := range outputs {
+		if len(output.Result) > 0 {
+			results[i] = output.Result[1:]
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,78 @@
+package seed
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/hasura/graphql-engine/cli/v2/internal/hasura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDriver_ExportMSSQLData(t *testing.T) {
+	results := [][]hasura.MSSQLRunSQLOutput{
+		{
+			{ResultType: hasura.TuplesOK, Result: [][]interface{}{
+				{"name", "name", "name", "type", "is_identity"},
+				{"dbo", "files", "id", "uniqueidentifier", "0"},
+				{"dbo", "files", "owner_id", "int", "0"},
+				{"dbo", "files", "content", "varbinary", "0"},
+				{"dbo", "users", "id", "int", "1"},
+				{"dbo", "users", "name", "nvarchar", "0"},
+				{"dbo", "users", "created_at", "datetime2", "0"},
+			}},
+			{ResultType: hasura.TuplesOK, Result: [][]interface{}{
+				{"name", "name", "name", "name"},
+				{"dbo", "files", "dbo", "users"},
+				{"dbo", "orders", "dbo", "users"},
+			}},
+		},
+		{
+			{ResultType: hasura.TuplesOK, Result: [][]interface{}{
+				{"id", "name", "created_at"},
+				{"1", "Zoë O'Neil", "2021-06-01T10:30:00.1234567"},
+			}},
+			{ResultType: hasura.TuplesOK, Result: [][]interface{}{
+				{"id", "owner_id", "content"},
+				{"6F9619FF-8B86-D011-B42D-00C04FC964FF", "1", "0x89504E47"},
+				{"0E984725-C51C-4BF4-9960-E1C80E27ABA0", nil, nil},
+			}},
+		},
+	}
+	var queries []string
+	d := &Driver{
+		SendBulk: func(args []hasura.RequestBody) (io.Reader, error) {
+			for _, arg := range args {
+				assert.Equal(t, "mssql_run_sql", arg.Type)
+				input := arg.Args.(hasura.MSSQLRunSQLInput)
+				assert.True(t, input.ReadOnly)
+				assert.Equal(t, "mssql", input.Source)
+				queries = append(queries, input.SQL)
+			}
+			b, err := json.Marshal(results[0])
+			results = results[1:]
+			return strings.NewReader(string(b)), err
+		},
+	}
+	r, err := d.ExportMSSQLData([]string{"files", "dbo.users"}, "mssql")
+	require.NoError(t, err)
+	require.Len(t, queries, 4)
+	assert.Contains(t, queries[0], `(s.name = N'dbo' AND t.name = N'files') OR (s.name = N'dbo' AND t.name = N'users')`)
+	// users are exported before the files referencing them
+	assert.Equal(t, `SELECT CONVERT(nvarchar(max), [id]), CONVERT(nvarchar(max), [name]), CONVERT(nvarchar(max), [created_at], 126) FROM [dbo].[users]`, queries[2])
+	assert.Equal(t, `SELECT CONVERT(nvarchar(max), [id]), CONVERT(nvarchar(max), [owner_id]), CONVERT(varchar(max), CONVERT(varbinary(max), [content]), 1) FROM [dbo].[files]`, queries[3])
+
+	b, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, `SET IDENTITY_INSERT [dbo].[users] ON;
+INSERT INTO [dbo].[users] ([id], [name], [created_at]) VALUES
+(1, N'Zoë O''Neil', N'2021-06-01T10:30:00.1234567');
+SET IDENTITY_INSERT [dbo].[users] OFF;
+INSERT INTO [dbo].[files] ([id], [owner_id], [content]) VALUES
+(N'6F9619FF-8B86-D011-B42D-00C04FC964FF', 1, 0x89504E47),
+(N'0E984725-C51C-4BF4-9960-E1C80E27ABA0', NULL, NULL);
+`, string(b))
+}
@@ -0,0 +1,309 @@
+package seed
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hasura/graphql-engine/cli/v2/internal/hasura"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWhereConditions(t *testing.T) {
+	where, err := WhereConditions([]string{"authors", "public.articles"}, []string{
+		"authors:id < 10",
+		"public.articles: created_at > now()::date - 7",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"public.authors":  "id < 10",
+		"public.articles": "created_at > now()::date - 7",
+	}, where)
+
+	// a condition without table prefix is for all tables
+	where, err = WhereConditions([]string{"authors"}, []string{"created_at::date > '2021-01-01'"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"public.authors": "created_at::date > '2021-01-01'"}, where)
+
+	_, err = WhereConditions([]string{"authors", "articles"}, []string{"id < 10", "authors:id > 1"})
+	assert.Error(t, err)
+}
+
+func TestDriver_ExportData(t *testing.T) {
+	results := [][]hasura.PGRunSQLOutput{
+		{
+			{ResultType: hasura.TuplesOK, Result: [][]string{
+				{"nspname", "relname", "attname"},
+				{"public", "articles", "id"},
+				{"public", "articles", "title"},
+				{"public", "articles", "author_id"},
+				{"public", "authors", "id"},
+				{"public", "authors", "name"},
+			}},
+			{ResultType: hasura.TuplesOK, Result: [][]string{
+				{"nspname", "relname", "nspname", "relname", "array_to_json", "array_to_json"},
+				{"public", "articles", "public", "authors", `["author_id"]`, `["id"]`},
+			}},
+		},
+		{
+			{ResultType: hasura.TuplesOK, Result: [][]string{
+				{"?column?", "array_to_json"},
+				{"0", `["1","Jane"]`},
+				{"1", `["10","Hello",null]`},
+				{"1", `["11","It's me","1"]`},
+			}},
+		},
+	}
+	var queries [][]string
+	d := &Driver{
+		SendBulk: func(args []hasura.RequestBody) (io.Reader, error) {
+			var sqls []string
+			for _, arg := range args {
+				input := arg.Args.(hasura.PGRunSQLInput)
+				assert.True(t, input.ReadOnly)
+				assert.Equal(t, "default", input.Source)
+				sqls = append(sqls, input.SQL)
+			}
+			queries = append(queries, sqls)
+			b, err := json.Marshal(results[len(queries)-1])
+			return strings.NewReader(string(b)), err
+		},
+	}
+	r, err := d.ExportData([]string{"articles", "authors"}, "default", ExportDataOptions{
+		Where:         map[string]string{"public.authors": "id < 100"},
+		Limit:         50,
+		SamplePercent: 10,
+	})
+	require.NoError(t, err)
+	require.Len(t, queries, 2)
+	assert.Contains(t, queries[0][0], `AND a.attgenerated = ''`)
+	// authors are exported before the articles referencing them
+	assert.Equal(t, `WITH "t0" AS (SELECT * FROM "public"."authors" TABLESAMPLE BERNOULLI (10) WHERE (id < 100) LIMIT 50),
+"t1" AS (SELECT * FROM "public"."articles" TABLESAMPLE BERNOULLI (10) WHERE ("author_id" IS NULL OR ("author_id") IN (SELECT "id" FROM "t0")) LIMIT 50)
+SELECT 0, array_to_json(ARRAY["id"::text, "name"::text]::text[])::text FROM "t0"
+UNION ALL
+SELECT 1, array_to_json(ARRAY["id"::text, "title"::text, "author_id"::text]::text[])::text FROM "t1"
+ORDER BY 1`, queries[1][0])
+
+	b, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, `INSERT INTO "public"."authors" ("id", "name") VALUES
+('1', 'Jane');
+INSERT INTO "public"."articles" ("id", "title", "author_id") VALUES
+('10', 'Hello', NULL),
+('11', 'It''s me', '1');
+`, string(b))
+}
+
+func TestDriver_ExportData_WithDependencies(t *testing.T) {
+	results := [][]hasura.PGRunSQLOutput{
+		{
+			{ResultType: hasura.TuplesOK, Result: [][]string{
+				{"nspname", "relname"},
+				{"public", "customers"},
+				{"public", "orders"},
+			}},
+		},
+		{
+			{ResultType: hasura.TuplesOK, Result: [][]string{
+				{"nspname", "relname", "attname"},
+				{"public", "customers", "id"},
+				{"public", "orders", "id"},
+				{"public", "orders", "customer_id"},
+			}},
+			{ResultType: hasura.TuplesOK, Result: [][]string{
+				{"nspname", "relname", "nspname", "relname", "array_to_json", "array_to_json"},
+				{"public", "orders", "public", "customers", `["customer_id"]`, `["id"]`},
+			}},
+		},
+		{
+			{ResultType: hasura.TuplesOK, Result: [][]string{
+				{"?column?", "array_to_json"},
+				{"0", `["7"]`},
+				{"1", `["100","7"]`},
+			}},
+		},
+	}
+	var queries []string
+	d := &Driver{
+		SendBulk: func(args []hasura.RequestBody) (io.Reader, error) {
+			for _, arg := range args {
+				queries = append(queries, arg.Args.(hasura.PGRunSQLInput).SQL)
+			}
+			b, err := json.Marshal(results[0])
+			results = results[1:]
+			return strings.NewReader(string(b)), err
+		},
+	}
+	r, err := d.ExportData([]string{"orders"}, "default", ExportDataOptions{Limit: 10, WithDependencies: true})
+	require.NoError(t, err)
+	require.Len(t, queries, 4)
+	assert.Contains(t, queries[0], `WHERE (n.nspname, c.relname) IN (('public', 'orders'))`)
+	// the orders are selected first, then the customers they reference
+	assert.Equal(t, `WITH "t1" AS (SELECT * FROM "public"."orders" LIMIT 10),
+"t0" AS (SELECT * FROM "public"."customers" WHERE ("id") IN (SELECT "customer_id" FROM "t1"))
+SELECT 0, array_to_json(ARRAY["id"::text]::text[])::text FROM "t0"
+UNION ALL
+SELECT 1, array_to_json(ARRAY["id"::text, "customer_id"::text]::text[])::text FROM "t1"
+ORDER BY 1`, queries[3])
+
+	b, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, `INSERT INTO "public"."customers" ("id") VALUES
+('7');
+INSERT INTO "public"."orders" ("id", "customer_id") VALUES
+('100', '7');
+`, string(b))
+}
+
+func TestExportQuery_WithDependencies(t *testing.T) {
+	users := tableName{"public", "users"}
+	stores := tableName{"public", "stores"}
+	orders := tableName{"public", "orders"}
+	employees := tableName{"public", "employees"}
+	columns := map[tableName][]string{
+		users:     {"id"},
+		stores:    {"id", "owner_id"},
+		orders:    {"id", "store_id", "user_id"},
+		employees: {"id", "manager_id"},
+	}
+	tests := []struct {
+		name        string
+		tables      []tableName
+		requested   map[tableName]bool
+		foreignKeys []foreignKey
+		opts        ExportDataOptions
+		want        string
+		wantErr     string
+	}{
+		{
+			"the rows referenced by the rows of other tables are selected along with the requested rows",
+			[]tableName{users, stores, orders},
+			map[tableName]bool{users: true, orders: true},
+			[]foreignKey{
+				{table: stores, references: users, columns: []string{"owner_id"}, refs: []string{"id"}},
+				{table: orders, references: stores, columns: []string{"store_id"}, refs: []string{"id"}},
+				{table: orders, references: users, columns: []string{"user_id"}, refs: []string{"id"}},
+			},
+			ExportDataOptions{Where: map[string]string{"public.users": "id < 3"}, Limit: 10, WithDependencies: true},
+			`WITH "t2" AS (SELECT * FROM "public"."orders" LIMIT 10),
+"t1" AS (SELECT * FROM "public"."stores" WHERE ("id") IN (SELECT "store_id" FROM "t2")),
+"t0_selected" AS (SELECT * FROM "public"."users" WHERE (id < 3) LIMIT 10),
+"t0" AS (SELECT * FROM "t0_selected" UNION ALL SELECT * FROM "public"."users" x WHERE (("id") IN (SELECT "owner_id" FROM "t1") OR ("id") IN (SELECT "user_id" FROM "t2")) AND NOT EXISTS (SELECT 1 FROM "t0_selected" s WHERE (s."id") = (x."id")))
+SELECT 0, array_to_json(ARRAY["id"::text]::text[])::text FROM "t0"
+UNION ALL
+SELECT 1, array_to_json(ARRAY["id"::text, "owner_id"::text]::text[])::text FROM "t1"
+UNION ALL
+SELECT 2, array_to_json(ARRAY["id"::text, "store_id"::text, "user_id"::text]::text[])::text FROM "t2"
+ORDER BY 1`,
+			"",
+		},
+		{
+			"the rows referenced through a foreign key of the table to itself are selected recursively",
+			[]tableName{employees},
+			map[tableName]bool{employees: true},
+			[]foreignKey{
+				{table: employees, references: employees, columns: []string{"manager_id"}, refs: []string{"id"}},
+			},
+			ExportDataOptions{Where: map[string]string{"public.employees": "id = 1"}, WithDependencies: true},
+			`WITH RECURSIVE "t0_rows" AS (SELECT * FROM "public"."employees" WHERE (id = 1)),
+"t0_keys"("id") AS (SELECT v.* FROM "t0_rows" y CROSS JOIN LATERAL (VALUES (y."manager_id")) v("id") WHERE v."id" IS NOT NULL UNION SELECT v.* FROM "public"."employees" y JOIN "t0_keys" r ON (y."id") = (r."id") CROSS JOIN LATERAL (VALUES (y."manager_id")) v("id") WHERE v."id" IS NOT NULL),
+"t0" AS (SELECT * FROM "t0_rows" UNION ALL SELECT * FROM "public"."employees" x WHERE (x."id") IN (SELECT "id" FROM "t0_keys") AND NOT EXISTS (SELECT 1 FROM "t0_rows" s WHERE (s."id") = (x."id")))
+SELECT 0, array_to_json(ARRAY["id"::text, "manager_id"::text]::text[])::text FROM "t0"
+ORDER BY 1`,
+			"",
+		},
+		{
+			"tables referencing each other are not supported",
+			[]tableName{users, stores},
+			map[tableName]bool{users: true},
+			[]foreignKey{
+				{table: users, references: stores, columns: []string{"store_id"}, refs: []string{"id"}},
+				{table: stores, references: users, columns: []string{"owner_id"}, refs: []string{"id"}},
+			},
+			ExportDataOptions{WithDependencies: true},
+			"",
+			"tables public.users and public.stores reference each other, directly or through other tables, the rows of tables referencing each other cannot be exported with dependencies",
+		},
+		{
+			"tables referencing each other through other tables are not supported",
+			[]tableName{users, stores, orders},
+			map[tableName]bool{orders: true},
+			[]foreignKey{
+				{table: stores, references: users, columns: []string{"owner_id"}, refs: []string{"id"}},
+				{table: orders, references: stores, columns: []string{"store_id"}, refs: []string{"id"}},
+				{table: users, references: orders, columns: []string{"last_order_id"}, refs: []string{"id"}},
+			},
+			ExportDataOptions{WithDependencies: true},
+			"",
+			"tables public.users and public.orders reference each other, directly or through other tables, the rows of tables referencing each other cannot be exported with dependencies",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := exportQuery(tc.tables, tc.requested, columns, tc.foreignKeys, tc.opts)
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestReadAnonymizeConfig(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "anonymize.yaml", []byte("users:\n  email: email\n  phone: phone\nauth.accounts:\n  login: text\n"), 0644))
+	config, err := ReadAnonymizeConfig(fs, "anonymize.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, AnonymizeConfig{
+		"public.users":  {"email": "email", "phone": "phone"},
+		"auth.accounts": {"login": "text"},
+	}, config)
+
+	require.NoError(t, afero.WriteFile(fs, "anonymize.yaml", []byte("users:\n  email: mail\n"), 0644))
+	_, err = ReadAnonymizeConfig(fs, "anonymize.yaml")
+	assert.Error(t, err)
+}
+
+func TestAnonymizer_Fake(t *testing.T) {
+	a := &anonymizer{salt: []byte("salt")}
+	tests := []struct {
+		kind    string
+		value   string
+		pattern string
+	}{
+		{AnonymizeEmail, "jane.doe@company.com", `^[a-z]+\.[0-9a-f]{8}@example\.com$`},
+		{AnonymizeName, "Jane Doe", `^[A-Z][a-z]+ [A-Z][a-z]+$`},
+		{AnonymizeName, "Jane", `^[A-Z][a-z]+$`},
+		{AnonymizePhone, "+1 (555) 123-4567", `^\+[0-9] \([0-9]{3}\) [0-9]{3}-[0-9]{4}$`},
+		{AnonymizeText, "AB-12cd", `^[A-Z]{2}-[0-9]{2}[a-z]{2}$`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.kind, func(t *testing.T) {
+			fake := a.fake(tc.kind, tc.value)
+			assert.Regexp(t, regexp.MustCompile(tc.pattern), fake)
+			assert.NotEqual(t, tc.value, fake)
+			// a value is always replaced by the same fake value
+			assert.Equal(t, fake, a.fake(tc.kind, tc.value))
+		})
+	}
+}
+
+func TestAnonymizedColumns(t *testing.T) {
+	foreignKeys := []foreignKey{
+		{table: tableName{"public", "logins"}, references: tableName{"public", "users"}, columns: []string{"user_email"}, refs: []string{"email"}},
+		{table: tableName{"public", "audit"}, references: tableName{"public", "logins"}, columns: []string{"login_email"}, refs: []string{"user_email"}},
+	}
+	assert.Equal(t, map[tableName]map[string]string{
+		{"public", "users"}:  {"email": AnonymizeEmail},
+		{"public", "logins"}: {"user_email": AnonymizeEmail},
+		{"public", "audit"}:  {"login_email": AnonymizeEmail},
+	}, anonymizedColumns(AnonymizeConfig{"public.audit": {"login_email": AnonymizeEmail}}, foreignKeys))
+}
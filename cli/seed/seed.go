@@ -4,16 +4,20 @@ import (
 	"io"
 
 	"github.com/hasura/graphql-engine/cli/v2/internal/hasura"
+	"github.com/hasura/graphql-engine/cli/v2/internal/statestore"
 )
 
 type sendBulk func([]hasura.RequestBody) (io.Reader, error)
 type Driver struct {
 	SendBulk     sendBulk
 	PGDumpClient hasura.PGDump
+	// StateStore records the applied seed files, they are not tracked if it
+	// is nil
+	StateStore statestore.SeedsStateStore
 }
 
 func NewDriver(s sendBulk, pgDumpClient hasura.PGDump) *Driver {
-	return &Driver{s, pgDumpClient}
+	return &Driver{SendBulk: s, PGDumpClient: pgDumpClient}
 }
 
 func IsSeedsSupported(kind hasura.SourceKind) bool {
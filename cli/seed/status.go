@@ -0,0 +1,115 @@
+package seed
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hasura/graphql-engine/cli/v2"
+	"github.com/spf13/afero"
+)
+
+// ErrSeedsNotTracked is returned for the operations on the applied seed files
+// by a driver which does not track them
+var ErrSeedsNotTracked = errors.New("applied seed files are not tracked, they are tracked in the catalog state from config v3")
+
+const (
+	SeedStatusApplied    = "applied"
+	SeedStatusChanged    = "changed"
+	SeedStatusNotApplied = "not applied"
+	// SeedStatusDirty is the status of a seed file which failed to be
+	// applied or to be recorded as applied
+	SeedStatusDirty = "dirty"
+)
+
+// SeedFileStatus is the state of a seed file of a database
+type SeedFileStatus struct {
+	// Name of the file relative to the seeds directory of the database
+	Name string `json:"name"`
+	// Status is applied, changed if the file changed since it was applied,
+	// dirty or not applied
+	Status    string     `json:"status"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// SeedsStatus returns the state of the seed files of source, sorted by name
+func (d *Driver) SeedsStatus(fs afero.Fs, rootSeedsDirectory string, source cli.Source) ([]SeedFileStatus, error) {
+	if d.StateStore == nil {
+		return nil, ErrSeedsNotTracked
+	}
+	seedsDirectory := seedsDirectoryOf(rootSeedsDirectory, source)
+	var files []seedFile
+	if _, err := fs.Stat(seedsDirectory); !os.IsNotExist(err) {
+		if files, err = readSeedFiles(fs, seedsDirectory, nil); err != nil {
+			return nil, err
+		}
+	}
+	applied, err := d.StateStore.GetSeeds(source.Name)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]SeedFileStatus, 0, len(files))
+	for _, file := range files {
+		status := SeedFileStatus{Name: file.name, Status: SeedStatusNotApplied}
+		if seed, ok := applied[seedName(file.name)]; ok {
+			appliedAt := seed.AppliedAt
+			status.AppliedAt = &appliedAt
+			status.Status = SeedStatusApplied
+			if seed.Dirty {
+				status.Status = SeedStatusDirty
+			} else if seed.Checksum != checksum(file.content) {
+				status.Status = SeedStatusChanged
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses, nil
+}
+
+// PendingSeedFiles returns the seed files of filenames, of all the seed
+// files of source if filenames is empty, which were not applied or changed
+// since they were applied. Dirty seed files are reported as an error.
+func (d *Driver) PendingSeedFiles(fs afero.Fs, rootSeedsDirectory string, filenames []string, source cli.Source) ([]string, error) {
+	if d.StateStore == nil {
+		return nil, ErrSeedsNotTracked
+	}
+	files, err := readSeedFiles(fs, seedsDirectoryOf(rootSeedsDirectory, source), filenames)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := d.StateStore.GetSeeds(source.Name)
+	if err != nil {
+		return nil, err
+	}
+	var pending, dirty []string
+	for _, file := range files {
+		seed, ok := applied[seedName(file.name)]
+		if ok && seed.Dirty {
+			dirty = append(dirty, file.name)
+		} else if !ok || seed.Checksum != checksum(file.content) {
+			pending = append(pending, file.name)
+		}
+	}
+	if len(dirty) > 0 {
+		return nil, fmt.Errorf("seed files %s are dirty, they failed to be applied or to be recorded as applied: check the database and apply them again with --force", strings.Join(dirty, ", "))
+	}
+	return pending, nil
+}
+
+// seedName is the name a seed file is recorded with
+func seedName(name string) string {
+	return filepath.ToSlash(filepath.Clean(name))
+}
+
+// checksum returns the sha256 hash of the contents of a seed file
+func checksum(content []byte) string {
+	h := sha256.Sum256(content)
+	return hex.EncodeToString(h[:])
+}
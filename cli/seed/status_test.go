@@ -0,0 +1,138 @@
+package seed
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hasura/graphql-engine/cli/v2"
+	"github.com/hasura/graphql-engine/cli/v2/internal/hasura"
+	"github.com/hasura/graphql-engine/cli/v2/internal/statestore"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memorySeedsStateStore keeps the applied seed files in memory
+type memorySeedsStateStore map[string]map[string]statestore.AppliedSeed
+
+func (m memorySeedsStateStore) GetSeeds(database string) (map[string]statestore.AppliedSeed, error) {
+	seeds := map[string]statestore.AppliedSeed{}
+	for name, seed := range m[database] {
+		seeds[name] = seed
+	}
+	return seeds, nil
+}
+
+func (m memorySeedsStateStore) SetSeeds(database string, seeds map[string]statestore.AppliedSeed) error {
+	if m[database] == nil {
+		m[database] = map[string]statestore.AppliedSeed{}
+	}
+	for name, seed := range seeds {
+		m[database][name] = seed
+	}
+	return nil
+}
+
+func TestDriver_SeedsStatus(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	source := cli.Source{Name: "default", Kind: hasura.SourceKindPG}
+	write := func(name, content string) {
+		require.NoError(t, afero.WriteFile(fs, filepath.Join("seeds", "default", name), []byte(content), 0644))
+	}
+	write("1_authors.sql", "INSERT INTO authors (name) VALUES ('Jane');")
+	write("public.articles.csv", "id,title\n1,Hello\n")
+
+	var sent []hasura.RequestBody
+	d := &Driver{
+		SendBulk: func(args []hasura.RequestBody) (io.Reader, error) {
+			sent = append(sent, args...)
+			return strings.NewReader(""), nil
+		},
+		StateStore: memorySeedsStateStore{},
+	}
+	pending, err := d.PendingSeedFiles(fs, "seeds", nil, source)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1_authors.sql", "public.articles.csv"}, pending)
+
+	// applied seed files are recorded
+	_, err = d.ApplySeedsToDatabase(fs, "seeds", []string{"1_authors.sql"}, source)
+	require.NoError(t, err)
+	assert.Len(t, sent, 1)
+	statuses, err := d.SeedsStatus(fs, "seeds", source)
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.Equal(t, "1_authors.sql", statuses[0].Name)
+	assert.Equal(t, SeedStatusApplied, statuses[0].Status)
+	assert.NotNil(t, statuses[0].AppliedAt)
+	assert.Equal(t, SeedFileStatus{Name: "public.articles.csv", Status: SeedStatusNotApplied}, statuses[1])
+
+	// a seed file changed since it was applied is pending again
+	write("1_authors.sql", "INSERT INTO authors (name) VALUES ('John');")
+	pending, err = d.PendingSeedFiles(fs, "seeds", nil, source)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1_authors.sql", "public.articles.csv"}, pending)
+	statuses, err = d.SeedsStatus(fs, "seeds", source)
+	require.NoError(t, err)
+	assert.Equal(t, SeedStatusChanged, statuses[0].Status)
+
+	_, err = d.ApplySeedsToDatabase(fs, "seeds", pending, source)
+	require.NoError(t, err)
+	pending, err = d.PendingSeedFiles(fs, "seeds", nil, source)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	_, err = (&Driver{}).SeedsStatus(fs, "seeds", source)
+	assert.Equal(t, ErrSeedsNotTracked, err)
+}
+
+// failingSeedsStateStore fails to record the seed files once they are
+// recorded as dirty
+type failingSeedsStateStore struct {
+	memorySeedsStateStore
+}
+
+func (f failingSeedsStateStore) SetSeeds(database string, seeds map[string]statestore.AppliedSeed) error {
+	for _, seed := range seeds {
+		if !seed.Dirty {
+			return errors.New("catalog state is unreachable")
+		}
+	}
+	return f.memorySeedsStateStore.SetSeeds(database, seeds)
+}
+
+func TestDriver_DirtySeeds(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	source := cli.Source{Name: "default", Kind: hasura.SourceKindPG}
+	require.NoError(t, afero.WriteFile(fs, filepath.Join("seeds", "default", "1_authors.sql"), []byte("INSERT INTO authors (name) VALUES ('Jane');"), 0644))
+
+	var sent []hasura.RequestBody
+	sendBulk := func(args []hasura.RequestBody) (io.Reader, error) {
+		sent = append(sent, args...)
+		return strings.NewReader(""), nil
+	}
+	store := memorySeedsStateStore{}
+	d := &Driver{SendBulk: sendBulk, StateStore: failingSeedsStateStore{store}}
+	_, err := d.ApplySeedsToDatabase(fs, "seeds", nil, source)
+	require.Error(t, err)
+	assert.Len(t, sent, 1)
+
+	// seed files applied but not recorded are not applied again
+	d = &Driver{SendBulk: sendBulk, StateStore: store}
+	_, err = d.PendingSeedFiles(fs, "seeds", nil, source)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "seed files 1_authors.sql are dirty")
+	statuses, err := d.SeedsStatus(fs, "seeds", source)
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+	assert.Equal(t, SeedStatusDirty, statuses[0].Status)
+
+	// seed files applied again are recorded as applied
+	_, err = d.ApplySeedsToDatabase(fs, "seeds", []string{"1_authors.sql"}, source)
+	require.NoError(t, err)
+	pending, err := d.PendingSeedFiles(fs, "seeds", nil, source)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}